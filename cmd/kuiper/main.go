@@ -25,6 +25,7 @@ import (
 	"net/rpc"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -149,7 +150,7 @@ func main() {
 		{
 			Name:    "create",
 			Aliases: []string{"create"},
-			Usage:   "create stream $stream_name | create stream $stream_name -f $stream_def_file | create table $table_name | create table $table_name -f $table_def_file| create rule $rule_name $rule_json | create rule $rule_name -f $rule_def_file | create plugin $plugin_type $plugin_name $plugin_json | create plugin $plugin_type $plugin_name -f $plugin_def_file | create service $service_name $service_json ",
+			Usage:   "create stream $stream_name | create stream $stream_name -f $stream_def_file | create table $table_name | create table $table_name -f $table_def_file| create rule $rule_name $rule_json | create rule $rule_name -f $rule_def_file | create plugin $plugin_type $plugin_name $plugin_json | create plugin $plugin_type $plugin_name -f $plugin_def_file | create service $service_name $service_json | create schema $schema_name $schema_json",
 
 			Subcommands: []cli.Command{
 				{
@@ -334,12 +335,33 @@ func main() {
 						return nil
 					},
 				},
+				{
+					Name:  "schema",
+					Usage: "create schema $schema_name $schema_json",
+					Action: func(c *cli.Context) error {
+						if len(c.Args()) < 2 {
+							fmt.Printf("Expect schema name and json.\n")
+							return nil
+						}
+						var reply string
+						err = client.Call("Server.CreateSchema", &server.RPCArgDesc{
+							Name: c.Args()[0],
+							Json: c.Args()[1],
+						}, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
 			},
 		},
 		{
 			Name:    "describe",
 			Aliases: []string{"describe"},
-			Usage:   "describe stream $stream_name | describe table $table_name | describe rule $rule_name | describe plugin $plugin_type $plugin_name | describe udf $udf_name | describe service $service_name | describe service_func $service_func_name",
+			Usage:   "describe stream $stream_name | describe table $table_name | describe rule $rule_name | describe plugin $plugin_type $plugin_name | describe udf $udf_name | describe service $service_name | describe service_func $service_func_name | describe schema $schema_name [$version]",
 			Subcommands: []cli.Command{
 				{
 					Name:  "stream",
@@ -468,13 +490,40 @@ func main() {
 						return nil
 					},
 				},
+				{
+					Name:  "schema",
+					Usage: "describe schema $schema_name [$version]",
+					Action: func(c *cli.Context) error {
+						if len(c.Args()) != 1 && len(c.Args()) != 2 {
+							fmt.Printf("Expect schema name and optional version.\n")
+							return nil
+						}
+						args := &server.SchemaDesc{Name: c.Args()[0]}
+						if len(c.Args()) == 2 {
+							v, err := strconv.Atoi(c.Args()[1])
+							if err != nil {
+								fmt.Printf("Invalid schema version %s.\n", c.Args()[1])
+								return nil
+							}
+							args.Version = v
+						}
+						var reply string
+						err = client.Call("Server.DescSchema", args, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
 			},
 		},
 
 		{
 			Name:    "drop",
 			Aliases: []string{"drop"},
-			Usage:   "drop stream $stream_name | drop table $table_name |drop rule $rule_name | drop plugin $plugin_type $plugin_name -r $stop | drop service $service_name",
+			Usage:   "drop stream $stream_name | drop table $table_name |drop rule $rule_name | drop plugin $plugin_type $plugin_name -r $stop | drop service $service_name | drop schema $schema_name",
 			Subcommands: []cli.Command{
 				{
 					Name:  "stream",
@@ -576,13 +625,32 @@ func main() {
 						return nil
 					},
 				},
+				{
+					Name:  "schema",
+					Usage: "drop schema $schema_name",
+					Action: func(c *cli.Context) error {
+						if len(c.Args()) != 1 {
+							fmt.Printf("Expect schema name.\n")
+							return nil
+						}
+						name := c.Args()[0]
+						var reply string
+						err = client.Call("Server.DropSchema", name, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
 			},
 		},
 
 		{
 			Name:    "show",
 			Aliases: []string{"show"},
-			Usage:   "show streams | show tables | show rules | show plugins $plugin_type | show services | show service_funcs",
+			Usage:   "show streams | show tables | show rules | show plugins $plugin_type | show services | show service_funcs | show schemas",
 
 			Subcommands: []cli.Command{
 				{
@@ -677,10 +745,64 @@ func main() {
 						}
 						return nil
 					},
+				}, {
+					Name:  "schemas",
+					Usage: "show schemas",
+					Action: func(c *cli.Context) error {
+						var reply string
+						err = client.Call("Server.ShowSchemas", 0, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
 				},
 			},
 		},
 
+		{
+			Name:    "infer",
+			Aliases: []string{"infer"},
+			Usage:   "infer stream [-n $samples] [-t $timeout] $stream_stmt",
+			Subcommands: []cli.Command{
+				{
+					Name:  "stream",
+					Usage: "infer stream [-n $samples] [-t $timeout] $stream_stmt",
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "samples, n",
+							Usage: "the max number of samples to read from the source",
+							Value: 10,
+						},
+						cli.IntFlag{
+							Name:  "timeout, t",
+							Usage: "the max time in milliseconds to wait for the samples",
+							Value: 10000,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						if len(c.Args()) != 1 {
+							fmt.Printf("Expect stream statement.\n")
+							return nil
+						}
+						var reply string
+						err = client.Call("Server.InferStream", &server.InferDesc{
+							Sql:     c.Args()[0],
+							Samples: c.Int("samples"),
+							Timeout: c.Int("timeout"),
+						}, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
+			},
+		},
 		{
 			Name:    "getstatus",
 			Aliases: []string{"getstatus"},
@@ -907,8 +1029,10 @@ func getPluginType(arg string) (ptype int, err error) {
 		ptype = 1
 	case "function":
 		ptype = 2
+	case "format":
+		ptype = 3
 	default:
-		err = fmt.Errorf("Invalid plugin type %s, should be \"source\", \"sink\" or \"function\".\n", arg)
+		err = fmt.Errorf("Invalid plugin type %s, should be \"source\", \"sink\", \"function\" or \"format\".\n", arg)
 	}
 	return
 }
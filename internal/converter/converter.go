@@ -0,0 +1,113 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/converter/delimited"
+	"github.com/lf-edge/ekuiper/internal/converter/protobuf"
+	"github.com/lf-edge/ekuiper/internal/plugin"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"strings"
+)
+
+// The built-in converters keyed by the lower case format name. Formats not found here are looked up in the format plugins.
+var converters = map[string]message.ConverterCreator{
+	message.FormatJson: func(_ map[string]interface{}) (message.Converter, error) {
+		return jsonConverter{}, nil
+	},
+	message.FormatBinary: func(_ map[string]interface{}) (message.Converter, error) {
+		return binaryConverter{}, nil
+	},
+	message.FormatProtobuf: func(props map[string]interface{}) (message.Converter, error) {
+		schemaId, _ := props["schemaId"].(string)
+		if schemaId == "" {
+			return nil, fmt.Errorf("schemaId is required for format %s", message.FormatProtobuf)
+		}
+		return protobuf.NewConverter(schemaId)
+	},
+	message.FormatDelimited: delimited.NewConverter,
+}
+
+// RegisterConverter adds a converter for the format which is compiled in. It must be called in init
+// and the format name must be unique.
+func RegisterConverter(name string, creator message.ConverterCreator) {
+	converters[strings.ToLower(name)] = creator
+}
+
+// IsFormatSupported returns whether the format is a built-in converter or an installed format plugin. The sinks may have
+// their own format property of other meanings such as the image format of the image sink, which must be left untouched.
+func IsFormatSupported(format string) bool {
+	format = strings.ToLower(format)
+	if _, ok := converters[format]; ok {
+		return true
+	}
+	return plugin.HasConverter(format)
+}
+
+// GetOrCreateConverter returns the converter for the format in the props. The props also carry the format specific
+// settings such as the schemaId for protobuf and the delimiter for delimited text.
+func GetOrCreateConverter(props map[string]interface{}) (message.Converter, error) {
+	format := message.FormatJson
+	if f, ok := props["format"]; ok {
+		if fs, ok := f.(string); ok && fs != "" {
+			format = strings.ToLower(fs)
+		}
+	}
+	if creator, ok := converters[format]; ok {
+		return creator(props)
+	}
+	c, err := plugin.GetConverter(format, props)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported format %s: %v", format, err)
+	}
+	return c, nil
+}
+
+type jsonConverter struct{}
+
+func (c jsonConverter) Encode(d interface{}) ([]byte, error) {
+	return json.Marshal(d)
+}
+
+func (c jsonConverter) Decode(b []byte) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	e := json.Unmarshal(b, &result)
+	return result, e
+}
+
+type binaryConverter struct{}
+
+func (c binaryConverter) Encode(d interface{}) ([]byte, error) {
+	switch dt := d.(type) {
+	case []byte:
+		return dt, nil
+	case map[string]interface{}:
+		// Only a single bytea field like the output of the binary stream can be encoded
+		if len(dt) == 1 {
+			for _, v := range dt {
+				if b, ok := v.([]byte); ok {
+					return b, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("cannot encode %v to binary, only a single bytea field is supported", d)
+}
+
+func (c binaryConverter) Decode(b []byte) (map[string]interface{}, error) {
+	return map[string]interface{}{message.DefaultField: b}, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package converter
+
+import (
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConverters(t *testing.T) {
+	tests := []struct {
+		format   string
+		schemaId string
+		input    interface{}
+		encoded  []byte
+		decoded  map[string]interface{}
+		err      string
+	}{
+		{
+			format:  "json",
+			input:   map[string]interface{}{"a": "b", "c": 1.0},
+			encoded: []byte(`{"a":"b","c":1}`),
+			decoded: map[string]interface{}{"a": "b", "c": 1.0},
+		}, {
+			format:  "binary",
+			input:   map[string]interface{}{"self": []byte("hello")},
+			encoded: []byte("hello"),
+			decoded: map[string]interface{}{"self": []byte("hello")},
+		}, {
+			format:   "protobuf",
+			schemaId: "test1.Address",
+			input:    map[string]interface{}{"city": "test"},
+			encoded:  []byte{0x0a, 0x04, 0x74, 0x65, 0x73, 0x74},
+			decoded:  map[string]interface{}{"city": "test", "street": ""},
+		}, {
+			format: "protobuf",
+			err:    "schemaId is required for format protobuf",
+		}, {
+			format:  "delimited",
+			input:   map[string]interface{}{"a": "b", "c": 1.0},
+			encoded: []byte(`b,1`),
+			decoded: map[string]interface{}{"col0": "b", "col1": "1"},
+		}, {
+			format: "xml",
+			err:    "unsupported format xml: ",
+		},
+	}
+	for i, tt := range tests {
+		c, err := GetOrCreateConverter(map[string]interface{}{"format": tt.format, "schemaId": tt.schemaId})
+		if (tt.err == "" && err != nil) || !strings.HasPrefix(testx.Errstring(err), tt.err) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		b, err := c.Encode(tt.input)
+		if err != nil {
+			t.Errorf("%d: encode error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.encoded, b) {
+			t.Errorf("%d: encode mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.encoded, b)
+		}
+		r, err := c.Decode(b)
+		if err != nil {
+			t.Errorf("%d: decode error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.decoded, r) {
+			t.Errorf("%d: decode mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.decoded, r)
+		}
+	}
+}
+
+type upperConverter struct{}
+
+func (c upperConverter) Encode(d interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(d.(map[string]interface{})["a"].(string))), nil
+}
+
+func (c upperConverter) Decode(b []byte) (map[string]interface{}, error) {
+	return map[string]interface{}{"a": strings.ToLower(string(b))}, nil
+}
+
+func TestRegisterConverter(t *testing.T) {
+	RegisterConverter("Upper", func(_ map[string]interface{}) (message.Converter, error) {
+		return upperConverter{}, nil
+	})
+	c, err := GetOrCreateConverter(map[string]interface{}{"format": "upper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.Encode(map[string]interface{}{"a": "hello"})
+	if err != nil || string(b) != "HELLO" {
+		t.Errorf("encode mismatch, got %s, %v", b, err)
+	}
+	r, err := c.Decode([]byte("WORLD"))
+	if err != nil || !reflect.DeepEqual(map[string]interface{}{"a": "world"}, r) {
+		t.Errorf("decode mismatch, got %v, %v", r, err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delimited
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultDelimiter = ','
+
+type Converter struct {
+	delimiter rune
+	// Whether the payload has a header line of the column names
+	hasHeader bool
+	// The column names in order, usually the stream fields
+	fields []string
+}
+
+// NewConverter creates the delimited text converter with the properties:
+//   - delimiter: the single character separator of the columns, default to comma
+//   - hasHeader: whether the first line of the payload is the column names
+//   - fields: the column names in order. If not set, the columns are named col0, col1...
+func NewConverter(props map[string]interface{}) (message.Converter, error) {
+	c := &Converter{
+		delimiter: defaultDelimiter,
+	}
+	if d, ok := props["delimiter"]; ok {
+		if ds, ok := d.(string); ok && utf8.RuneCountInString(ds) == 1 && !strings.ContainsAny(ds, "\"\r\n") {
+			c.delimiter, _ = utf8.DecodeRuneInString(ds)
+		} else {
+			return nil, fmt.Errorf("invalid delimiter %v, must be a single character", d)
+		}
+	}
+	if h, ok := props["hasHeader"]; ok {
+		hb, err := cast.ToBool(h, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hasHeader %v, must be a bool", h)
+		}
+		c.hasHeader = hb
+	}
+	if f, ok := props["fields"]; ok {
+		fs, err := cast.ToStringSlice(f, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fields %v, must be a string array", f)
+		}
+		c.fields = fs
+	}
+	return c, nil
+}
+
+// Encode converts a map into a line of the values. The columns are in the order of the fields or the sorted keys if fields are not set.
+// If hasHeader is true, a header line of the column names is prepended. The values which contain the delimiter, quotes or
+// line breaks are quoted.
+func (c *Converter) Encode(d interface{}) ([]byte, error) {
+	m, ok := d.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %v, must be a map", d)
+	}
+	cols := c.fields
+	if len(cols) == 0 {
+		cols = make([]string, 0, len(m))
+		for k := range m {
+			cols = append(cols, k)
+		}
+		sort.Strings(cols)
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = c.delimiter
+	if c.hasHeader {
+		if err := w.Write(cols); err != nil {
+			return nil, err
+		}
+	}
+	values := make([]string, len(cols))
+	for i, k := range cols {
+		if v, ok := m[k]; ok && v != nil {
+			values[i] = cast.ToStringAlways(v)
+		}
+	}
+	if err := w.Write(values); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// Decode splits a line into columns. All the values are strings which will be converted by the stream schema.
+// A message can only contain one data line, but a quoted value can contain line breaks.
+func (c *Converter) Decode(b []byte) (map[string]interface{}, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comma = c.delimiter
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	cols := c.fields
+	if c.hasHeader {
+		if len(records) < 2 {
+			return nil, fmt.Errorf("no data line found after the header")
+		}
+		cols = records[0]
+		records = records[1:]
+	}
+	switch len(records) {
+	case 0:
+		return nil, fmt.Errorf("no data line found")
+	case 1:
+	default:
+		return nil, fmt.Errorf("found %d data lines, only one line is allowed in a message", len(records))
+	}
+	values := records[0]
+	result := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		if i < len(cols) {
+			result[cols[i]] = v
+		} else {
+			result[fmt.Sprintf("col%d", i)] = v
+		}
+	}
+	return result, nil
+}
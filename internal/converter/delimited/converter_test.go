@@ -0,0 +1,148 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delimited
+
+import (
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"reflect"
+	"testing"
+)
+
+func TestNewConverter(t *testing.T) {
+	tests := []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{},
+		}, {
+			props: map[string]interface{}{"delimiter": "|", "hasHeader": true, "fields": []interface{}{"a", "b"}},
+		}, {
+			props: map[string]interface{}{"delimiter": ""},
+			err:   "invalid delimiter , must be a single character",
+		}, {
+			props: map[string]interface{}{"delimiter": "||"},
+			err:   "invalid delimiter ||, must be a single character",
+		}, {
+			props: map[string]interface{}{"hasHeader": "yes"},
+			err:   "invalid hasHeader yes, must be a bool",
+		}, {
+			props: map[string]interface{}{"fields": "a"},
+			err:   "invalid fields a, must be a string array",
+		},
+	}
+	for i, tt := range tests {
+		_, err := NewConverter(tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		props   map[string]interface{}
+		payload []byte
+		result  map[string]interface{}
+		err     string
+	}{
+		{
+			props:   map[string]interface{}{"fields": []string{"temperature", "humidity", "status"}},
+			payload: []byte("23.4,55,OK"),
+			result:  map[string]interface{}{"temperature": "23.4", "humidity": "55", "status": "OK"},
+		}, {
+			props:   map[string]interface{}{"fields": []string{"temperature", "humidity"}},
+			payload: []byte("23.4,55,OK\r\n"),
+			result:  map[string]interface{}{"temperature": "23.4", "humidity": "55", "col2": "OK"},
+		}, {
+			props:   map[string]interface{}{},
+			payload: []byte("23.4,,OK"),
+			result:  map[string]interface{}{"col0": "23.4", "col1": "", "col2": "OK"},
+		}, {
+			props:   map[string]interface{}{"delimiter": "|", "hasHeader": true},
+			payload: []byte("t|h\n23.4|55"),
+			result:  map[string]interface{}{"t": "23.4", "h": "55"},
+		}, {
+			props:   map[string]interface{}{"fields": []string{"id", "msg"}},
+			payload: []byte("1,\"a,\"\"b\"\"\nc\""),
+			result:  map[string]interface{}{"id": "1", "msg": "a,\"b\"\nc"},
+		}, {
+			props:   map[string]interface{}{},
+			payload: []byte("1,2\n3,4"),
+			err:     "found 2 data lines, only one line is allowed in a message",
+		}, {
+			props:   map[string]interface{}{"hasHeader": true},
+			payload: []byte("t,h"),
+			err:     "no data line found after the header",
+		},
+	}
+	for i, tt := range tests {
+		c, err := NewConverter(tt.props)
+		if err != nil {
+			t.Errorf("%d: create converter error %v", i, err)
+			continue
+		}
+		r, err := c.Decode(tt.payload)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		} else if !reflect.DeepEqual(tt.result, r) {
+			t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.result, r)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		props  map[string]interface{}
+		input  interface{}
+		result []byte
+		err    string
+	}{
+		{
+			props:  map[string]interface{}{"fields": []string{"temperature", "humidity", "status"}},
+			input:  map[string]interface{}{"temperature": 23.4, "humidity": 55, "status": "OK"},
+			result: []byte("23.4,55,OK"),
+		}, {
+			props:  map[string]interface{}{"fields": []string{"temperature", "humidity", "status"}},
+			input:  map[string]interface{}{"temperature": 23.4, "status": nil},
+			result: []byte("23.4,,"),
+		}, {
+			props:  map[string]interface{}{"delimiter": ";", "hasHeader": true},
+			input:  map[string]interface{}{"b": true, "a": "x"},
+			result: []byte("a;b\nx;true"),
+		}, {
+			props:  map[string]interface{}{"fields": []string{"id", "msg"}},
+			input:  map[string]interface{}{"id": 1, "msg": "a,\"b\"\nc"},
+			result: []byte("1,\"a,\"\"b\"\"\nc\""),
+		}, {
+			props: map[string]interface{}{},
+			input: []interface{}{1, 2},
+			err:   "unsupported type [1 2], must be a map",
+		},
+	}
+	for i, tt := range tests {
+		c, err := NewConverter(tt.props)
+		if err != nil {
+			t.Errorf("%d: create converter error %v", i, err)
+			continue
+		}
+		r, err := c.Encode(tt.input)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		} else if !reflect.DeepEqual(tt.result, r) {
+			t.Errorf("%d: result mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.result, r)
+		}
+	}
+}
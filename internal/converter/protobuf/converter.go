@@ -0,0 +1,151 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protobuf
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"path"
+	"strings"
+	"sync"
+)
+
+type Converter struct {
+	descriptor *desc.MessageDescriptor
+	fc         *FieldConverter
+}
+
+var ( // Do not call these directly, use the get methods
+	protoParser *protoparse.Parser
+	schemaDir   string
+	parserOnce  sync.Once
+	// A buffer of the parsed message descriptors keyed by schema id
+	descriptors = &sync.Map{}
+)
+
+// RegistryDir is the sub folder of the schema folder to save the protobuf files of the schema registry
+const RegistryDir = "registry"
+
+func getParser() *protoparse.Parser {
+	parserOnce.Do(func() {
+		if conf.IsTesting {
+			schemaDir, _ = conf.GetLoc("converter/protobuf/test/schemas/")
+		} else if etcDir, err := conf.GetConfLoc(); err == nil {
+			schemaDir = path.Join(etcDir, "schemas", "protobuf")
+		}
+		// The files managed by the schema registry are kept in a sub folder so that they never overwrite the user files
+		protoParser = &protoparse.Parser{ImportPaths: []string{schemaDir, path.Join(schemaDir, RegistryDir)}}
+	})
+	return protoParser
+}
+
+// GetSchemaDir returns the folder of the protobuf schema files managed by the users
+func GetSchemaDir() string {
+	getParser()
+	return schemaDir
+}
+
+// GetRegistryDir returns the folder of the protobuf schema files managed by the schema registry
+func GetRegistryDir() string {
+	return path.Join(GetSchemaDir(), RegistryDir)
+}
+
+// Invalidate removes the cached descriptors of the schema file so that the changed file will be parsed again
+func Invalidate(fileName string) {
+	descriptors.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), fileName+".") {
+			descriptors.Delete(key)
+		}
+		return true
+	})
+}
+
+// NewConverter creates the protobuf converter for the schema id in the form of fileName.messageName
+// such as schema1.Person which refers to the message Person in etc/schemas/protobuf/schema1.proto
+func NewConverter(schemaId string) (message.Converter, error) {
+	md, err := GetMessageDescriptor(schemaId)
+	if err != nil {
+		return nil, err
+	}
+	return &Converter{
+		descriptor: md,
+		fc:         GetFieldConverter(),
+	}, nil
+}
+
+// GetMessageDescriptor finds the message descriptor by the schema id in the form of fileName.messageName
+func GetMessageDescriptor(schemaId string) (*desc.MessageDescriptor, error) {
+	if v, ok := descriptors.Load(schemaId); ok {
+		return v.(*desc.MessageDescriptor), nil
+	}
+	r := strings.SplitN(schemaId, ".", 2)
+	if len(r) != 2 || r[0] == "" || r[1] == "" {
+		return nil, fmt.Errorf("invalid schemaId %s for protobuf, must be in the form of fileName.messageName", schemaId)
+	}
+	fds, err := getParser().ParseFiles(r[0] + ".proto")
+	if err != nil {
+		return nil, fmt.Errorf("parse schema file %s.proto error: %v", r[0], err)
+	}
+	md := fds[0].FindMessage(r[1])
+	if md == nil {
+		if p := fds[0].GetPackage(); p != "" {
+			md = fds[0].FindMessage(p + "." + r[1])
+		}
+	}
+	if md == nil {
+		return nil, fmt.Errorf("message type %s not found in schema file %s.proto", r[1], r[0])
+	}
+	descriptors.Store(schemaId, md)
+	return md, nil
+}
+
+// Encode converts a map into the protobuf binary. The fields absent in the map are left unset.
+func (c *Converter) Encode(d interface{}) ([]byte, error) {
+	m, ok := d.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %v, must be a map", d)
+	}
+	msg := c.fc.mf.NewDynamicMessage(c.descriptor)
+	for _, field := range c.descriptor.GetFields() {
+		v, ok := m[field.GetName()]
+		if !ok || v == nil {
+			continue
+		}
+		fv, err := c.fc.EncodeField(field, v)
+		if err != nil {
+			return nil, err
+		}
+		if err := msg.TrySetField(field, fv); err != nil {
+			return nil, fmt.Errorf("set field %s error: %v", field.GetName(), err)
+		}
+	}
+	return msg.Marshal()
+}
+
+// Decode converts the protobuf binary into a map keyed by the field names
+func (c *Converter) Decode(b []byte) (map[string]interface{}, error) {
+	result := c.fc.mf.NewDynamicMessage(c.descriptor)
+	if err := proto.Unmarshal(b, result); err != nil {
+		return nil, err
+	}
+	if m, ok := c.fc.DecodeMessage(result, c.descriptor).(map[string]interface{}); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("message %s cannot be decoded to a map", c.descriptor.GetFullyQualifiedName())
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protobuf
+
+import (
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewConverter(t *testing.T) {
+	tests := []struct {
+		schemaId string
+		err      string
+	}{
+		{
+			schemaId: "test1.Person",
+		}, {
+			schemaId: "test1",
+			err:      "invalid schemaId test1 for protobuf, must be in the form of fileName.messageName",
+		}, {
+			schemaId: "test1.Animal",
+			err:      "message type Animal not found in schema file test1.proto",
+		}, {
+			schemaId: "notexist.Person",
+			err:      "parse schema file notexist.proto error: open ",
+		},
+	}
+	for i, tt := range tests {
+		_, err := NewConverter(tt.schemaId)
+		if (tt.err == "" && err != nil) || !strings.HasPrefix(testx.Errstring(err), tt.err) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	c, err := NewConverter("test1.Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input  map[string]interface{}
+		result map[string]interface{}
+		err    string
+	}{
+		{
+			input: map[string]interface{}{
+				"name":  "test",
+				"id":    int64(1),
+				"email": "Dddd",
+				"tags":  []interface{}{"a", "b"},
+				"address": map[string]interface{}{
+					"city":   "Shanghai",
+					"street": "Nanjing Rd",
+				},
+				"score": 89.5,
+				"vip":   true,
+			},
+			result: map[string]interface{}{
+				"name":  "test",
+				"id":    int64(1),
+				"email": "Dddd",
+				"tags":  []string{"a", "b"},
+				"address": map[string]interface{}{
+					"city":   "Shanghai",
+					"street": "Nanjing Rd",
+				},
+				"score": 89.5,
+				"vip":   true,
+			},
+		}, {
+			input: map[string]interface{}{
+				"name":       "test",
+				"id":         2,
+				"notInProto": "ignored",
+			},
+			result: map[string]interface{}{
+				"name":    "test",
+				"id":      int64(2),
+				"email":   "",
+				"tags":    []string(nil),
+				"address": nil,
+				"score":   0.0,
+				"vip":     false,
+			},
+		}, {
+			input: map[string]interface{}{
+				"name": "test",
+				"id":   "abc",
+			},
+			err: "invalid type for int type field 'id': cannot convert string(abc) to int64",
+		},
+	}
+	for i, tt := range tests {
+		b, err := c.Encode(tt.input)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: encode error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		r, err := c.Decode(b)
+		if err != nil {
+			t.Errorf("%d: decode error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.result, r) {
+			t.Errorf("%d: result mismatch:\n  exp=%#v\n  got=%#v\n\n", i, tt.result, r)
+		}
+	}
+}
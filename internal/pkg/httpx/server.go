@@ -0,0 +1,191 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpx
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// server is the http server of an address which dispatches the requests to the handlers by path.
+// It is shared by the sources and sinks which serve on the same address like http push and websocket.
+type server struct {
+	sync.RWMutex
+	address  string
+	certPath string
+	keyPath  string
+	server   *http.Server
+	handlers map[string]http.Handler
+}
+
+var (
+	servers  = make(map[string]*server)
+	serverMu sync.Mutex
+)
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.RLock()
+	h, ok := s.handlers[r.URL.Path]
+	s.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+// CheckOrigin returns the function to check the origin of the websocket handshake requests. The requests without origin
+// header or from the allowed origins are accepted, and * allows any origin.
+func CheckOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, o := range allowedOrigins {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Auth is the authentication of the requests to the http servers started by the sources and sinks
+type Auth struct {
+	// The basic auth username and password
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// The bearer token of the Authorization header
+	Token string `json:"token"`
+}
+
+func (a *Auth) Validate() error {
+	if a.Token != "" && a.Username != "" {
+		return errors.New("only one of basic auth and bearer token can be set")
+	}
+	return nil
+}
+
+// Authorized checks the basic auth or the bearer token of the request. All the requests are authorized if not set.
+func (a *Auth) Authorized(r *http.Request) bool {
+	switch {
+	case a.Token != "":
+		auth := r.Header.Get("Authorization")
+		return subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+a.Token)) == 1
+	case a.Username != "":
+		u, p, ok := r.BasicAuth()
+		return ok && subtle.ConstantTimeCompare([]byte(u), []byte(a.Username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(p), []byte(a.Password)) == 1
+	default:
+		return true
+	}
+}
+
+// Unauthorized responds the challenge of the authentication with status 401
+func (a *Auth) Unauthorized(w http.ResponseWriter) {
+	if a.Token != "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	} else {
+		w.Header().Set("WWW-Authenticate", `Basic realm="ekuiper"`)
+	}
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+// RegisterHandler serves the handler at the path of the server listening to the address. The server is started by the
+// first handler of the address. If the certification and private key are set, the server serves https and all the
+// handlers of the address must have the same tls settings.
+func RegisterHandler(logger api.Logger, address string, certPath string, keyPath string, path string, h http.Handler) error {
+	serverMu.Lock()
+	defer serverMu.Unlock()
+	s, ok := servers[address]
+	if ok {
+		if s.certPath != certPath || s.keyPath != keyPath {
+			return fmt.Errorf("http server %s is already started with different tls settings", address)
+		}
+		s.Lock()
+		defer s.Unlock()
+		if _, ok := s.handlers[path]; ok {
+			return fmt.Errorf("http path %s of %s is already used", path, address)
+		}
+		s.handlers[path] = h
+		return nil
+	}
+	s = &server{
+		address:  address,
+		certPath: certPath,
+		keyPath:  keyPath,
+		handlers: map[string]http.Handler{path: h},
+	}
+	s.server = &http.Server{Handler: s}
+	if certPath != "" {
+		cp, err := conf.ProcessPath(certPath)
+		if err != nil {
+			return err
+		}
+		kp, err := conf.ProcessPath(keyPath)
+		if err != nil {
+			return err
+		}
+		cer, err := tls.LoadX509KeyPair(cp, kp)
+		if err != nil {
+			return fmt.Errorf("load http server certification error: %v", err)
+		}
+		s.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cer}}
+	}
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("http server listens to %s error: %v", address, err)
+	}
+	go func() {
+		var err error
+		if s.server.TLSConfig != nil {
+			err = s.server.ServeTLS(ln, "", "")
+		} else {
+			err = s.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Errorf("http server %s stops with error: %v", address, err)
+		}
+	}()
+	servers[address] = s
+	return nil
+}
+
+// UnregisterHandler removes the handler of the path and stops the server if no path is served
+func UnregisterHandler(address string, path string) {
+	serverMu.Lock()
+	defer serverMu.Unlock()
+	s, ok := servers[address]
+	if !ok {
+		return
+	}
+	s.Lock()
+	delete(s.handlers, path)
+	n := len(s.handlers)
+	s.Unlock()
+	if n == 0 {
+		_ = s.server.Close()
+		delete(servers, address)
+	}
+}
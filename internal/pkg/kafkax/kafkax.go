@@ -0,0 +1,153 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kafkax holds the connection settings of the kafka brokers shared by the kafka source and sink.
+package kafkax
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"strings"
+	"time"
+)
+
+const (
+	SaslNone        = "none"
+	SaslPlain       = "plain"
+	SaslScramSha256 = "scram-sha-256"
+	SaslScramSha512 = "scram-sha-512"
+)
+
+// ConnConfig is the connection settings of the kafka brokers
+type ConnConfig struct {
+	// The comma separated broker addresses such as 127.0.0.1:9092,127.0.0.1:9093
+	Brokers            string `json:"brokers"`
+	SaslAuthType       string `json:"saslAuthType"`
+	SaslUserName       string `json:"saslUserName"`
+	SaslPassword       string `json:"saslPassword"`
+	Tls                bool   `json:"tls"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+	CertificationPath  string `json:"certificationPath"`
+	PrivateKeyPath     string `json:"privateKeyPath"`
+}
+
+// Validate checks the settings and fills the defaults
+func (c *ConnConfig) Validate() error {
+	if len(c.BrokerList()) == 0 {
+		return errors.New("missing property brokers")
+	}
+	if c.SaslAuthType == "" {
+		c.SaslAuthType = SaslNone
+	}
+	switch c.SaslAuthType {
+	case SaslNone:
+	case SaslPlain, SaslScramSha256, SaslScramSha512:
+		if c.SaslUserName == "" || c.SaslPassword == "" {
+			return fmt.Errorf("saslUserName and saslPassword are required for sasl auth type %s", c.SaslAuthType)
+		}
+	default:
+		return fmt.Errorf("invalid saslAuthType %s, must be none, plain, scram-sha-256 or scram-sha-512", c.SaslAuthType)
+	}
+	if (c.CertificationPath == "") != (c.PrivateKeyPath == "") {
+		return errors.New("certificationPath and privateKeyPath must be set together")
+	}
+	return nil
+}
+
+// BrokerList returns the addresses of the brokers
+func (c *ConnConfig) BrokerList() []string {
+	var result []string
+	for _, b := range strings.Split(c.Brokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+func (c *ConnConfig) mechanism() (sasl.Mechanism, error) {
+	switch c.SaslAuthType {
+	case SaslPlain:
+		return plain.Mechanism{Username: c.SaslUserName, Password: c.SaslPassword}, nil
+	case SaslScramSha256:
+		return scram.Mechanism(scram.SHA256, c.SaslUserName, c.SaslPassword)
+	case SaslScramSha512:
+		return scram.Mechanism(scram.SHA512, c.SaslUserName, c.SaslPassword)
+	default:
+		return nil, nil
+	}
+}
+
+func (c *ConnConfig) tlsConfig() (*tls.Config, error) {
+	if !c.Tls {
+		return nil, nil
+	}
+	t := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CertificationPath != "" {
+		cp, err := conf.ProcessPath(c.CertificationPath)
+		if err != nil {
+			return nil, err
+		}
+		kp, err := conf.ProcessPath(c.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		cer, err := tls.LoadX509KeyPair(cp, kp)
+		if err != nil {
+			return nil, err
+		}
+		t.Certificates = []tls.Certificate{cer}
+	}
+	return t, nil
+}
+
+// Dialer returns the dialer for the reader
+func (c *ConnConfig) Dialer() (*kafka.Dialer, error) {
+	m, err := c.mechanism()
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		SASLMechanism: m,
+		TLS:           t,
+	}, nil
+}
+
+// Transport returns the transport for the writer and the client
+func (c *ConnConfig) Transport() (*kafka.Transport, error) {
+	m, err := c.mechanism()
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Transport{
+		SASL: m,
+		TLS:  t,
+	}, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkax
+
+import (
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		c       ConnConfig
+		brokers []string
+		err     string
+	}{
+		{
+			c:   ConnConfig{Brokers: " , "},
+			err: "missing property brokers",
+		}, {
+			c:   ConnConfig{Brokers: "localhost:9092", SaslAuthType: SaslScramSha256, SaslUserName: "u"},
+			err: "saslUserName and saslPassword are required for sasl auth type scram-sha-256",
+		}, {
+			c:   ConnConfig{Brokers: "localhost:9092", SaslAuthType: "gssapi"},
+			err: "invalid saslAuthType gssapi, must be none, plain, scram-sha-256 or scram-sha-512",
+		}, {
+			c:   ConnConfig{Brokers: "localhost:9092", PrivateKeyPath: "key.pem"},
+			err: "certificationPath and privateKeyPath must be set together",
+		}, {
+			c:       ConnConfig{Brokers: "localhost:9092, localhost:9093,"},
+			brokers: []string{"localhost:9092", "localhost:9093"},
+		}, {
+			c:       ConnConfig{Brokers: "localhost:9092", SaslAuthType: SaslPlain, SaslUserName: "u", SaslPassword: "p"},
+			brokers: []string{"localhost:9092"},
+		},
+	}
+	for i, tt := range tests {
+		err := tt.c.Validate()
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(tt.brokers, tt.c.BrokerList()) {
+			t.Errorf("%d: brokers mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.brokers, tt.c.BrokerList())
+		}
+	}
+}
+
+func TestMechanism(t *testing.T) {
+	var tests = []struct {
+		auth string
+		name string
+	}{
+		{auth: SaslNone, name: ""},
+		{auth: SaslPlain, name: "PLAIN"},
+		{auth: SaslScramSha256, name: "SCRAM-SHA-256"},
+		{auth: SaslScramSha512, name: "SCRAM-SHA-512"},
+	}
+	for i, tt := range tests {
+		c := &ConnConfig{Brokers: "localhost:9092", SaslAuthType: tt.auth, SaslUserName: "u", SaslPassword: "p"}
+		d, err := c.Dialer()
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		name := ""
+		if d.SASLMechanism != nil {
+			name = d.SASLMechanism.Name()
+		}
+		if name != tt.name {
+			t.Errorf("%d: mechanism mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.name, name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mqttx holds the MQTT 5 client shared by the mqtt source and sink.
+package mqttx
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"net/url"
+	"time"
+)
+
+// connectTimeout is the time to wait for the first connection
+const connectTimeout = 10 * time.Second
+
+// V5Config is the connection settings of a MQTT 5 client
+type V5Config struct {
+	Server   string
+	ClientId string
+	Username string
+	Password string
+	// The TLS settings used by the ssl, tls, mqtts and wss servers
+	TLS *tls.Config
+	// Called in a goroutine after each connection including the reconnections
+	OnConnectionUp func(cm *autopaho.ConnectionManager, ca *paho.Connack)
+	// Called when the connection is lost, before reconnecting
+	OnConnectionLost func()
+	// Handle the received messages, only needed by the subscribers
+	OnPublish func(p *paho.Publish)
+}
+
+// LoadTLS loads the certification and the private key. It returns nil if neither of them is set.
+func LoadTLS(certPath, keyPath string, insecureSkipVerify bool) (*tls.Config, error) {
+	if certPath == "" && keyPath == "" {
+		return nil, nil
+	}
+	cp, err := conf.ProcessPath(certPath)
+	if err != nil {
+		return nil, err
+	}
+	kp, err := conf.ProcessPath(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	cer, err := tls.LoadX509KeyPair(cp, kp)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cer}, InsecureSkipVerify: insecureSkipVerify}, nil
+}
+
+// ConnectV5 connects to the server and waits for the first connection. The connection keeps reconnecting
+// until it is disconnected or the ctx is done.
+func ConnectV5(ctx context.Context, c *V5Config, logger api.Logger) (*autopaho.ConnectionManager, error) {
+	u, err := url.Parse(c.Server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server %s: %v", c.Server, err)
+	}
+	cfg := autopaho.ClientConfig{
+		BrokerUrls: []*url.URL{u},
+		TlsCfg:     c.TLS,
+		KeepAlive:  30,
+		// Retry soon as the v3 client does
+		ConnectRetryDelay: 5 * time.Second,
+		ConnectTimeout:    connectTimeout,
+		OnConnectionUp: func(cm *autopaho.ConnectionManager, ca *paho.Connack) {
+			logger.Infof("The connection to server %s is established by client %s", c.Server, c.ClientId)
+			if c.OnConnectionUp != nil {
+				c.OnConnectionUp(cm, ca)
+			}
+		},
+		OnConnectError: func(err error) {
+			logger.Errorf("The connection to server %s fails with error %v, will try to re-connect later.", c.Server, err)
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: c.ClientId,
+			// Shared by all the connections of the manager one by one
+			PingHandler: &pinger{},
+			OnClientError: func(err error) {
+				logger.Errorf("The connection %s is disconnected due to error %v, will try to re-connect later.", c.Server+": "+c.ClientId, err)
+				if c.OnConnectionLost != nil {
+					c.OnConnectionLost()
+				}
+			},
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				logger.Errorf("The connection %s is disconnected by the server with reason code %d, will try to re-connect later.", c.Server+": "+c.ClientId, d.ReasonCode)
+				if c.OnConnectionLost != nil {
+					c.OnConnectionLost()
+				}
+			},
+		},
+	}
+	if c.OnPublish != nil {
+		cfg.Router = paho.NewSingleHandlerRouter(c.OnPublish)
+	}
+	if c.Username != "" || c.Password != "" {
+		cfg.SetUsernamePassword(c.Username, []byte(c.Password))
+	}
+	cm, err := autopaho.NewConnection(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	wctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	if err := cm.AwaitConnection(wctx); err != nil {
+		_ = cm.Disconnect(context.Background())
+		return nil, fmt.Errorf("found error when connecting to %s: %v", c.Server, err)
+	}
+	return cm, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqttx
+
+import (
+	"context"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/mqttx/mqttxtest"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadTLS(t *testing.T) {
+	c, err := LoadTLS("", "", false)
+	if c != nil || err != nil {
+		t.Errorf("expect no tls, got %v, %v", c, err)
+	}
+	_, err = LoadTLS("not_exist.pem", "not_exist.key", false)
+	if err == nil {
+		t.Errorf("expect error for the missing files")
+	}
+}
+
+func TestConnectV5(t *testing.T) {
+	b, err := mqttxtest.NewBroker(t, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	var tests = []struct {
+		server string
+		err    string
+	}{
+		{
+			server: "tcp://127.0.0.1:1883\n",
+			err:    "invalid server tcp://127.0.0.1:1883\n: parse \"tcp://127.0.0.1:1883\\n\": net/url: invalid control character in URL",
+		}, {
+			server: b.URL(),
+		},
+	}
+	for i, tt := range tests {
+		up := make(chan struct{}, 1)
+		cm, err := ConnectV5(context.Background(), &V5Config{Server: tt.server, ClientId: "test", OnConnectionUp: func(cm *autopaho.ConnectionManager, ca *paho.Connack) {
+			up <- struct{}{}
+		}}, conf.Log)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+		if cm == nil {
+			continue
+		}
+		select {
+		case <-up:
+		case <-time.After(time.Second):
+			t.Errorf("%d: connection up is not called", i)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if err := cm.Disconnect(ctx); err != nil {
+			t.Errorf("%d: disconnect error %v", i, err)
+		}
+		cancel()
+	}
+}
+
+func TestPingerStopFirst(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	p := &pinger{}
+	p.Stop()
+	done := make(chan struct{})
+	go func() {
+		p.Start(c1, time.Second)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("pinger does not stop")
+	}
+	// The next connection starts normally
+	done = make(chan struct{})
+	go func() {
+		p.Start(c1, time.Second)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Errorf("pinger stops without stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+	p.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("pinger does not stop")
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mqttxtest provides a minimal in-process MQTT 5 broker for the tests of the mqtt source and sink.
+package mqttxtest
+
+import (
+	"errors"
+	"github.com/eclipse/paho.golang/packets"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Broker is a MQTT 5 broker which supports the QoS 0, 1 and 2 publishing, the topic aliases, the wildcard
+// subscriptions and the shared subscriptions. The messages are always delivered to the subscribers with QoS 0.
+type Broker struct {
+	// Received holds all the messages published by the clients. Their topic aliases are resolved.
+	Received chan *packets.Publish
+
+	t        testing.TB
+	ln       net.Listener
+	aliasMax uint16
+	// The goroutines serving the listener and the connections
+	wg sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	subs   map[*client][]string
+	groups map[string]int
+	// The count of the received messages which have the topic alias only
+	aliasOnly int
+}
+
+type client struct {
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+func (c *client) write(p packets.Packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = p.WriteTo(c.conn)
+}
+
+// NewBroker starts a broker on a random local port. The clients can use the topic aliases up to aliasMax.
+// The errors of the clients are logged to the test.
+func NewBroker(t testing.TB, aliasMax uint16) (*Broker, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	b := &Broker{
+		Received: make(chan *packets.Publish, 1024),
+		t:        t,
+		ln:       ln,
+		aliasMax: aliasMax,
+		subs:     make(map[*client][]string),
+		groups:   make(map[string]int),
+	}
+	b.wg.Add(1)
+	go b.serve()
+	return b, nil
+}
+
+// URL is the server url to connect to
+func (b *Broker) URL() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+// Subscribers returns the number of the subscribers whose filters include the filter
+func (b *Broker) Subscribers(filter string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for _, fs := range b.subs {
+		for _, f := range fs {
+			if f == filter {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// AliasOnly returns the count of the received messages which are published by the topic alias without the topic
+func (b *Broker) AliasOnly() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.aliasOnly
+}
+
+// Publish sends a message to the subscribers as if it is published by a client
+func (b *Broker) Publish(p *packets.Publish) {
+	b.route(p)
+}
+
+// Close stops the broker, closes all the connections and waits for them to exit, so that nothing is logged to the
+// test after it is closed
+func (b *Broker) Close() {
+	_ = b.ln.Close()
+	b.mu.Lock()
+	b.closed = true
+	for c := range b.subs {
+		_ = c.conn.Close()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+func (b *Broker) serve() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &client{conn: conn}
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		b.subs[c] = nil
+		b.wg.Add(1)
+		b.mu.Unlock()
+		go b.handle(c)
+	}
+}
+
+func (b *Broker) handle(c *client) {
+	defer b.wg.Done()
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, c)
+		b.mu.Unlock()
+		_ = c.conn.Close()
+	}()
+	aliases := make(map[uint16]string)
+	for {
+		cp, err := packets.ReadPacket(c.conn)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				b.t.Logf("mqtt broker fails to read packet: %v", err)
+			}
+			return
+		}
+		switch p := cp.Content.(type) {
+		case *packets.Connect:
+			c.write(&packets.Connack{Properties: &packets.Properties{TopicAliasMaximum: &b.aliasMax}})
+		case *packets.Subscribe:
+			ack := &packets.Suback{PacketID: p.PacketID, Properties: &packets.Properties{}}
+			b.mu.Lock()
+			for f, o := range p.Subscriptions {
+				b.subs[c] = append(b.subs[c], f)
+				ack.Reasons = append(ack.Reasons, o.QoS)
+			}
+			b.mu.Unlock()
+			c.write(ack)
+		case *packets.Publish:
+			// The flags are not unpacked by the packets lib
+			p.Retain = cp.Flags&0x01 != 0
+			p.Duplicate = cp.Flags&0x08 != 0
+			if p.Properties != nil && p.Properties.TopicAlias != nil {
+				a := *p.Properties.TopicAlias
+				if a == 0 || a > b.aliasMax {
+					b.t.Logf("mqtt broker receives invalid topic alias %d", a)
+					return
+				}
+				if p.Topic == "" {
+					t, ok := aliases[a]
+					if !ok {
+						b.t.Logf("mqtt broker receives unknown topic alias %d", a)
+						return
+					}
+					p.Topic = t
+					b.mu.Lock()
+					b.aliasOnly++
+					b.mu.Unlock()
+				} else {
+					aliases[a] = p.Topic
+				}
+			}
+			r := *p
+			b.Received <- &r
+			b.route(p)
+			switch p.QoS {
+			case 1:
+				c.write(&packets.Puback{PacketID: p.PacketID, Properties: &packets.Properties{}})
+			case 2:
+				c.write(&packets.Pubrec{PacketID: p.PacketID, Properties: &packets.Properties{}})
+			}
+		case *packets.Pubrel:
+			c.write(&packets.Pubcomp{PacketID: p.PacketID, Properties: &packets.Properties{}})
+		case *packets.Pingreq:
+			c.write(&packets.Pingresp{})
+		case *packets.Disconnect:
+			return
+		}
+	}
+}
+
+// route delivers the message to all the matched subscribers and one member of each matched shared group
+func (b *Broker) route(p *packets.Publish) {
+	m := &packets.Publish{Topic: p.Topic, Payload: p.Payload, Properties: &packets.Properties{}}
+	if p.Properties != nil {
+		props := *p.Properties
+		props.TopicAlias = nil
+		m.Properties = &props
+	}
+	b.mu.Lock()
+	var targets []*client
+	members := make(map[string][]*client)
+	for c, fs := range b.subs {
+		for _, f := range fs {
+			if strings.HasPrefix(f, "$share/") {
+				parts := strings.SplitN(f, "/", 3)
+				if len(parts) == 3 && match(parts[2], p.Topic) {
+					members[f] = append(members[f], c)
+				}
+			} else if match(f, p.Topic) {
+				targets = append(targets, c)
+				break
+			}
+		}
+	}
+	for g, cs := range members {
+		targets = append(targets, cs[b.groups[g]%len(cs)])
+		b.groups[g]++
+	}
+	b.mu.Unlock()
+	for _, c := range targets {
+		c.write(m)
+	}
+}
+
+// match checks if the topic matches the filter with the wildcards + and #
+func match(filter, topic string) bool {
+	fs := strings.Split(filter, "/")
+	ts := strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) || (f != "+" && f != ts[i]) {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqttx
+
+import (
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"net"
+	"sync"
+	"time"
+)
+
+// pinger sends the keep alive pings. The client calls Start and Stop once for each connection, but the Stop may
+// come before the Start when the connection is closed at once. The default pinger of paho misses such a Stop and
+// keeps running, which blocks the disconnection. So the starts and the stops are counted to match them.
+// The connection is closed if the ping response times out, which makes the client reconnect.
+type pinger struct {
+	mu     sync.Mutex
+	starts int
+	stops  int
+	// The stop channel of the running Start
+	stop chan struct{}
+	// The count of the pings without response
+	outstanding int
+	lastPing    time.Time
+}
+
+func (p *pinger) Start(c net.Conn, pt time.Duration) {
+	p.mu.Lock()
+	p.starts++
+	if p.stops >= p.starts {
+		p.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	p.stop = stop
+	p.outstanding = 0
+	p.lastPing = time.Time{}
+	p.mu.Unlock()
+
+	ticker := time.NewTicker(pt / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			timeout := p.outstanding > 0 && time.Since(p.lastPing) > pt+pt/2
+			due := time.Since(p.lastPing) >= pt
+			if due && !timeout {
+				p.outstanding++
+				p.lastPing = time.Now()
+			}
+			p.mu.Unlock()
+			if timeout {
+				_ = c.Close()
+				return
+			}
+			if due {
+				if _, err := packets.NewControlPacket(packets.PINGREQ).WriteTo(c); err != nil {
+					_ = c.Close()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *pinger) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stops++
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func (p *pinger) PingResp() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.outstanding = 0
+}
+
+func (p *pinger) SetDebug(paho.Logger) {}
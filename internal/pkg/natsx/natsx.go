@@ -0,0 +1,64 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package natsx holds the connection settings of the nats servers shared by the nats source and sink.
+package natsx
+
+import (
+	"errors"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/nats-io/nats.go"
+	"strings"
+)
+
+// ConnConfig is the connection settings of the nats servers
+type ConnConfig struct {
+	// The comma separated server urls such as nats://127.0.0.1:4222,nats://127.0.0.1:4223
+	Server   string `json:"server"`
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+	// The path of the user credentials file for the decentralized authentication
+	Credentials string `json:"credentials"`
+}
+
+// Validate checks the settings
+func (c *ConnConfig) Validate() error {
+	if strings.TrimSpace(c.Server) == "" {
+		return errors.New("missing property server")
+	}
+	if (c.UserName == "") != (c.Password == "") {
+		return errors.New("userName and password must be set together")
+	}
+	return nil
+}
+
+// Connect connects to the servers. The connection keeps reconnecting until closed.
+func (c *ConnConfig) Connect(name string) (*nats.Conn, error) {
+	opts := []nats.Option{nats.Name(name), nats.MaxReconnects(-1)}
+	if c.UserName != "" {
+		opts = append(opts, nats.UserInfo(c.UserName, c.Password))
+	}
+	if c.Token != "" {
+		opts = append(opts, nats.Token(c.Token))
+	}
+	if c.Credentials != "" {
+		p, err := conf.ProcessPath(c.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, nats.UserCredentials(p))
+	}
+	return nats.Connect(c.Server, opts...)
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package natsx
+
+import (
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		c   ConnConfig
+		err string
+	}{
+		{
+			c:   ConnConfig{Server: " "},
+			err: "missing property server",
+		}, {
+			c:   ConnConfig{Server: "nats://127.0.0.1:4222", UserName: "u"},
+			err: "userName and password must be set together",
+		}, {
+			c: ConnConfig{Server: "nats://127.0.0.1:4222,nats://127.0.0.1:4223", UserName: "u", Password: "p"},
+		}, {
+			c: ConnConfig{Server: "nats://127.0.0.1:4222", Token: "t"},
+		},
+	}
+	for i, tt := range tests {
+		err := tt.c.Validate()
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
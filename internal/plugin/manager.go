@@ -25,6 +25,7 @@ import (
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
 	"github.com/lf-edge/ekuiper/pkg/kv"
+	"github.com/lf-edge/ekuiper/pkg/message"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -97,12 +98,13 @@ const (
 	SOURCE PluginType = iota
 	SINK
 	FUNCTION
+	FORMAT
 )
 
 const DELETED = "$deleted"
 
 var (
-	PluginTypes = []string{"sources", "sinks", "functions"}
+	PluginTypes = []string{"sources", "sinks", "functions", "formats"}
 	once        sync.Once
 	singleton   *Manager
 )
@@ -257,6 +259,25 @@ func GetSource(t string) (api.Source, error) {
 	return s, nil
 }
 
+// GetLookupSource returns the lookup source exported by the source plugin. The exported symbol can be an
+// api.LookupSource or a function that returns it.
+func GetLookupSource(t string) (api.LookupSource, error) {
+	nf, err := getPlugin(t, SOURCE)
+	if err != nil {
+		return nil, err
+	}
+	var s api.LookupSource
+	switch t := nf.(type) {
+	case api.LookupSource:
+		s = t
+	case func() api.LookupSource:
+		s = t()
+	default:
+		return nil, fmt.Errorf("exported symbol %s is not type of api.LookupSource or function that return api.LookupSource", t)
+	}
+	return s, nil
+}
+
 func GetSink(t string) (api.Sink, error) {
 	nf, err := getPlugin(t, SINK)
 	if err != nil {
@@ -274,6 +295,33 @@ func GetSink(t string) (api.Sink, error) {
 	return s, nil
 }
 
+// GetConverter returns the converter exported by the format plugin. The exported symbol can be a message.Converter
+// or a function which creates the converter by the properties such as the schemaId.
+func GetConverter(t string, props map[string]interface{}) (message.Converter, error) {
+	nf, err := getPlugin(t, FORMAT)
+	if err != nil {
+		return nil, err
+	}
+	switch t := nf.(type) {
+	case message.Converter:
+		return t, nil
+	case func(map[string]interface{}) (message.Converter, error):
+		return t(props)
+	default:
+		return nil, fmt.Errorf("exported symbol %s is not type of message.Converter or function that return message.Converter", t)
+	}
+}
+
+// HasConverter returns whether the format plugin of the name is installed
+func HasConverter(t string) bool {
+	m, err := NewPluginManager()
+	if err != nil {
+		return false
+	}
+	_, ok := m.registry.Get(FORMAT, t)
+	return ok
+}
+
 type Manager struct {
 	pluginDir string
 	etcDir    string
@@ -305,8 +353,8 @@ func NewPluginManager() (*Manager, error) {
 			outerErr = fmt.Errorf("error when opening db: %v.", err)
 		}
 		defer db.Close()
-		plugins := make([]map[string]string, 3)
-		for i := 0; i < 3; i++ {
+		plugins := make([]map[string]string, len(PluginTypes))
+		for i := 0; i < len(PluginTypes); i++ {
 			names, err := findAll(PluginType(i), dir)
 			if err != nil {
 				outerErr = fmt.Errorf("fail to find existing plugins: %s", err)
@@ -354,6 +402,10 @@ func findAll(t PluginType, pluginDir string) (result map[string]string, err erro
 	dir := path.Join(pluginDir, PluginTypes[t])
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
+		// The folder of a newly added plugin type may not exist in the old installation
+		if os.IsNotExist(err) {
+			err = nil
+		}
 		return
 	}
 
@@ -93,7 +93,7 @@ type (
 )
 
 func isInternalSink(fiName string) bool {
-	internal := []string{`edgex.json`, `log.json`, `mqtt.json`, `nop.json`, `rest.json`}
+	internal := []string{`edgex.json`, `kafka.json`, `log.json`, `memory.json`, `mqtt.json`, `nats.json`, `nop.json`, `rest.json`, `sql.json`, `websocket.json`}
 	for _, v := range internal {
 		if v == fiName {
 			return true
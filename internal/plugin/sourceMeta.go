@@ -44,7 +44,7 @@ type (
 )
 
 func isInternalSource(fiName string) bool {
-	internal := []string{`edgex.json`, `httppull.json`, `mqtt.json`}
+	internal := []string{`edgex.json`, `httppull.json`, `httppush.json`, `kafka.json`, `memory.json`, `mqtt.json`, `nats.json`, `sql.json`, `websocket.json`}
 	for _, v := range internal {
 		if v == fiName {
 			return true
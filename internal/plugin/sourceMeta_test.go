@@ -17,6 +17,9 @@ package plugin
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"os"
+	"path"
 	"reflect"
 	"testing"
 )
@@ -29,6 +32,21 @@ var (
 )
 
 func TestGetSourceMeta(t *testing.T) {
+	// Write the conf keys into a temp folder instead of the etc folder of the repo
+	base := t.TempDir()
+	if err := os.MkdirAll(path.Join(base, "etc", "sources"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	old, ok := os.LookupEnv(conf.KuiperBaseKey)
+	os.Setenv(conf.KuiperBaseKey, base)
+	defer func() {
+		if ok {
+			os.Setenv(conf.KuiperBaseKey, old)
+		} else {
+			os.Unsetenv(conf.KuiperBaseKey)
+		}
+	}()
+
 	source := new(sourceProperty)
 	var cf map[string]map[string]interface{}
 	if err := json.Unmarshal([]byte(gCf), &cf); nil != err {
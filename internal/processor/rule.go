@@ -190,6 +190,23 @@ func (p *RuleProcessor) getRuleByJson(name, ruleJson string) (*api.Rule, error)
 	if rule.Options.LateTol < 0 {
 		return nil, fmt.Errorf("rule option lateTolerance %d is invalid, require a positive integer", rule.Options.LateTol)
 	}
+	if rule.Options.IdleTimeout < 0 {
+		return nil, fmt.Errorf("rule option idleTimeout %d is invalid, require a positive integer", rule.Options.IdleTimeout)
+	}
+	for s, t := range rule.Options.IdleTimeouts {
+		if t < 0 {
+			return nil, fmt.Errorf("rule option idleTimeouts %d of stream %s is invalid, require a positive integer", t, s)
+		}
+	}
+	if rule.Options.EmitInterval < 0 {
+		return nil, fmt.Errorf("rule option emitInterval %d is invalid, require a positive integer", rule.Options.EmitInterval)
+	}
+	if rule.Options.EmitCount < 0 {
+		return nil, fmt.Errorf("rule option emitCount %d is invalid, require a positive integer", rule.Options.EmitCount)
+	}
+	if len(rule.Options.LateActions) > 0 && !rule.Options.IsEventTime {
+		return nil, fmt.Errorf("rule option lateActions is only available when isEventTime is true")
+	}
 	return rule, nil
 }
 
@@ -139,6 +139,91 @@ func TestRuleActionParse_Apply(t *testing.T) {
 					SendError:          true,
 				},
 			},
+		}, {
+			ruleStr: `{
+				"id": "ruleTest3",
+				"sql": "SELECT * from demo",
+				"actions": [
+					{
+						"log": {}
+					}
+				],
+				"options": {
+					"isEventTime": true,
+					"lateActions": [
+						{
+							"log": {}
+						}
+					]
+				}
+			}`,
+			result: &api.Rule{
+				Triggered: false,
+				Id:        "ruleTest3",
+				Sql:       "SELECT * from demo",
+				Actions: []map[string]interface{}{
+					{
+						"log": map[string]interface{}{},
+					},
+				},
+				Options: &api.RuleOption{
+					IsEventTime:        true,
+					LateTol:            1000,
+					Concurrency:        1,
+					BufferLength:       1024,
+					SendMetaToSink:     false,
+					Qos:                api.AtMostOnce,
+					CheckpointInterval: 300000,
+					SendError:          true,
+					LateActions: []map[string]interface{}{
+						{
+							"log": map[string]interface{}{},
+						},
+					},
+				},
+			},
+		}, {
+			ruleStr: `{
+				"id": "ruleTest4",
+				"sql": "SELECT * from demo",
+				"actions": [
+					{
+						"log": {}
+					}
+				],
+				"options": {
+					"invalidActions": [
+						{
+							"log": {}
+						}
+					]
+				}
+			}`,
+			result: &api.Rule{
+				Triggered: false,
+				Id:        "ruleTest4",
+				Sql:       "SELECT * from demo",
+				Actions: []map[string]interface{}{
+					{
+						"log": map[string]interface{}{},
+					},
+				},
+				Options: &api.RuleOption{
+					IsEventTime:        false,
+					LateTol:            1000,
+					Concurrency:        1,
+					BufferLength:       1024,
+					SendMetaToSink:     false,
+					Qos:                api.AtMostOnce,
+					CheckpointInterval: 300000,
+					SendError:          true,
+					InvalidActions: []map[string]interface{}{
+						{
+							"log": map[string]interface{}{},
+						},
+					},
+				},
+			},
 		},
 	}
 
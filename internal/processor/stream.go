@@ -19,11 +19,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/schema"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
 	"github.com/lf-edge/ekuiper/pkg/kv"
+	"sort"
 	"strings"
+	"time"
 )
 
 var (
@@ -184,6 +188,32 @@ func (p *StreamProcessor) ShowStream(st ast.StreamType) ([]string, error) {
 	return result, nil
 }
 
+// FindSchemaReferences returns the names of the streams and tables which refer the schema by SCHEMAID
+func (p *StreamProcessor) FindSchemaReferences(name string) ([]string, error) {
+	err := p.db.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error when opening db: %v", err)
+	}
+	keys, err := p.db.Keys()
+	p.db.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error when loading data from db: %v", err)
+	}
+	sort.Strings(keys)
+	var result []string
+	for _, k := range keys {
+		stmt, err := xsql.GetDataSource(p.db, k)
+		if err != nil {
+			log.Warnf("fail to resolve stream %s when finding the references of schema %s: %v", k, name, err)
+			continue
+		}
+		if stmt.Options != nil && stmt.Options.SCHEMAID != "" && strings.SplitN(stmt.Options.SCHEMAID, ".", 2)[0] == name {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
+
 func (p *StreamProcessor) getStream(name string, st ast.StreamType) (string, error) {
 	vs, err := xsql.GetDataSourceStatement(p.db, name)
 	if vs != nil && vs.StreamType == st {
@@ -228,9 +258,18 @@ func printOptions(opts *ast.Options, buff *bytes.Buffer) {
 	if opts.FORMAT != "" {
 		buff.WriteString(fmt.Sprintf("FORMAT: %s\n", opts.FORMAT))
 	}
+	if opts.KIND != "" {
+		buff.WriteString(fmt.Sprintf("KIND: %s\n", opts.KIND))
+	}
 	if opts.KEY != "" {
 		buff.WriteString(fmt.Sprintf("KEY: %s\n", opts.KEY))
 	}
+	if opts.DELIMITER != "" {
+		buff.WriteString(fmt.Sprintf("DELIMITER: %s\n", opts.DELIMITER))
+	}
+	if opts.SCHEMAID != "" {
+		buff.WriteString(fmt.Sprintf("SCHEMAID: %s\n", opts.SCHEMAID))
+	}
 	if opts.RETAIN_SIZE != 0 {
 		buff.WriteString(fmt.Sprintf("RETAIN_SIZE: %d\n", opts.RETAIN_SIZE))
 	}
@@ -327,3 +366,108 @@ func printFieldType(ft ast.FieldType) (result string) {
 	}
 	return
 }
+
+const (
+	DefaultInferSamples = 10
+	DefaultInferTimeout = 10 * time.Second
+)
+
+// InferStream reads at most n samples from the source defined by the create statement and proposes a create
+// statement with the fields inferred from the samples. The fields defined in the original statement are ignored.
+// If n or timeout is not positive, the default value is used.
+func (p *StreamProcessor) InferStream(statement string, n int, timeout time.Duration) (string, error) {
+	if n <= 0 {
+		n = DefaultInferSamples
+	}
+	if timeout <= 0 {
+		timeout = DefaultInferTimeout
+	}
+	parser := xsql.NewParser(strings.NewReader(statement))
+	stmt, err := xsql.Language.Parse(parser)
+	if err != nil {
+		return "", err
+	}
+	s, ok := stmt.(*ast.StreamStmt)
+	if !ok {
+		return "", fmt.Errorf("Infer stream fails: only create stream or table statement is supported.")
+	}
+	stt := ast.StreamTypeMap[s.StreamType]
+	samples, err := node.SampleSource(s.StreamType, s.Options, n, timeout)
+	if err != nil {
+		return "", fmt.Errorf("Infer %s fails: %v.", stt, err)
+	}
+	fields, err := schema.InferFromSamples(samples)
+	if err != nil {
+		return "", fmt.Errorf("Infer %s fails: %v.", stt, err)
+	}
+	var buff bytes.Buffer
+	buff.WriteString(fmt.Sprintf("CREATE %s %s (\n", strings.ToUpper(stt), s.Name))
+	for i, f := range fields {
+		buff.WriteString("\t" + printFieldName(f.Name) + " " + printFieldSql(f.FieldType))
+		if i < len(fields)-1 {
+			buff.WriteString(",")
+		}
+		buff.WriteString("\n")
+	}
+	buff.WriteString(") WITH (" + printOptionsSql(s.Options) + ");")
+	return buff.String(), nil
+}
+
+// printFieldName quotes the field name with backquote if it is a keyword or not a valid identifier
+func printFieldName(name string) string {
+	scanner := xsql.NewScanner(strings.NewReader(name))
+	if tok, lit := scanner.Scan(); tok == ast.IDENT && lit == name {
+		if tok1, _ := scanner.Scan(); tok1 == ast.EOF {
+			return name
+		}
+	}
+	return "`" + name + "`"
+}
+
+func printFieldSql(ft ast.FieldType) string {
+	switch t := ft.(type) {
+	case *ast.BasicType:
+		return strings.ToUpper(t.Type.String())
+	case *ast.ArrayType:
+		if t.FieldType != nil {
+			return "ARRAY(" + printFieldSql(t.FieldType) + ")"
+		}
+		return "ARRAY(" + strings.ToUpper(t.Type.String()) + ")"
+	case *ast.RecType:
+		fs := make([]string, len(t.StreamFields))
+		for i, f := range t.StreamFields {
+			fs[i] = printFieldName(f.Name) + " " + printFieldSql(f.FieldType)
+		}
+		return "STRUCT(" + strings.Join(fs, ", ") + ")"
+	}
+	return ""
+}
+
+func printOptionsSql(opts *ast.Options) string {
+	var result []string
+	add := func(key, value string) {
+		if value != "" {
+			result = append(result, fmt.Sprintf("%s=%q", key, value))
+		}
+	}
+	add("DATASOURCE", opts.DATASOURCE)
+	add("TYPE", opts.TYPE)
+	add("CONF_KEY", opts.CONF_KEY)
+	add("FORMAT", opts.FORMAT)
+	add("KIND", opts.KIND)
+	add("KEY", opts.KEY)
+	add("DELIMITER", opts.DELIMITER)
+	add("SCHEMAID", opts.SCHEMAID)
+	if opts.RETAIN_SIZE != 0 {
+		result = append(result, fmt.Sprintf("RETAIN_SIZE=\"%d\"", opts.RETAIN_SIZE))
+	}
+	if opts.SHARED {
+		result = append(result, "SHARED=\"true\"")
+	}
+	if opts.STRICT_VALIDATION {
+		result = append(result, "STRICT_VALIDATION=\"true\"")
+	}
+	add("TIMESTAMP", opts.TIMESTAMP)
+	add("TIMESTAMP_FORMAT", opts.TIMESTAMP_FORMAT)
+	return strings.Join(result, ", ")
+}
@@ -17,9 +17,13 @@ package processor
 import (
 	"fmt"
 	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -203,3 +207,78 @@ func TestTableProcessor(t *testing.T) {
 		}
 	}
 }
+
+func TestInferStream(t *testing.T) {
+	var tests = []struct {
+		s   string
+		r   string
+		err string
+	}{
+		{
+			s: `CREATE STREAM demo () WITH (DATASOURCE="lookup.json", TYPE="file", CONF_KEY="test", FORMAT="json");`,
+			r: "CREATE STREAM demo (\n\tid BIGINT,\n\tname STRING,\n\tsize BIGINT\n) WITH (DATASOURCE=\"lookup.json\", TYPE=\"file\", CONF_KEY=\"test\", FORMAT=\"json\");",
+		},
+		{
+			s: `CREATE TABLE demoTable (id BIGINT) WITH (DATASOURCE="infer.json", TYPE="file", CONF_KEY="test", KEY="id");`,
+			r: "CREATE TABLE demoTable (\n\t`battery-level` BIGINT,\n\t`from` STRING,\n\tid BIGINT,\n\tlocation STRUCT(alt BIGINT, lat FLOAT, lng FLOAT),\n\tok BOOLEAN,\n\treadings ARRAY(STRUCT(ts BIGINT, value FLOAT)),\n\ttags ARRAY(STRING),\n\ttemperature FLOAT\n) WITH (DATASOURCE=\"infer.json\", TYPE=\"file\", CONF_KEY=\"test\", KEY=\"id\");",
+		},
+		{
+			s:   `CREATE STREAM demo () WITH (DATASOURCE="notexist.json", TYPE="file", CONF_KEY="test");`,
+			err: "Infer stream fails: file ",
+		},
+		{
+			s:   `SHOW STREAMS;`,
+			err: "Infer stream fails: only create stream or table statement is supported.",
+		},
+	}
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+
+	streamDB := path.Join(testx.GetDbDir(), "streamTest")
+	for i, tt := range tests {
+		result, err := NewStreamProcessor(streamDB).InferStream(tt.s, 10, time.Second)
+		if tt.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%d. %q: unexpected error %v", i, tt.s, err)
+		} else {
+			if tt.r != result {
+				t.Errorf("%d. %q\n\nstmt mismatch:\nexp=%s\ngot=%s\n\n", i, tt.s, tt.r, result)
+			}
+			// The proposed statement must be valid
+			if _, err := xsql.Language.Parse(xsql.NewParser(strings.NewReader(result))); err != nil {
+				t.Errorf("%d. the proposed statement is invalid: %v", i, err)
+			}
+		}
+	}
+}
+
+func TestFindSchemaReferences(t *testing.T) {
+	p := NewStreamProcessor(path.Join(DbDir, "streamSchemaRef"))
+	stmts := []string{
+		`CREATE STREAM refJson () WITH (DATASOURCE="users", FORMAT="JSON", SCHEMAID="refSchema");`,
+		`CREATE TABLE refProto () WITH (DATASOURCE="users", FORMAT="PROTOBUF", SCHEMAID="refSchema.Person");`,
+		`CREATE STREAM refOther () WITH (DATASOURCE="users", FORMAT="PROTOBUF", SCHEMAID="refSchema2.Person");`,
+		`CREATE STREAM refNone (id BIGINT) WITH (DATASOURCE="users", FORMAT="JSON");`,
+	}
+	for _, s := range stmts {
+		if _, err := p.ExecStmt(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for _, n := range []string{"refJson", "refOther", "refNone"} {
+			_, _ = p.DropStream(n, ast.TypeStream)
+		}
+		_, _ = p.DropStream("refProto", ast.TypeTable)
+	}()
+	result, err := p.FindSchemaReferences("refSchema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"refJson", "refProto"}; !reflect.DeepEqual(exp, result) {
+		t.Errorf("references mismatch:\n  exp=%v\n  got=%v\n\n", exp, result)
+	}
+}
@@ -0,0 +1,473 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/lf-edge/ekuiper/internal/converter/protobuf"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"math"
+	"sort"
+	"strings"
+)
+
+// InferStreamFields converts the schema in the registry to the stream fields. The schemaId is the schema name
+// for json schema and avro. For protobuf, it is in the form of schemaName.messageName.
+func InferStreamFields(schemaId string) (ast.StreamFields, error) {
+	r, err := GetRegistry()
+	if err != nil {
+		return nil, err
+	}
+	name := strings.SplitN(schemaId, ".", 2)[0]
+	info, err := r.Get(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch info.Type {
+	case TypeProtobuf:
+		md, err := protobuf.GetMessageDescriptor(schemaId)
+		if err != nil {
+			return nil, err
+		}
+		return protoFields(md, map[string]bool{})
+	case TypeJsonSchema:
+		return parseJsonSchema(info.Content)
+	case TypeAvro:
+		return parseAvro(info.Content)
+	}
+	return nil, fmt.Errorf("unsupported schema type %s", info.Type)
+}
+
+func toArrayType(elem ast.FieldType) (ast.FieldType, error) {
+	switch et := elem.(type) {
+	case *ast.BasicType:
+		return &ast.ArrayType{Type: et.Type}, nil
+	case *ast.RecType:
+		return &ast.ArrayType{Type: ast.STRUCT, FieldType: et}, nil
+	default:
+		return nil, fmt.Errorf("nested array is not supported")
+	}
+}
+
+func protoFields(md *desc.MessageDescriptor, visited map[string]bool) (ast.StreamFields, error) {
+	name := md.GetFullyQualifiedName()
+	if visited[name] {
+		return nil, fmt.Errorf("recursive message %s is not supported", name)
+	}
+	visited[name] = true
+	defer delete(visited, name)
+	result := make(ast.StreamFields, 0, len(md.GetFields()))
+	for _, f := range md.GetFields() {
+		ft, err := protoFieldType(f, visited)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", f.GetName(), err)
+		}
+		result = append(result, ast.StreamField{Name: f.GetName(), FieldType: ft})
+	}
+	return result, nil
+}
+
+func protoFieldType(f *desc.FieldDescriptor, visited map[string]bool) (ast.FieldType, error) {
+	if f.IsMap() {
+		return nil, fmt.Errorf("map type is not supported")
+	}
+	var ft ast.FieldType
+	switch f.GetType() {
+	case dpb.FieldDescriptorProto_TYPE_DOUBLE, dpb.FieldDescriptorProto_TYPE_FLOAT:
+		ft = &ast.BasicType{Type: ast.FLOAT}
+	case dpb.FieldDescriptorProto_TYPE_INT32, dpb.FieldDescriptorProto_TYPE_SFIXED32, dpb.FieldDescriptorProto_TYPE_SINT32,
+		dpb.FieldDescriptorProto_TYPE_INT64, dpb.FieldDescriptorProto_TYPE_SFIXED64, dpb.FieldDescriptorProto_TYPE_SINT64,
+		dpb.FieldDescriptorProto_TYPE_FIXED32, dpb.FieldDescriptorProto_TYPE_UINT32,
+		dpb.FieldDescriptorProto_TYPE_FIXED64, dpb.FieldDescriptorProto_TYPE_UINT64, dpb.FieldDescriptorProto_TYPE_ENUM:
+		ft = &ast.BasicType{Type: ast.BIGINT}
+	case dpb.FieldDescriptorProto_TYPE_BOOL:
+		ft = &ast.BasicType{Type: ast.BOOLEAN}
+	case dpb.FieldDescriptorProto_TYPE_STRING:
+		ft = &ast.BasicType{Type: ast.STRINGS}
+	case dpb.FieldDescriptorProto_TYPE_BYTES:
+		ft = &ast.BasicType{Type: ast.BYTEA}
+	case dpb.FieldDescriptorProto_TYPE_MESSAGE:
+		sfs, err := protoFields(f.GetMessageType(), visited)
+		if err != nil {
+			return nil, err
+		}
+		ft = &ast.RecType{StreamFields: sfs}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", f.GetType())
+	}
+	if f.IsRepeated() {
+		return toArrayType(ft)
+	}
+	return ft, nil
+}
+
+// parseJsonSchema converts the properties of the root object to stream fields in alphabetical order
+func parseJsonSchema(content string) (ast.StreamFields, error) {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(content), &m); err != nil {
+		return nil, err
+	}
+	if t, _ := m["type"].(string); t != "object" {
+		return nil, fmt.Errorf("the root type must be object")
+	}
+	return jsonSchemaFields(m)
+}
+
+func jsonSchemaFields(m map[string]interface{}) (ast.StreamFields, error) {
+	props, ok := m["properties"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("properties of object not found")
+	}
+	names := make([]string, 0, len(props))
+	for k := range props {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	result := make(ast.StreamFields, 0, len(names))
+	for _, n := range names {
+		ft, err := jsonSchemaType(props[n])
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %v", n, err)
+		}
+		result = append(result, ast.StreamField{Name: n, FieldType: ft})
+	}
+	return result, nil
+}
+
+func jsonSchemaType(p interface{}) (ast.FieldType, error) {
+	m, ok := p.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid definition %v", p)
+	}
+	var t string
+	switch tt := m["type"].(type) {
+	case string:
+		t = tt
+	case []interface{}: // nullable type like ["string", "null"]
+		for _, e := range tt {
+			if s, ok := e.(string); ok && s != "null" {
+				t = s
+				break
+			}
+		}
+	}
+	switch t {
+	case "integer":
+		return &ast.BasicType{Type: ast.BIGINT}, nil
+	case "number":
+		return &ast.BasicType{Type: ast.FLOAT}, nil
+	case "boolean":
+		return &ast.BasicType{Type: ast.BOOLEAN}, nil
+	case "string":
+		if e, _ := m["contentEncoding"].(string); e == "base64" {
+			return &ast.BasicType{Type: ast.BYTEA}, nil
+		}
+		return &ast.BasicType{Type: ast.STRINGS}, nil
+	case "object":
+		sfs, err := jsonSchemaFields(m)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.RecType{StreamFields: sfs}, nil
+	case "array":
+		elem, err := jsonSchemaType(m["items"])
+		if err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+		return toArrayType(elem)
+	}
+	return nil, fmt.Errorf("unsupported type %v", m["type"])
+}
+
+// parseAvro converts the fields of the root record to stream fields in the defined order
+func parseAvro(content string) (ast.StreamFields, error) {
+	var m interface{}
+	if err := json.Unmarshal([]byte(content), &m); err != nil {
+		return nil, err
+	}
+	r, ok := m.(map[string]interface{})
+	if !ok || r["type"] != "record" {
+		return nil, fmt.Errorf("the root type must be record")
+	}
+	return avroFields(r)
+}
+
+func avroFields(r map[string]interface{}) (ast.StreamFields, error) {
+	fields, ok := r["fields"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("fields of record not found")
+	}
+	result := make(ast.StreamFields, 0, len(fields))
+	for _, f := range fields {
+		fm, ok := f.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid field %v", f)
+		}
+		name, _ := fm["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("field name not found in %v", f)
+		}
+		ft, err := avroType(fm["type"])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", name, err)
+		}
+		result = append(result, ast.StreamField{Name: name, FieldType: ft})
+	}
+	return result, nil
+}
+
+func avroType(t interface{}) (ast.FieldType, error) {
+	switch tt := t.(type) {
+	case string:
+		switch tt {
+		case "int", "long":
+			return &ast.BasicType{Type: ast.BIGINT}, nil
+		case "float", "double":
+			return &ast.BasicType{Type: ast.FLOAT}, nil
+		case "boolean":
+			return &ast.BasicType{Type: ast.BOOLEAN}, nil
+		case "string":
+			return &ast.BasicType{Type: ast.STRINGS}, nil
+		case "bytes":
+			return &ast.BasicType{Type: ast.BYTEA}, nil
+		}
+	case []interface{}: // union, only the nullable type like ["null", "string"] is supported
+		var nonNull []interface{}
+		for _, e := range tt {
+			if e != "null" {
+				nonNull = append(nonNull, e)
+			}
+		}
+		if len(nonNull) == 1 {
+			return avroType(nonNull[0])
+		}
+	case map[string]interface{}:
+		switch tt["type"] {
+		case "record":
+			sfs, err := avroFields(tt)
+			if err != nil {
+				return nil, err
+			}
+			return &ast.RecType{StreamFields: sfs}, nil
+		case "array":
+			elem, err := avroType(tt["items"])
+			if err != nil {
+				return nil, fmt.Errorf("items: %v", err)
+			}
+			return toArrayType(elem)
+		case "enum":
+			return &ast.BasicType{Type: ast.STRINGS}, nil
+		case "fixed":
+			return &ast.BasicType{Type: ast.BYTEA}, nil
+		default:
+			// primitive type with logical type such as {"type": "long", "logicalType": "timestamp-millis"}
+			if s, ok := tt["type"].(string); ok {
+				return avroType(s)
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
+
+// InferFromSamples infers the stream fields from the sample messages. The types of all the samples are merged:
+// bigint and float are merged to float, the fields of structs are merged by name and the fields are ordered by name.
+// The fields which are always null or empty array cannot be inferred, and they are omitted.
+func InferFromSamples(samples []map[string]interface{}) (ast.StreamFields, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no sample data to infer the schema")
+	}
+	var result ast.StreamFields
+	for _, sample := range samples {
+		sfs, err := sampleFields(sample)
+		if err != nil {
+			return nil, err
+		}
+		result, err = mergeFields(result, sfs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pruneFields(result), nil
+}
+
+// sampleFields infers the fields of a map. The type of the field is nil if cannot be decided like null value.
+func sampleFields(m map[string]interface{}) (ast.StreamFields, error) {
+	result := make(ast.StreamFields, 0, len(m))
+	for k, v := range m {
+		ft, err := sampleType(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", k, err)
+		}
+		result = append(result, ast.StreamField{Name: k, FieldType: ft})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func sampleType(v interface{}) (ast.FieldType, error) {
+	switch vt := v.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return &ast.BasicType{Type: ast.BOOLEAN}, nil
+	case float64:
+		if vt == math.Trunc(vt) {
+			return &ast.BasicType{Type: ast.BIGINT}, nil
+		}
+		return &ast.BasicType{Type: ast.FLOAT}, nil
+	case float32:
+		return sampleType(float64(vt))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return &ast.BasicType{Type: ast.BIGINT}, nil
+	case string:
+		return &ast.BasicType{Type: ast.STRINGS}, nil
+	case []byte:
+		return &ast.BasicType{Type: ast.BYTEA}, nil
+	case map[string]interface{}:
+		sfs, err := sampleFields(vt)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.RecType{StreamFields: sfs}, nil
+	case []interface{}:
+		var elem ast.FieldType
+		for _, e := range vt {
+			et, err := sampleType(e)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := et.(*ast.ArrayType); ok {
+				return nil, fmt.Errorf("nested array is not supported")
+			}
+			elem, err = mergeType(elem, et)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if elem == nil {
+			return nil, nil
+		}
+		return toArrayType(elem)
+	case []map[string]interface{}:
+		arr := make([]interface{}, len(vt))
+		for i, e := range vt {
+			arr[i] = e
+		}
+		return sampleType(arr)
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
+
+func mergeFields(a, b ast.StreamFields) (ast.StreamFields, error) {
+	result := make(ast.StreamFields, len(a), len(a)+len(b))
+	copy(result, a)
+	for _, f := range b {
+		found := false
+		for i, r := range result {
+			if r.Name == f.Name {
+				ft, err := mergeType(r.FieldType, f.FieldType)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: %v", f.Name, err)
+				}
+				result[i].FieldType = ft
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, f)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func mergeType(a, b ast.FieldType) (ast.FieldType, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+	switch at := a.(type) {
+	case *ast.BasicType:
+		if bt, ok := b.(*ast.BasicType); ok {
+			if at.Type == bt.Type {
+				return at, nil
+			}
+			if (at.Type == ast.BIGINT && bt.Type == ast.FLOAT) || (at.Type == ast.FLOAT && bt.Type == ast.BIGINT) {
+				return &ast.BasicType{Type: ast.FLOAT}, nil
+			}
+		}
+	case *ast.RecType:
+		if bt, ok := b.(*ast.RecType); ok {
+			sfs, err := mergeFields(at.StreamFields, bt.StreamFields)
+			if err != nil {
+				return nil, err
+			}
+			return &ast.RecType{StreamFields: sfs}, nil
+		}
+	case *ast.ArrayType:
+		if bt, ok := b.(*ast.ArrayType); ok {
+			elem, err := mergeType(arrayElem(at), arrayElem(bt))
+			if err != nil {
+				return nil, err
+			}
+			return toArrayType(elem)
+		}
+	}
+	return nil, fmt.Errorf("conflicting types %s and %s", printType(a), printType(b))
+}
+
+func arrayElem(at *ast.ArrayType) ast.FieldType {
+	if at.FieldType != nil {
+		return at.FieldType
+	}
+	return &ast.BasicType{Type: at.Type}
+}
+
+// pruneFields removes the fields whose types are not decided
+func pruneFields(sfs ast.StreamFields) ast.StreamFields {
+	result := make(ast.StreamFields, 0, len(sfs))
+	for _, f := range sfs {
+		switch ft := f.FieldType.(type) {
+		case nil:
+			continue
+		case *ast.RecType:
+			f.FieldType = &ast.RecType{StreamFields: pruneFields(ft.StreamFields)}
+		case *ast.ArrayType:
+			if rt, ok := ft.FieldType.(*ast.RecType); ok {
+				f.FieldType = &ast.ArrayType{Type: ast.STRUCT, FieldType: &ast.RecType{StreamFields: pruneFields(rt.StreamFields)}}
+			}
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func printType(ft ast.FieldType) string {
+	switch t := ft.(type) {
+	case *ast.BasicType:
+		return t.Type.String()
+	case *ast.ArrayType:
+		return ast.ARRAY.String()
+	case *ast.RecType:
+		return ast.STRUCT.String()
+	}
+	return ""
+}
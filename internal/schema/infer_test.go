@@ -0,0 +1,204 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"reflect"
+	"testing"
+)
+
+func TestParseJsonSchema(t *testing.T) {
+	var tests = []struct {
+		content string
+		fields  ast.StreamFields
+		err     string
+	}{
+		{
+			content: `{
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"id": {"type": "integer"},
+					"score": {"type": ["number", "null"]},
+					"vip": {"type": "boolean"},
+					"avatar": {"type": "string", "contentEncoding": "base64"},
+					"tags": {"type": "array", "items": {"type": "string"}},
+					"address": {"type": "object", "properties": {"city": {"type": "string"}}},
+					"contacts": {"type": "array", "items": {"type": "object", "properties": {"phone": {"type": "string"}}}}
+				}
+			}`,
+			fields: ast.StreamFields{
+				{Name: "address", FieldType: &ast.RecType{StreamFields: ast.StreamFields{
+					{Name: "city", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				}}},
+				{Name: "avatar", FieldType: &ast.BasicType{Type: ast.BYTEA}},
+				{Name: "contacts", FieldType: &ast.ArrayType{Type: ast.STRUCT, FieldType: &ast.RecType{StreamFields: ast.StreamFields{
+					{Name: "phone", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				}}}},
+				{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+				{Name: "name", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				{Name: "score", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+				{Name: "tags", FieldType: &ast.ArrayType{Type: ast.STRINGS}},
+				{Name: "vip", FieldType: &ast.BasicType{Type: ast.BOOLEAN}},
+			},
+		}, {
+			content: `{"type": "array", "items": {"type": "string"}}`,
+			err:     "the root type must be object",
+		}, {
+			content: `{"type": "object", "properties": {"a": {"type": "array", "items": {"type": "array", "items": {"type": "string"}}}}}`,
+			err:     "property a: nested array is not supported",
+		}, {
+			content: `{"type": "object", "properties": {"a": {"type": "null"}}}`,
+			err:     "property a: unsupported type null",
+		}, {
+			content: `{"type": "object"`,
+			err:     "unexpected end of JSON input",
+		},
+	}
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	for i, tt := range tests {
+		fields, err := parseJsonSchema(tt.content)
+		if !reflect.DeepEqual(tt.err, errstring(err)) {
+			t.Errorf("%d: error mismatch\nexp=%s\ngot=%s", i, tt.err, errstring(err))
+		} else if tt.err == "" && !reflect.DeepEqual(tt.fields, fields) {
+			t.Errorf("%d: fields mismatch\nexp=%v\ngot=%v", i, tt.fields, fields)
+		}
+	}
+}
+
+func TestParseAvro(t *testing.T) {
+	var tests = []struct {
+		content string
+		fields  ast.StreamFields
+		err     string
+	}{
+		{
+			content: `{
+				"type": "record",
+				"name": "Person",
+				"fields": [
+					{"name": "name", "type": "string"},
+					{"name": "id", "type": "long"},
+					{"name": "score", "type": ["null", "double"]},
+					{"name": "vip", "type": "boolean"},
+					{"name": "avatar", "type": "bytes"},
+					{"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+					{"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["LOW", "HIGH"]}},
+					{"name": "tags", "type": {"type": "array", "items": "string"}},
+					{"name": "address", "type": {"type": "record", "name": "Address", "fields": [{"name": "city", "type": "string"}]}}
+				]
+			}`,
+			fields: ast.StreamFields{
+				{Name: "name", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+				{Name: "score", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+				{Name: "vip", FieldType: &ast.BasicType{Type: ast.BOOLEAN}},
+				{Name: "avatar", FieldType: &ast.BasicType{Type: ast.BYTEA}},
+				{Name: "ts", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+				{Name: "level", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				{Name: "tags", FieldType: &ast.ArrayType{Type: ast.STRINGS}},
+				{Name: "address", FieldType: &ast.RecType{StreamFields: ast.StreamFields{
+					{Name: "city", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				}}},
+			},
+		}, {
+			content: `"string"`,
+			err:     "the root type must be record",
+		}, {
+			content: `{"type": "record", "name": "r", "fields": [{"name": "a", "type": ["string", "long"]}]}`,
+			err:     "field a: unsupported type [string long]",
+		}, {
+			content: `{"type": "record", "name": "r", "fields": [{"name": "a", "type": {"type": "map", "values": "long"}}]}`,
+			err:     "field a: unsupported type map",
+		}, {
+			content: `{"type": "record", "name": "r", "fields": [{"type": "long"}]}`,
+			err:     "field name not found in map[type:long]",
+		},
+	}
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	for i, tt := range tests {
+		fields, err := parseAvro(tt.content)
+		if !reflect.DeepEqual(tt.err, errstring(err)) {
+			t.Errorf("%d: error mismatch\nexp=%s\ngot=%s", i, tt.err, errstring(err))
+		} else if tt.err == "" && !reflect.DeepEqual(tt.fields, fields) {
+			t.Errorf("%d: fields mismatch\nexp=%v\ngot=%v", i, tt.fields, fields)
+		}
+	}
+}
+
+func TestInferFromSamples(t *testing.T) {
+	var tests = []struct {
+		samples []map[string]interface{}
+		fields  ast.StreamFields
+		err     string
+	}{
+		{
+			samples: []map[string]interface{}{
+				{"a": 1.0, "b": "s", "c": []interface{}{}, "d": nil, "e": map[string]interface{}{"x": true}},
+				{"a": 1.5, "c": []interface{}{1.0, 2.5}, "e": map[string]interface{}{"y": []byte("b")}},
+			},
+			fields: ast.StreamFields{
+				{Name: "a", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+				{Name: "b", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				{Name: "c", FieldType: &ast.ArrayType{Type: ast.FLOAT}},
+				{Name: "e", FieldType: &ast.RecType{StreamFields: ast.StreamFields{
+					{Name: "x", FieldType: &ast.BasicType{Type: ast.BOOLEAN}},
+					{Name: "y", FieldType: &ast.BasicType{Type: ast.BYTEA}},
+				}}},
+			},
+		}, {
+			samples: []map[string]interface{}{
+				{"a": []interface{}{map[string]interface{}{"x": 1, "z": nil}}},
+				{"a": []interface{}{map[string]interface{}{"y": "s"}}},
+			},
+			fields: ast.StreamFields{
+				{Name: "a", FieldType: &ast.ArrayType{Type: ast.STRUCT, FieldType: &ast.RecType{StreamFields: ast.StreamFields{
+					{Name: "x", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+					{Name: "y", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				}}}},
+			},
+		}, {
+			samples: []map[string]interface{}{{"a": 1.0}, {"a": "s"}},
+			err:     "field a: conflicting types bigint and string",
+		}, {
+			samples: []map[string]interface{}{{"a": map[string]interface{}{"b": 1.0}}, {"a": map[string]interface{}{"b": []interface{}{1.0}}}},
+			err:     "field a: field b: conflicting types bigint and array",
+		}, {
+			samples: []map[string]interface{}{{"a": []interface{}{[]interface{}{1.0}}}},
+			err:     "field a: nested array is not supported",
+		}, {
+			samples: nil,
+			err:     "no sample data to infer the schema",
+		},
+	}
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	for i, tt := range tests {
+		fields, err := InferFromSamples(tt.samples)
+		if !reflect.DeepEqual(tt.err, errstring(err)) {
+			t.Errorf("%d: error mismatch\nexp=%s\ngot=%s", i, tt.err, errstring(err))
+		} else if tt.err == "" && !reflect.DeepEqual(tt.fields, fields) {
+			t.Errorf("%d: fields mismatch\nexp=%v\ngot=%v", i, tt.fields, fields)
+		}
+	}
+}
+
+func errstring(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
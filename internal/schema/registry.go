@@ -0,0 +1,322 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/converter/protobuf"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	TypeProtobuf   = "protobuf"
+	TypeJsonSchema = "jsonschema"
+	TypeAvro       = "avro"
+)
+
+var nameReg = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// Info is a version of a schema definition
+type Info struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Version int    `json:"version"`
+}
+
+// Registry stores all the versions of the schemas in the kv store. The latest version of a protobuf schema is
+// also written into the registry sub folder of the protobuf schema folder so that the protobuf converter can parse it.
+// The files in the protobuf schema folder are managed by the users and never touched by the registry.
+type Registry struct {
+	sync.RWMutex
+	db kv.KeyValue
+	// Find the streams which refer the schema, the schema cannot be deleted if referred
+	refFinder func(name string) ([]string, error)
+}
+
+var (
+	registry    *Registry
+	registryErr error
+	once        sync.Once
+)
+
+// GetRegistry returns the singleton of the schema registry
+func GetRegistry() (*Registry, error) {
+	once.Do(func() {
+		dataDir, err := conf.GetDataLoc()
+		if err != nil {
+			registryErr = fmt.Errorf("cannot find db folder: %s", err)
+			return
+		}
+		registry = &Registry{
+			db: kv.GetDefaultKVStore(path.Join(dataDir, "schemas")),
+		}
+		registryErr = registry.initFiles()
+	})
+	return registry, registryErr
+}
+
+// initFiles writes out the latest protobuf schemas in case the files are missing
+func (r *Registry) initFiles() error {
+	names, err := r.List()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		info, err := r.Get(name, 0)
+		if err != nil {
+			return err
+		}
+		if info.Type == TypeProtobuf {
+			if err := writeProtoFile(info); err != nil {
+				conf.Log.Errorf("write schema file for %s error: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Create adds a new schema with the version 1
+func (r *Registry) Create(info *Info) error {
+	if err := validate(info); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	if err := r.db.Open(); err != nil {
+		return fmt.Errorf("error when opening db: %v", err)
+	}
+	defer r.db.Close()
+	info.Version = 1
+	v, err := json.Marshal([]*Info{info})
+	if err != nil {
+		return err
+	}
+	if err := r.db.Setnx(info.Name, string(v)); err != nil {
+		return fmt.Errorf("schema %s already exists", info.Name)
+	}
+	if err := writeProtoFile(info); err != nil {
+		if e := r.db.Delete(info.Name); e != nil {
+			conf.Log.Errorf("rollback schema %s error: %v", info.Name, e)
+		}
+		return err
+	}
+	return nil
+}
+
+// Update adds a new version of the existing schema. The type of the schema cannot be changed.
+func (r *Registry) Update(info *Info) error {
+	if err := validate(info); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	if err := r.db.Open(); err != nil {
+		return fmt.Errorf("error when opening db: %v", err)
+	}
+	defer r.db.Close()
+	versions, err := r.getVersions(info.Name)
+	if err != nil {
+		return err
+	}
+	latest := versions[len(versions)-1]
+	if latest.Type != info.Type {
+		return fmt.Errorf("cannot change the type of schema %s from %s to %s", info.Name, latest.Type, info.Type)
+	}
+	info.Version = latest.Version + 1
+	old, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	v, err := json.Marshal(append(versions, info))
+	if err != nil {
+		return err
+	}
+	if err := r.db.Set(info.Name, string(v)); err != nil {
+		return err
+	}
+	if err := writeProtoFile(info); err != nil {
+		if e := r.db.Set(info.Name, string(old)); e != nil {
+			conf.Log.Errorf("rollback schema %s error: %v", info.Name, e)
+		}
+		return err
+	}
+	return nil
+}
+
+// Get returns the schema of the version. If the version is 0, return the latest version.
+func (r *Registry) Get(name string, version int) (*Info, error) {
+	r.RLock()
+	defer r.RUnlock()
+	if err := r.db.Open(); err != nil {
+		return nil, fmt.Errorf("error when opening db: %v", err)
+	}
+	defer r.db.Close()
+	versions, err := r.getVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("version %d of schema %s is not found", version, name))
+}
+
+// List returns the names of all the schemas in alphabetical order
+func (r *Registry) List() ([]string, error) {
+	r.RLock()
+	defer r.RUnlock()
+	if err := r.db.Open(); err != nil {
+		return nil, fmt.Errorf("error when opening db: %v", err)
+	}
+	defer r.db.Close()
+	keys, err := r.db.Keys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// SetReferenceFinder sets the function to find the streams which refer the schema
+func (r *Registry) SetReferenceFinder(f func(name string) ([]string, error)) {
+	r.Lock()
+	defer r.Unlock()
+	r.refFinder = f
+}
+
+// Delete removes all the versions of the schema. The schema referred by any stream cannot be deleted.
+func (r *Registry) Delete(name string) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.refFinder != nil {
+		streams, err := r.refFinder(name)
+		if err != nil {
+			return err
+		}
+		if len(streams) > 0 {
+			return fmt.Errorf("schema %s is referred by stream %s, drop the streams before deleting the schema", name, strings.Join(streams, ","))
+		}
+	}
+	if err := r.db.Open(); err != nil {
+		return fmt.Errorf("error when opening db: %v", err)
+	}
+	defer r.db.Close()
+	versions, err := r.getVersions(name)
+	if err != nil {
+		return err
+	}
+	if err := r.db.Delete(name); err != nil {
+		return err
+	}
+	if versions[0].Type == TypeProtobuf {
+		protobuf.Invalidate(name)
+		if err := os.Remove(path.Join(protobuf.GetRegistryDir(), name+".proto")); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// must run after db opened
+func (r *Registry) getVersions(name string) ([]*Info, error) {
+	var s string
+	if ok, _ := r.db.Get(name, &s); !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("schema %s is not found", name))
+	}
+	var versions []*Info
+	if err := json.Unmarshal([]byte(s), &versions); err != nil || len(versions) == 0 {
+		return nil, fmt.Errorf("error unmarshall schema %s, the data in db may be corrupted", name)
+	}
+	return versions, nil
+}
+
+func validate(info *Info) error {
+	if !nameReg.MatchString(info.Name) {
+		return fmt.Errorf("invalid schema name %s, must start with a letter and contain only letters, digits and underscores", info.Name)
+	}
+	if strings.TrimSpace(info.Content) == "" {
+		return fmt.Errorf("the content of schema %s is empty", info.Name)
+	}
+	switch strings.ToLower(info.Type) {
+	case TypeProtobuf:
+		info.Type = TypeProtobuf
+		// The file of the same name in the schema folder belongs to the user and takes precedence
+		if _, err := os.Stat(path.Join(protobuf.GetSchemaDir(), info.Name+".proto")); err == nil {
+			return fmt.Errorf("schema file %s.proto already exists in the protobuf schema folder", info.Name)
+		}
+		// Parse the new content and resolve the imports in the schema folder and the registry folder
+		p := &protoparse.Parser{
+			Accessor: func(filename string) (io.ReadCloser, error) {
+				if filename == info.Name+".proto" {
+					return ioutil.NopCloser(strings.NewReader(info.Content)), nil
+				}
+				f, err := os.Open(path.Join(protobuf.GetSchemaDir(), filename))
+				if os.IsNotExist(err) {
+					return os.Open(path.Join(protobuf.GetRegistryDir(), filename))
+				}
+				return f, err
+			},
+		}
+		if _, err := p.ParseFiles(info.Name + ".proto"); err != nil {
+			return fmt.Errorf("invalid protobuf schema %s: %v", info.Name, err)
+		}
+	case TypeJsonSchema:
+		info.Type = TypeJsonSchema
+		if _, err := parseJsonSchema(info.Content); err != nil {
+			return fmt.Errorf("invalid json schema %s: %v", info.Name, err)
+		}
+	case TypeAvro:
+		info.Type = TypeAvro
+		if _, err := parseAvro(info.Content); err != nil {
+			return fmt.Errorf("invalid avro schema %s: %v", info.Name, err)
+		}
+	default:
+		return fmt.Errorf("unsupported schema type %s, must be %s, %s or %s", info.Type, TypeProtobuf, TypeJsonSchema, TypeAvro)
+	}
+	return nil
+}
+
+func writeProtoFile(info *Info) error {
+	if info.Type != TypeProtobuf {
+		return nil
+	}
+	dir := protobuf.GetRegistryDir()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path.Join(dir, info.Name+".proto"), []byte(info.Content), 0666); err != nil {
+		return err
+	}
+	protobuf.Invalidate(info.Name)
+	return nil
+}
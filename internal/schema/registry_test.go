@@ -0,0 +1,179 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"github.com/lf-edge/ekuiper/internal/converter/protobuf"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r, err := GetRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// clean up the data of the previous run
+	names, _ := r.List()
+	for _, n := range names {
+		_ = r.Delete(n)
+	}
+	defer os.RemoveAll(protobuf.GetRegistryDir())
+
+	// The schema is rolled back if the file cannot be written
+	_ = os.RemoveAll(protobuf.GetRegistryDir())
+	if err := ioutil.WriteFile(protobuf.GetRegistryDir(), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(&Info{Name: "regProtoFail", Type: "protobuf", Content: `syntax = "proto3";message Msg {string name = 1;}`}); err == nil {
+		t.Errorf("create schema should fail when the file cannot be written")
+	}
+	_ = os.Remove(protobuf.GetRegistryDir())
+	if _, err := r.Get("regProtoFail", 0); err == nil {
+		t.Errorf("schema regProtoFail should be rolled back")
+	}
+
+	var tests = []struct {
+		action string
+		info   *Info
+		err    string
+	}{
+		{
+			action: "create",
+			info:   &Info{Name: "regJson", Type: "jsonschema", Content: `{"type":"object","properties":{"a":{"type":"integer"}}}`},
+		}, {
+			action: "create",
+			info:   &Info{Name: "regJson", Type: "jsonschema", Content: `{"type":"object","properties":{"b":{"type":"string"}}}`},
+			err:    "schema regJson already exists",
+		}, {
+			action: "create",
+			info:   &Info{Name: "reg-bad", Type: "avro", Content: `{"type":"record","fields":[]}`},
+			err:    "invalid schema name reg-bad, must start with a letter and contain only letters, digits and underscores",
+		}, {
+			action: "create",
+			info:   &Info{Name: "regXml", Type: "xml", Content: `<a/>`},
+			err:    "unsupported schema type xml, must be protobuf, jsonschema or avro",
+		}, {
+			action: "create",
+			info:   &Info{Name: "regAvro", Type: "AVRO", Content: `{"type":"record","name":"r","fields":[{"name":"a","type":"long"}]}`},
+		}, {
+			action: "create",
+			info:   &Info{Name: "regProto", Type: "protobuf", Content: `syntax = "proto3";message Msg {string name = 1;}`},
+		}, {
+			action: "create",
+			info:   &Info{Name: "test1", Type: "protobuf", Content: `syntax = "proto3";message Msg {string name = 1;}`},
+			err:    "schema file test1.proto already exists in the protobuf schema folder",
+		}, {
+			action: "create",
+			info:   &Info{Name: "regProtoBad", Type: "protobuf", Content: `syntax = "proto3";message Msg {string name = 1`},
+			err:    "invalid protobuf schema regProtoBad",
+		}, {
+			action: "update",
+			info:   &Info{Name: "regJson", Type: "jsonschema", Content: `{"type":"object","properties":{"b":{"type":"string"}}}`},
+		}, {
+			action: "update",
+			info:   &Info{Name: "regJson", Type: "avro", Content: `{"type":"record","name":"r","fields":[{"name":"a","type":"long"}]}`},
+			err:    "cannot change the type of schema regJson from jsonschema to avro",
+		}, {
+			action: "update",
+			info:   &Info{Name: "regNone", Type: "avro", Content: `{"type":"record","name":"r","fields":[{"name":"a","type":"long"}]}`},
+			err:    "schema regNone is not found",
+		}, {
+			action: "update",
+			info:   &Info{Name: "regProto", Type: "protobuf", Content: `syntax = "proto3";message Msg {string name = 1;int64 age = 2;}`},
+		},
+	}
+	for i, tt := range tests {
+		switch tt.action {
+		case "create":
+			err = r.Create(tt.info)
+		case "update":
+			err = r.Update(tt.info)
+		}
+		if tt.err == "" && err != nil {
+			t.Errorf("%d: unexpected error %v", i, err)
+		} else if tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)) {
+			t.Errorf("%d: error mismatch\nexp=%s\ngot=%v", i, tt.err, err)
+		}
+	}
+
+	names, err = r.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"regAvro", "regJson", "regProto"}; !reflect.DeepEqual(exp, names) {
+		t.Errorf("list mismatch\nexp=%v\ngot=%v", exp, names)
+	}
+
+	info, err := r.Get("regJson", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != 1 || !strings.Contains(info.Content, `"a"`) {
+		t.Errorf("get version 1 mismatch, got %v", info)
+	}
+	info, err = r.Get("regJson", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != 2 || !strings.Contains(info.Content, `"b"`) {
+		t.Errorf("get latest version mismatch, got %v", info)
+	}
+	if _, err = r.Get("regJson", 3); err == nil || err.Error() != "version 3 of schema regJson is not found" {
+		t.Errorf("get non-existing version should fail, got %v", err)
+	}
+
+	// The latest protobuf schema is written to the registry folder and the cache is refreshed
+	fields, err := InferStreamFields("regProto.Msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := ast.StreamFields{
+		{Name: "name", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+		{Name: "age", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+	}
+	if !reflect.DeepEqual(exp, fields) {
+		t.Errorf("infer protobuf fields mismatch\nexp=%v\ngot=%v", exp, fields)
+	}
+
+	// The schema referred by a stream cannot be deleted
+	r.SetReferenceFinder(func(name string) ([]string, error) {
+		if name == "regJson" {
+			return []string{"demo"}, nil
+		}
+		return nil, nil
+	})
+	if err := r.Delete("regJson"); err == nil || err.Error() != "schema regJson is referred by stream demo, drop the streams before deleting the schema" {
+		t.Errorf("delete referred schema error mismatch, got %v", err)
+	}
+	r.SetReferenceFinder(nil)
+
+	for _, n := range []string{"regAvro", "regJson", "regProto"} {
+		if err := r.Delete(n); err != nil {
+			t.Errorf("delete %s error: %v", n, err)
+		}
+	}
+	if _, err := os.Stat(path.Join(protobuf.GetRegistryDir(), "regProto.proto")); !os.IsNotExist(err) {
+		t.Errorf("protobuf schema file should be removed")
+	}
+	if err := r.Delete("regJson"); err == nil {
+		t.Errorf("delete non-existing schema should fail")
+	}
+}
@@ -23,3 +23,15 @@ type PluginDesc struct {
 	Type int
 	Stop bool
 }
+
+type SchemaDesc struct {
+	Name    string
+	Version int
+}
+
+type InferDesc struct {
+	Sql     string
+	Samples int
+	// The timeout in milliseconds
+	Timeout int
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/plugin"
+	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/service"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -32,6 +33,7 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -45,6 +47,13 @@ type statementDescriptor struct {
 	Sql string `json:"sql,omitempty"`
 }
 
+type inferDescriptor struct {
+	Sql     string `json:"sql"`
+	Samples int    `json:"samples"`
+	// The timeout in milliseconds
+	Timeout int `json:"timeout"`
+}
+
 func decodeStatementDescriptor(reader io.ReadCloser) (statementDescriptor, error) {
 	sd := statementDescriptor{}
 	err := json.NewDecoder(reader).Decode(&sd)
@@ -93,6 +102,7 @@ func createRestServer(ip string, port int) *http.Server {
 	r.HandleFunc("/", rootHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/ping", pingHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams", streamsHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/streams/infer", streamInferHandler).Methods(http.MethodPost)
 	r.HandleFunc("/streams/{name}", streamHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
 	r.HandleFunc("/tables", tablesHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/tables/{name}", tableHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
@@ -115,6 +125,8 @@ func createRestServer(ip string, port int) *http.Server {
 	r.HandleFunc("/plugins/functions/prebuild", prebuildFuncsPlugins).Methods(http.MethodGet)
 	r.HandleFunc("/plugins/functions/{name}", functionHandler).Methods(http.MethodDelete, http.MethodGet)
 	r.HandleFunc("/plugins/functions/{name}/register", functionRegisterHandler).Methods(http.MethodPost)
+	r.HandleFunc("/plugins/formats", formatsHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/plugins/formats/{name}", formatHandler).Methods(http.MethodDelete, http.MethodGet)
 	r.HandleFunc("/plugins/udfs", functionsListHandler).Methods(http.MethodGet)
 	r.HandleFunc("/plugins/udfs/{name}", functionsGetHandler).Methods(http.MethodGet)
 
@@ -135,6 +147,9 @@ func createRestServer(ip string, port int) *http.Server {
 	r.HandleFunc("/services/functions/{name}", serviceFunctionHandler).Methods(http.MethodGet)
 	r.HandleFunc("/services/{name}", serviceHandler).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
 
+	r.HandleFunc("/schemas", schemasHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/schemas/{name}", schemaHandler).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
+
 	server := &http.Server{
 		Addr: fmt.Sprintf("%s:%d", ip, port),
 		// Good practice to set timeouts to avoid Slowloris attacks.
@@ -245,6 +260,22 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 	sourceManageHandler(w, r, ast.TypeStream)
 }
 
+// propose a create stream or table statement by the sample data of the source
+func streamInferHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	v := &inferDescriptor{}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		handleError(w, err, "Invalid body: Error decoding the infer request payload", logger)
+		return
+	}
+	content, err := streamProcessor.InferStream(v.Sql, v.Samples, time.Duration(v.Timeout)*time.Millisecond)
+	if err != nil {
+		handleError(w, err, "infer stream error", logger)
+		return
+	}
+	jsonResponse(&statementDescriptor{Sql: content}, w, logger)
+}
+
 //list or create tables
 func tablesHandler(w http.ResponseWriter, r *http.Request) {
 	sourcesManageHandler(w, r, ast.TypeTable)
@@ -532,6 +563,16 @@ func functionHandler(w http.ResponseWriter, r *http.Request) {
 	pluginHandler(w, r, plugin.FUNCTION)
 }
 
+//list or create format plugin
+func formatsHandler(w http.ResponseWriter, r *http.Request) {
+	pluginsHandler(w, r, plugin.FORMAT)
+}
+
+//delete a format plugin
+func formatHandler(w http.ResponseWriter, r *http.Request) {
+	pluginHandler(w, r, plugin.FORMAT)
+}
+
 type functionList struct {
 	Functions []string `json:"functions,omitempty"`
 }
@@ -940,3 +981,80 @@ func serviceFunctionHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	jsonResponse(j, w, logger)
 }
+
+func schemasHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		content, err := schemaRegistry.List()
+		if err != nil {
+			handleError(w, err, "schema list command error", logger)
+			return
+		}
+		jsonResponse(content, w, logger)
+	case http.MethodPost:
+		sd := &schema.Info{}
+		err := json.NewDecoder(r.Body).Decode(sd)
+		// Problems decoding
+		if err != nil {
+			handleError(w, err, "Invalid body: Error decoding the schema request payload", logger)
+			return
+		}
+		err = schemaRegistry.Create(sd)
+		if err != nil {
+			handleError(w, err, "schema create command error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(fmt.Sprintf("schema %s is created", sd.Name)))
+	}
+}
+
+func schemaHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	switch r.Method {
+	case http.MethodDelete:
+		err := schemaRegistry.Delete(name)
+		if err != nil {
+			handleError(w, err, fmt.Sprintf("delete schema %s error", name), logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(fmt.Sprintf("schema %s is deleted", name)))
+	case http.MethodGet:
+		version := 0
+		if v := r.URL.Query().Get("version"); v != "" {
+			var err error
+			version, err = strconv.Atoi(v)
+			if err != nil {
+				handleError(w, err, "Invalid version parameter", logger)
+				return
+			}
+		}
+		j, err := schemaRegistry.Get(name, version)
+		if err != nil {
+			handleError(w, err, fmt.Sprintf("describe schema %s error", name), logger)
+			return
+		}
+		jsonResponse(j, w, logger)
+	case http.MethodPut:
+		sd := &schema.Info{}
+		err := json.NewDecoder(r.Body).Decode(sd)
+		// Problems decoding
+		if err != nil {
+			handleError(w, err, "Invalid body: Error decoding the schema request payload", logger)
+			return
+		}
+		sd.Name = name
+		err = schemaRegistry.Update(sd)
+		if err != nil {
+			handleError(w, err, "schema update command error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(fmt.Sprintf("schema %s is updated to version %d", sd.Name, sd.Version)))
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/lf-edge/ekuiper/internal/plugin"
+	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/service"
 	"github.com/lf-edge/ekuiper/internal/topo/sink"
 	"strings"
@@ -89,6 +90,15 @@ func (t *Server) Stream(stream string, reply *string) error {
 	return nil
 }
 
+func (t *Server) InferStream(arg *InferDesc, reply *string) error {
+	r, err := streamProcessor.InferStream(arg.Sql, arg.Samples, time.Duration(arg.Timeout)*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	*reply = r
+	return nil
+}
+
 func (t *Server) CreateRule(rule *RPCArgDesc, reply *string) error {
 	r, err := ruleProcessor.ExecCreate(rule.Name, rule.Json)
 	if err != nil {
@@ -412,6 +422,66 @@ func (t *Server) ShowServiceFuncs(_ int, reply *string) error {
 	return nil
 }
 
+func (t *Server) CreateSchema(arg *RPCArgDesc, reply *string) error {
+	sd := &schema.Info{}
+	if arg.Json != "" {
+		if err := json.Unmarshal([]byte(arg.Json), sd); err != nil {
+			return fmt.Errorf("Parse schema %s error : %s.", arg.Json, err)
+		}
+	}
+	if sd.Name == "" {
+		sd.Name = arg.Name
+	} else if sd.Name != arg.Name {
+		return fmt.Errorf("Create schema error: name mismatch.")
+	}
+	err := schemaRegistry.Create(sd)
+	if err != nil {
+		return fmt.Errorf("Create schema error: %s", err)
+	}
+	*reply = fmt.Sprintf("Schema %s is created.", arg.Name)
+	return nil
+}
+
+func (t *Server) DescSchema(arg *SchemaDesc, reply *string) error {
+	s, err := schemaRegistry.Get(arg.Name, arg.Version)
+	if err != nil {
+		return fmt.Errorf("Desc schema error : %s.", err)
+	} else {
+		r, err := marshalDesc(s)
+		if err != nil {
+			return fmt.Errorf("Describe schema error: %v", err)
+		}
+		*reply = r
+	}
+	return nil
+}
+
+func (t *Server) DropSchema(name string, reply *string) error {
+	err := schemaRegistry.Delete(name)
+	if err != nil {
+		return fmt.Errorf("Drop schema error : %s.", err)
+	}
+	*reply = fmt.Sprintf("Schema %s is dropped", name)
+	return nil
+}
+
+func (t *Server) ShowSchemas(_ int, reply *string) error {
+	s, err := schemaRegistry.List()
+	if err != nil {
+		return fmt.Errorf("Show schemas error: %s.", err)
+	}
+	if len(s) == 0 {
+		*reply = "No schema definitions are found."
+	} else {
+		r, err := marshalDesc(s)
+		if err != nil {
+			return fmt.Errorf("Show schemas error: %v", err)
+		}
+		*reply = r
+	}
+	return nil
+}
+
 func marshalDesc(m interface{}) (string, error) {
 	s, err := json.Marshal(m)
 	if err != nil {
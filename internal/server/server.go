@@ -18,6 +18,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/plugin"
 	"github.com/lf-edge/ekuiper/internal/processor"
+	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/service"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -42,6 +43,7 @@ var (
 	streamProcessor *processor.StreamProcessor
 	pluginManager   *plugin.Manager
 	serviceManager  *service.Manager
+	schemaRegistry  *schema.Registry
 )
 
 func StartUp(Version, LoadFileType string) {
@@ -69,6 +71,11 @@ func StartUp(Version, LoadFileType string) {
 		logger.Panic(err)
 	}
 	xsql.InitFuncRegisters(serviceManager, pluginManager)
+	schemaRegistry, err = schema.GetRegistry()
+	if err != nil {
+		logger.Panic(err)
+	}
+	schemaRegistry.SetReferenceFinder(streamProcessor.FindSchemaReferences)
 
 	registry = &RuleRegistry{internal: make(map[string]*RuleState)}
 
@@ -17,43 +17,17 @@ package service
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/golang/protobuf/proto"
-	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/jhump/protoreflect/dynamic"
 	kconf "github.com/lf-edge/ekuiper/internal/conf"
+	pf "github.com/lf-edge/ekuiper/internal/converter/protobuf"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	"sync"
 )
 
-const (
-	wrapperBool   = "google.protobuf.BoolValue"
-	wrapperBytes  = "google.protobuf.BytesValue"
-	wrapperDouble = "google.protobuf.DoubleValue"
-	wrapperFloat  = "google.protobuf.FloatValue"
-	wrapperInt32  = "google.protobuf.Int32Value"
-	wrapperInt64  = "google.protobuf.Int64Value"
-	wrapperString = "google.protobuf.StringValue"
-	wrapperUInt32 = "google.protobuf.UInt32Value"
-	wrapperUInt64 = "google.protobuf.UInt64Value"
-	wrapperVoid   = "google.protobuf.EMPTY"
-)
-
-var WRAPPER_TYPES = map[string]struct{}{
-	wrapperBool:   {},
-	wrapperBytes:  {},
-	wrapperDouble: {},
-	wrapperFloat:  {},
-	wrapperInt32:  {},
-	wrapperInt64:  {},
-	wrapperString: {},
-	wrapperUInt32: {},
-	wrapperUInt64: {},
-}
-
 type descriptor interface {
 	GetFunctions() []string
 }
@@ -192,7 +166,7 @@ func (d *wrappedProtoDescriptor) ConvertParamsToJson(method string, params []int
 			return nil, fmt.Errorf("can't find method %s in proto", method)
 		}
 		im := m.GetInputType()
-		if im.GetFullyQualifiedName() == wrapperString {
+		if im.GetFullyQualifiedName() == pf.WrapperString {
 			ss, err := cast.ToString(params[0], cast.STRICT)
 			if err != nil {
 				return nil, err
@@ -236,7 +210,7 @@ func (d *wrappedProtoDescriptor) convertParams(im *desc.MessageDescriptor, param
 		}
 		// For non map params, treat it as special case of multiple params
 		if len(fields) == 1 {
-			param0, err := d.encodeField(fields[0], params[0])
+			param0, err := pf.GetFieldConverter().EncodeField(fields[0], params[0])
 			if err != nil {
 				return nil, err
 			}
@@ -247,7 +221,7 @@ func (d *wrappedProtoDescriptor) convertParams(im *desc.MessageDescriptor, param
 	default:
 		if len(fields) == len(params) {
 			for i, field := range fields {
-				param, err := d.encodeField(field, params[i])
+				param, err := pf.GetFieldConverter().EncodeField(field, params[i])
 				if err != nil {
 					return nil, err
 				}
@@ -263,11 +237,11 @@ func (d *wrappedProtoDescriptor) convertParams(im *desc.MessageDescriptor, param
 func (d *wrappedProtoDescriptor) ConvertReturn(method string, returnVal interface{}) (interface{}, error) {
 	m := d.MethodDescriptor(method)
 	t := m.GetOutputType()
-	if _, ok := WRAPPER_TYPES[t.GetFullyQualifiedName()]; ok {
-		return decodeField(returnVal, t.FindFieldByNumber(1), cast.STRICT)
+	if _, ok := pf.WRAPPER_TYPES[t.GetFullyQualifiedName()]; ok {
+		return pf.GetFieldConverter().DecodeField(returnVal, t.FindFieldByNumber(1), cast.STRICT)
 	} else { // MUST be a map
 		if retMap, ok := returnVal.(map[string]interface{}); ok {
-			return decodeMap(retMap, t, cast.CONVERT_SAMEKIND)
+			return pf.GetFieldConverter().DecodeMap(retMap, t, cast.CONVERT_SAMEKIND)
 		} else {
 			return nil, fmt.Errorf("fail to convert return val, must be a map but got %v", returnVal)
 		}
@@ -276,7 +250,7 @@ func (d *wrappedProtoDescriptor) ConvertReturn(method string, returnVal interfac
 
 func (d *wrappedProtoDescriptor) ConvertReturnMessage(method string, returnVal *dynamic.Message) (interface{}, error) {
 	m := d.MethodDescriptor(method)
-	return decodeMessage(returnVal, m.GetOutputType()), nil
+	return pf.GetFieldConverter().DecodeMessage(returnVal, m.GetOutputType()), nil
 }
 
 func (d *wrappedProtoDescriptor) ConvertReturnJson(method string, returnVal []byte) (interface{}, error) {
@@ -286,14 +260,14 @@ func (d *wrappedProtoDescriptor) ConvertReturnJson(method string, returnVal []by
 		return nil, err
 	}
 	m := d.MethodDescriptor(method)
-	return decodeMap(r, m.GetOutputType(), cast.CONVERT_SAMEKIND)
+	return pf.GetFieldConverter().DecodeMap(r, m.GetOutputType(), cast.CONVERT_SAMEKIND)
 }
 
 func (d *wrappedProtoDescriptor) ConvertReturnText(method string, returnVal []byte) (interface{}, error) {
 	m := d.MethodDescriptor(method)
 	t := m.GetOutputType()
-	if _, ok := WRAPPER_TYPES[t.GetFullyQualifiedName()]; ok {
-		return decodeField(string(returnVal), t.FindFieldByNumber(1), cast.CONVERT_ALL)
+	if _, ok := pf.WRAPPER_TYPES[t.GetFullyQualifiedName()]; ok {
+		return pf.GetFieldConverter().DecodeField(string(returnVal), t.FindFieldByNumber(1), cast.CONVERT_ALL)
 	} else {
 		return nil, fmt.Errorf("fail to convert return val to text, return type must be primitive type but got %s", t.GetName())
 	}
@@ -319,7 +293,7 @@ func (d *wrappedProtoDescriptor) unfoldMap(ft *desc.MessageDescriptor, i interfa
 			if !ok {
 				return nil, fmt.Errorf("field %s not found", field.GetName())
 			}
-			fv, err := d.encodeField(field, v)
+			fv, err := pf.GetFieldConverter().EncodeField(field, v)
 			if err != nil {
 				return nil, err
 			}
@@ -331,282 +305,3 @@ func (d *wrappedProtoDescriptor) unfoldMap(ft *desc.MessageDescriptor, i interfa
 	return result, nil
 }
 
-func (d *wrappedProtoDescriptor) encodeMap(im *desc.MessageDescriptor, i interface{}) (*dynamic.Message, error) {
-	result := d.mf.NewDynamicMessage(im)
-	fields := im.GetFields()
-	if m, ok := i.(map[string]interface{}); ok {
-		for _, field := range fields {
-			v, ok := m[field.GetName()]
-			if !ok {
-				return nil, fmt.Errorf("field %s not found", field.GetName())
-			}
-			fv, err := d.encodeField(field, v)
-			if err != nil {
-				return nil, err
-			}
-			result.SetFieldByName(field.GetName(), fv)
-		}
-	}
-	return result, nil
-}
-
-func (d *wrappedProtoDescriptor) encodeField(field *desc.FieldDescriptor, v interface{}) (interface{}, error) {
-	fn := field.GetName()
-	ft := field.GetType()
-	if field.IsRepeated() {
-		var (
-			result interface{}
-			err    error
-		)
-		switch ft {
-		case dpb.FieldDescriptorProto_TYPE_DOUBLE:
-			result, err = cast.ToFloat64Slice(v, cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_FLOAT:
-			result, err = cast.ToTypedSlice(v, func(input interface{}, sn cast.Strictness) (interface{}, error) {
-				r, err := cast.ToFloat64(input, sn)
-				if err != nil {
-					return 0, nil
-				} else {
-					return float32(r), nil
-				}
-			}, "float", cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_INT32, dpb.FieldDescriptorProto_TYPE_SFIXED32, dpb.FieldDescriptorProto_TYPE_SINT32:
-			result, err = cast.ToTypedSlice(v, func(input interface{}, sn cast.Strictness) (interface{}, error) {
-				r, err := cast.ToInt(input, sn)
-				if err != nil {
-					return 0, nil
-				} else {
-					return int32(r), nil
-				}
-			}, "int", cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_INT64, dpb.FieldDescriptorProto_TYPE_SFIXED64, dpb.FieldDescriptorProto_TYPE_SINT64:
-			result, err = cast.ToInt64Slice(v, cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_FIXED32, dpb.FieldDescriptorProto_TYPE_UINT32:
-			result, err = cast.ToTypedSlice(v, func(input interface{}, sn cast.Strictness) (interface{}, error) {
-				r, err := cast.ToUint64(input, sn)
-				if err != nil {
-					return 0, nil
-				} else {
-					return uint32(r), nil
-				}
-			}, "uint", cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_FIXED64, dpb.FieldDescriptorProto_TYPE_UINT64:
-			result, err = cast.ToUint64Slice(v, cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_BOOL:
-			result, err = cast.ToBoolSlice(v, cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_STRING:
-			result, err = cast.ToStringSlice(v, cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_BYTES:
-			result, err = cast.ToBytesSlice(v, cast.STRICT)
-		case dpb.FieldDescriptorProto_TYPE_MESSAGE:
-			result, err = cast.ToTypedSlice(v, func(input interface{}, sn cast.Strictness) (interface{}, error) {
-				r, err := cast.ToStringMap(v)
-				if err == nil {
-					return d.encodeMap(field.GetMessageType(), r)
-				} else {
-					return nil, fmt.Errorf("invalid type for map type field '%s': %v", fn, err)
-				}
-			}, "map", cast.STRICT)
-		default:
-			return nil, fmt.Errorf("invalid type for field '%s'", fn)
-		}
-		if err != nil {
-			err = fmt.Errorf("failed to encode field '%s':%v", fn, err)
-		}
-		return result, err
-	} else {
-		return d.encodeSingleField(field, v)
-	}
-}
-
-func (d *wrappedProtoDescriptor) encodeSingleField(field *desc.FieldDescriptor, v interface{}) (interface{}, error) {
-	fn := field.GetName()
-	switch field.GetType() {
-	case dpb.FieldDescriptorProto_TYPE_DOUBLE:
-		r, err := cast.ToFloat64(v, cast.STRICT)
-		if err == nil {
-			return r, nil
-		} else {
-			return nil, fmt.Errorf("invalid type for float type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_FLOAT:
-		r, err := cast.ToFloat64(v, cast.STRICT)
-		if err == nil {
-			return float32(r), nil
-		} else {
-			return nil, fmt.Errorf("invalid type for float type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_INT32, dpb.FieldDescriptorProto_TYPE_SFIXED32, dpb.FieldDescriptorProto_TYPE_SINT32:
-		r, err := cast.ToInt(v, cast.STRICT)
-		if err == nil {
-			return int32(r), nil
-		} else {
-			return nil, fmt.Errorf("invalid type for int type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_INT64, dpb.FieldDescriptorProto_TYPE_SFIXED64, dpb.FieldDescriptorProto_TYPE_SINT64:
-		r, err := cast.ToInt64(v, cast.STRICT)
-		if err == nil {
-			return r, nil
-		} else {
-			return nil, fmt.Errorf("invalid type for int type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_FIXED32, dpb.FieldDescriptorProto_TYPE_UINT32:
-		r, err := cast.ToUint64(v, cast.STRICT)
-		if err == nil {
-			return uint32(r), nil
-		} else {
-			return nil, fmt.Errorf("invalid type for uint type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_FIXED64, dpb.FieldDescriptorProto_TYPE_UINT64:
-		r, err := cast.ToUint64(v, cast.STRICT)
-		if err == nil {
-			return r, nil
-		} else {
-			return nil, fmt.Errorf("invalid type for uint type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_BOOL:
-		r, err := cast.ToBool(v, cast.STRICT)
-		if err == nil {
-			return r, nil
-		} else {
-			return nil, fmt.Errorf("invalid type for bool type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_STRING:
-		r, err := cast.ToString(v, cast.STRICT)
-		if err == nil {
-			return r, nil
-		} else {
-			return nil, fmt.Errorf("invalid type for string type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_BYTES:
-		r, err := cast.ToBytes(v, cast.STRICT)
-		if err == nil {
-			return r, nil
-		} else {
-			return nil, fmt.Errorf("invalid type for bytes type field '%s': %v", fn, err)
-		}
-	case dpb.FieldDescriptorProto_TYPE_MESSAGE:
-		r, err := cast.ToStringMap(v)
-		if err == nil {
-			return d.encodeMap(field.GetMessageType(), r)
-		} else {
-			return nil, fmt.Errorf("invalid type for map type field '%s': %v", fn, err)
-		}
-	default:
-		return nil, fmt.Errorf("invalid type for field '%s'", fn)
-	}
-}
-
-func decodeMessage(message *dynamic.Message, outputType *desc.MessageDescriptor) interface{} {
-	if _, ok := WRAPPER_TYPES[outputType.GetFullyQualifiedName()]; ok {
-		return message.GetFieldByNumber(1)
-	} else if wrapperVoid == outputType.GetFullyQualifiedName() {
-		return nil
-	}
-	result := make(map[string]interface{})
-	for _, field := range outputType.GetFields() {
-		decodeMessageField(message.GetField(field), field, result, cast.STRICT)
-	}
-	return result
-}
-
-func decodeMessageField(src interface{}, field *desc.FieldDescriptor, result map[string]interface{}, sn cast.Strictness) error {
-	if f, err := decodeField(src, field, sn); err != nil {
-		return err
-	} else {
-		result[field.GetName()] = f
-		return nil
-	}
-}
-
-func decodeField(src interface{}, field *desc.FieldDescriptor, sn cast.Strictness) (interface{}, error) {
-	var (
-		r interface{}
-		e error
-	)
-	fn := field.GetName()
-	switch field.GetType() {
-	case dpb.FieldDescriptorProto_TYPE_DOUBLE, dpb.FieldDescriptorProto_TYPE_FLOAT:
-		if field.IsRepeated() {
-			r, e = cast.ToFloat64Slice(src, sn)
-		} else {
-			r, e = cast.ToFloat64(src, sn)
-		}
-	case dpb.FieldDescriptorProto_TYPE_INT32, dpb.FieldDescriptorProto_TYPE_SFIXED32, dpb.FieldDescriptorProto_TYPE_SINT32, dpb.FieldDescriptorProto_TYPE_INT64, dpb.FieldDescriptorProto_TYPE_SFIXED64, dpb.FieldDescriptorProto_TYPE_SINT64, dpb.FieldDescriptorProto_TYPE_FIXED32, dpb.FieldDescriptorProto_TYPE_UINT32, dpb.FieldDescriptorProto_TYPE_FIXED64, dpb.FieldDescriptorProto_TYPE_UINT64:
-		if field.IsRepeated() {
-			r, e = cast.ToInt64Slice(src, sn)
-		} else {
-			r, e = cast.ToInt64(src, sn)
-		}
-	case dpb.FieldDescriptorProto_TYPE_BOOL:
-		if field.IsRepeated() {
-			r, e = cast.ToBoolSlice(src, sn)
-		} else {
-			r, e = cast.ToBool(src, sn)
-		}
-	case dpb.FieldDescriptorProto_TYPE_STRING:
-		if field.IsRepeated() {
-			r, e = cast.ToStringSlice(src, sn)
-		} else {
-			r, e = cast.ToString(src, sn)
-		}
-	case dpb.FieldDescriptorProto_TYPE_BYTES:
-		if field.IsRepeated() {
-			r, e = cast.ToBytesSlice(src, sn)
-		} else {
-			r, e = cast.ToBytes(src, sn)
-		}
-	case dpb.FieldDescriptorProto_TYPE_MESSAGE:
-		if field.IsRepeated() {
-			r, e = cast.ToTypedSlice(src, func(input interface{}, ssn cast.Strictness) (interface{}, error) {
-				return decodeSubMessage(input, field.GetMessageType(), ssn)
-			}, "map", sn)
-		} else {
-			r, e = decodeSubMessage(src, field.GetMessageType(), sn)
-		}
-	default:
-		return nil, fmt.Errorf("unsupported type for %s", fn)
-	}
-	if e != nil {
-		e = fmt.Errorf("invalid type of return value for '%s': %v", fn, e)
-	}
-	return r, e
-}
-
-func decodeMap(src map[string]interface{}, ft *desc.MessageDescriptor, sn cast.Strictness) (map[string]interface{}, error) {
-	result := make(map[string]interface{})
-	for _, field := range ft.GetFields() {
-		val, ok := src[field.GetName()]
-		if !ok {
-			continue
-		}
-		err := decodeMessageField(val, field, result, sn)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return result, nil
-}
-
-func decodeSubMessage(input interface{}, ft *desc.MessageDescriptor, sn cast.Strictness) (interface{}, error) {
-	var m = map[string]interface{}{}
-	switch v := input.(type) {
-	case map[interface{}]interface{}:
-		for k, val := range v {
-			m[cast.ToStringAlways(k)] = val
-		}
-		return decodeMap(m, ft, sn)
-	case map[string]interface{}:
-		return decodeMap(v, ft, sn)
-	case proto.Message:
-		message, err := dynamic.AsDynamicMessage(v)
-		if err != nil {
-			return nil, err
-		}
-		return decodeMessage(message, ft), nil
-	case *dynamic.Message:
-		return decodeMessage(v, ft), nil
-	default:
-		return nil, fmt.Errorf("cannot decode %[1]T(%[1]v) to map", input)
-	}
-}
@@ -86,6 +86,7 @@ func (s *checkpointStore) getLatest() *completedCheckpoint {
 type Coordinator struct {
 	tasksToTrigger          []Responder
 	tasksToWaitFor          []Responder
+	sourceTasks             []StreamTask
 	sinkTasks               []SinkTask
 	pendingCheckpoints      *sync.Map
 	completedCheckpoints    *checkpointStore
@@ -132,6 +133,7 @@ func NewCoordinator(ruleId string, sources []StreamTask, operators []NonSourceTa
 	return &Coordinator{
 		tasksToTrigger:     sourceResponders,
 		tasksToWaitFor:     allResponders,
+		sourceTasks:        sources,
 		sinkTasks:          sinks,
 		pendingCheckpoints: new(sync.Map),
 		completedCheckpoints: &checkpointStore{
@@ -262,6 +264,12 @@ func (c *Coordinator) complete(checkpointId int64) {
 		for _, sink := range c.sinkTasks {
 			sink.SaveCache()
 		}
+		//notify the sources to acknowledge the upstream
+		for _, source := range c.sourceTasks {
+			if l, ok := source.(CheckpointListener); ok {
+				l.CheckpointComplete(checkpointId)
+			}
+		}
 		c.completedCheckpoints.add(ccp.(*pendingCheckpoint).finalize())
 		c.pendingCheckpoints.Delete(checkpointId)
 		//Drop the previous pendingCheckpoints
@@ -39,6 +39,11 @@ type SinkTask interface {
 	SaveCache()
 }
 
+// CheckpointListener is the task to be notified when a checkpoint completes
+type CheckpointListener interface {
+	CheckpointComplete(checkpointId int64)
+}
+
 type BufferOrEvent struct {
 	Data    interface{}
 	Channel string
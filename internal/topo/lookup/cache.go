@@ -0,0 +1,94 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lookup
+
+import (
+	"container/list"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"sync"
+)
+
+type cacheItem struct {
+	key       string
+	data      []api.SourceTuple
+	timestamp int64
+}
+
+// Cache is a LRU cache of the lookup results. The items expire after ttl milliseconds.
+// If size is not positive, the number of items is not limited. If ttl is not positive, the items never expire.
+type Cache struct {
+	ttl   int64
+	size  int
+	mutex sync.Mutex
+	items map[string]*list.Element
+	// The most recently used item is at the front
+	order *list.List
+}
+
+func NewCache(ttl int64, size int) *Cache {
+	return &Cache{
+		ttl:   ttl,
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// Get returns the cached lookup result of the key and whether it is found and not expired
+func (c *Cache) Get(key string) ([]api.SourceTuple, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := e.Value.(*cacheItem)
+	if c.ttl > 0 && conf.GetNowInMilli()-item.timestamp >= c.ttl {
+		c.remove(e)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return item.data, true
+}
+
+// Set caches the lookup result of the key and evicts the least recently used item if the cache is full
+func (c *Cache) Set(key string, data []api.SourceTuple) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := conf.GetNowInMilli()
+	if e, ok := c.items[key]; ok {
+		item := e.Value.(*cacheItem)
+		item.data = data
+		item.timestamp = now
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cacheItem{key: key, data: data, timestamp: now})
+	if c.size > 0 && c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+func (c *Cache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.items, e.Value.(*cacheItem).key)
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lookup
+
+import (
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"reflect"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	type op struct {
+		ts    int64
+		set   bool   // set or get
+		key   string // the key to set or get
+		found bool   // whether the get should hit
+	}
+	var tests = []struct {
+		ttl  int64
+		size int
+		ops  []op
+		len  int
+	}{
+		{ // never expire, no limit
+			ops: []op{
+				{ts: 0, set: true, key: "a"},
+				{ts: 1000, set: true, key: "b"},
+				{ts: 100000, key: "a", found: true},
+				{ts: 100000, key: "c", found: false},
+			},
+			len: 2,
+		}, { // expire by ttl
+			ttl: 1000,
+			ops: []op{
+				{ts: 0, set: true, key: "a"},
+				{ts: 500, set: true, key: "b"},
+				{ts: 999, key: "a", found: true},
+				{ts: 1000, key: "a", found: false},
+				{ts: 1200, key: "b", found: true},
+				{ts: 1200, set: true, key: "b"},
+				{ts: 2100, key: "b", found: true},
+			},
+			len: 1,
+		}, { // evict the least recently used
+			size: 2,
+			ops: []op{
+				{ts: 0, set: true, key: "a"},
+				{ts: 1, set: true, key: "b"},
+				{ts: 2, key: "a", found: true},
+				{ts: 3, set: true, key: "c"},
+				{ts: 4, key: "b", found: false},
+				{ts: 5, key: "a", found: true},
+				{ts: 6, key: "c", found: true},
+			},
+			len: 2,
+		},
+	}
+	defer func(c clock.Clock) {
+		conf.Clock = c
+	}(conf.Clock)
+	for i, tt := range tests {
+		mock := clock.NewMock()
+		conf.Clock = mock
+		c := NewCache(tt.ttl, tt.size)
+		for j, o := range tt.ops {
+			mock.Set(cast.TimeFromUnixMilli(o.ts))
+			data := []api.SourceTuple{api.NewDefaultSourceTuple(map[string]interface{}{"key": o.key}, nil)}
+			if o.set {
+				c.Set(o.key, data)
+				continue
+			}
+			r, ok := c.Get(o.key)
+			if ok != o.found {
+				t.Errorf("%d.%d get %s: expect found %v but got %v", i, j, o.key, o.found, ok)
+			} else if ok && !reflect.DeepEqual(r, data) {
+				t.Errorf("%d.%d get %s: expect %v but got %v", i, j, o.key, data, r)
+			}
+		}
+		if c.Len() != tt.len {
+			t.Errorf("%d: expect length %d but got %d", i, tt.len, c.Len())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memory implements an in process topic bus to chain the rules without an external broker. The topics are
+// separated by "/" and the subscriptions support the mqtt style wildcards "+" and "#".
+package memory
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"strings"
+	"sync"
+)
+
+// Message is a message delivered to the subscribers
+type Message struct {
+	Topic string
+	Data  map[string]interface{}
+}
+
+// Subscription receives the messages of the topics matching its filter
+type Subscription struct {
+	filter []string
+	ch     chan *Message
+	done   chan struct{}
+}
+
+// Messages returns the channel to receive the messages in publishing order
+func (s *Subscription) Messages() <-chan *Message {
+	return s.ch
+}
+
+var (
+	mu   sync.RWMutex
+	subs = make(map[*Subscription]bool)
+)
+
+// ValidateTopic checks the topic to publish to, which must not be empty or contain wildcards
+func ValidateTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("topic must not be empty")
+	}
+	if strings.ContainsAny(topic, "+#") {
+		return fmt.Errorf("invalid topic %s, wildcards are only allowed in subscriptions", topic)
+	}
+	return nil
+}
+
+// ValidateFilter checks the topic filter of a subscription. The "+" wildcard must occupy a whole level and the "#"
+// wildcard must occupy the last level.
+func ValidateFilter(filter string) error {
+	if filter == "" {
+		return fmt.Errorf("topic must not be empty")
+	}
+	levels := strings.Split(filter, "/")
+	for i, l := range levels {
+		if strings.ContainsAny(l, "+#") && len(l) > 1 {
+			return fmt.Errorf("invalid topic %s, wildcard must occupy a whole level", filter)
+		}
+		if l == "#" && i != len(levels)-1 {
+			return fmt.Errorf("invalid topic %s, wildcard # must be the last level", filter)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscription of the topic filter. The bufferLength is the number of the messages that can
+// be queued before the publishers are blocked.
+func Subscribe(filter string, bufferLength int) (*Subscription, error) {
+	if err := ValidateFilter(filter); err != nil {
+		return nil, err
+	}
+	if bufferLength < 0 {
+		return nil, fmt.Errorf("invalid bufferLength %d, must not be negative", bufferLength)
+	}
+	s := &Subscription{
+		filter: strings.Split(filter, "/"),
+		ch:     make(chan *Message, bufferLength),
+		done:   make(chan struct{}),
+	}
+	mu.Lock()
+	subs[s] = true
+	mu.Unlock()
+	return s, nil
+}
+
+// Unsubscribe removes the subscription and releases the publishers blocked by it
+func Unsubscribe(s *Subscription) {
+	mu.Lock()
+	defer mu.Unlock()
+	if subs[s] {
+		delete(subs, s)
+		close(s.done)
+	}
+}
+
+// Publish delivers the data to all the subscriptions matching the topic. It blocks until every matching subscription
+// has accepted the message, is unsubscribed or the context is done, so a slow subscriber slows down the publisher
+// instead of losing messages. The data is dropped if there is no matching subscription.
+func Publish(ctx api.StreamContext, topic string, data map[string]interface{}) error {
+	levels := strings.Split(topic, "/")
+	mu.RLock()
+	var matched []*Subscription
+	for s := range subs {
+		if match(s.filter, levels) {
+			matched = append(matched, s)
+		}
+	}
+	mu.RUnlock()
+	for i, s := range matched {
+		d := data
+		// Each subscriber gets its own copy of the top level map so that the rules do not affect each other
+		if i < len(matched)-1 {
+			d = make(map[string]interface{}, len(data))
+			for k, v := range data {
+				d[k] = v
+			}
+		}
+		select {
+		case s.ch <- &Message{Topic: topic, Data: d}:
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func match(filter []string, levels []string) bool {
+	for i, f := range filter {
+		if f == "#" {
+			return true
+		}
+		if i >= len(levels) {
+			return false
+		}
+		if f != "+" && f != levels[i] {
+			return false
+		}
+	}
+	return len(filter) == len(levels)
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateFilter(t *testing.T) {
+	var tests = []struct {
+		filter string
+		err    string
+	}{
+		{filter: "a/b/c"},
+		{filter: "a/+/c"},
+		{filter: "a/#"},
+		{filter: "#"},
+		{filter: "", err: "topic must not be empty"},
+		{filter: "a/b+/c", err: "invalid topic a/b+/c, wildcard must occupy a whole level"},
+		{filter: "a/#/c", err: "invalid topic a/#/c, wildcard # must be the last level"},
+	}
+	for i, tt := range tests {
+		err := ValidateFilter(tt.filter)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var tests = []struct {
+		filter string
+		topic  string
+		result bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/b", "a/b/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"+/+/c", "a/b/c", true},
+		{"a/#", "a", true},
+		{"a/#", "a/b/c", true},
+		{"a/#", "b/c", false},
+		{"#", "a/b", true},
+	}
+	for i, tt := range tests {
+		r := match(strings.Split(tt.filter, "/"), strings.Split(tt.topic, "/"))
+		if r != tt.result {
+			t.Errorf("%d: %s matches %s should be %v", i, tt.filter, tt.topic, tt.result)
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	s1, err := Subscribe("devices/+/temp", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unsubscribe(s1)
+	s2, err := Subscribe("devices/#", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unsubscribe(s2)
+
+	for i, topic := range []string{"devices/d1/temp", "devices/d2/humidity", "devices/d2/temp", "others"} {
+		if err := Publish(ctx, topic, map[string]interface{}{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp1 := []*Message{
+		{Topic: "devices/d1/temp", Data: map[string]interface{}{"i": 0}},
+		{Topic: "devices/d2/temp", Data: map[string]interface{}{"i": 2}},
+	}
+	exp2 := []*Message{
+		{Topic: "devices/d1/temp", Data: map[string]interface{}{"i": 0}},
+		{Topic: "devices/d2/humidity", Data: map[string]interface{}{"i": 1}},
+		{Topic: "devices/d2/temp", Data: map[string]interface{}{"i": 2}},
+	}
+	if r := drain(s1); !reflect.DeepEqual(exp1, r) {
+		t.Errorf("subscription 1 result mismatch:\n  exp=%v\n  got=%v\n\n", exp1, r)
+	}
+	if r := drain(s2); !reflect.DeepEqual(exp2, r) {
+		t.Errorf("subscription 2 result mismatch:\n  exp=%v\n  got=%v\n\n", exp2, r)
+	}
+}
+
+func TestPublishBackPressure(t *testing.T) {
+	ctx, cancel := context.Background().WithCancel()
+	s, err := Subscribe("bp", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Publish(ctx, "bp", map[string]interface{}{"i": 0}); err != nil {
+		t.Fatal(err)
+	}
+	// The buffer is full, so the publisher is blocked until the message is consumed
+	published := make(chan error)
+	go func() {
+		published <- Publish(ctx, "bp", map[string]interface{}{"i": 1})
+	}()
+	select {
+	case <-published:
+		t.Fatal("publish should be blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-s.Messages()
+	if err := <-published; err != nil {
+		t.Fatal(err)
+	}
+	// Unsubscribe releases the blocked publisher
+	go func() {
+		published <- Publish(ctx, "bp", map[string]interface{}{"i": 2})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	Unsubscribe(s)
+	if err := <-published; err != nil {
+		t.Fatal(err)
+	}
+	// Cancel releases the blocked publisher with error
+	s, _ = Subscribe("bp", 0)
+	defer Unsubscribe(s)
+	go func() {
+		published <- Publish(ctx, "bp", map[string]interface{}{"i": 3})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-published; err == nil {
+		t.Fatal("publish should fail after cancel")
+	}
+}
+
+func drain(s *Subscription) []*Message {
+	var result []*Message
+	for {
+		select {
+		case m := <-s.Messages():
+			result = append(result, m)
+		default:
+			return result
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// IntervalJoinConfig describes a join of two streams without window. The two sides are joined
+// if the time of the second side minus the time of the first side is in [Lower, Upper]
+type IntervalJoinConfig struct {
+	JoinType ast.JoinType
+	// The emitters of the first side (FROM) and the second side (JOIN)
+	Emitters [2]string
+	// The time expressions of each side
+	Times [2]ast.Expr
+	// The equal join keys of each side, the tuples are buffered by the keys
+	Keys      [2][]ast.Expr
+	Lower     int64
+	Upper     int64
+	Condition ast.Expr
+}
+
+// IntervalJoinTuple is a buffered tuple with its time
+type IntervalJoinTuple struct {
+	Tuple   *xsql.Tuple
+	Ts      int64
+	Matched bool
+}
+
+// IntervalJoinState is the buffers of both sides and the max time ever seen, which is saved in checkpoint
+type IntervalJoinState struct {
+	Buffers [2]map[string][]*IntervalJoinTuple
+	MaxTs   int64
+}
+
+const INTERVAL_JOIN_KEY = "$$intervalJoin"
+
+func init() {
+	gob.Register(&IntervalJoinState{})
+}
+
+// clone copies the buffers and the buffered tuples whose matched flags are changed by the later joins.
+// The tuples themselves are immutable and shared.
+func (s *IntervalJoinState) clone() *IntervalJoinState {
+	c := &IntervalJoinState{MaxTs: s.MaxTs}
+	for i, buffer := range s.Buffers {
+		c.Buffers[i] = make(map[string][]*IntervalJoinTuple, len(buffer))
+		for key, tuples := range buffer {
+			ct := make([]*IntervalJoinTuple, len(tuples))
+			for j, t := range tuples {
+				tc := *t
+				ct[j] = &tc
+			}
+			c.Buffers[i][key] = ct
+		}
+	}
+	return c
+}
+
+// IntervalJoinNode buffers the tuples of both streams by the join key, and joins each incoming tuple with the
+// buffered tuples of the other stream in the time range. The buffered tuples are evicted once no
+// tuple can join them anymore, that is to say, the max time minus the late tolerance is out of their range.
+type IntervalJoinNode struct {
+	*defaultSinkNode
+	config      *IntervalJoinConfig
+	lateTol     int64
+	statManager StatManager
+	fv          *xsql.FunctionValuer
+	// states
+	state *IntervalJoinState
+}
+
+func NewIntervalJoinNode(name string, c IntervalJoinConfig, options *api.RuleOption) (*IntervalJoinNode, error) {
+	n := &IntervalJoinNode{
+		config:  &c,
+		lateTol: options.LateTol,
+	}
+	n.defaultSinkNode = &defaultSinkNode{
+		input: make(chan interface{}, options.BufferLength),
+		defaultNode: &defaultNode{
+			outputs:   make(map[string]chan<- interface{}),
+			name:      name,
+			sendError: options.SendError,
+		},
+	}
+	return n, nil
+}
+
+func (n *IntervalJoinNode) Exec(ctx api.StreamContext, errCh chan<- error) {
+	n.ctx = ctx
+	log := ctx.GetLogger()
+	log.Debugf("IntervalJoinNode %s is started", n.name)
+
+	if len(n.outputs) <= 0 {
+		go func() { errCh <- fmt.Errorf("no output channel found") }()
+		return
+	}
+	stats, err := NewStatManager("op", ctx)
+	if err != nil {
+		go func() { errCh <- err }()
+		return
+	}
+	n.statManager = stats
+	n.fv, _ = xsql.NewFunctionValuersForOp(ctx, xsql.FuncRegisters)
+	go func() {
+		if s, err := ctx.GetState(INTERVAL_JOIN_KEY); err == nil {
+			switch st := s.(type) {
+			case *IntervalJoinState:
+				n.state = st
+				log.Infof("Restore interval join state %+v", st)
+			case nil:
+				log.Debugf("Restore interval join state, nothing")
+			default:
+				errCh <- fmt.Errorf("restore interval join state %v error, invalid type", st)
+			}
+		} else {
+			log.Warnf("Restore interval join state fails: %s", err)
+		}
+		if n.state == nil {
+			n.state = &IntervalJoinState{}
+		}
+		for i := range n.state.Buffers {
+			if n.state.Buffers[i] == nil {
+				n.state.Buffers[i] = make(map[string][]*IntervalJoinTuple)
+			}
+		}
+
+		for {
+			log.Debugf("IntervalJoinNode %s is looping", n.name)
+			select {
+			case item, opened := <-n.input:
+				// The state is saved asynchronously once the checkpoint is triggered by the barrier,
+				// so put a copy which will not be changed by the following tuples.
+				if b, ok := item.(*checkpoint.BufferOrEvent); ok {
+					if _, ok := b.Data.(*checkpoint.Barrier); ok {
+						ctx.PutState(INTERVAL_JOIN_KEY, n.state.clone())
+					}
+				}
+				processed := false
+				if item, processed = n.preprocess(item); processed {
+					break
+				}
+				n.statManager.IncTotalRecordsIn()
+				n.statManager.ProcessTimeStart()
+				if !opened {
+					n.statManager.IncTotalExceptions()
+					break
+				}
+				switch d := item.(type) {
+				case error:
+					n.Broadcast(d)
+					n.statManager.IncTotalExceptions()
+				case *xsql.Tuple:
+					log.Debugf("IntervalJoinNode receive tuple input %s", d)
+					r, err := n.join(d)
+					if err != nil {
+						n.Broadcast(fmt.Errorf("run Interval Join error: %s", err))
+						n.statManager.IncTotalExceptions()
+						n.statManager.ProcessTimeEnd()
+						break
+					}
+					if r.Len() > 0 {
+						n.Broadcast(r)
+						n.statManager.IncTotalRecordsOut()
+					}
+					n.statManager.ProcessTimeEnd()
+				default:
+					n.Broadcast(fmt.Errorf("run Interval Join error: invalid input type but got %[1]T(%[1]v)", d))
+					n.statManager.IncTotalExceptions()
+				}
+				n.statManager.SetBufferLength(int64(len(n.input)))
+			case <-ctx.Done():
+				log.Infoln("Cancelling interval join node....")
+				return
+			}
+		}
+	}()
+}
+
+// join joins the tuple with the buffered tuples of the other side, then buffers it and evicts the expired tuples
+func (n *IntervalJoinNode) join(tuple *xsql.Tuple) (*xsql.JoinTupleSets, error) {
+	side := -1
+	for i, e := range n.config.Emitters {
+		if tuple.Emitter == e {
+			side = i
+			break
+		}
+	}
+	if side < 0 {
+		return nil, fmt.Errorf("receive tuple from unknown emitter %s", tuple.Emitter)
+	}
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(tuple, n.fv)}
+	ts, err := cast.InterfaceToUnixMilli(ve.Eval(n.config.Times[side]), "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid join time: %v", err)
+	}
+	key := ""
+	for _, k := range n.config.Keys[side] {
+		r := ve.Eval(k)
+		if e, ok := r.(error); ok {
+			return nil, e
+		}
+		key += fmt.Sprintf("%v,", r)
+	}
+	current := &IntervalJoinTuple{Tuple: tuple, Ts: ts}
+	result := &xsql.JoinTupleSets{Content: make([]xsql.JoinTuple, 0)}
+	for _, other := range n.state.Buffers[1-side][key] {
+		pair := [2]*IntervalJoinTuple{}
+		pair[side], pair[1-side] = current, other
+		if diff := pair[1].Ts - pair[0].Ts; diff < n.config.Lower || diff > n.config.Upper {
+			continue
+		}
+		merged := xsql.JoinTuple{Tuples: []xsql.Tuple{*pair[0].Tuple, *pair[1].Tuple}}
+		if n.config.Condition != nil {
+			cve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(&merged, n.fv)}
+			r := cve.Eval(n.config.Condition)
+			if e, ok := r.(error); ok {
+				return nil, e
+			}
+			if b, ok := r.(bool); !ok || !b {
+				continue
+			}
+		}
+		current.Matched, other.Matched = true, true
+		result.Content = append(result.Content, merged)
+	}
+	n.state.Buffers[side][key] = append(n.state.Buffers[side][key], current)
+	if ts > n.state.MaxTs {
+		n.state.MaxTs = ts
+	}
+	n.evict(result)
+	return result, nil
+}
+
+// evict removes the tuples which cannot be joined by the later tuples anymore.
+// For outer join, the evicted tuples of the outer side which have never matched are emitted alone.
+func (n *IntervalJoinNode) evict(result *xsql.JoinTupleSets) {
+	watermark := n.state.MaxTs - n.lateTol
+	for side, buffer := range n.state.Buffers {
+		emitUnmatched := n.config.JoinType == ast.FULL_JOIN ||
+			(side == 0 && n.config.JoinType == ast.LEFT_JOIN) || (side == 1 && n.config.JoinType == ast.RIGHT_JOIN)
+		for key, tuples := range buffer {
+			kept := tuples[:0]
+			for _, t := range tuples {
+				// The latest time of the other side which can join this tuple
+				var end int64
+				if side == 0 {
+					end = t.Ts + n.config.Upper
+				} else {
+					end = t.Ts - n.config.Lower
+				}
+				if end >= watermark {
+					kept = append(kept, t)
+				} else if emitUnmatched && !t.Matched {
+					result.Content = append(result.Content, xsql.JoinTuple{Tuples: []xsql.Tuple{*t.Tuple}})
+				}
+			}
+			if len(kept) == 0 {
+				delete(buffer, key)
+			} else {
+				buffer[key] = kept
+			}
+		}
+	}
+}
+
+func (n *IntervalJoinNode) GetMetrics() [][]interface{} {
+	if n.statManager != nil {
+		return [][]interface{}{
+			n.statManager.GetMetrics(),
+		}
+	} else {
+		return nil
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntervalJoin(t *testing.T) {
+	c := IntervalJoinConfig{
+		JoinType: ast.FULL_JOIN,
+		Emitters: [2]string{"a", "b"},
+		Times:    [2]ast.Expr{&ast.FieldRef{Name: "ts", StreamName: "a"}, &ast.FieldRef{Name: "ts", StreamName: "b"}},
+		Keys:     [2][]ast.Expr{{&ast.FieldRef{Name: "id", StreamName: "a"}}, {&ast.FieldRef{Name: "id", StreamName: "b"}}},
+		Lower:    -100,
+		Upper:    500,
+	}
+	var tests = []struct {
+		emitter string
+		id      int
+		ts      int
+		result  []string
+	}{
+		{emitter: "a", id: 1, ts: 1000},
+		{emitter: "b", id: 2, ts: 1100},
+		{emitter: "b", id: 1, ts: 1400, result: []string{"a1000 b1400", "b1100"}},
+		{emitter: "a", id: 1, ts: 1450, result: []string{"a1450 b1400"}},
+		{emitter: "a", id: 1, ts: 2100},
+		// late tuple in the tolerance
+		{emitter: "b", id: 3, ts: 1950},
+		{emitter: "a", id: 3, ts: 2000, result: []string{"a2000 b1950"}},
+	}
+	n, _ := NewIntervalJoinNode("test", c, &api.RuleOption{LateTol: 100})
+	n.fv, _ = xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+	n.state = &IntervalJoinState{Buffers: [2]map[string][]*IntervalJoinTuple{{}, {}}}
+	for i, tt := range tests {
+		r, err := n.join(&xsql.Tuple{Emitter: tt.emitter, Message: xsql.Message{"id": tt.id, "ts": tt.ts}})
+		if err != nil {
+			t.Errorf("%d: join error %v", i, err)
+			continue
+		}
+		var result []string
+		for _, jt := range r.Content {
+			var s []string
+			for _, tuple := range jt.Tuples {
+				s = append(s, fmt.Sprintf("%s%v", tuple.Emitter, tuple.Message["ts"]))
+			}
+			result = append(result, strings.Join(s, " "))
+		}
+		if !reflect.DeepEqual(tt.result, result) {
+			t.Errorf("%d: result mismatch\n exp=%v\n got=%v", i, tt.result, result)
+		}
+	}
+	if len(n.state.Buffers[0]) != 2 || len(n.state.Buffers[1]) != 1 {
+		t.Errorf("buffers mismatch, got %v", n.state.Buffers)
+	}
+}
+
+func TestIntervalJoinStateClone(t *testing.T) {
+	c := IntervalJoinConfig{
+		JoinType: ast.INNER_JOIN,
+		Emitters: [2]string{"a", "b"},
+		Times:    [2]ast.Expr{&ast.FieldRef{Name: "ts", StreamName: "a"}, &ast.FieldRef{Name: "ts", StreamName: "b"}},
+		Lower:    0,
+		Upper:    500,
+	}
+	n, _ := NewIntervalJoinNode("test", c, &api.RuleOption{})
+	n.fv, _ = xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+	n.state = &IntervalJoinState{Buffers: [2]map[string][]*IntervalJoinTuple{{}, {}}}
+	if _, err := n.join(&xsql.Tuple{Emitter: "a", Message: xsql.Message{"ts": 1000}}); err != nil {
+		t.Fatalf("join error %v", err)
+	}
+	snapshot := n.state.clone()
+	exp := n.state.clone()
+	if _, err := n.join(&xsql.Tuple{Emitter: "b", Message: xsql.Message{"ts": 1200}}); err != nil {
+		t.Fatalf("join error %v", err)
+	}
+	if !n.state.Buffers[0][""][0].Matched {
+		t.Errorf("the buffered tuple should be matched")
+	}
+	if !reflect.DeepEqual(exp, snapshot) {
+		t.Errorf("snapshot changed by the following join\n exp=%+v\n got=%+v", exp, snapshot)
+	}
+}
+
+func TestIntervalJoinDatetime(t *testing.T) {
+	c := IntervalJoinConfig{
+		JoinType: ast.INNER_JOIN,
+		Emitters: [2]string{"a", "b"},
+		Times:    [2]ast.Expr{&ast.FieldRef{Name: "ts", StreamName: "a"}, &ast.FieldRef{Name: "ts", StreamName: "b"}},
+		Lower:    0,
+		Upper:    5000,
+	}
+	n, _ := NewIntervalJoinNode("test", c, &api.RuleOption{})
+	n.fv, _ = xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+	n.state = &IntervalJoinState{Buffers: [2]map[string][]*IntervalJoinTuple{{}, {}}}
+	now := time.Now()
+	if _, err := n.join(&xsql.Tuple{Emitter: "a", Message: xsql.Message{"ts": now}}); err != nil {
+		t.Fatalf("join error %v", err)
+	}
+	r, err := n.join(&xsql.Tuple{Emitter: "b", Message: xsql.Message{"ts": now.Add(3 * time.Second)}})
+	if err != nil {
+		t.Fatalf("join error %v", err)
+	}
+	if r.Len() != 1 {
+		t.Errorf("expect 1 joined result but got %d", r.Len())
+	}
+}
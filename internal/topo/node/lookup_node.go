@@ -0,0 +1,324 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/plugin"
+	"github.com/lf-edge/ekuiper/internal/topo/lookup"
+	"github.com/lf-edge/ekuiper/internal/topo/source"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"strings"
+)
+
+// LookupConfig describes the join of the stream with a lookup table
+type LookupConfig struct {
+	JoinType ast.JoinType
+	// The name of the lookup table which is also the emitter of the looked up tuples
+	Name    string
+	Options *ast.Options
+	// The columns of the lookup table and the expressions of the stream to get the lookup values
+	Keys   []string
+	Values []ast.Expr
+	// The conditions in the ON clause other than the keys
+	Condition ast.Expr
+}
+
+// LookupCacheConfig is the lookup property of the source configuration
+type LookupCacheConfig struct {
+	Cache bool `json:"cache"`
+	// The time in millisecond after which the cached result expires. 0 means never expire
+	CacheTtl int `json:"cacheTtl"`
+	// The maximum number of cached keys. 0 means no limit
+	CacheSize int `json:"cacheSize"`
+	// Whether to cache the keys which have no matched rows
+	CacheMissingKey bool `json:"cacheMissingKey"`
+}
+
+// LookupNode queries the lookup table by the keys of each incoming tuple and joins the tuple with the result rows.
+// The input can be a tuple, a window or the result of another join.
+type LookupNode struct {
+	*defaultSinkNode
+	config       *LookupConfig
+	statManager  StatManager
+	fv           *xsql.FunctionValuer
+	source       api.LookupSource
+	cache        *lookup.Cache
+	cacheMissing bool
+}
+
+func NewLookupNode(name string, c LookupConfig, options *api.RuleOption) (*LookupNode, error) {
+	n := &LookupNode{
+		config: &c,
+	}
+	n.defaultSinkNode = &defaultSinkNode{
+		input: make(chan interface{}, options.BufferLength),
+		defaultNode: &defaultNode{
+			outputs:   make(map[string]chan<- interface{}),
+			name:      name,
+			sendError: options.SendError,
+		},
+	}
+	return n, nil
+}
+
+func (n *LookupNode) Exec(ctx api.StreamContext, errCh chan<- error) {
+	n.ctx = ctx
+	log := ctx.GetLogger()
+	log.Debugf("LookupNode %s is started", n.name)
+
+	if len(n.outputs) <= 0 {
+		go func() { errCh <- fmt.Errorf("no output channel found") }()
+		return
+	}
+	stats, err := NewStatManager("op", ctx)
+	if err != nil {
+		go func() { errCh <- err }()
+		return
+	}
+	n.statManager = stats
+	n.fv, _ = xsql.NewFunctionValuersForOp(ctx, xsql.FuncRegisters)
+	go func() {
+		if err := n.open(ctx); err != nil {
+			n.drainError(ctx, errCh, err)
+			return
+		}
+		for {
+			log.Debugf("LookupNode %s is looping", n.name)
+			select {
+			case item, opened := <-n.input:
+				processed := false
+				if item, processed = n.preprocess(item); processed {
+					break
+				}
+				n.statManager.IncTotalRecordsIn()
+				n.statManager.ProcessTimeStart()
+				if !opened {
+					n.statManager.ProcessTimeEnd()
+					n.statManager.IncTotalExceptions()
+					break
+				}
+				switch d := item.(type) {
+				case error:
+					n.Broadcast(d)
+					n.statManager.ProcessTimeEnd()
+					n.statManager.IncTotalExceptions()
+				case *xsql.Tuple, xsql.WindowTuplesSet, *xsql.JoinTupleSets:
+					log.Debugf("LookupNode receive input %s", d)
+					r, err := n.lookup(ctx, d)
+					if err != nil {
+						n.Broadcast(fmt.Errorf("run Lookup error: %s", err))
+						n.statManager.ProcessTimeEnd()
+						n.statManager.IncTotalExceptions()
+						break
+					}
+					if r.Len() > 0 {
+						n.Broadcast(r)
+						n.statManager.IncTotalRecordsOut()
+					}
+					n.statManager.ProcessTimeEnd()
+				default:
+					n.Broadcast(fmt.Errorf("run Lookup error: invalid input type but got %[1]T(%[1]v)", d))
+					n.statManager.ProcessTimeEnd()
+					n.statManager.IncTotalExceptions()
+				}
+				n.statManager.SetBufferLength(int64(len(n.input)))
+			case <-ctx.Done():
+				log.Infoln("Cancelling lookup node....")
+				if err := n.source.Close(ctx); err != nil {
+					log.Warnf("close lookup source fails: %v", err)
+				}
+				return
+			}
+		}
+	}()
+}
+
+func (n *LookupNode) open(ctx api.StreamContext) error {
+	t := n.config.Options.TYPE
+	props := getSourceConf(ctx, t, n.config.Options)
+	s, err := getLookupSource(t)
+	if err != nil {
+		return err
+	}
+	if err := s.Configure(n.config.Options.DATASOURCE, props); err != nil {
+		return err
+	}
+	if err := s.Open(ctx); err != nil {
+		return err
+	}
+	n.source = s
+	if c, ok := props["lookup"]; ok {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid lookup property %v, expect a map", c)
+		}
+		cfg := &LookupCacheConfig{}
+		if err := cast.MapToStruct(m, cfg); err != nil {
+			return fmt.Errorf("read lookup properties %v fail with error: %v", m, err)
+		}
+		if cfg.Cache {
+			n.cache = lookup.NewCache(int64(cfg.CacheTtl), cfg.CacheSize)
+			n.cacheMissing = cfg.CacheMissingKey
+		}
+	}
+	return nil
+}
+
+func (n *LookupNode) drainError(ctx api.StreamContext, errCh chan<- error, err error) {
+	select {
+	case errCh <- err:
+	case <-ctx.Done():
+	}
+}
+
+func (n *LookupNode) lookup(ctx api.StreamContext, data interface{}) (*xsql.JoinTupleSets, error) {
+	result := &xsql.JoinTupleSets{Content: make([]xsql.JoinTuple, 0)}
+	switch d := data.(type) {
+	case *xsql.Tuple:
+		if err := n.join(ctx, &xsql.JoinTuple{Tuples: []xsql.Tuple{*d}}, result); err != nil {
+			return nil, err
+		}
+	case xsql.WindowTuplesSet:
+		if len(d.Content) > 1 {
+			return nil, fmt.Errorf("lookup join of multiple streams must be done after the join of streams")
+		}
+		for _, wt := range d.Content {
+			for _, t := range wt.Tuples {
+				if err := n.join(ctx, &xsql.JoinTuple{Tuples: []xsql.Tuple{t}}, result); err != nil {
+					return nil, err
+				}
+			}
+		}
+		result.WindowRange = d.WindowRange
+	case *xsql.JoinTupleSets:
+		for i := range d.Content {
+			if err := n.join(ctx, &d.Content[i], result); err != nil {
+				return nil, err
+			}
+		}
+		result.WindowRange = d.WindowRange
+	}
+	return result, nil
+}
+
+// join looks up the rows by the key values of the tuple and appends the joined tuples to the result
+func (n *LookupNode) join(ctx api.StreamContext, jt *xsql.JoinTuple, result *xsql.JoinTupleSets) error {
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(jt, n.fv)}
+	values := make([]interface{}, len(n.config.Values))
+	isNull := false
+	for i, v := range n.config.Values {
+		r := ve.Eval(v)
+		if e, ok := r.(error); ok {
+			return e
+		}
+		if r == nil {
+			isNull = true
+		}
+		values[i] = r
+	}
+	matched := false
+	// Null never equals to anything
+	if !isNull {
+		rows, err := n.fetch(ctx, values)
+		if err != nil {
+			return err
+		}
+		now := conf.GetNowInMilli()
+		for _, row := range rows {
+			tuples := make([]xsql.Tuple, len(jt.Tuples), len(jt.Tuples)+1)
+			copy(tuples, jt.Tuples)
+			merged := xsql.JoinTuple{Tuples: append(tuples, xsql.Tuple{Emitter: n.config.Name, Message: row.Message(), Metadata: row.Meta(), Timestamp: now})}
+			if n.config.Condition != nil {
+				cve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(&merged, n.fv)}
+				r := cve.Eval(n.config.Condition)
+				if e, ok := r.(error); ok {
+					return e
+				}
+				if b, ok := r.(bool); !ok || !b {
+					continue
+				}
+			}
+			matched = true
+			result.Content = append(result.Content, merged)
+		}
+	}
+	if !matched && n.config.JoinType == ast.LEFT_JOIN {
+		result.Content = append(result.Content, xsql.JoinTuple{Tuples: jt.Tuples})
+	}
+	return nil
+}
+
+// fetch gets the rows of the key values from the cache or the lookup source
+func (n *LookupNode) fetch(ctx api.StreamContext, values []interface{}) ([]api.SourceTuple, error) {
+	key := cacheKey(values)
+	if n.cache != nil {
+		if rows, ok := n.cache.Get(key); ok {
+			return rows, nil
+		}
+	}
+	rows, err := n.source.Lookup(ctx, n.config.Keys, values)
+	if err != nil {
+		return nil, err
+	}
+	if n.cache != nil && (len(rows) > 0 || n.cacheMissing) {
+		n.cache.Set(key, rows)
+	}
+	return rows, nil
+}
+
+// cacheKey encodes the key values with their types and lengths, so that the different values never share a key
+// even if they are printed the same, such as ["a b", "c"] and ["a", "b c"]
+func cacheKey(values []interface{}) string {
+	var b strings.Builder
+	for _, v := range values {
+		s := fmt.Sprintf("%v", v)
+		fmt.Fprintf(&b, "%T:%d:%s", v, len(s), s)
+	}
+	return b.String()
+}
+
+func (n *LookupNode) GetMetrics() [][]interface{} {
+	if n.statManager != nil {
+		return [][]interface{}{
+			n.statManager.GetMetrics(),
+		}
+	} else {
+		return nil
+	}
+}
+
+func doGetLookupSource(t string) (api.LookupSource, error) {
+	var (
+		s   api.LookupSource
+		err error
+	)
+	switch t {
+	case "sqlite":
+		s = &source.SQLiteLookupSource{}
+	case "sql":
+		s = &source.SQLLookupSource{}
+	default:
+		s, err = plugin.GetLookupSource(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/lookup"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"reflect"
+	"testing"
+)
+
+// mockLookupSource returns a row of the key values and counts the lookups
+type mockLookupSource struct {
+	count int
+}
+
+func (m *mockLookupSource) Open(_ api.StreamContext) error {
+	return nil
+}
+
+func (m *mockLookupSource) Configure(_ string, _ map[string]interface{}) error {
+	return nil
+}
+
+func (m *mockLookupSource) Lookup(_ api.StreamContext, _ []string, values []interface{}) ([]api.SourceTuple, error) {
+	m.count++
+	return []api.SourceTuple{api.NewDefaultSourceTuple(map[string]interface{}{"key": fmt.Sprintf("%#v", values)}, nil)}, nil
+}
+
+func (m *mockLookupSource) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func TestLookupNodeCacheKey(t *testing.T) {
+	var tests = []struct {
+		values []interface{}
+		// Whether the result is from the cache
+		cached bool
+	}{
+		{values: []interface{}{"a b", "c"}},
+		{values: []interface{}{"a", "b c"}},
+		{values: []interface{}{int64(1)}},
+		{values: []interface{}{"1"}},
+		{values: []interface{}{"a b", "c"}, cached: true},
+		{values: []interface{}{"1"}, cached: true},
+	}
+	s := &mockLookupSource{}
+	n := &LookupNode{
+		config: &LookupConfig{Keys: []string{"id"}},
+		source: s,
+		cache:  lookup.NewCache(0, 0),
+	}
+	ctx := context.Background()
+	for i, tt := range tests {
+		count := s.count
+		rows, err := n.fetch(ctx, tt.values)
+		if err != nil {
+			t.Fatalf("%d: fetch error %v", i, err)
+		}
+		if exp := map[string]interface{}{"key": fmt.Sprintf("%#v", tt.values)}; len(rows) != 1 || !reflect.DeepEqual(exp, rows[0].Message()) {
+			t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, exp, rows)
+		}
+		if cached := s.count == count; cached != tt.cached {
+			t.Errorf("%d: cached mismatch, exp %v but got %v", i, tt.cached, cached)
+		}
+	}
+}
@@ -210,6 +210,12 @@ func getSourceConf(ctx api.StreamContext, sourceType string, options *ast.Option
 		f = "json"
 	}
 	props["format"] = strings.ToLower(f)
+	if options.SCHEMAID != "" {
+		props["schemaId"] = options.SCHEMAID
+	}
+	if options.DELIMITER != "" {
+		props["delimiter"] = options.DELIMITER
+	}
 	logger.Debugf("get conf for %s with conf key %s: %v", sourceType, confkey, props)
 	return props
 }
@@ -15,7 +15,9 @@
 package node
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"sync"
@@ -26,6 +28,12 @@ type UnOperation interface {
 	Apply(ctx api.StreamContext, data interface{}, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) interface{}
 }
 
+// ValidatingOperation is implemented by the operations which validate the records such as the preprocessor.
+// Only their nodes report the invalid records metric.
+type ValidatingOperation interface {
+	ValidatesRecords() bool
+}
+
 // UnFunc implements UnOperation as type func (context.Context, interface{})
 type UnFunc func(api.StreamContext, interface{}) interface{}
 
@@ -40,12 +48,18 @@ type UnaryOperator struct {
 	funcRegisters []xsql.FunctionRegister
 	mutex         sync.RWMutex
 	cancelled     bool
+	invalidNode   *defaultNode //emit the records which fail the strict validation
 }
 
 // NewUnary creates *UnaryOperator value
 func New(name string, registers []xsql.FunctionRegister, options *api.RuleOption) *UnaryOperator {
 	return &UnaryOperator{
 		funcRegisters: registers,
+		invalidNode: &defaultNode{
+			outputs:   make(map[string]chan<- interface{}),
+			name:      name,
+			sendError: options.SendError,
+		},
 		defaultSinkNode: &defaultSinkNode{
 			input: make(chan interface{}, options.BufferLength),
 			defaultNode: &defaultNode{
@@ -66,6 +80,8 @@ func (o *UnaryOperator) SetOperation(op UnOperation) {
 // Exec is the entry point for the executor
 func (o *UnaryOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 	o.ctx = ctx
+	o.invalidNode.ctx = ctx
+	o.invalidNode.qos = o.qos
 	log := ctx.GetLogger()
 	log.Debugf("Unary operator %s is started", o.name)
 
@@ -100,7 +116,11 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 		cancel()
 	}()
 
-	stats, err := NewStatManager("op", ctx)
+	var extras []string
+	if v, ok := o.op.(ValidatingOperation); ok && v.ValidatesRecords() {
+		extras = append(extras, RecordsInvalidTotal)
+	}
+	stats, err := NewStatManager("op", ctx, extras...)
 	if err != nil {
 		o.drainError(errCh, err, ctx)
 		return
@@ -125,6 +145,18 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 			switch val := result.(type) {
 			case nil:
 				continue
+			case *xsql.ValidationError:
+				stats.IncTotalRecordsInvalid()
+				// Without invalid actions, the invalid record is handled as an error like before
+				if len(o.invalidNode.outputs) == 0 {
+					logger.Errorf("Operation %s error: %s", ctx.GetOpId(), val)
+					o.Broadcast(val)
+					stats.IncTotalExceptions()
+					continue
+				}
+				logger.Debugf("Operation %s drops invalid record: %s", ctx.GetOpId(), val)
+				o.emitInvalid(val)
+				continue
 			case error:
 				logger.Errorf("Operation %s error: %s", ctx.GetOpId(), val)
 				o.Broadcast(val)
@@ -148,6 +180,31 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 	}
 }
 
+// InvalidEmitter returns the emitter of the records which are dropped by the strict validation
+func (o *UnaryOperator) InvalidEmitter() api.Emitter {
+	return o.invalidNode
+}
+
+// Broadcast also sends the checkpoint barriers to the invalid outputs so that they can be aligned
+func (o *UnaryOperator) Broadcast(val interface{}) error {
+	if _, ok := val.(*checkpoint.Barrier); ok && len(o.invalidNode.outputs) > 0 {
+		_ = o.invalidNode.Broadcast(val)
+	}
+	return o.defaultSinkNode.Broadcast(val)
+}
+
+func (o *UnaryOperator) emitInvalid(e *xsql.ValidationError) {
+	r, err := json.Marshal([]map[string]interface{}{{
+		"message": e.Message,
+		"errors":  e.Errors,
+	}})
+	if err != nil {
+		o.ctx.GetLogger().Errorf("fail to encode invalid record %v: %v", e.Message, err)
+	} else {
+		_ = o.invalidNode.Broadcast(r)
+	}
+}
+
 func (o *UnaryOperator) drainError(errCh chan<- error, err error, ctx api.StreamContext) {
 	go func() {
 		select {
@@ -25,9 +25,11 @@ const ExceptionsTotal = "exceptions_total"
 const ProcessLatencyUs = "process_latency_us"
 const LastInvocation = "last_invocation"
 const BufferLength = "buffer_length"
+const RecordsLateTotal = "records_late_total"
+const RecordsInvalidTotal = "records_invalid_total"
 
 var (
-	MetricNames        = []string{RecordsInTotal, RecordsOutTotal, ExceptionsTotal, ProcessLatencyUs, BufferLength, LastInvocation}
+	MetricNames        = []string{RecordsInTotal, RecordsOutTotal, ExceptionsTotal, ProcessLatencyUs, BufferLength, LastInvocation, RecordsLateTotal, RecordsInvalidTotal}
 	prometheuseMetrics *PrometheusMetrics
 	mutex              sync.RWMutex
 )
@@ -42,11 +44,13 @@ func GetPrometheusMetrics() *PrometheusMetrics {
 }
 
 type MetricGroup struct {
-	TotalRecordsIn  *prometheus.CounterVec
-	TotalRecordsOut *prometheus.CounterVec
-	TotalExceptions *prometheus.CounterVec
-	ProcessLatency  *prometheus.GaugeVec
-	BufferLength    *prometheus.GaugeVec
+	TotalRecordsIn      *prometheus.CounterVec
+	TotalRecordsOut     *prometheus.CounterVec
+	TotalExceptions     *prometheus.CounterVec
+	ProcessLatency      *prometheus.GaugeVec
+	BufferLength        *prometheus.GaugeVec
+	TotalRecordsLate    *prometheus.CounterVec
+	TotalRecordsInvalid *prometheus.CounterVec
 }
 
 type PrometheusMetrics struct {
@@ -81,13 +85,23 @@ func newPrometheusMetrics() *PrometheusMetrics {
 			Name: prefix + "_" + BufferLength,
 			Help: "The length of the plan buffer which is shared by all instances of " + prefix,
 		}, labelNames)
-		prometheus.MustRegister(totalRecordsIn, totalRecordsOut, totalExceptions, processLatency, bufferLength)
+		totalRecordsLate := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "_" + RecordsLateTotal,
+			Help: "Total number of late messages dropped from the event time window of " + prefix,
+		}, labelNames)
+		totalRecordsInvalid := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "_" + RecordsInvalidTotal,
+			Help: "Total number of messages which fail the strict validation of " + prefix,
+		}, labelNames)
+		prometheus.MustRegister(totalRecordsIn, totalRecordsOut, totalExceptions, processLatency, bufferLength, totalRecordsLate, totalRecordsInvalid)
 		vecs = append(vecs, &MetricGroup{
-			TotalRecordsIn:  totalRecordsIn,
-			TotalRecordsOut: totalRecordsOut,
-			TotalExceptions: totalExceptions,
-			ProcessLatency:  processLatency,
-			BufferLength:    bufferLength,
+			TotalRecordsIn:      totalRecordsIn,
+			TotalRecordsOut:     totalRecordsOut,
+			TotalExceptions:     totalExceptions,
+			ProcessLatency:      processLatency,
+			BufferLength:        bufferLength,
+			TotalRecordsLate:    totalRecordsLate,
+			TotalRecordsInvalid: totalRecordsInvalid,
 		})
 	}
 	return &PrometheusMetrics{vecs: vecs}
@@ -19,11 +19,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/converter"
 	"github.com/lf-edge/ekuiper/internal/plugin"
 	ct "github.com/lf-edge/ekuiper/internal/template"
 	"github.com/lf-edge/ekuiper/internal/topo/sink"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"strings"
 	"sync"
 	"text/template"
 	"time"
@@ -170,6 +173,30 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 				}
 			}
 		}
+		var c message.Converter
+		format := message.FormatJson
+		if f, ok := m.options["format"]; ok {
+			if t, ok := f.(string); !ok {
+				logger.Warnf("invalid type for format property, should be a string value.", f)
+			} else {
+				format = strings.ToLower(t)
+			}
+		}
+		if format != message.FormatJson && !converter.IsFormatSupported(format) {
+			// The format property belongs to the sink itself such as the image format of the image sink
+			logger.Debugf("format %s is not a registered converter, leave it to the sink", format)
+		} else if format != message.FormatJson {
+			cv, err := converter.GetOrCreateConverter(m.options)
+			if err != nil {
+				msg := fmt.Sprintf("property format %s is invalid: %v", format, err)
+				logger.Warnf(msg)
+				result <- fmt.Errorf(msg)
+				return
+			}
+			c = cv
+			// Each row is encoded as a message of the format
+			sendSingle = true
+		}
 
 		m.reset()
 		logger.Infof("open sink node %d instances", m.concurrency)
@@ -218,9 +245,9 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 							}
 							stats.SetBufferLength(int64(len(m.input)))
 							if runAsync {
-								go doCollect(sink, data, stats, omitIfEmpty, sendSingle, tp, ctx)
+								go doCollect(sink, data, stats, omitIfEmpty, sendSingle, tp, c, ctx)
 							} else {
-								doCollect(sink, data, stats, omitIfEmpty, sendSingle, tp, ctx)
+								doCollect(sink, data, stats, omitIfEmpty, sendSingle, tp, c, ctx)
 							}
 						case <-ctx.Done():
 							logger.Infof("sink node %s instance %d done", m.name, instance)
@@ -250,9 +277,9 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 							}
 							stats.SetBufferLength(int64(len(m.input)))
 							if runAsync {
-								go doCollectCacheTuple(sink, data, stats, retryInterval, retryCount, omitIfEmpty, sendSingle, tp, cache.Complete, ctx)
+								go doCollectCacheTuple(sink, data, stats, retryInterval, retryCount, omitIfEmpty, sendSingle, tp, c, cache.Complete, ctx)
 							} else {
-								doCollectCacheTuple(sink, data, stats, retryInterval, retryCount, omitIfEmpty, sendSingle, tp, cache.Complete, ctx)
+								doCollectCacheTuple(sink, data, stats, retryInterval, retryCount, omitIfEmpty, sendSingle, tp, c, cache.Complete, ctx)
 							}
 						case <-ctx.Done():
 							logger.Infof("sink node %s instance %d done", m.name, instance)
@@ -283,12 +310,12 @@ func extractInput(v []byte) ([]map[string]interface{}, error) {
 	return j, nil
 }
 
-func doCollect(sink api.Sink, item interface{}, stats StatManager, omitIfEmpty bool, sendSingle bool, tp *template.Template, ctx api.StreamContext) {
+func doCollect(sink api.Sink, item interface{}, stats StatManager, omitIfEmpty bool, sendSingle bool, tp *template.Template, c message.Converter, ctx api.StreamContext) {
 	stats.IncTotalRecordsIn()
 	stats.ProcessTimeStart()
 	defer stats.ProcessTimeEnd()
 	logger := ctx.GetLogger()
-	outdatas := getOutData(stats, ctx, item, omitIfEmpty, sendSingle, tp)
+	outdatas := getOutData(stats, ctx, item, omitIfEmpty, sendSingle, tp, c)
 
 	for _, outdata := range outdatas {
 		if err := sink.Collect(ctx, outdata); err != nil {
@@ -300,7 +327,8 @@ func doCollect(sink api.Sink, item interface{}, stats StatManager, omitIfEmpty b
 	}
 }
 
-func getOutData(stats StatManager, ctx api.StreamContext, item interface{}, omitIfEmpty bool, sendSingle bool, tp *template.Template) [][]byte {
+// getOutData converts the result into the payloads to send. If the converter is set, each row is encoded by it.
+func getOutData(stats StatManager, ctx api.StreamContext, item interface{}, omitIfEmpty bool, sendSingle bool, tp *template.Template, c message.Converter) [][]byte {
 	logger := ctx.GetLogger()
 	var outdatas [][]byte
 	switch val := item.(type) {
@@ -344,7 +372,26 @@ func getOutData(stats StatManager, ctx api.StreamContext, item interface{}, omit
 						stats.IncTotalExceptions()
 						return nil
 					}
-					outdatas = append(outdatas, output.Bytes())
+					if c == nil {
+						outdatas = append(outdatas, output.Bytes())
+						continue
+					}
+					// The template must produce a json object to be encoded into the format
+					r = make(map[string]interface{})
+					if err := json.Unmarshal(output.Bytes(), &r); err != nil {
+						logger.Warnf("sink node %s instance %d publish %s decode template output error: %v", ctx.GetOpId(), ctx.GetInstanceId(), output.String(), err)
+						stats.IncTotalExceptions()
+						return nil
+					}
+				}
+				if c != nil {
+					if ot, e := c.Encode(r); e != nil {
+						logger.Warnf("sink node %s instance %d publish %s encode error: %v", ctx.GetOpId(), ctx.GetInstanceId(), r, e)
+						stats.IncTotalExceptions()
+						return nil
+					} else {
+						outdatas = append(outdatas, ot)
+					}
 				} else {
 					if ot, e := json.Marshal(r); e != nil {
 						logger.Warnf("sink node %s instance %d publish %s marshal error: %v", ctx.GetOpId(), ctx.GetInstanceId(), r, e)
@@ -365,12 +412,12 @@ func getOutData(stats StatManager, ctx api.StreamContext, item interface{}, omit
 	return outdatas
 }
 
-func doCollectCacheTuple(sink api.Sink, item *CacheTuple, stats StatManager, retryInterval, retryCount int, omitIfEmpty bool, sendSingle bool, tp *template.Template, signalCh chan<- int, ctx api.StreamContext) {
+func doCollectCacheTuple(sink api.Sink, item *CacheTuple, stats StatManager, retryInterval, retryCount int, omitIfEmpty bool, sendSingle bool, tp *template.Template, c message.Converter, signalCh chan<- int, ctx api.StreamContext) {
 	stats.IncTotalRecordsIn()
 	stats.ProcessTimeStart()
 	defer stats.ProcessTimeEnd()
 	logger := ctx.GetLogger()
-	outdatas := getOutData(stats, ctx, item.data, omitIfEmpty, sendSingle, tp)
+	outdatas := getOutData(stats, ctx, item.data, omitIfEmpty, sendSingle, tp, c)
 	for _, outdata := range outdatas {
 	outerloop:
 		for {
@@ -421,6 +468,16 @@ func doGetSink(name string, action map[string]interface{}) (api.Sink, error) {
 		s = &sink.RestSink{}
 	case "nop":
 		s = &sink.NopSink{}
+	case "websocket":
+		s = &sink.WebsocketSink{}
+	case "memory":
+		s = &sink.MemorySink{}
+	case "sql":
+		s = &sink.SQLSink{}
+	case "kafka":
+		s = &sink.KafkaSink{}
+	case "nats":
+		s = &sink.NatsSink{}
 	default:
 		s, err = plugin.GetSink(name)
 		if err != nil {
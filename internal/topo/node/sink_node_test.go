@@ -121,3 +121,73 @@ func TestSinkTemplate_Apply(t *testing.T) {
 		}
 	}
 }
+
+func TestSinkFormat_Apply(t *testing.T) {
+	conf.InitConf()
+	var tests = []struct {
+		config map[string]interface{}
+		data   []byte
+		result [][]byte
+	}{
+		{
+			config: map[string]interface{}{
+				"format":   "protobuf",
+				"schemaId": "test1.Address",
+			},
+			data:   []byte(`[{"city":"a"},{"city":"b","street":"c"}]`),
+			result: [][]byte{{0x0a, 0x01, 0x61}, {0x0a, 0x01, 0x62, 0x12, 0x01, 0x63}},
+		}, {
+			config: map[string]interface{}{
+				"format":       "protobuf",
+				"schemaId":     "test1.Address",
+				"dataTemplate": `{"city":"{{.name}}","street":"{{.road}}"}`,
+			},
+			data:   []byte(`[{"name":"a","road":"c"}]`),
+			result: [][]byte{{0x0a, 0x01, 0x61, 0x12, 0x01, 0x63}},
+		}, {
+			config: map[string]interface{}{
+				"format": "delimited",
+				"fields": []interface{}{"temperature", "humidity", "status"},
+			},
+			data:   []byte(`[{"temperature":23.4,"humidity":55,"status":"OK"},{"temperature":20,"status":"NG"}]`),
+			result: [][]byte{[]byte("23.4,55,OK"), []byte("20,,NG")},
+		}, {
+			config: map[string]interface{}{
+				"format":    "delimited",
+				"delimiter": "|",
+				"hasHeader": true,
+			},
+			data:   []byte(`[{"temperature":23.4,"humidity":55}]`),
+			result: [][]byte{[]byte("humidity|temperature\n55|23.4")},
+		}, {
+			config: map[string]interface{}{
+				"format": "json",
+			},
+			data:   []byte(`[{"city":"a"},{"city":"b","street":"c"}]`),
+			result: [][]byte{[]byte(`[{"city":"a"},{"city":"b","street":"c"}]`)},
+		}, {
+			// The format owned by the sink like the image sink is not a converter
+			config: map[string]interface{}{
+				"format": "png",
+			},
+			data:   []byte(`[{"city":"a"},{"city":"b","street":"c"}]`),
+			result: [][]byte{[]byte(`[{"city":"a"},{"city":"b","street":"c"}]`)},
+		},
+	}
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	contextLogger := conf.Log.WithField("rule", "TestSinkFormat_Apply")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+
+	for i, tt := range tests {
+		mockSink := mocknode.NewMockSink()
+		s := NewSinkNodeWithSink("mockSink", mockSink, tt.config)
+		s.Open(ctx, make(chan error))
+		s.input <- tt.data
+		time.Sleep(1 * time.Second)
+		s.close(ctx, contextLogger)
+		results := mockSink.GetResults()
+		if !reflect.DeepEqual(tt.result, results) {
+			t.Errorf("%d \tresult mismatch:\n\nexp=%x\n\ngot=%x\n\n", i, tt.result, results)
+		}
+	}
+}
@@ -17,11 +17,13 @@ package node
 import (
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/plugin"
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/internal/topo/source"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/message"
 	"sync"
 )
 
@@ -34,6 +36,12 @@ type SourceNode struct {
 	props        map[string]interface{}
 	mutex        sync.RWMutex
 	sources      []api.Source
+	// The stream field names in order, used to map the columns of delimited format
+	columns []string
+	// The latest offset of each source instance and the offsets to acknowledge for each pending checkpoint
+	offsets    map[int]interface{}
+	ackOffsets map[int64]map[int]interface{}
+	ackSources map[int]api.Acknowledgeable
 }
 
 func NewSourceNode(name string, st ast.StreamType, options *ast.Options) *SourceNode {
@@ -57,6 +65,12 @@ func NewSourceNode(name string, st ast.StreamType, options *ast.Options) *Source
 	}
 }
 
+// WithColumns sets the stream field names in the defined order
+func (m *SourceNode) WithColumns(columns []string) *SourceNode {
+	m.columns = columns
+	return m
+}
+
 const OffsetKey = "$$offset"
 
 func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
@@ -86,6 +100,10 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 		if m.options.RETAIN_SIZE > 0 && m.streamType == ast.TypeTable {
 			props["$retainSize"] = m.options.RETAIN_SIZE
 		}
+		// Map the columns of delimited text to the stream fields in order if not specified in the conf
+		if _, ok := props["fields"]; !ok && props["format"] == message.FormatDelimited && len(m.columns) > 0 {
+			props["fields"] = m.columns
+		}
 		m.reset()
 		logger.Infof("open source node %d instances", m.concurrency)
 		for i := 0; i < m.concurrency; i++ { // workers
@@ -127,6 +145,10 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 						m.drainError(errCh, err, ctx, logger)
 						return
 					case data := <-buffer.Out:
+						// The EOF only ends a batch of the table
+						if data.Message() == nil && m.streamType == ast.TypeStream {
+							continue
+						}
 						stats.IncTotalRecordsIn()
 						stats.ProcessTimeStart()
 						tuple := &xsql.Tuple{Emitter: m.name, Message: data.Message(), Timestamp: conf.GetNowInMilli(), Metadata: data.Meta()}
@@ -137,7 +159,16 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 						stats.IncTotalRecordsOut()
 						stats.SetBufferLength(int64(buffer.GetLength()))
 						if rw, ok := si.source.(api.Rewindable); ok {
-							if offset, err := rw.GetOffset(); err != nil {
+							var (
+								offset interface{}
+								err    error
+							)
+							if ot, ok := data.(api.OffsetTuple); ok {
+								offset = ot.Offset()
+							} else {
+								offset, err = rw.GetOffset()
+							}
+							if err != nil {
 								m.drainError(errCh, err, ctx, logger)
 							} else {
 								err = ctx.PutState(OffsetKey, offset)
@@ -145,6 +176,7 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 									m.drainError(errCh, err, ctx, logger)
 								}
 								logger.Debugf("Source save offset %v", offset)
+								m.ack(instance, si.source, offset, logger)
 							}
 						}
 						logger.Debugf("source node %s has consumed tuple of timestamp %d", m.name, tuple.Timestamp)
@@ -157,6 +189,70 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 
 func (m *SourceNode) reset() {
 	m.statManagers = nil
+	m.mutex.Lock()
+	m.offsets = make(map[int]interface{})
+	m.ackOffsets = make(map[int64]map[int]interface{})
+	m.ackSources = make(map[int]api.Acknowledgeable)
+	m.mutex.Unlock()
+}
+
+// ack acknowledges the offset to the source at once, or records it to acknowledge once a checkpoint including it
+// completes. The shared sources are acknowledged at once as they are not rewound from the checkpoint.
+func (m *SourceNode) ack(instance int, s api.Source, offset interface{}, logger api.Logger) {
+	a, ok := s.(api.Acknowledgeable)
+	if !ok {
+		return
+	}
+	if m.qos >= api.AtLeastOnce && !m.options.SHARED {
+		m.mutex.Lock()
+		m.offsets[instance] = offset
+		m.ackSources[instance] = a
+		m.mutex.Unlock()
+		return
+	}
+	if err := a.Ack(offset); err != nil {
+		logger.Warnf("Source %s fails to acknowledge offset %v: %v", m.name, offset, err)
+	}
+}
+
+// Broadcast records the offsets processed before a checkpoint barrier to acknowledge them once the checkpoint
+// completes. The offset of a tuple is updated after it is sent, so the recorded offsets never cover the tuples
+// after the barrier.
+func (m *SourceNode) Broadcast(val interface{}) error {
+	if b, ok := val.(*checkpoint.Barrier); ok {
+		m.mutex.Lock()
+		if len(m.offsets) > 0 {
+			offsets := make(map[int]interface{}, len(m.offsets))
+			for k, v := range m.offsets {
+				offsets[k] = v
+			}
+			m.ackOffsets[b.CheckpointId] = offsets
+		}
+		m.mutex.Unlock()
+	}
+	return m.defaultNode.Broadcast(val)
+}
+
+// CheckpointComplete acknowledges the offsets recorded for the checkpoint. The offsets of the previous checkpoints
+// are covered by them and dropped.
+func (m *SourceNode) CheckpointComplete(checkpointId int64) {
+	m.mutex.Lock()
+	offsets := m.ackOffsets[checkpointId]
+	for cid := range m.ackOffsets {
+		if cid <= checkpointId {
+			delete(m.ackOffsets, cid)
+		}
+	}
+	sources := make(map[int]api.Acknowledgeable, len(offsets))
+	for instance := range offsets {
+		sources[instance] = m.ackSources[instance]
+	}
+	m.mutex.Unlock()
+	for instance, offset := range offsets {
+		if err := sources[instance].Ack(offset); err != nil {
+			m.ctx.GetLogger().Warnf("Source %s fails to acknowledge offset %v of checkpoint %d: %v", m.name, offset, checkpointId, err)
+		}
+	}
 }
 
 func doGetSource(t string) (api.Source, error) {
@@ -169,8 +265,20 @@ func doGetSource(t string) (api.Source, error) {
 		s = &source.MQTTSource{}
 	case "httppull":
 		s = &source.HTTPPullSource{}
+	case "httppush":
+		s = &source.HTTPPushSource{}
+	case "memory":
+		s = &source.MemorySource{}
+	case "websocket":
+		s = &source.WebsocketSource{}
 	case "file":
 		s = &source.FileSource{}
+	case "sql":
+		s = &source.SQLSource{}
+	case "kafka":
+		s = &source.KafkaSource{}
+	case "nats":
+		s = &source.NatsSource{}
 	default:
 		s, err = plugin.GetSource(t)
 		if err != nil {
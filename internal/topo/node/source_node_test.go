@@ -16,7 +16,9 @@ package node
 
 import (
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"reflect"
@@ -54,6 +56,32 @@ func TestGetConf_Apply(t *testing.T) {
 	}
 }
 
+func TestGetConfWithFormat_Apply(t *testing.T) {
+	result := map[string]interface{}{
+		"interval":  100,
+		"seed":      1,
+		"format":    "delimited",
+		"delimiter": "|",
+		"pattern": map[string]interface{}{
+			"count": 50,
+		},
+		"deduplicate": 50,
+	}
+	n := NewSourceNode("test", ast.TypeStream, &ast.Options{
+		DATASOURCE: "test",
+		TYPE:       "random",
+		CONF_KEY:   "dedup",
+		FORMAT:     "DELIMITED",
+		DELIMITER:  "|",
+	})
+	contextLogger := conf.Log.WithField("rule", "test")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	conf := getSourceConf(ctx, n.sourceType, n.options)
+	if !reflect.DeepEqual(result, conf) {
+		t.Errorf("result mismatch:\n\nexp=%s\n\ngot=%s\n\n", result, conf)
+	}
+}
+
 func TestGetConfAndConvert_Apply(t *testing.T) {
 	result := map[string]interface{}{
 		"interval": 100,
@@ -105,3 +133,69 @@ type randomSourceConfig struct {
 	Pattern     map[string]interface{} `json:"pattern"`
 	Deduplicate int                    `json:"deduplicate"`
 }
+
+type mockAckSource struct {
+	acked []interface{}
+}
+
+func (m *mockAckSource) Open(_ api.StreamContext, _ chan<- api.SourceTuple, _ chan<- error) {}
+
+func (m *mockAckSource) Configure(_ string, _ map[string]interface{}) error {
+	return nil
+}
+
+func (m *mockAckSource) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func (m *mockAckSource) Ack(offset interface{}) error {
+	m.acked = append(m.acked, offset)
+	return nil
+}
+
+func TestSourceAck(t *testing.T) {
+	var tests = []struct {
+		qos    api.Qos
+		shared bool
+		acked  []interface{}
+	}{
+		{
+			qos:   api.AtMostOnce,
+			acked: []interface{}{1, 2, 3},
+		}, {
+			qos:    api.AtLeastOnce,
+			shared: true,
+			acked:  []interface{}{1, 2, 3},
+		}, {
+			// Only the offsets before the completed checkpoint are acknowledged
+			qos:   api.AtLeastOnce,
+			acked: []interface{}{2},
+		}, {
+			qos:   api.ExactlyOnce,
+			acked: []interface{}{2},
+		},
+	}
+	for i, tt := range tests {
+		n := NewSourceNode("test", ast.TypeStream, &ast.Options{
+			DATASOURCE: "demo",
+			TYPE:       "mock",
+			SHARED:     tt.shared,
+		})
+		n.ctx = context.Background()
+		n.SetQos(tt.qos)
+		n.reset()
+		s := &mockAckSource{}
+		logger := conf.Log
+		n.ack(0, s, 1, logger)
+		n.ack(0, s, 2, logger)
+		_ = n.Broadcast(&checkpoint.Barrier{CheckpointId: 1})
+		n.ack(0, s, 3, logger)
+		_ = n.Broadcast(&checkpoint.Barrier{CheckpointId: 2})
+		n.CheckpointComplete(1)
+		// Already acknowledged by the later checkpoint
+		n.CheckpointComplete(0)
+		if !reflect.DeepEqual(tt.acked, s.acked) {
+			t.Errorf("%d: acked mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.acked, s.acked)
+		}
+	}
+}
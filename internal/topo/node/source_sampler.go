@@ -0,0 +1,71 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"time"
+)
+
+// SampleSource opens a temporary instance of the source defined by the stream options and collects at most n
+// messages from it. The sampling ends when n messages are received, the source sends out an EOF or the timeout
+// is reached. The messages received before the timeout are returned.
+func SampleSource(st ast.StreamType, options *ast.Options, n int, timeout time.Duration) ([]map[string]interface{}, error) {
+	t := NewSourceNode("sampler", st, options).sourceType
+	contextLogger := conf.Log.WithField("sampler", t)
+	ctx, cancel := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger).WithCancel()
+	defer cancel()
+	props := getSourceConf(ctx, t, options)
+	s, err := getSource(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Configure(options.DATASOURCE, props); err != nil {
+		return nil, err
+	}
+	consumer := make(chan api.SourceTuple, n)
+	errCh := make(chan error, 1)
+	go s.Open(ctx, consumer, errCh)
+	defer func() {
+		if err := s.Close(ctx); err != nil {
+			contextLogger.Warnf("close source fails: %v", err)
+		}
+	}()
+
+	result := make([]map[string]interface{}, 0, n)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for len(result) < n {
+		select {
+		case data := <-consumer:
+			msg := data.Message()
+			// EOF of batch sources like file
+			if msg == nil {
+				return result, nil
+			}
+			result = append(result, msg)
+		case err := <-errCh:
+			return nil, fmt.Errorf("sampling source %s error: %v", t, err)
+		case <-timer.C:
+			contextLogger.Infof("sampling source %s timeout with %d messages received", t, len(result))
+			return result, nil
+		}
+	}
+	return result, nil
+}
@@ -41,3 +41,7 @@ func getSink(name string, action map[string]interface{}) (api.Sink, error) {
 	}
 	return doGetSink(name, action)
 }
+
+func getLookupSource(t string) (api.LookupSource, error) {
+	return doGetLookupSource(t)
+}
@@ -28,3 +28,7 @@ func getSource(t string) (api.Source, error) {
 func getSink(name string, action map[string]interface{}) (api.Sink, error) {
 	return doGetSink(name, action)
 }
+
+func getLookupSource(t string) (api.LookupSource, error) {
+	return doGetLookupSource(t)
+}
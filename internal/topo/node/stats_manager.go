@@ -27,6 +27,8 @@ type StatManager interface {
 	IncTotalRecordsIn()
 	IncTotalRecordsOut()
 	IncTotalExceptions()
+	IncTotalRecordsLate()
+	IncTotalRecordsInvalid()
 	ProcessTimeStart()
 	ProcessTimeEnd()
 	SetBufferLength(l int64)
@@ -36,31 +38,39 @@ type StatManager interface {
 //The statManager is not thread safe. Make sure it is used in only one instance
 type DefaultStatManager struct {
 	//metrics
-	totalRecordsIn  int64
-	totalRecordsOut int64
-	totalExceptions int64
-	processLatency  int64
-	lastInvocation  time.Time
-	bufferLength    int64
+	totalRecordsIn      int64
+	totalRecordsOut     int64
+	totalExceptions     int64
+	totalRecordsLate    int64
+	totalRecordsInvalid int64
+	processLatency      int64
+	lastInvocation      time.Time
+	bufferLength        int64
 	//configs
 	opType           string //"source", "op", "sink"
 	prefix           string
 	processTimeStart time.Time
 	opId             string
 	instanceId       int
+	reportLate       bool
+	reportInvalid    bool
 }
 
 type PrometheusStatManager struct {
 	DefaultStatManager
 	//prometheus metrics
-	pTotalRecordsIn  prometheus.Counter
-	pTotalRecordsOut prometheus.Counter
-	pTotalExceptions prometheus.Counter
-	pProcessLatency  prometheus.Gauge
-	pBufferLength    prometheus.Gauge
-}
-
-func NewStatManager(opType string, ctx api.StreamContext) (StatManager, error) {
+	pTotalRecordsIn      prometheus.Counter
+	pTotalRecordsOut     prometheus.Counter
+	pTotalExceptions     prometheus.Counter
+	pTotalRecordsLate    prometheus.Counter
+	pTotalRecordsInvalid prometheus.Counter
+	pProcessLatency      prometheus.Gauge
+	pBufferLength        prometheus.Gauge
+}
+
+// NewStatManager creates the stat manager of a node. The extra metrics such as RecordsLateTotal are only reported if
+// they are specified because only some kinds of nodes produce them.
+func NewStatManager(opType string, ctx api.StreamContext, extras ...string) (StatManager, error) {
 	var prefix string
 	switch opType {
 	case "source":
@@ -72,17 +82,28 @@ func NewStatManager(opType string, ctx api.StreamContext) (StatManager, error) {
 	default:
 		return nil, fmt.Errorf("invalid opType %s, must be \"source\", \"sink\" or \"op\"", opType)
 	}
+	dsm := DefaultStatManager{
+		opType:     opType,
+		prefix:     prefix,
+		opId:       ctx.GetOpId(),
+		instanceId: ctx.GetInstanceId(),
+	}
+	for _, e := range extras {
+		switch e {
+		case RecordsLateTotal:
+			dsm.reportLate = true
+		case RecordsInvalidTotal:
+			dsm.reportInvalid = true
+		default:
+			return nil, fmt.Errorf("invalid extra metric %s", e)
+		}
+	}
 
 	var sm StatManager
 	if conf.Config != nil && conf.Config.Basic.Prometheus {
 		ctx.GetLogger().Debugf("Create prometheus stat manager")
 		psm := &PrometheusStatManager{
-			DefaultStatManager: DefaultStatManager{
-				opType:     opType,
-				prefix:     prefix,
-				opId:       ctx.GetOpId(),
-				instanceId: ctx.GetInstanceId(),
-			},
+			DefaultStatManager: dsm,
 		}
 		//assign prometheus
 		mg := GetPrometheusMetrics().GetMetricsGroup(opType)
@@ -92,14 +113,15 @@ func NewStatManager(opType string, ctx api.StreamContext) (StatManager, error) {
 		psm.pTotalExceptions = mg.TotalExceptions.WithLabelValues(ctx.GetRuleId(), opType, ctx.GetOpId(), strInId)
 		psm.pProcessLatency = mg.ProcessLatency.WithLabelValues(ctx.GetRuleId(), opType, ctx.GetOpId(), strInId)
 		psm.pBufferLength = mg.BufferLength.WithLabelValues(ctx.GetRuleId(), opType, ctx.GetOpId(), strInId)
+		if dsm.reportLate {
+			psm.pTotalRecordsLate = mg.TotalRecordsLate.WithLabelValues(ctx.GetRuleId(), opType, ctx.GetOpId(), strInId)
+		}
+		if dsm.reportInvalid {
+			psm.pTotalRecordsInvalid = mg.TotalRecordsInvalid.WithLabelValues(ctx.GetRuleId(), opType, ctx.GetOpId(), strInId)
+		}
 		sm = psm
 	} else {
-		sm = &DefaultStatManager{
-			opType:     opType,
-			prefix:     prefix,
-			opId:       ctx.GetOpId(),
-			instanceId: ctx.GetInstanceId(),
-		}
+		sm = &dsm
 	}
 	return sm, nil
 }
@@ -118,6 +140,14 @@ func (sm *DefaultStatManager) IncTotalExceptions() {
 	sm.processTimeStart = t
 }
 
+func (sm *DefaultStatManager) IncTotalRecordsLate() {
+	sm.totalRecordsLate++
+}
+
+func (sm *DefaultStatManager) IncTotalRecordsInvalid() {
+	sm.totalRecordsInvalid++
+}
+
 func (sm *DefaultStatManager) ProcessTimeStart() {
 	sm.lastInvocation = time.Now()
 	sm.processTimeStart = sm.lastInvocation
@@ -150,6 +180,20 @@ func (sm *PrometheusStatManager) IncTotalExceptions() {
 	sm.processTimeStart = t
 }
 
+func (sm *PrometheusStatManager) IncTotalRecordsLate() {
+	sm.totalRecordsLate++
+	if sm.pTotalRecordsLate != nil {
+		sm.pTotalRecordsLate.Inc()
+	}
+}
+
+func (sm *PrometheusStatManager) IncTotalRecordsInvalid() {
+	sm.totalRecordsInvalid++
+	if sm.pTotalRecordsInvalid != nil {
+		sm.pTotalRecordsInvalid.Inc()
+	}
+}
+
 func (sm *PrometheusStatManager) ProcessTimeEnd() {
 	if !sm.processTimeStart.IsZero() {
 		sm.processLatency = int64(time.Since(sm.processTimeStart) / time.Microsecond)
@@ -172,6 +216,17 @@ func (sm *DefaultStatManager) GetMetrics() []interface{} {
 	} else {
 		result = append(result, 0)
 	}
+	// The extra metrics which are not reported are nil to keep the position of the MetricNames
+	if sm.reportLate {
+		result = append(result, sm.totalRecordsLate)
+	} else {
+		result = append(result, nil)
+	}
+	if sm.reportInvalid {
+		result = append(result, sm.totalRecordsInvalid)
+	} else {
+		result = append(result, nil)
+	}
 
 	return result
 }
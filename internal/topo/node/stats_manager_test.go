@@ -0,0 +1,76 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"reflect"
+	"testing"
+)
+
+func TestStatManagerExtras(t *testing.T) {
+	var tests = []struct {
+		extras  []string
+		late    interface{}
+		invalid interface{}
+		err     string
+	}{
+		{
+			extras:  nil,
+			late:    nil,
+			invalid: nil,
+		}, {
+			extras:  []string{RecordsLateTotal},
+			late:    int64(1),
+			invalid: nil,
+		}, {
+			extras:  []string{RecordsInvalidTotal},
+			late:    nil,
+			invalid: int64(1),
+		}, {
+			extras: []string{"unknown"},
+			err:    "invalid extra metric unknown",
+		},
+	}
+	contextLogger := conf.Log.WithField("rule", "TestStatManagerExtras")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for i, tt := range tests {
+		sm, err := NewStatManager("op", ctx, tt.extras...)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%v", i, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: create stat manager error: %v", i, err)
+			continue
+		}
+		sm.IncTotalRecordsLate()
+		sm.IncTotalRecordsInvalid()
+		metrics := sm.GetMetrics()
+		if len(metrics) != len(MetricNames) {
+			t.Errorf("%d: metrics length mismatch:\n  exp=%d\n  got=%d", i, len(MetricNames), len(metrics))
+			continue
+		}
+		if !reflect.DeepEqual(tt.late, metrics[6]) {
+			t.Errorf("%d: late metric mismatch:\n  exp=%v\n  got=%v", i, tt.late, metrics[6])
+		}
+		if !reflect.DeepEqual(tt.invalid, metrics[7]) {
+			t.Errorf("%d: invalid metric mismatch:\n  exp=%v\n  got=%v", i, tt.invalid, metrics[7])
+		}
+	}
+}
@@ -17,11 +17,14 @@ package node
 import (
 	"context"
 	"fmt"
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 	"math"
 	"sort"
+	"time"
 )
 
 type WatermarkTuple struct {
@@ -46,17 +49,35 @@ type WatermarkGenerator struct {
 	interval      int
 	//ticker          *clock.Ticker
 	stream chan<- interface{}
+	// The idle timeout of each topic and the processing time of its last event, used to detect the idle topics
+	idleTimeouts     map[string]int64
+	topicToActiveTs  map[string]int64
+	generatorStartTs int64
 	//state
 	lastWatermarkTs int64
 }
 
-func NewWatermarkGenerator(window *WindowConfig, l int64, s []string, stream chan<- interface{}) (*WatermarkGenerator, error) {
+// NewWatermarkGenerator creates the generator for the input topics. The idle timeout of a topic is set in idles,
+// or defaults to idle. A topic with a timeout of 0 never idles.
+func NewWatermarkGenerator(window *WindowConfig, l int64, idle int64, idles map[string]int64, s []string, stream chan<- interface{}) (*WatermarkGenerator, error) {
 	w := &WatermarkGenerator{
-		window:        window,
-		topicToTs:     make(map[string]int64),
-		lateTolerance: l,
-		inputTopics:   s,
-		stream:        stream,
+		window:           window,
+		topicToTs:        make(map[string]int64),
+		lateTolerance:    l,
+		inputTopics:      s,
+		stream:           stream,
+		idleTimeouts:     make(map[string]int64),
+		topicToActiveTs:  make(map[string]int64),
+		generatorStartTs: conf.GetNowInMilli(),
+	}
+	for _, topic := range s {
+		timeout, ok := idles[topic]
+		if !ok {
+			timeout = idle
+		}
+		if timeout > 0 {
+			w.idleTimeouts[topic] = timeout
+		}
 	}
 	switch window.Type {
 	case ast.NOT_WINDOW:
@@ -69,6 +90,8 @@ func NewWatermarkGenerator(window *WindowConfig, l int64, s []string, stream cha
 	case ast.SESSION_WINDOW:
 		//Use timeout to update watermark
 		w.interval = window.Interval
+	case ast.STATE_WINDOW:
+		//The window is triggered by the conditions, the tuples are scanned in order once the watermark passes them
 	default:
 		return nil, fmt.Errorf("unsupported window type %d", window.Type)
 	}
@@ -82,6 +105,9 @@ func (w *WatermarkGenerator) track(s string, ts int64, ctx api.StreamContext) bo
 	if !ok || ts > currentVal {
 		w.topicToTs[s] = ts
 	}
+	if len(w.idleTimeouts) > 0 {
+		w.topicToActiveTs[s] = conf.GetNowInMilli()
+	}
 	r := ts >= w.lastWatermarkTs
 	if r {
 		w.trigger(ctx)
@@ -106,6 +132,9 @@ func (w *WatermarkGenerator) trigger(ctx api.StreamContext) {
 }
 
 func (w *WatermarkGenerator) computeWatermarkTs(_ context.Context) int64 {
+	if len(w.idleTimeouts) > 0 {
+		return w.computeWatermarkTsWithIdle()
+	}
 	var ts int64
 	if len(w.topicToTs) >= len(w.inputTopics) {
 		ts = math.MaxInt64
@@ -118,6 +147,33 @@ func (w *WatermarkGenerator) computeWatermarkTs(_ context.Context) int64 {
 	return ts - w.lateTolerance
 }
 
+// computeWatermarkTsWithIdle excludes the topics which have not received any event for their idle timeout
+// so that a quiet stream does not stall the watermark. If all topics are idle, the watermark does not advance.
+func (w *WatermarkGenerator) computeWatermarkTsWithIdle() int64 {
+	now := conf.GetNowInMilli()
+	var ts int64 = math.MaxInt64
+	for _, key := range w.inputTopics {
+		activeTs, ok := w.topicToActiveTs[key]
+		if !ok {
+			activeTs = w.generatorStartTs
+		}
+		if timeout, ok := w.idleTimeouts[key]; ok && now-activeTs >= timeout {
+			continue
+		}
+		eventTs, ok := w.topicToTs[key]
+		if !ok {
+			return -w.lateTolerance
+		}
+		if ts > eventTs {
+			ts = eventTs
+		}
+	}
+	if ts == math.MaxInt64 {
+		return w.lastWatermarkTs
+	}
+	return ts - w.lateTolerance
+}
+
 //If window end cannot be determined yet, return max int64 so that it can be recalculated for the next watermark
 func (w *WatermarkGenerator) getNextWindow(inputs []*xsql.Tuple, current int64, watermark int64, triggered bool) int64 {
 	switch w.window.Type {
@@ -183,7 +239,13 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 		nextWindowEndTs int64
 		prevWindowEndTs int64
 		lastTicked      bool
+		emitTicker      *clock.Ticker
+		emitC           <-chan time.Time
 	)
+	if o.emitInterval > 0 {
+		emitTicker = conf.GetTicker(o.emitInterval)
+		emitC = emitTicker.C
+	}
 
 	o.watermarkGenerator.lastWatermarkTs = 0
 	if s, err := ctx.GetState(WATERMARK_KEY); err == nil && s != nil {
@@ -213,7 +275,10 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 				o.Broadcast(d)
 				o.statManager.IncTotalExceptions()
 			case xsql.Event:
-				if d.IsWatermark() {
+				if d.IsWatermark() && o.window.Type == ast.STATE_WINDOW {
+					inputs = o.scanStateByWatermark(inputs, d.GetTimestamp(), ctx)
+					ctx.PutState(MSG_COUNT_KEY, o.msgCount)
+				} else if d.IsWatermark() {
 					watermarkTs := d.GetTimestamp()
 					windowEndTs := nextWindowEndTs
 					ticked := false
@@ -252,6 +317,13 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 					log.Debugf("event window receive tuple %s", tuple.Message)
 					if o.watermarkGenerator.track(tuple.Emitter, d.GetTimestamp(), ctx) {
 						inputs = append(inputs, tuple)
+						if o.window.Type == ast.TUMBLING_WINDOW {
+							o.countEarly(inputs, ctx)
+						}
+					} else {
+						log.Debugf("event window drop late tuple %s", tuple.Message)
+						o.statManager.IncTotalRecordsLate()
+						o.emitLate(tuple)
 					}
 				}
 				o.statManager.ProcessTimeEnd()
@@ -261,17 +333,48 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 				o.Broadcast(fmt.Errorf("run Window error: expect xsql.Event type but got %[1]T(%[1]v)", d))
 				o.statManager.IncTotalExceptions()
 			}
+		case <-emitC:
+			if len(inputs) > 0 {
+				o.statManager.ProcessTimeStart()
+				log.Debugf("triggered by emit interval")
+				o.emitEarly(inputs, xsql.TriggerInterval, ctx)
+				o.statManager.ProcessTimeEnd()
+			}
 		// is cancelling
 		case <-ctx.Done():
 			log.Infoln("Cancelling window....")
 			if o.ticker != nil {
 				o.ticker.Stop()
 			}
+			if emitTicker != nil {
+				emitTicker.Stop()
+			}
 			return
 		}
 	}
 }
 
+// scanStateByWatermark runs the state window for the tuples which are not later than the watermark in the timestamp order.
+// The opened window tuples are kept at the beginning of the returned inputs followed by the tuples later than the watermark.
+func (o *WindowOperator) scanStateByWatermark(inputs []*xsql.Tuple, watermark int64, ctx api.StreamContext) []*xsql.Tuple {
+	pending := inputs[o.msgCount:]
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Timestamp < pending[j].Timestamp
+	})
+	i := 0
+	for ; i < len(pending); i++ {
+		if pending[i].Timestamp > watermark {
+			break
+		}
+	}
+	if i == 0 {
+		return inputs
+	}
+	rest := pending[i:]
+	opened := o.scanState(inputs[:o.msgCount+i], ctx)
+	return append(opened, rest...)
+}
+
 func getEarliestEventTs(inputs []*xsql.Tuple, startTs int64, endTs int64) int64 {
 	var minTs int64 = math.MaxInt64
 	for _, t := range inputs {
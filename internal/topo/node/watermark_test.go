@@ -0,0 +1,102 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"testing"
+)
+
+func TestWatermarkIdle(t *testing.T) {
+	var tests = []struct {
+		idle    int64
+		idles   map[string]int64
+		events  []string // the topic of the event sent at each second
+		expLast int64
+	}{
+		{ // the quiet stream stalls the watermark without idle timeout
+			idle:    0,
+			events:  []string{"s1", "s2", "s1", "s1", "s1", "s1"},
+			expLast: 1001,
+		}, {
+			idle:    2000,
+			events:  []string{"s1", "s2", "s1", "s1", "s1", "s1"},
+			expLast: 5000,
+		}, { // the stream never produces
+			idle:    2000,
+			events:  []string{"s1", "s1", "s1", "s1"},
+			expLast: 3000,
+		}, { // the idle stream joins again
+			idle:    2000,
+			events:  []string{"s1", "s2", "s1", "s1", "s1", "s2"},
+			expLast: 4000,
+		}, { // all streams are idle
+			idle:    1000,
+			events:  []string{"s1", "s2", "", "", ""},
+			expLast: 1001,
+		}, { // only the quiet stream idles
+			idles:   map[string]int64{"s2": 2000},
+			events:  []string{"s1", "s2", "s1", "s1", "s1", "s1"},
+			expLast: 5000,
+		}, { // the quiet stream never idles
+			idle:    2000,
+			idles:   map[string]int64{"s2": 0},
+			events:  []string{"s1", "s2", "s1", "s1", "s1", "s1"},
+			expLast: 1001,
+		}, { // the active stream idles at once
+			idles:   map[string]int64{"s1": 1000},
+			events:  []string{"s1", "s2", "", "s2"},
+			expLast: 3001,
+		},
+	}
+	defer func(c clock.Clock) {
+		conf.Clock = c
+	}(conf.Clock)
+	for i, tt := range tests {
+		mock := clock.NewMock()
+		mock.Set(cast.TimeFromUnixMilli(0))
+		conf.Clock = mock
+		store, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+		ctx := context.Background().WithMeta("mockRule0", "window", store)
+		stream := make(chan interface{}, 100)
+		w, err := NewWatermarkGenerator(&WindowConfig{Type: ast.TUMBLING_WINDOW, Length: 1000}, 0, tt.idle, tt.idles, []string{"s1", "s2"}, stream)
+		if err != nil {
+			t.Errorf("%d: create watermark generator error: %v", i, err)
+			continue
+		}
+		for j, topic := range tt.events {
+			mock.Set(cast.TimeFromUnixMilli(int64(j * 1000)))
+			if topic != "" {
+				// the event time is the processing time plus 1 millisecond for s2
+				ts := int64(j * 1000)
+				if topic == "s2" {
+					ts += 1
+				}
+				w.track(topic, ts, ctx)
+			} else {
+				w.trigger(ctx)
+			}
+		}
+		if w.lastWatermarkTs != tt.expLast {
+			t.Errorf("%d: last watermark mismatch, expect %d but got %d", i, tt.expLast, w.lastWatermarkTs)
+		}
+	}
+}
@@ -16,9 +16,11 @@ package node
 
 import (
 	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"github.com/benbjohnson/clock"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -31,6 +33,9 @@ type WindowConfig struct {
 	Type     ast.WindowType
 	Length   int
 	Interval int //If interval is not set, it is equals to Length
+	// For state window only
+	BeginCondition ast.Expr
+	EndCondition   ast.Expr
 }
 
 type WindowOperator struct {
@@ -39,17 +44,29 @@ type WindowOperator struct {
 	interval           int
 	isEventTime        bool
 	watermarkGenerator *WatermarkGenerator //For event time only
+	lateNode           *defaultNode        //For event time only, emit the late tuples
 
 	statManager StatManager
-	ticker      *clock.Ticker //For processing time only
+	ticker      *clock.Ticker        //For processing time only
+	fv          *xsql.FunctionValuer //For state window only
+	// For early emission of tumbling window only
+	emitInterval int
+	emitCount    int
+	earlyCount   int // the count of received messages since the last emission
 	// states
 	triggerTime int64
-	msgCount    int
+	// For count window, it is the count of received messages.
+	// For state window, it is the count of messages in the opened window which are at the beginning of the inputs.
+	msgCount int
 }
 
 const WINDOW_INPUTS_KEY = "$$windowInputs"
 const TRIGGER_TIME_KEY = "$$triggerTime"
 const MSG_COUNT_KEY = "$$msgCount"
+const EARLY_COUNT_KEY = "$$earlyCount"
+
+// WINDOW_TRIGGER_META is the metadata key of the window trigger when early emission is enabled
+const WINDOW_TRIGGER_META = "window_trigger"
 
 func init() {
 	gob.Register([]*xsql.Tuple{})
@@ -66,15 +83,23 @@ func NewWindowOp(name string, w WindowConfig, streams []string, options *api.Rul
 			sendError: options.SendError,
 		},
 	}
+	o.lateNode = &defaultNode{
+		outputs:   make(map[string]chan<- interface{}),
+		name:      name,
+		sendError: options.SendError,
+	}
 	o.isEventTime = options.IsEventTime
 	o.window = &w
 	if o.window.Interval == 0 && o.window.Type == ast.COUNT_WINDOW {
 		//if no interval value is set and it's count window, then set interval to length value.
 		o.window.Interval = o.window.Length
 	}
+	// The planner makes sure early emission is only set for tumbling window
+	o.emitInterval = options.EmitInterval
+	o.emitCount = options.EmitCount
 	if options.IsEventTime {
 		//Create watermark generator
-		if w, err := NewWatermarkGenerator(o.window, options.LateTol, streams, o.input); err != nil {
+		if w, err := NewWatermarkGenerator(o.window, options.LateTol, options.IdleTimeout, options.IdleTimeouts, streams, o.input); err != nil {
 			return nil, err
 		} else {
 			o.watermarkGenerator = w
@@ -88,6 +113,8 @@ func NewWindowOp(name string, w WindowConfig, streams []string, options *api.Rul
 // output: xsql.WindowTuplesSet
 func (o *WindowOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 	o.ctx = ctx
+	o.lateNode.ctx = ctx
+	o.lateNode.qos = o.qos
 	log := ctx.GetLogger()
 	log.Debugf("Window operator %s is started", o.name)
 
@@ -95,7 +122,7 @@ func (o *WindowOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 		go func() { errCh <- fmt.Errorf("no output channel found") }()
 		return
 	}
-	stats, err := NewStatManager("op", ctx)
+	stats, err := NewStatManager("op", ctx, RecordsLateTotal)
 	if err != nil {
 		go func() { errCh <- err }()
 		return
@@ -131,7 +158,18 @@ func (o *WindowOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 			errCh <- fmt.Errorf("restore window state `msgCount` %v error, invalid type", s)
 		}
 	}
-	log.Infof("Start with window state triggerTime: %d, msgCount: %d", o.triggerTime, o.msgCount)
+	o.earlyCount = 0
+	if s, err := ctx.GetState(EARLY_COUNT_KEY); err == nil && s != nil {
+		if si, ok := s.(int); ok {
+			o.earlyCount = si
+		} else {
+			errCh <- fmt.Errorf("restore window state `earlyCount` %v error, invalid type", s)
+		}
+	}
+	log.Infof("Start with window state triggerTime: %d, msgCount: %d, earlyCount: %d", o.triggerTime, o.msgCount, o.earlyCount)
+	if o.window.Type == ast.STATE_WINDOW {
+		o.fv, _ = xsql.NewFunctionValuersForOp(ctx, xsql.FuncRegisters)
+	}
 	if o.isEventTime {
 		go o.execEventWindow(ctx, inputs, errCh)
 	} else {
@@ -163,6 +201,15 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 		o.interval = o.window.Interval
 	}
 
+	var (
+		emitTicker *clock.Ticker
+		emitC      <-chan time.Time
+	)
+	if o.emitInterval > 0 {
+		emitTicker = conf.GetTicker(o.emitInterval)
+		emitC = emitTicker.C
+	}
+
 	if o.ticker != nil {
 		c = o.ticker.C
 		//resume previous window
@@ -258,6 +305,10 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 						ctx.PutState(TRIGGER_TIME_KEY, o.triggerTime)
 						log.Debugf("Session window set start time %d", o.triggerTime)
 					}
+				case ast.TUMBLING_WINDOW:
+					o.countEarly(inputs, ctx)
+				case ast.STATE_WINDOW:
+					inputs = o.scanState(inputs, ctx)
 				case ast.COUNT_WINDOW:
 					o.msgCount++
 					log.Debugf(fmt.Sprintf("msgCount: %d", o.msgCount))
@@ -321,12 +372,22 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 				ctx.PutState(TRIGGER_TIME_KEY, o.triggerTime)
 				timeoutTicker = nil
 			}
+		case <-emitC:
+			if len(inputs) > 0 {
+				o.statManager.ProcessTimeStart()
+				log.Debugf("triggered by emit interval")
+				o.emitEarly(inputs, xsql.TriggerInterval, ctx)
+				o.statManager.ProcessTimeEnd()
+			}
 		// is cancelling
 		case <-ctx.Done():
 			log.Infoln("Cancelling window....")
 			if o.ticker != nil {
 				o.ticker.Stop()
 			}
+			if emitTicker != nil {
+				emitTicker.Stop()
+			}
 			return
 		}
 	}
@@ -394,6 +455,7 @@ func (o *WindowOperator) scan(inputs []*xsql.Tuple, triggerTime int64, ctx api.S
 		Content: make([]xsql.WindowTuples, 0),
 		WindowRange: &xsql.WindowRange{
 			WindowEnd: triggerTime,
+			Trigger:   xsql.TriggerFinal,
 		},
 	}
 	i := 0
@@ -433,11 +495,14 @@ func (o *WindowOperator) scan(inputs []*xsql.Tuple, triggerTime int64, ctx api.S
 		if o.isEventTime {
 			results.Sort()
 		}
+		o.recordTrigger(results)
 		log.Debugf("Sent: %v", results)
 		//blocking if one of the channel is full
 		o.Broadcast(results)
 		triggered = true
 		o.triggerTime = triggerTime
+		o.earlyCount = 0
+		ctx.PutState(EARLY_COUNT_KEY, o.earlyCount)
 		o.statManager.IncTotalRecordsOut()
 		log.Debugf("done scan")
 	}
@@ -445,6 +510,142 @@ func (o *WindowOperator) scan(inputs []*xsql.Tuple, triggerTime int64, ctx api.S
 	return inputs[:i], triggered
 }
 
+// scanState runs the state window for the inputs in order. The first msgCount inputs are the
+// tuples of the opened window and the rest are the new tuples. A window is opened by a tuple which
+// matches the begin condition and is emitted when a tuple matches the end condition. The tuples
+// before the window opens are dropped. Return the tuples of the window which is still open.
+func (o *WindowOperator) scanState(inputs []*xsql.Tuple, ctx api.StreamContext) []*xsql.Tuple {
+	log := ctx.GetLogger()
+	opened := make([]*xsql.Tuple, o.msgCount, len(inputs))
+	copy(opened, inputs[:o.msgCount])
+	for _, tuple := range inputs[o.msgCount:] {
+		if len(opened) == 0 {
+			if !o.evalStateCondition(o.window.BeginCondition, tuple) {
+				continue
+			}
+			o.triggerTime = tuple.Timestamp
+			ctx.PutState(TRIGGER_TIME_KEY, o.triggerTime)
+			log.Debugf("state window %s opened at %d", o.name, o.triggerTime)
+		}
+		opened = append(opened, tuple)
+		if o.evalStateCondition(o.window.EndCondition, tuple) {
+			results := xsql.WindowTuplesSet{
+				Content: make([]xsql.WindowTuples, 0),
+				WindowRange: &xsql.WindowRange{
+					WindowStart: o.triggerTime,
+					WindowEnd:   tuple.Timestamp,
+					Trigger:     xsql.TriggerFinal,
+				},
+			}
+			for _, t := range opened {
+				results = results.AddTuple(t)
+			}
+			log.Debugf("Sent: %v", results)
+			o.Broadcast(results)
+			o.statManager.IncTotalRecordsOut()
+			o.triggerTime = tuple.Timestamp
+			ctx.PutState(TRIGGER_TIME_KEY, o.triggerTime)
+			opened = make([]*xsql.Tuple, 0, len(inputs))
+		}
+	}
+	o.msgCount = len(opened)
+	return opened
+}
+
+// countEarly counts the received message and emits the current tumbling window once emitCount is reached.
+func (o *WindowOperator) countEarly(inputs []*xsql.Tuple, ctx api.StreamContext) {
+	if o.emitCount <= 0 {
+		return
+	}
+	o.earlyCount++
+	if o.earlyCount >= o.emitCount {
+		ctx.GetLogger().Debugf("triggered by emit count %d", o.earlyCount)
+		o.emitEarly(inputs, xsql.TriggerCount, ctx)
+	} else {
+		ctx.PutState(EARLY_COUNT_KEY, o.earlyCount)
+	}
+}
+
+// emitEarly sends out the intermediate result of the current tumbling window which is still open.
+// For processing time, all the inputs belong to the current window. For event time, the current
+// window is the one which the earliest input belongs to.
+func (o *WindowOperator) emitEarly(inputs []*xsql.Tuple, trigger string, ctx api.StreamContext) {
+	length := int64(o.window.Length)
+	var windowEnd int64
+	if o.isEventTime {
+		windowEnd = int64(math.MaxInt64)
+		for _, tuple := range inputs {
+			if tuple.Timestamp < windowEnd {
+				windowEnd = tuple.Timestamp
+			}
+		}
+		if windowEnd%length != 0 {
+			windowEnd = windowEnd + length - windowEnd%length
+		}
+	} else {
+		windowEnd = o.triggerTime + length
+	}
+	results := xsql.WindowTuplesSet{
+		Content: make([]xsql.WindowTuples, 0),
+		WindowRange: &xsql.WindowRange{
+			WindowStart: windowEnd - length,
+			WindowEnd:   windowEnd,
+			Trigger:     trigger,
+		},
+	}
+	for _, tuple := range inputs {
+		if !o.isEventTime || tuple.Timestamp <= windowEnd {
+			results = results.AddTuple(tuple)
+		}
+	}
+	if o.isEventTime {
+		results.Sort()
+	}
+	o.recordTrigger(results)
+	ctx.GetLogger().Debugf("Sent early: %v", results)
+	o.Broadcast(results)
+	o.earlyCount = 0
+	ctx.PutState(EARLY_COUNT_KEY, o.earlyCount)
+	o.statManager.IncTotalRecordsOut()
+}
+
+// recordTrigger records the trigger in the metadata of the emitted tuples if early emission is enabled,
+// so that the intermediate results and the final result can be told apart by meta(window_trigger).
+// The metadata is copied because the tuples of an open window are emitted more than once.
+func (o *WindowOperator) recordTrigger(results xsql.WindowTuplesSet) {
+	if o.emitInterval <= 0 && o.emitCount <= 0 {
+		return
+	}
+	for _, wt := range results.Content {
+		for i := range wt.Tuples {
+			md := make(xsql.Metadata, len(wt.Tuples[i].Metadata)+1)
+			for k, v := range wt.Tuples[i].Metadata {
+				md[k] = v
+			}
+			md[WINDOW_TRIGGER_META] = results.Trigger
+			wt.Tuples[i].Metadata = md
+		}
+	}
+}
+
+// evalStateCondition evaluates the begin or end condition of the state window.
+// An evaluation error is sent out and regarded as false.
+func (o *WindowOperator) evalStateCondition(condition ast.Expr, tuple *xsql.Tuple) bool {
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(tuple, o.fv)}
+	switch r := ve.Eval(condition).(type) {
+	case error:
+		o.Broadcast(fmt.Errorf("run state window error: %s", r))
+		o.statManager.IncTotalExceptions()
+	case bool:
+		return r
+	case nil: // null is false
+	default:
+		o.Broadcast(fmt.Errorf("run state window error: invalid condition that returns non-bool value %[1]T(%[1]v)", r))
+		o.statManager.IncTotalExceptions()
+	}
+	return false
+}
+
 func (o *WindowOperator) calDelta(triggerTime int64, delta int64, log api.Logger) int64 {
 	lastTriggerTime := o.triggerTime
 	if lastTriggerTime <= 0 {
@@ -462,6 +663,30 @@ func (o *WindowOperator) calDelta(triggerTime int64, delta int64, log api.Logger
 	return delta
 }
 
+// LateEmitter returns the emitter of the late tuples which are dropped by the event time window
+func (o *WindowOperator) LateEmitter() api.Emitter {
+	return o.lateNode
+}
+
+// Broadcast also sends the checkpoint barriers to the late outputs so that they can be aligned
+func (o *WindowOperator) Broadcast(val interface{}) error {
+	if _, ok := val.(*checkpoint.Barrier); ok && len(o.lateNode.outputs) > 0 {
+		_ = o.lateNode.Broadcast(val)
+	}
+	return o.defaultSinkNode.Broadcast(val)
+}
+
+func (o *WindowOperator) emitLate(tuple *xsql.Tuple) {
+	if len(o.lateNode.outputs) == 0 {
+		return
+	}
+	if r, err := json.Marshal([]map[string]interface{}{tuple.Message}); err != nil {
+		o.ctx.GetLogger().Errorf("fail to encode late tuple %v: %v", tuple.Message, err)
+	} else {
+		_ = o.lateNode.Broadcast(r)
+	}
+}
+
 func (o *WindowOperator) GetMetrics() [][]interface{} {
 	if o.statManager != nil {
 		return [][]interface{}{
@@ -0,0 +1,244 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAggFunc_Apply1(t *testing.T) {
+	var tests = []struct {
+		sql    string
+		data   interface{}
+		result []map[string]interface{}
+	}{
+		{
+			sql: "SELECT stddev(a) AS sd, stddevs(a) AS sds, var(a) AS v, vars(a) AS vs FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": 2}},
+						{Emitter: "test", Message: xsql.Message{"a": 4}},
+						{Emitter: "test", Message: xsql.Message{"a": 4}},
+						{Emitter: "test", Message: xsql.Message{"a": 4}},
+						{Emitter: "test", Message: xsql.Message{"b": "x"}},
+						{Emitter: "test", Message: xsql.Message{"a": 5}},
+						{Emitter: "test", Message: xsql.Message{"a": 5}},
+						{Emitter: "test", Message: xsql.Message{"a": 7}},
+						{Emitter: "test", Message: xsql.Message{"a": 9}},
+					},
+				}},
+			},
+			result: []map[string]interface{}{{
+				"sd":  2.0,
+				"sds": 2.138089935299395,
+				"v":   4.0,
+				"vs":  4.571428571428571,
+			}},
+		},
+		{
+			sql: "SELECT median(a) AS m, percentile(a, 0.25) AS p25, percentile(a, 0.75) AS p75, percentile(a, 1) AS p100, mode(a) AS mo, mode(b) AS mb FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": 9.0, "b": "y"}},
+						{Emitter: "test", Message: xsql.Message{"a": 4.0, "b": "x"}},
+						{Emitter: "test", Message: xsql.Message{"a": 2.0, "b": "y"}},
+						{Emitter: "test", Message: xsql.Message{"a": 5.0, "b": "x"}},
+						{Emitter: "test", Message: xsql.Message{"a": 4.0}},
+						{Emitter: "test", Message: xsql.Message{"a": 7.0}},
+						{Emitter: "test", Message: xsql.Message{"a": 5.0}},
+						{Emitter: "test", Message: xsql.Message{"a": 4.0}},
+					},
+				}},
+			},
+			result: []map[string]interface{}{{
+				"m":    4.5,
+				"p25":  4.0,
+				"p75":  5.5,
+				"p100": 9.0,
+				"mo":   4.0,
+				"mb":   "y",
+			}},
+		},
+		{
+			// Numbers of the same value are counted together
+			sql: "SELECT mode(a) AS mo FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": 2}},
+						{Emitter: "test", Message: xsql.Message{"a": 2}},
+						{Emitter: "test", Message: xsql.Message{"a": 1}},
+						{Emitter: "test", Message: xsql.Message{"a": 1.0}},
+						{Emitter: "test", Message: xsql.Message{"a": int64(1)}},
+					},
+				}},
+			},
+			result: []map[string]interface{}{{
+				"mo": 1.0,
+			}},
+		},
+		{
+			// The first met value is returned on a tie
+			sql: "SELECT mode(a) AS mo FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": "a"}},
+						{Emitter: "test", Message: xsql.Message{"a": "b"}},
+						{Emitter: "test", Message: xsql.Message{"a": "b"}},
+						{Emitter: "test", Message: xsql.Message{"a": "a"}},
+					},
+				}},
+			},
+			result: []map[string]interface{}{{
+				"mo": "a",
+			}},
+		},
+		{
+			// Large integers are not merged
+			sql: "SELECT mode(a) AS mo FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": int64(9007199254740992)}},
+						{Emitter: "test", Message: xsql.Message{"a": int64(9007199254740993)}},
+						{Emitter: "test", Message: xsql.Message{"a": 5}},
+						{Emitter: "test", Message: xsql.Message{"a": 5}},
+					},
+				}},
+			},
+			result: []map[string]interface{}{{
+				"mo": 5.0,
+			}},
+		},
+		{
+			sql: "SELECT stddev(a) AS sd, stddevs(a) AS sds, median(a) AS m, mode(a) AS mo FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": 3}},
+						{Emitter: "test", Message: xsql.Message{"b": "x"}},
+					},
+				}},
+			},
+			result: []map[string]interface{}{{
+				"sd": 0.0,
+				"m":  3.0,
+				"mo": 3.0,
+			}},
+		},
+		{
+			sql: "SELECT var(a) AS v, median(a) AS m, percentile(a, 0.5) AS p, mode(a) AS mo FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"b": "x"}},
+					},
+				}},
+			},
+			result: []map[string]interface{}{{}},
+		},
+	}
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	contextLogger := conf.Log.WithField("rule", "TestAggFunc_Apply1")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for i, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		if err != nil {
+			t.Errorf("parse sql %s error %v", tt.sql, err)
+			continue
+		}
+		pp := &ProjectOp{Fields: stmt.Fields, IsAggregate: true}
+		fv, afv := xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+		result := pp.Apply(ctx, tt.data, fv, afv)
+		var mapRes []map[string]interface{}
+		if v, ok := result.([]byte); ok {
+			err := json.Unmarshal(v, &mapRes)
+			if err != nil {
+				t.Errorf("Failed to parse the input into map.\n")
+				continue
+			}
+			if !reflect.DeepEqual(tt.result, mapRes) {
+				t.Errorf("%d. %q\n\nresult mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.sql, tt.result, mapRes)
+			}
+		} else {
+			t.Errorf("%d. %q\n\nThe returned result is not type of []byte: %#v\n", i, tt.sql, result)
+		}
+	}
+}
+
+func TestAggFuncError(t *testing.T) {
+	var tests = []struct {
+		sql    string
+		data   interface{}
+		result interface{}
+	}{
+		{
+			sql: "SELECT stddev(a) AS sd FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": 3}},
+						{Emitter: "test", Message: xsql.Message{"a": "x"}},
+					},
+				}},
+			},
+			result: fmt.Errorf("run Select error: call func stddev error: run stddev function error: requires number but found string(x)"),
+		},
+		{
+			sql: "SELECT mode(a) AS mo FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": map[string]interface{}{"b": 1}}},
+					},
+				}},
+			},
+			result: fmt.Errorf("run Select error: call func mode error: run mode function error: found invalid arg map[string]interface {}(map[b:1])"),
+		},
+	}
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	contextLogger := conf.Log.WithField("rule", "TestAggFuncError")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for i, tt := range tests {
+		stmt, _ := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		pp := &ProjectOp{Fields: stmt.Fields, IsAggregate: true}
+		fv, afv := xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+		result := pp.Apply(ctx, tt.data, fv, afv)
+		if !reflect.DeepEqual(tt.result, result) {
+			t.Errorf("%d. %q\n\nresult mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.sql, tt.result, result)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyticFunc_Apply1(t *testing.T) {
+	var tests = []struct {
+		sql    string
+		data   []*xsql.Tuple
+		result [][]map[string]interface{}
+	}{
+		{
+			sql: "SELECT a, lag(a) AS l, lag(a, 2) AS l2 FROM test",
+			data: []*xsql.Tuple{
+				{Emitter: "test", Message: xsql.Message{"a": 1}},
+				{Emitter: "test", Message: xsql.Message{"a": 2}},
+				{Emitter: "test", Message: xsql.Message{"a": 3}},
+				{Emitter: "test", Message: xsql.Message{"a": 4}},
+			},
+			result: [][]map[string]interface{}{{{
+				"a": 1.0,
+			}}, {{
+				"a": 2.0,
+				"l": 1.0,
+			}}, {{
+				"a":  3.0,
+				"l":  2.0,
+				"l2": 1.0,
+			}}, {{
+				"a":  4.0,
+				"l":  3.0,
+				"l2": 2.0,
+			}}},
+		},
+		{
+			sql: "SELECT a, lag(a) OVER (PARTITION BY b) AS l FROM test",
+			data: []*xsql.Tuple{
+				{Emitter: "test", Message: xsql.Message{"a": 1, "b": "x"}},
+				{Emitter: "test", Message: xsql.Message{"a": 2, "b": "y"}},
+				{Emitter: "test", Message: xsql.Message{"a": 3, "b": "x"}},
+				{Emitter: "test", Message: xsql.Message{"a": 4, "b": "y"}},
+			},
+			result: [][]map[string]interface{}{{{
+				"a": 1.0,
+			}}, {{
+				"a": 2.0,
+			}}, {{
+				"a": 3.0,
+				"l": 1.0,
+			}}, {{
+				"a": 4.0,
+				"l": 2.0,
+			}}},
+		},
+		{
+			sql: "SELECT latest(a) AS l, latest(a, 0) AS d FROM test",
+			data: []*xsql.Tuple{
+				{Emitter: "test", Message: xsql.Message{"b": "x"}},
+				{Emitter: "test", Message: xsql.Message{"a": 2}},
+				{Emitter: "test", Message: xsql.Message{"b": "y"}},
+			},
+			result: [][]map[string]interface{}{{{
+				"d": 0.0,
+			}}, {{
+				"l": 2.0,
+				"d": 2.0,
+			}}, {{
+				"l": 2.0,
+				"d": 2.0,
+			}}},
+		},
+		{
+			sql: "SELECT changed_col(true, a) AS c, had_changed(true, a, b) AS h, had_changed(false, a) AS hn FROM test",
+			data: []*xsql.Tuple{
+				{Emitter: "test", Message: xsql.Message{"a": 1, "b": "x"}},
+				{Emitter: "test", Message: xsql.Message{"a": 1, "b": "x"}},
+				{Emitter: "test", Message: xsql.Message{"b": "x"}},
+				{Emitter: "test", Message: xsql.Message{"a": 1, "b": "y"}},
+				{Emitter: "test", Message: xsql.Message{"a": 2, "b": "y"}},
+			},
+			result: [][]map[string]interface{}{{{
+				"c":  1.0,
+				"h":  true,
+				"hn": true,
+			}}, {{
+				"h":  false,
+				"hn": false,
+			}}, {{
+				"h":  false,
+				"hn": true,
+			}}, {{
+				"h":  true,
+				"hn": true,
+			}}, {{
+				"c":  2.0,
+				"h":  true,
+				"hn": true,
+			}}},
+		},
+	}
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	contextLogger := conf.Log.WithField("rule", "TestAnalyticFunc_Apply1")
+	for i, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		if err != nil || stmt == nil {
+			t.Errorf("parse sql %s error %v", tt.sql, err)
+			continue
+		}
+		tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+		ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("mockRule0", "project", tempStore)
+		pp := &ProjectOp{Fields: stmt.Fields}
+		fv, afv := xsql.NewFunctionValuersForOp(ctx, xsql.FuncRegisters)
+		for j, d := range tt.data {
+			result := pp.Apply(ctx, d, fv, afv)
+			var mapRes []map[string]interface{}
+			if v, ok := result.([]byte); ok {
+				err := json.Unmarshal(v, &mapRes)
+				if err != nil {
+					t.Errorf("Failed to parse the input into map.\n")
+					continue
+				}
+				if !reflect.DeepEqual(tt.result[j], mapRes) {
+					t.Errorf("%d.%d %q\n\nresult mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, j, tt.sql, tt.result[j], mapRes)
+				}
+			} else {
+				t.Errorf("The returned result is not type of []byte\n")
+			}
+		}
+	}
+}
@@ -17,6 +17,7 @@ package operator
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -33,10 +34,24 @@ type defaultFieldProcessor struct {
 	streamFields    []interface{}
 	timestampFormat string
 	isBinary        bool
+	// validate all the fields and report the errors with the full json path as *xsql.ValidationError
+	strictValidation bool
 }
 
+// ValidatesRecords reports the invalid records metric in the operator node
+func (p *defaultFieldProcessor) ValidatesRecords() bool {
+	return true
+}
+
+// errCollected indicates the field errors are already collected into the validation error
+var errCollected = errors.New("field errors collected")
+
 func (p *defaultFieldProcessor) processField(tuple *xsql.Tuple, fv *xsql.FunctionValuer) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
+	var verr *xsql.ValidationError
+	if p.strictValidation {
+		verr = &xsql.ValidationError{Message: tuple.Message}
+	}
 	if p.streamFields != nil {
 		for _, f := range p.streamFields {
 			switch sf := f.(type) {
@@ -44,8 +59,13 @@ func (p *defaultFieldProcessor) processField(tuple *xsql.Tuple, fv *xsql.Functio
 				if p.isBinary {
 					tuple.Message[sf.Name] = tuple.Message[message.DefaultField]
 				}
-				if e := p.addRecField(sf.FieldType, result, tuple.Message, sf.Name); e != nil {
-					return nil, e
+				if e := p.addRecField(sf.FieldType, result, tuple.Message, sf.Name, sf.Name, verr); e != nil {
+					if verr == nil {
+						return nil, e
+					}
+					if e != errCollected {
+						verr.Add(e)
+					}
 				}
 			case string: //schemaless
 				if p.isBinary {
@@ -63,10 +83,15 @@ func (p *defaultFieldProcessor) processField(tuple *xsql.Tuple, fv *xsql.Functio
 	} else {
 		result = tuple.Message
 	}
+	if verr != nil && len(verr.Errors) > 0 {
+		return nil, verr
+	}
 	return result, nil
 }
 
-func (p *defaultFieldProcessor) addRecField(ft ast.FieldType, r map[string]interface{}, j xsql.Message, n string) error {
+// addRecField converts the field n of j into r by the field type. The path is the name of the field to show in the
+// error message. In strict validation mode, verr is not nil and the path is the full json path of the field.
+func (p *defaultFieldProcessor) addRecField(ft ast.FieldType, r map[string]interface{}, j xsql.Message, n string, path string, verr *xsql.ValidationError) error {
 	if t, ok := j.Value(n); ok {
 		v := reflect.ValueOf(t)
 		jtype := v.Kind()
@@ -88,32 +113,32 @@ func (p *defaultFieldProcessor) addRecField(ft ast.FieldType, r map[string]inter
 					}
 				} else if jtype == reflect.String {
 					if i, err := strconv.Atoi(t.(string)); err != nil {
-						return fmt.Errorf("invalid data type for %s, expect bigint but found %[2]T(%[2]v)", n, t)
+						return fmt.Errorf("invalid data type for %s, expect bigint but found %[2]T(%[2]v)", path, t)
 					} else {
 						r[n] = i
 					}
 				} else if jtype == reflect.Uint64 {
 					r[n] = t.(uint64)
 				} else {
-					return fmt.Errorf("invalid data type for %s, expect bigint but found %[2]T(%[2]v)", n, t)
+					return fmt.Errorf("invalid data type for %s, expect bigint but found %[2]T(%[2]v)", path, t)
 				}
 			case ast.FLOAT:
 				if jtype == reflect.Float64 {
 					r[n] = t.(float64)
 				} else if jtype == reflect.String {
 					if f, err := strconv.ParseFloat(t.(string), 64); err != nil {
-						return fmt.Errorf("invalid data type for %s, expect float but found %[2]T(%[2]v)", n, t)
+						return fmt.Errorf("invalid data type for %s, expect float but found %[2]T(%[2]v)", path, t)
 					} else {
 						r[n] = f
 					}
 				} else {
-					return fmt.Errorf("invalid data type for %s, expect float but found %[2]T(%[2]v)", n, t)
+					return fmt.Errorf("invalid data type for %s, expect float but found %[2]T(%[2]v)", path, t)
 				}
 			case ast.STRINGS:
 				if jtype == reflect.String {
 					r[n] = t.(string)
 				} else {
-					return fmt.Errorf("invalid data type for %s, expect string but found %[2]T(%[2]v)", n, t)
+					return fmt.Errorf("invalid data type for %s, expect string but found %[2]T(%[2]v)", path, t)
 				}
 			case ast.DATETIME:
 				switch jtype {
@@ -125,29 +150,29 @@ func (p *defaultFieldProcessor) addRecField(ft ast.FieldType, r map[string]inter
 					r[n] = cast.TimeFromUnixMilli(ai)
 				case reflect.String:
 					if t, err := p.parseTime(t.(string)); err != nil {
-						return fmt.Errorf("invalid data type for %s, cannot convert to datetime: %s", n, err)
+						return fmt.Errorf("invalid data type for %s, cannot convert to datetime: %s", path, err)
 					} else {
 						r[n] = t
 					}
 				default:
-					return fmt.Errorf("invalid data type for %s, expect datatime but find %[2]T(%[2]v)", n, t)
+					return fmt.Errorf("invalid data type for %s, expect datatime but find %[2]T(%[2]v)", path, t)
 				}
 			case ast.BOOLEAN:
 				if jtype == reflect.Bool {
 					r[n] = t.(bool)
 				} else if jtype == reflect.String {
 					if i, err := strconv.ParseBool(t.(string)); err != nil {
-						return fmt.Errorf("invalid data type for %s, expect boolean but found %[2]T(%[2]v)", n, t)
+						return fmt.Errorf("invalid data type for %s, expect boolean but found %[2]T(%[2]v)", path, t)
 					} else {
 						r[n] = i
 					}
 				} else {
-					return fmt.Errorf("invalid data type for %s, expect boolean but found %[2]T(%[2]v)", n, t)
+					return fmt.Errorf("invalid data type for %s, expect boolean but found %[2]T(%[2]v)", path, t)
 				}
 			case ast.BYTEA:
 				if jtype == reflect.String {
 					if b, err := base64.StdEncoding.DecodeString(t.(string)); err != nil {
-						return fmt.Errorf("invalid data type for %s, expect bytea but found %[2]T(%[2]v) which cannot base64 decode", n, t)
+						return fmt.Errorf("invalid data type for %s, expect bytea but found %[2]T(%[2]v) which cannot base64 decode", path, t)
 					} else {
 						r[n] = b
 					}
@@ -155,7 +180,7 @@ func (p *defaultFieldProcessor) addRecField(ft ast.FieldType, r map[string]inter
 					if b, ok := t.([]byte); ok {
 						r[n] = b
 					} else {
-						return fmt.Errorf("invalid data type for %s, expect bytea but found %[2]T(%[2]v)", n, t)
+						return fmt.Errorf("invalid data type for %s, expect bytea but found %[2]T(%[2]v)", path, t)
 					}
 				}
 			default:
@@ -170,13 +195,20 @@ func (p *defaultFieldProcessor) addRecField(ft ast.FieldType, r map[string]inter
 			} else if jtype == reflect.String {
 				err := json.Unmarshal([]byte(t.(string)), &s)
 				if err != nil {
-					return fmt.Errorf("invalid data type for %s, expect array but found %[2]T(%[2]v)", n, t)
+					return fmt.Errorf("invalid data type for %s, expect array but found %[2]T(%[2]v)", path, t)
 				}
 			} else {
-				return fmt.Errorf("invalid data type for %s, expect array but found %[2]T(%[2]v)", n, t)
+				return fmt.Errorf("invalid data type for %s, expect array but found %[2]T(%[2]v)", path, t)
 			}
 
-			if tempArr, err := p.addArrayField(st, s); err != nil {
+			arrPath := ""
+			if verr != nil {
+				arrPath = path
+			}
+			if tempArr, err := p.addArrayField(st, s, arrPath, verr); err != nil {
+				if verr != nil {
+					return err
+				}
 				return fmt.Errorf("fail to parse field %s: %s", n, err)
 			} else {
 				r[n] = tempArr
@@ -190,36 +222,54 @@ func (p *defaultFieldProcessor) addRecField(ft ast.FieldType, r map[string]inter
 			} else if jtype == reflect.Map {
 				nextJ, ok = t.(map[string]interface{})
 				if !ok {
-					return fmt.Errorf("invalid data type for %s, expect map but found %[2]T(%[2]v)", n, t)
+					return fmt.Errorf("invalid data type for %s, expect map but found %[2]T(%[2]v)", path, t)
 				}
 			} else if jtype == reflect.String {
 				err := json.Unmarshal([]byte(t.(string)), &nextJ)
 				if err != nil {
-					return fmt.Errorf("invalid data type for %s, expect map but found %[2]T(%[2]v)", n, t)
+					return fmt.Errorf("invalid data type for %s, expect map but found %[2]T(%[2]v)", path, t)
 				}
 			} else {
-				return fmt.Errorf("invalid data type for %s, expect struct but found %[2]T(%[2]v)", n, t)
+				return fmt.Errorf("invalid data type for %s, expect struct but found %[2]T(%[2]v)", path, t)
 			}
 			nextR := make(map[string]interface{})
+			collected := false
 			for _, nextF := range st.StreamFields {
 				nextP := strings.ToLower(nextF.Name)
-				if e := p.addRecField(nextF.FieldType, nextR, nextJ, nextP); e != nil {
-					return e
+				nextPath := nextP
+				if verr != nil {
+					nextPath = path + "." + nextP
+				}
+				if e := p.addRecField(nextF.FieldType, nextR, nextJ, nextP, nextPath, verr); e != nil {
+					if verr == nil {
+						return e
+					}
+					if e != errCollected {
+						verr.Add(e)
+					}
+					collected = true
 				}
 			}
+			if collected {
+				return errCollected
+			}
 			r[n] = nextR
 		default:
 			return fmt.Errorf("unsupported type %T", st)
 		}
 		return nil
 	} else {
+		if verr != nil {
+			return fmt.Errorf("field %s not found", path)
+		}
 		return fmt.Errorf("invalid data %s, field %s not found", j, n)
 	}
 }
 
 //ft must be ast.ArrayType
 //side effect: r[p] will be set to the new array
-func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []interface{}) (interface{}, error) {
+//path is the json path of the array to show in the error message, it is empty if not in strict validation mode
+func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []interface{}, path string, verr *xsql.ValidationError) (interface{}, error) {
 	if ft.FieldType != nil { //complex type array or struct
 		switch st := ft.FieldType.(type) { //Only two complex types supported here
 		case *ast.ArrayType: //TODO handle array of array. Now the type is treated as interface{}
@@ -237,12 +287,12 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 				} else if jtype == reflect.String {
 					err := json.Unmarshal([]byte(t.(string)), &s)
 					if err != nil {
-						return nil, fmt.Errorf("invalid data type for [%d], expect array but found %[2]T(%[2]v)", i, t)
+						return nil, fmt.Errorf("invalid data type for %s[%d], expect array but found %[3]T(%[3]v)", path, i, t)
 					}
 				} else {
-					return nil, fmt.Errorf("invalid data type for [%d], expect array but found %[2]T(%[2]v)", i, t)
+					return nil, fmt.Errorf("invalid data type for %s[%d], expect array but found %[3]T(%[3]v)", path, i, t)
 				}
-				if tempArr, err := p.addArrayField(st, s); err != nil {
+				if tempArr, err := p.addArrayField(st, s, fmt.Sprintf("%s[%d]", path, i), verr); err != nil {
 					return nil, err
 				} else {
 					if !tempSlice.IsValid() {
@@ -258,6 +308,7 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 				return []map[string]interface{}(nil), nil
 			}
 			tempSlice := make([]map[string]interface{}, 0)
+			collected := false
 			for i, t := range srcSlice {
 				jtype := reflect.ValueOf(t).Kind()
 				j := make(map[string]interface{})
@@ -269,26 +320,39 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 				} else if jtype == reflect.Map {
 					j, ok = t.(map[string]interface{})
 					if !ok {
-						return nil, fmt.Errorf("invalid data type for [%d], expect map but found %[2]T(%[2]v)", i, t)
+						return nil, fmt.Errorf("invalid data type for %s[%d], expect map but found %[3]T(%[3]v)", path, i, t)
 					}
 
 				} else if jtype == reflect.String {
 					err := json.Unmarshal([]byte(t.(string)), &j)
 					if err != nil {
-						return nil, fmt.Errorf("invalid data type for [%d], expect map but found %[2]T(%[2]v)", i, t)
+						return nil, fmt.Errorf("invalid data type for %s[%d], expect map but found %[3]T(%[3]v)", path, i, t)
 					}
 				} else {
-					return nil, fmt.Errorf("invalid data type for [%d], expect map but found %[2]T(%[2]v)", i, t)
+					return nil, fmt.Errorf("invalid data type for %s[%d], expect map but found %[3]T(%[3]v)", path, i, t)
 				}
 				r := make(map[string]interface{})
 				for _, f := range st.StreamFields {
 					n := f.Name
-					if e := p.addRecField(f.FieldType, r, j, n); e != nil {
-						return nil, e
+					np := n
+					if verr != nil {
+						np = fmt.Sprintf("%s[%d].%s", path, i, n)
+					}
+					if e := p.addRecField(f.FieldType, r, j, n, np, verr); e != nil {
+						if verr == nil {
+							return nil, e
+						}
+						if e != errCollected {
+							verr.Add(e)
+						}
+						collected = true
 					}
 				}
 				tempSlice = append(tempSlice, r)
 			}
+			if collected {
+				return nil, errCollected
+			}
 			return tempSlice, nil
 		default:
 			return nil, fmt.Errorf("unsupported type %T", st)
@@ -308,12 +372,12 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 					tempSlice = append(tempSlice, int(t.(float64)))
 				} else if jtype == reflect.String {
 					if v, err := strconv.Atoi(t.(string)); err != nil {
-						return nil, fmt.Errorf("invalid data type for [%d], expect float but found %[2]T(%[2]v)", i, t)
+						return nil, fmt.Errorf("invalid data type for %s[%d], expect float but found %[3]T(%[3]v)", path, i, t)
 					} else {
 						tempSlice = append(tempSlice, v)
 					}
 				} else {
-					return nil, fmt.Errorf("invalid data type for [%d], expect float but found %[2]T(%[2]v)", i, t)
+					return nil, fmt.Errorf("invalid data type for %s[%d], expect float but found %[3]T(%[3]v)", path, i, t)
 				}
 			}
 			return tempSlice, nil
@@ -328,12 +392,12 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 					tempSlice = append(tempSlice, t.(float64))
 				} else if jtype == reflect.String {
 					if f, err := strconv.ParseFloat(t.(string), 64); err != nil {
-						return nil, fmt.Errorf("invalid data type for [%d], expect float but found %[2]T(%[2]v)", i, t)
+						return nil, fmt.Errorf("invalid data type for %s[%d], expect float but found %[3]T(%[3]v)", path, i, t)
 					} else {
 						tempSlice = append(tempSlice, f)
 					}
 				} else {
-					return nil, fmt.Errorf("invalid data type for [%d], expect float but found %[2]T(%[2]v)", i, t)
+					return nil, fmt.Errorf("invalid data type for %s[%d], expect float but found %[3]T(%[3]v)", path, i, t)
 				}
 			}
 			return tempSlice, nil
@@ -346,7 +410,7 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 				if reflect.ValueOf(t).Kind() == reflect.String {
 					tempSlice = append(tempSlice, t.(string))
 				} else {
-					return nil, fmt.Errorf("invalid data type for [%d], expect string but found %[2]T(%[2]v)", i, t)
+					return nil, fmt.Errorf("invalid data type for %s[%d], expect string but found %[3]T(%[3]v)", path, i, t)
 				}
 			}
 			return tempSlice, nil
@@ -371,7 +435,7 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 						tempSlice = append(tempSlice, ai)
 					}
 				default:
-					return nil, fmt.Errorf("invalid data type for [%d], expect datetime but found %[2]T(%[2]v)", i, t)
+					return nil, fmt.Errorf("invalid data type for %s[%d], expect datetime but found %[3]T(%[3]v)", path, i, t)
 				}
 			}
 			return tempSlice, nil
@@ -386,12 +450,12 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 					tempSlice = append(tempSlice, t.(bool))
 				} else if jtype == reflect.String {
 					if v, err := strconv.ParseBool(t.(string)); err != nil {
-						return nil, fmt.Errorf("invalid data type for [%d], expect boolean but found %[2]T(%[2]v)", i, t)
+						return nil, fmt.Errorf("invalid data type for %s[%d], expect boolean but found %[3]T(%[3]v)", path, i, t)
 					} else {
 						tempSlice = append(tempSlice, v)
 					}
 				} else {
-					return nil, fmt.Errorf("invalid data type for [%d], expect boolean but found %[2]T(%[2]v)", i, t)
+					return nil, fmt.Errorf("invalid data type for %s[%d], expect boolean but found %[3]T(%[3]v)", path, i, t)
 				}
 			}
 			return tempSlice, nil
@@ -404,7 +468,7 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 				jtype := reflect.ValueOf(t).Kind()
 				if jtype == reflect.String {
 					if b, err := base64.StdEncoding.DecodeString(t.(string)); err != nil {
-						return nil, fmt.Errorf("invalid data type for [%d], expect bytea but found %[2]T(%[2]v) which cannot base64 decode", i, t)
+						return nil, fmt.Errorf("invalid data type for %s[%d], expect bytea but found %[3]T(%[3]v) which cannot base64 decode", path, i, t)
 					} else {
 						tempSlice = append(tempSlice, b)
 					}
@@ -412,7 +476,7 @@ func (p *defaultFieldProcessor) addArrayField(ft *ast.ArrayType, srcSlice []inte
 					if b, ok := t.([]byte); ok {
 						tempSlice = append(tempSlice, b)
 					} else {
-						return nil, fmt.Errorf("invalid data type for [%d], expect bytea but found %[2]T(%[2]v)", i, t)
+						return nil, fmt.Errorf("invalid data type for %s[%d], expect bytea but found %[3]T(%[3]v)", path, i, t)
 					}
 				}
 			}
@@ -0,0 +1,97 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+type LimitOp struct {
+	Limit  int
+	Offset int
+	// If the statement is aggregate without group by, the whole collection will be projected to one row
+	IsAggregate bool
+}
+
+/**
+ *  input: *xsql.Tuple from preprocessor | xsql.WindowTuplesSet from windowOp | xsql.JoinTupleSets from joinOp | xsql.GroupedTuplesSet from aggregateOp
+ *  output: the same type as the input with at most Limit rows after skipping Offset rows
+ */
+func (p *LimitOp) Apply(ctx api.StreamContext, data interface{}, _ *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) interface{} {
+	log := ctx.GetLogger()
+	log.Debugf("limit plan receive %s", data)
+	switch input := data.(type) {
+	case error:
+		return input
+	case xsql.Valuer:
+		// single row
+		if p.Offset > 0 {
+			return nil
+		}
+		return input
+	case xsql.WindowTuplesSet:
+		if p.IsAggregate {
+			if p.Offset > 0 {
+				return nil
+			}
+			return input
+		}
+		if len(input.Content) != 1 {
+			return fmt.Errorf("run Limit error: the input WindowTuplesSet with multiple tuples cannot be evaluated")
+		}
+		start, end := p.bounds(len(input.Content[0].Tuples))
+		if start == end {
+			return nil
+		}
+		input.Content[0].Tuples = input.Content[0].Tuples[start:end]
+		return input
+	case *xsql.JoinTupleSets:
+		if p.IsAggregate {
+			if p.Offset > 0 {
+				return nil
+			}
+			return input
+		}
+		start, end := p.bounds(len(input.Content))
+		if start == end {
+			return nil
+		}
+		input.Content = input.Content[start:end]
+		return input
+	case xsql.GroupedTuplesSet:
+		start, end := p.bounds(len(input))
+		if start == end {
+			return nil
+		}
+		return input[start:end]
+	default:
+		return fmt.Errorf("run Limit error: invalid input %[1]T(%[1]v)", input)
+	}
+}
+
+// bounds returns the slice range of a collection with the given length
+func (p *LimitOp) bounds(length int) (int, int) {
+	start := p.Offset
+	if start > length {
+		start = length
+	}
+	end := start + p.Limit
+	if end > length {
+		end = length
+	}
+	return start, end
+}
@@ -31,11 +31,11 @@ type Preprocessor struct {
 	timestampField string
 }
 
-func NewPreprocessor(fields []interface{}, allMeta bool, metaFields []string, iet bool, timestampField string, timestampFormat string, isBinary bool) (*Preprocessor, error) {
+func NewPreprocessor(fields []interface{}, allMeta bool, metaFields []string, iet bool, timestampField string, timestampFormat string, isBinary bool, strictValidation bool) (*Preprocessor, error) {
 	p := &Preprocessor{
 		allMeta: allMeta, metaFields: metaFields, isEventTime: iet, timestampField: timestampField}
 	p.defaultFieldProcessor = defaultFieldProcessor{
-		streamFields: fields, isBinary: isBinary, timestampFormat: timestampFormat,
+		streamFields: fields, isBinary: isBinary, timestampFormat: timestampFormat, strictValidation: strictValidation,
 	}
 	return p, nil
 }
@@ -55,6 +55,11 @@ func (p *Preprocessor) Apply(ctx api.StreamContext, data interface{}, fv *xsql.F
 
 	result, err := p.processField(tuple, fv)
 	if err != nil {
+		// Validation errors are routed to the invalid actions by the node
+		if verr, ok := err.(*xsql.ValidationError); ok {
+			verr.Op = "preprocessor"
+			return verr
+		}
 		return fmt.Errorf("error in preprocessor: %s", err)
 	}
 
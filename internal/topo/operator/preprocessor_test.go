@@ -29,6 +29,7 @@ import (
 	"log"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -966,6 +967,112 @@ func TestPreprocessorError(t *testing.T) {
 	}
 }
 
+func TestPreprocessorStrictValidation(t *testing.T) {
+	tests := []struct {
+		fields []ast.StreamField
+		data   []byte
+		result interface{}
+	}{
+		{
+			fields: []ast.StreamField{
+				{Name: "abc", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+				{Name: "def", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+			},
+			data: []byte(`{"abc": "dafsad", "def": 34}`),
+			result: &xsql.ValidationError{
+				Op:      "preprocessor",
+				Message: xsql.Message{"abc": "dafsad", "def": 34.0},
+				Errors: []string{
+					"invalid data type for abc, expect bigint but found string(dafsad)",
+					"invalid data type for def, expect string but found float64(34)",
+				},
+			},
+		}, {
+			fields: []ast.StreamField{
+				{Name: "a", FieldType: &ast.RecType{
+					StreamFields: []ast.StreamField{
+						{Name: "b", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+						{Name: "c", FieldType: &ast.RecType{
+							StreamFields: []ast.StreamField{
+								{Name: "d", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+							},
+						}},
+					},
+				}},
+			},
+			data: []byte(`{"a": {"d" : "hello", "c": {"d": true}}}`),
+			result: &xsql.ValidationError{
+				Op:      "preprocessor",
+				Message: xsql.Message{"a": map[string]interface{}{"d": "hello", "c": map[string]interface{}{"d": true}}},
+				Errors: []string{
+					"field a.b not found",
+					"invalid data type for a.c.d, expect float but found bool(true)",
+				},
+			},
+		}, {
+			fields: []ast.StreamField{
+				{Name: "a", FieldType: &ast.ArrayType{
+					Type: ast.STRUCT,
+					FieldType: &ast.RecType{
+						StreamFields: []ast.StreamField{
+							{Name: "b", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+							{Name: "c", FieldType: &ast.ArrayType{Type: ast.FLOAT}},
+						},
+					},
+				}},
+			},
+			data: []byte(`{"a": [{"b": 1, "c": [1.5]}, {"b": "x", "c": [2, "y"]}]}`),
+			result: &xsql.ValidationError{
+				Op: "preprocessor",
+				Message: xsql.Message{"a": []interface{}{
+					map[string]interface{}{"b": 1.0, "c": []interface{}{1.5}},
+					map[string]interface{}{"b": "x", "c": []interface{}{2.0, "y"}},
+				}},
+				Errors: []string{
+					"invalid data type for a[1].b, expect bigint but found string(x)",
+					"invalid data type for a[1].c[1], expect float but found string(y)",
+				},
+			},
+		}, {
+			fields: []ast.StreamField{
+				{Name: "a", FieldType: &ast.RecType{
+					StreamFields: []ast.StreamField{
+						{Name: "b", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+					},
+				}},
+			},
+			data: []byte(`{"a": {"b" : "hello"}}`),
+			result: &xsql.Tuple{Message: xsql.Message{
+				"a": map[string]interface{}{"b": "hello"},
+			}},
+		},
+	}
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+
+	defer conf.CloseLogger()
+	contextLogger := conf.Log.WithField("rule", "TestPreprocessorStrictValidation")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for i, tt := range tests {
+		pp, _ := NewPreprocessor(convertFields(tt.fields), false, nil, false, "", "", false, true)
+		dm := make(map[string]interface{})
+		if e := json.Unmarshal(tt.data, &dm); e != nil {
+			log.Fatal(e)
+			return
+		} else {
+			tuple := &xsql.Tuple{Message: dm}
+			fv, afv := xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+			result := pp.Apply(ctx, tuple, fv, afv)
+			if !reflect.DeepEqual(tt.result, result) {
+				t.Errorf("%d. %q\n\nresult mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tuple, tt.result, result)
+			}
+			// The error keeps the prefix of the preprocessor errors
+			if verr, ok := result.(*xsql.ValidationError); ok && !strings.HasPrefix(verr.Error(), "error in preprocessor: ") {
+				t.Errorf("%d. error prefix mismatch, got %s", i, verr.Error())
+			}
+		}
+	}
+}
+
 func TestPreprocessorForBinary(t *testing.T) {
 	docsFolder, err := conf.GetLoc("docs/")
 	if err != nil {
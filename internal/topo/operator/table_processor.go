@@ -36,7 +36,7 @@ type TableProcessor struct {
 func NewTableProcessor(name string, fields []interface{}, options *ast.Options) (*TableProcessor, error) {
 	p := &TableProcessor{emitterName: name, batchEmitted: true, retainSize: 1}
 	p.defaultFieldProcessor = defaultFieldProcessor{
-		streamFields: fields, isBinary: false, timestampFormat: options.TIMESTAMP_FORMAT,
+		streamFields: fields, isBinary: false, timestampFormat: options.TIMESTAMP_FORMAT, strictValidation: options.STRICT_VALIDATION,
 	}
 	if options.RETAIN_SIZE > 0 {
 		p.retainSize = options.RETAIN_SIZE
@@ -69,6 +69,10 @@ func (p *TableProcessor) Apply(ctx api.StreamContext, data interface{}, fv *xsql
 	if tuple.Message != nil {
 		result, err := p.processField(tuple, fv)
 		if err != nil {
+			if verr, ok := err.(*xsql.ValidationError); ok {
+				verr.Op = "table processor"
+				return verr
+			}
 			return fmt.Errorf("error in table processor: %s", err)
 		}
 		tuple.Message = result
@@ -95,5 +99,5 @@ func (p *TableProcessor) Apply(ctx api.StreamContext, data interface{}, fv *xsql
 }
 
 func isBatch(t string) bool {
-	return t == "file" || t == ""
+	return t == "file" || t == "sql" || t == ""
 }
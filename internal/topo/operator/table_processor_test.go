@@ -148,3 +148,41 @@ func TestTableProcessor_Apply(t *testing.T) {
 
 	}
 }
+
+func TestTableProcessorSQLBatch(t *testing.T) {
+	var tests = []struct {
+		data   []xsql.Message
+		result []xsql.Tuple
+	}{
+		{
+			data: []xsql.Message{{"id": 1}, {"id": 2}},
+			result: []xsql.Tuple{
+				{Message: xsql.Message{"id": 1}, Emitter: "demo"},
+				{Message: xsql.Message{"id": 2}, Emitter: "demo"},
+			},
+		}, { // The table shrinks in the next query
+			data: []xsql.Message{{"id": 1}},
+			result: []xsql.Tuple{
+				{Message: xsql.Message{"id": 1}, Emitter: "demo"},
+			},
+		},
+	}
+
+	defer conf.CloseLogger()
+	contextLogger := conf.Log.WithField("rule", "TestTableProcessorSQLBatch")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	pp, _ := NewTableProcessor("demo", nil, &ast.Options{TYPE: "sql"})
+	fv, afv := xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+	for i, tt := range tests {
+		for _, m := range tt.data {
+			if r := pp.Apply(ctx, &xsql.Tuple{Emitter: "demo", Message: m}, fv, afv); r != nil {
+				t.Errorf("%d. should not emit before EOF, got %v", i, r)
+			}
+		}
+		result := pp.Apply(ctx, &xsql.Tuple{}, fv, afv)
+		exp := xsql.WindowTuples{Emitter: "demo", Tuples: tt.result}
+		if !reflect.DeepEqual(exp, result) {
+			t.Errorf("%d. result mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, exp, result)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"sort"
+	"strings"
+)
+
+// WindowFuncOp calculates the function calls with OVER clause for each row of a window.
+// The results are saved as the alias of each row so that the rows are not collapsed.
+type WindowFuncOp struct {
+	Calls []*ast.Call
+}
+
+/**
+ *  input: xsql.WindowTuplesSet from windowOp or filterOp
+ *  output: xsql.WindowTuplesSet with the function results of each row
+ */
+func (p *WindowFuncOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) interface{} {
+	log := ctx.GetLogger()
+	log.Debugf("window function plan receive %s", data)
+	switch input := data.(type) {
+	case error:
+		return input
+	case xsql.WindowTuplesSet:
+		if len(input.Content) != 1 {
+			return fmt.Errorf("run Window Function error: the input WindowTuplesSet with multiple tuples cannot be evaluated")
+		}
+		for _, c := range p.Calls {
+			if err := p.calculate(c, input, fv, afv); err != nil {
+				return fmt.Errorf("run Window Function error: %s", err)
+			}
+		}
+		return input
+	default:
+		return fmt.Errorf("run Window Function error: invalid input %[1]T(%[1]v), window functions only support window without join", input)
+	}
+}
+
+func (p *WindowFuncOp) calculate(c *ast.Call, input xsql.WindowTuplesSet, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) error {
+	tuples := input.Content[0].Tuples
+	partitions, err := partitionRows(c.Over.Partition, tuples, fv)
+	if err != nil {
+		return err
+	}
+	key := xsql.WindowFuncKey(c)
+	name := strings.ToLower(c.Name)
+	for _, rows := range partitions {
+		values, err := sortRows(c.Over.SortFields, tuples, rows)
+		if err != nil {
+			return err
+		}
+		// peer rows have the same values of all sort fields
+		isPeer := func(i, j int) bool {
+			for k, sf := range c.Over.SortFields {
+				if r, _ := compareSortValue(values[rows[i]][k], values[rows[j]][k], sf.Ascending); r != 0 {
+					return false
+				}
+			}
+			return true
+		}
+		rank, denseRank := 0, 0
+		for i, idx := range rows {
+			var r interface{}
+			switch name {
+			case "row_number":
+				r = i + 1
+			case "rank", "dense_rank":
+				if i == 0 || !isPeer(i-1, i) {
+					rank = i + 1
+					denseRank++
+				}
+				if name == "rank" {
+					r = rank
+				} else {
+					r = denseRank
+				}
+			default:
+				// The frame is the whole partition without ORDER BY.
+				// With ORDER BY, the frame is from the partition start or n preceding rows to the current row and its peers
+				start, end := 0, len(rows)
+				if len(c.Over.SortFields) > 0 {
+					if c.Over.Preceding > 0 {
+						end = i + 1
+						if i > c.Over.Preceding {
+							start = i - c.Over.Preceding
+						}
+					} else {
+						end = i + 1
+						for end < len(rows) && isPeer(i, end) {
+							end++
+						}
+					}
+				}
+				frame := make([]xsql.Tuple, 0, end-start)
+				for _, j := range rows[start:end] {
+					frame = append(frame, tuples[j])
+				}
+				ws := xsql.WindowTuplesSet{
+					Content:     []xsql.WindowTuples{{Emitter: input.Content[0].Emitter, Tuples: frame}},
+					WindowRange: input.WindowRange,
+				}
+				afv.SetData(ws)
+				ve := &xsql.ValuerEval{Valuer: xsql.MultiAggregateValuer(ws, fv, &tuples[idx], fv, afv, &xsql.WildcardValuer{Data: &tuples[idx]})}
+				r = ve.Eval(&ast.Call{Name: c.Name, Args: c.Args})
+				if e, ok := r.(error); ok {
+					return e
+				}
+			}
+			tuples[idx].AppendAlias(key, r)
+		}
+	}
+	return nil
+}
+
+// partitionRows splits the row indexes by the partition expressions in the order of their first appearance
+func partitionRows(pe *ast.PartitionExpr, tuples []xsql.Tuple, fv *xsql.FunctionValuer) ([][]int, error) {
+	if pe == nil {
+		rows := make([]int, len(tuples))
+		for i := range tuples {
+			rows[i] = i
+		}
+		return [][]int{rows}, nil
+	}
+	var (
+		result  [][]int
+		indexes = make(map[string]int)
+	)
+	for i := range tuples {
+		ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(&tuples[i], fv)}
+		key := ""
+		for _, e := range pe.Exprs {
+			r := ve.Eval(e)
+			if err, ok := r.(error); ok {
+				return nil, err
+			}
+			key += fmt.Sprintf("%v,", r)
+		}
+		if j, ok := indexes[key]; ok {
+			result[j] = append(result[j], i)
+		} else {
+			indexes[key] = len(result)
+			result = append(result, []int{i})
+		}
+	}
+	return result, nil
+}
+
+// sortRows sorts the row indexes stably by the sort fields and returns the sort field values of each row
+func sortRows(sfs ast.SortFields, tuples []xsql.Tuple, rows []int) (map[int][]interface{}, error) {
+	values := make(map[int][]interface{}, len(rows))
+	if len(sfs) == 0 {
+		return values, nil
+	}
+	for _, idx := range rows {
+		vs := make([]interface{}, len(sfs))
+		for k, sf := range sfs {
+			vs[k], _ = tuples[idx].Value(sf.Name)
+		}
+		values[idx] = vs
+	}
+	var err error
+	sort.SliceStable(rows, func(i, j int) bool {
+		for k, sf := range sfs {
+			r, e := compareSortValue(values[rows[i]][k], values[rows[j]][k], sf.Ascending)
+			if e != nil {
+				err = e
+				return false
+			}
+			if r != 0 {
+				return r < 0
+			}
+		}
+		return false
+	})
+	return values, err
+}
+
+// compareSortValue compares two values in the sorting direction. Nil values are always sorted to the last
+func compareSortValue(a, b interface{}, ascending bool) (int, error) {
+	switch {
+	case a == nil && b == nil:
+		return 0, nil
+	case a == nil:
+		return 1, nil
+	case b == nil:
+		return -1, nil
+	}
+	r := 0
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, fmt.Errorf("incompatible types for comparison: %T and %T", a, b)
+		}
+		r = strings.Compare(av, bv)
+	case bool:
+		bv, ok := b.(bool)
+		if !ok {
+			return 0, fmt.Errorf("incompatible types for comparison: %T and %T", a, b)
+		}
+		if av != bv {
+			if av {
+				r = 1
+			} else {
+				r = -1
+			}
+		}
+	default:
+		af, err := cast.ToFloat64(a, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return 0, fmt.Errorf("incompatible types for comparison: %T and %T", a, b)
+		}
+		bf, err := cast.ToFloat64(b, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return 0, fmt.Errorf("incompatible types for comparison: %T and %T", a, b)
+		}
+		if af < bf {
+			r = -1
+		} else if af > bf {
+			r = 1
+		}
+	}
+	if !ascending {
+		r = -r
+	}
+	return r, nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWindowFunc_Apply(t *testing.T) {
+	data := xsql.WindowTuplesSet{
+		Content: []xsql.WindowTuples{{
+			Emitter: "test",
+			Tuples: []xsql.Tuple{
+				{Emitter: "test", Message: xsql.Message{"id": "a", "ts": 1, "temp": 10}},
+				{Emitter: "test", Message: xsql.Message{"id": "b", "ts": 2, "temp": 20}},
+				{Emitter: "test", Message: xsql.Message{"id": "a", "ts": 3, "temp": 30}},
+				{Emitter: "test", Message: xsql.Message{"id": "a", "ts": 2, "temp": 30}},
+				{Emitter: "test", Message: xsql.Message{"id": "b", "ts": 4, "temp": 40}},
+			},
+		}},
+		WindowRange: &xsql.WindowRange{WindowStart: 0, WindowEnd: 10},
+	}
+	var tests = []struct {
+		sql    string
+		result []map[string]interface{}
+	}{
+		{
+			sql: "SELECT id, ts, row_number() OVER (PARTITION BY id ORDER BY ts) AS rn FROM test",
+			result: []map[string]interface{}{
+				{"id": "a", "ts": 1.0, "rn": 1.0},
+				{"id": "b", "ts": 2.0, "rn": 1.0},
+				{"id": "a", "ts": 3.0, "rn": 3.0},
+				{"id": "a", "ts": 2.0, "rn": 2.0},
+				{"id": "b", "ts": 4.0, "rn": 2.0},
+			},
+		}, {
+			sql: "SELECT temp, rank() OVER (ORDER BY temp DESC) AS r, dense_rank() OVER (ORDER BY temp DESC) AS dr FROM test",
+			result: []map[string]interface{}{
+				{"temp": 10.0, "r": 5.0, "dr": 4.0},
+				{"temp": 20.0, "r": 4.0, "dr": 3.0},
+				{"temp": 30.0, "r": 2.0, "dr": 2.0},
+				{"temp": 30.0, "r": 2.0, "dr": 2.0},
+				{"temp": 40.0, "r": 1.0, "dr": 1.0},
+			},
+		}, {
+			sql: "SELECT ts, sum(temp) OVER (PARTITION BY id ORDER BY ts) AS s, count(*) OVER (PARTITION BY id) AS c FROM test",
+			result: []map[string]interface{}{
+				{"ts": 1.0, "s": 10.0, "c": 3.0},
+				{"ts": 2.0, "s": 20.0, "c": 2.0},
+				{"ts": 3.0, "s": 70.0, "c": 3.0},
+				{"ts": 2.0, "s": 40.0, "c": 3.0},
+				{"ts": 4.0, "s": 60.0, "c": 2.0},
+			},
+		}, {
+			sql: "SELECT ts, avg(temp) OVER (ORDER BY ts ROWS 1 PRECEDING) AS ma, sum(temp) OVER (ORDER BY ts) AS s FROM test",
+			result: []map[string]interface{}{
+				{"ts": 1.0, "ma": 10.0, "s": 10.0},
+				{"ts": 2.0, "ma": 15.0, "s": 60.0},
+				{"ts": 3.0, "ma": 30.0, "s": 90.0},
+				{"ts": 2.0, "ma": 25.0, "s": 60.0},
+				{"ts": 4.0, "ma": 35.0, "s": 130.0},
+			},
+		},
+	}
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	contextLogger := conf.Log.WithField("rule", "TestWindowFunc_Apply")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for i, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		if err != nil || stmt == nil {
+			t.Errorf("parse sql %s error %v", tt.sql, err)
+			continue
+		}
+		fv, afv := xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+		op := &WindowFuncOp{Calls: getWindowFuncCalls(stmt.Fields)}
+		// Copy the tuples so that the alias of each case does not pollute the others
+		input := xsql.WindowTuplesSet{
+			Content:     []xsql.WindowTuples{{Emitter: "test", Tuples: append([]xsql.Tuple{}, data.Content[0].Tuples...)}},
+			WindowRange: data.WindowRange,
+		}
+		r := op.Apply(ctx, input, fv, afv)
+		pp := &ProjectOp{Fields: stmt.Fields}
+		result := pp.Apply(ctx, r, fv, afv)
+		var mapRes []map[string]interface{}
+		if v, ok := result.([]byte); ok {
+			err := json.Unmarshal(v, &mapRes)
+			if err != nil {
+				t.Errorf("Failed to parse the input into map.\n")
+				continue
+			}
+			if !reflect.DeepEqual(tt.result, mapRes) {
+				t.Errorf("%d. %q\n\nresult mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.sql, tt.result, mapRes)
+			}
+		} else {
+			t.Errorf("%d. The returned result is not type of []byte but %v\n", i, result)
+		}
+	}
+}
+
+func TestWindowFunc_ApplyError(t *testing.T) {
+	var tests = []struct {
+		sql    string
+		data   interface{}
+		result interface{}
+	}{
+		{
+			sql: "SELECT row_number() OVER (ORDER BY a) AS rn FROM test",
+			data: xsql.WindowTuplesSet{
+				Content: []xsql.WindowTuples{{
+					Emitter: "test",
+					Tuples: []xsql.Tuple{
+						{Emitter: "test", Message: xsql.Message{"a": 1}},
+						{Emitter: "test", Message: xsql.Message{"a": "x"}},
+					},
+				}},
+			},
+			result: errors.New("run Window Function error: incompatible types for comparison: string and int"),
+		}, {
+			sql:    "SELECT row_number() OVER (ORDER BY a) AS rn FROM test",
+			data:   &xsql.Tuple{Emitter: "test", Message: xsql.Message{"a": 1}},
+			result: errors.New("run Window Function error: invalid input *xsql.Tuple(&{test map[a:1] 0 map[] {map[]}}), window functions only support window without join"),
+		},
+	}
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	contextLogger := conf.Log.WithField("rule", "TestWindowFunc_ApplyError")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for i, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		if err != nil || stmt == nil {
+			t.Errorf("parse sql %s error %v", tt.sql, err)
+			continue
+		}
+		fv, afv := xsql.NewFunctionValuersForOp(nil, xsql.FuncRegisters)
+		op := &WindowFuncOp{Calls: getWindowFuncCalls(stmt.Fields)}
+		result := op.Apply(ctx, tt.data, fv, afv)
+		if !reflect.DeepEqual(tt.result, result) {
+			t.Errorf("%d. %q\n\nresult mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.sql, tt.result, result)
+		}
+	}
+}
+
+func getWindowFuncCalls(node ast.Node) []*ast.Call {
+	var result []*ast.Call
+	ast.WalkFunc(node, func(n ast.Node) bool {
+		if c, ok := n.(*ast.Call); ok && c.Over != nil {
+			result = append(result, c)
+		}
+		return true
+	})
+	return result
+}
@@ -16,9 +16,16 @@ package planner
 
 import (
 	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/converter/protobuf"
+	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
 	"github.com/lf-edge/ekuiper/pkg/kv"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"os"
+	"path"
 	"strconv"
 	"strings"
 )
@@ -34,6 +41,20 @@ func decorateStmt(s *ast.SelectStatement, store kv.KeyValue) ([]*ast.StreamStmt,
 		if err != nil {
 			return nil, fmt.Errorf("fail to get stream %s, please check if stream is created", s)
 		}
+		// Stream fields are defined by the referred schema in the registry
+		if streamStmt.StreamFields == nil && streamStmt.Options != nil && streamStmt.Options.SCHEMAID != "" {
+			fields, err := schema.InferStreamFields(streamStmt.Options.SCHEMAID)
+			if err != nil {
+				// The protobuf schema file may be put in the schema folder directly without the registry
+				if e, ok := err.(*errorx.Error); ok && e.Code() == errorx.NOT_FOUND && hasProtoFile(streamStmt.Options) {
+					conf.Log.Debugf("schema %s not found in registry, stream %s is schemaless", streamStmt.Options.SCHEMAID, s)
+				} else {
+					return nil, fmt.Errorf("fail to infer the fields of stream %s from schema %s: %v", s, streamStmt.Options.SCHEMAID, err)
+				}
+			} else {
+				streamStmt.StreamFields = fields
+			}
+		}
 		streamStmts[i] = streamStmt
 		// TODO fine grain control of schemaless
 		if streamStmt.StreamFields == nil {
@@ -118,6 +139,9 @@ func validate(s *ast.SelectStatement) (err error) {
 	if !allAggregate(s.Having) {
 		return fmt.Errorf("Not allowed to call non-aggregate functions in HAVING clause.")
 	}
+	if ast.HasWindowFuncs(s.Condition) || ast.HasWindowFuncs(s.Having) || ast.HasWindowFuncs(s.Dimensions) {
+		return fmt.Errorf("Window functions with OVER clause are only allowed in SELECT fields.")
+	}
 	for _, d := range s.Dimensions {
 		if ast.IsAggregate(d.Expr) {
 			return fmt.Errorf("Not allowed to call aggregate functions in GROUP BY clause.")
@@ -406,3 +430,13 @@ func (s *streamFieldMapSchemaless) bindRef(fr *ast.FieldRef) error {
 		return nil
 	}
 }
+
+// hasProtoFile checks if the protobuf schema of the stream is a file in the schema folder managed by the users
+func hasProtoFile(options *ast.Options) bool {
+	if !strings.EqualFold(options.FORMAT, message.FormatProtobuf) {
+		return false
+	}
+	name := strings.SplitN(options.SCHEMAID, ".", 2)[0]
+	_, err := os.Stat(path.Join(protobuf.GetSchemaDir(), name+".proto"))
+	return err == nil
+}
@@ -17,6 +17,7 @@ package planner
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/testx"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
@@ -113,6 +114,22 @@ var tests = []struct {
 		sql: `SELECT sin(temp) as temp1, cos(temp1) FROM src1`,
 		r:   newErrorStructWithS("unknown field temp1", ""),
 	},
+	{ // 14
+		sql: `SELECT temp, row_number() OVER (PARTITION BY name ORDER BY temp) AS rn FROM src1 GROUP BY TUMBLINGWINDOW(ss, 10)`,
+		r:   newErrorStruct(""),
+	},
+	{ // 15
+		sql: `SELECT temp, row_number() OVER (ORDER BY temp) AS rn FROM src1`,
+		r:   newErrorStruct("window functions with OVER clause must run in a window"),
+	},
+	{ // 16
+		sql: `SELECT count(*), sum(temp) OVER (ORDER BY temp) FROM src1 GROUP BY TUMBLINGWINDOW(ss, 10)`,
+		r:   newErrorStruct("window functions with OVER clause cannot be used with join, GROUP BY dimensions or aggregate functions"),
+	},
+	{ // 17
+		sql: `SELECT temp FROM src1 WHERE rank() OVER (ORDER BY temp) > 1 GROUP BY TUMBLINGWINDOW(ss, 10)`,
+		r:   newErrorStruct("Window functions with OVER clause are only allowed in SELECT fields."),
+	},
 }
 
 func Test_validation(t *testing.T) {
@@ -238,3 +255,108 @@ func Test_validationSchemaless(t *testing.T) {
 		}
 	}
 }
+
+func Test_validationSchemaId(t *testing.T) {
+	r, err := schema.GetRegistry()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_ = r.Delete("src1Schema")
+	err = r.Create(&schema.Info{
+		Name:    "src1Schema",
+		Type:    schema.TypeJsonSchema,
+		Content: `{"type":"object","properties":{"id1":{"type":"integer"},"temp":{"type":"integer"},"name":{"type":"string"}}}`,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer r.Delete("src1Schema")
+	store := kv.GetDefaultKVStore(path.Join(DbDir, "stream"))
+	err = store.Open()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer store.Close()
+	s, err := json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM src1 () WITH (DATASOURCE="src1", FORMAT="json", KEY="ts", SCHEMAID="src1Schema");`,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	store.Set("src1", string(s))
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+
+	// The fields are inferred from the schema, so the result is the same as the stream with fields
+	for i, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		if err != nil {
+			t.Errorf("%d. %q: error compile sql: %s\n", i, tt.sql, err)
+			continue
+		}
+		_, err = createLogicalPlan(stmt, &api.RuleOption{
+			SendError: true,
+		}, store)
+		if !reflect.DeepEqual(tt.r.err, testx.Errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.sql, tt.r.err, err)
+		}
+	}
+}
+
+func Test_validationSchemaIdNotFound(t *testing.T) {
+	store := kv.GetDefaultKVStore(path.Join(DbDir, "stream"))
+	err := store.Open()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer store.Close()
+	streamSqls := map[string]string{
+		"srcJson":  `CREATE STREAM srcJson () WITH (DATASOURCE="src1", FORMAT="json", SCHEMAID="noneSchema");`,
+		"srcProto": `CREATE STREAM srcProto () WITH (DATASOURCE="src1", FORMAT="protobuf", SCHEMAID="test1.Person");`,
+		"srcNone":  `CREATE STREAM srcNone () WITH (DATASOURCE="src1", FORMAT="protobuf", SCHEMAID="noneSchema.Person");`,
+	}
+	for n, sql := range streamSqls {
+		s, err := json.Marshal(&xsql.StreamInfo{
+			StreamType: ast.TypeStream,
+			Statement:  sql,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		store.Set(n, string(s))
+	}
+	var tests = []struct {
+		sql string
+		err string
+	}{
+		{
+			sql: `SELECT name FROM srcJson`,
+			err: "fail to infer the fields of stream srcJson from schema noneSchema: schema noneSchema is not found",
+		}, { // The protobuf schema file in the schema folder is used for decoding only
+			sql: `SELECT name FROM srcProto`,
+		}, {
+			sql: `SELECT name FROM srcNone`,
+			err: "fail to infer the fields of stream srcNone from schema noneSchema.Person: schema noneSchema is not found",
+		},
+	}
+	for i, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		if err != nil {
+			t.Errorf("%d. %q: error compile sql: %s\n", i, tt.sql, err)
+			continue
+		}
+		_, err = createLogicalPlan(stmt, &api.RuleOption{
+			SendError: true,
+		}, store)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.sql, tt.err, err)
+		}
+	}
+}
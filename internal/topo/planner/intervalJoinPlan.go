@@ -0,0 +1,168 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"reflect"
+)
+
+// IntervalJoinPlan joins two streams without window by the time range condition in the ON clause
+type IntervalJoinPlan struct {
+	baseLogicalPlan
+	join   ast.Join
+	config node.IntervalJoinConfig
+}
+
+func (p IntervalJoinPlan) Init() *IntervalJoinPlan {
+	p.baseLogicalPlan.self = &p
+	return &p
+}
+
+// newIntervalJoinPlan extracts the time range and the equal join keys from the ON clause like
+// a.id = b.id AND b.ts BETWEEN a.ts AND a.ts + 5s
+func newIntervalJoinPlan(from *ast.Table, joins ast.Joins) (*IntervalJoinPlan, error) {
+	if len(joins) != 1 {
+		return nil, errors.New("stream join without window only supports joining two streams")
+	}
+	join := joins[0]
+	if join.JoinType == ast.CROSS_JOIN {
+		return nil, errors.New("stream join without window does not support cross join")
+	}
+	c := node.IntervalJoinConfig{
+		JoinType: join.JoinType,
+		Emitters: [2]string{from.Name, join.Name},
+	}
+	sides := map[ast.StreamName]int{ast.StreamName(from.Name): 0, ast.StreamName(join.Name): 1}
+	found := false
+	for _, cond := range splitAnd(join.Expr) {
+		// The join keys and the time range are handled by the node, only the rest conditions are evaluated.
+		// The time range is not evaluated as an expression so that the time field can be datetime.
+		be, ok := cond.(*ast.BinaryExpr)
+		if !ok {
+			c.Condition = combine(c.Condition, cond)
+			continue
+		}
+		switch be.OP {
+		case ast.EQ:
+			l, lok := sides[singleSource(be.LHS)]
+			r, rok := sides[singleSource(be.RHS)]
+			if lok && rok && l != r {
+				c.Keys[l] = append(c.Keys[l], be.LHS)
+				c.Keys[r] = append(c.Keys[r], be.RHS)
+				continue
+			}
+		case ast.BETWEEN:
+			if found {
+				return nil, errors.New("interval join only supports one time range condition in ON clause")
+			}
+			side, ok := sides[singleSource(be.LHS)]
+			if !ok {
+				break
+			}
+			bt, ok := be.RHS.(*ast.BetweenExpr)
+			if !ok {
+				break
+			}
+			lowerTime, lower, err := timeBound(bt.Lower)
+			if err != nil {
+				return nil, err
+			}
+			higherTime, higher, err := timeBound(bt.Higher)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(lowerTime, higherTime) {
+				return nil, fmt.Errorf("the lower and upper bounds of interval join must be based on the same time field")
+			}
+			if other, ok := sides[singleSource(lowerTime)]; !ok || other == side {
+				return nil, fmt.Errorf("the bounds of interval join must be based on the time field of the other stream")
+			}
+			if lower > higher {
+				return nil, fmt.Errorf("the lower bound %d of interval join is larger than the upper bound %d", lower, higher)
+			}
+			c.Times[side] = be.LHS
+			c.Times[1-side] = lowerTime
+			// Normalize the range to the time of the second side minus the time of the first side
+			if side == 1 {
+				c.Lower, c.Upper = lower, higher
+			} else {
+				c.Lower, c.Upper = -higher, -lower
+			}
+			found = true
+			continue
+		}
+		c.Condition = combine(c.Condition, cond)
+	}
+	if !found {
+		return nil, errors.New("need to run stream join in windows or specify the time range in ON clause like b.ts BETWEEN a.ts AND a.ts + 5s")
+	}
+	return IntervalJoinPlan{
+		join:   join,
+		config: c,
+	}.Init(), nil
+}
+
+func (p *IntervalJoinPlan) PushDownPredicate(condition ast.Expr) (ast.Expr, LogicalPlan) {
+	if p.join.JoinType != ast.INNER_JOIN {
+		// the condition on the null values of the outer join cannot be pushed down
+		return condition, p.self
+	}
+	multipleSourcesCondition, singleSourceCondition := extractCondition(condition)
+	rest, _ := p.baseLogicalPlan.PushDownPredicate(singleSourceCondition)
+	return combine(multipleSourcesCondition, rest), p.self
+}
+
+func (p *IntervalJoinPlan) PruneColumns(fields []ast.Expr) error {
+	f := getFields(&p.join)
+	return p.baseLogicalPlan.PruneColumns(append(fields, f...))
+}
+
+// splitAnd splits the condition into the operands of the AND operators
+func splitAnd(expr ast.Expr) []ast.Expr {
+	if be, ok := expr.(*ast.BinaryExpr); ok && be.OP == ast.AND {
+		return append(splitAnd(be.LHS), splitAnd(be.RHS)...)
+	}
+	if pe, ok := expr.(*ast.ParenExpr); ok {
+		return splitAnd(pe.Expr)
+	}
+	return []ast.Expr{expr}
+}
+
+// singleSource returns the only stream referred by the expression, or empty if there are more or none
+func singleSource(expr ast.Expr) ast.StreamName {
+	srcs, hasDefault := getRefSources(expr)
+	if len(srcs) != 1 || hasDefault {
+		return ""
+	}
+	return srcs[0]
+}
+
+// timeBound parses the bound like a.ts, a.ts + 5s or a.ts - 5s into the time expression and the offset in milliseconds
+func timeBound(expr ast.Expr) (ast.Expr, int64, error) {
+	if be, ok := expr.(*ast.BinaryExpr); ok && (be.OP == ast.ADD || be.OP == ast.SUB) {
+		if il, ok := be.RHS.(*ast.IntegerLiteral); ok {
+			if be.OP == ast.SUB {
+				return be.LHS, -int64(il.Val), nil
+			}
+			return be.LHS, int64(il.Val), nil
+		}
+		return nil, 0, errors.New("the bound of interval join must be a time field plus or minus an integer or a duration like 5s")
+	}
+	return expr, 0, nil
+}
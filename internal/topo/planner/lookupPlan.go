@@ -0,0 +1,95 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// LookupPlan joins the stream with a lookup table by querying the table with the join keys of each event
+type LookupPlan struct {
+	baseLogicalPlan
+	join   ast.Join
+	config node.LookupConfig
+}
+
+func (p LookupPlan) Init() *LookupPlan {
+	p.baseLogicalPlan.self = &p
+	return &p
+}
+
+// newLookupPlan extracts the equal conditions between the columns of the lookup table and the expressions
+// of the stream from the ON clause like a.id = b.id AND a.name = b.name. The other conditions are evaluated after lookup.
+func newLookupPlan(join ast.Join, streamStmt *ast.StreamStmt) (*LookupPlan, error) {
+	if join.JoinType != ast.INNER_JOIN && join.JoinType != ast.LEFT_JOIN {
+		return nil, fmt.Errorf("only inner join and left join are supported for lookup table %s", join.Name)
+	}
+	c := node.LookupConfig{
+		JoinType: join.JoinType,
+		Name:     join.Name,
+		Options:  streamStmt.Options,
+	}
+	table := ast.StreamName(join.Name)
+	for _, cond := range splitAnd(join.Expr) {
+		if be, ok := cond.(*ast.BinaryExpr); ok && be.OP == ast.EQ {
+			if key, value, ok := lookupKey(table, be.LHS, be.RHS); ok {
+				c.Keys = append(c.Keys, key)
+				c.Values = append(c.Values, value)
+				continue
+			} else if key, value, ok := lookupKey(table, be.RHS, be.LHS); ok {
+				c.Keys = append(c.Keys, key)
+				c.Values = append(c.Values, value)
+				continue
+			}
+		}
+		c.Condition = combine(c.Condition, cond)
+	}
+	if len(c.Keys) == 0 {
+		return nil, fmt.Errorf("lookup table %s must be joined by the equal conditions of its columns in ON clause like %s.id = a.id", join.Name, join.Name)
+	}
+	return LookupPlan{
+		join:   join,
+		config: c,
+	}.Init(), nil
+}
+
+// lookupKey checks if the key is a column of the lookup table and the value refers no lookup table column
+func lookupKey(table ast.StreamName, key ast.Expr, value ast.Expr) (string, ast.Expr, bool) {
+	f, ok := key.(*ast.FieldRef)
+	if !ok || f.StreamName != table {
+		return "", nil, false
+	}
+	srcs, _ := getRefSources(value)
+	for _, s := range srcs {
+		if s == table {
+			return "", nil, false
+		}
+	}
+	return f.Name, value, true
+}
+
+func (p *LookupPlan) PushDownPredicate(condition ast.Expr) (ast.Expr, LogicalPlan) {
+	// The conditions on the lookup table are left as they cannot be pushed to the stream
+	multipleSourcesCondition, singleSourceCondition := extractCondition(condition)
+	rest, _ := p.baseLogicalPlan.PushDownPredicate(singleSourceCondition)
+	return combine(multipleSourcesCondition, rest), p.self
+}
+
+func (p *LookupPlan) PruneColumns(fields []ast.Expr) error {
+	f := getFields(&p.join)
+	return p.baseLogicalPlan.PruneColumns(append(fields, f...))
+}
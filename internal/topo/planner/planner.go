@@ -49,6 +49,11 @@ func PlanWithSourcesAndSinks(rule *api.Rule, storePath string, sources []*node.S
 	if rule.Options.SendMetaToSink && (len(streamsFromStmt) > 1 || stmt.Dimensions != nil) {
 		return nil, fmt.Errorf("Invalid option sendMetaToSink, it can not be applied to window")
 	}
+	if rule.Options.EmitInterval > 0 || rule.Options.EmitCount > 0 {
+		if stmt.Dimensions == nil || stmt.Dimensions.GetWindow() == nil || stmt.Dimensions.GetWindow().WindowType != ast.TUMBLING_WINDOW {
+			return nil, fmt.Errorf("Invalid option emitInterval or emitCount, it can only be applied to tumbling window")
+		}
+	}
 	store := kv.GetDefaultKVStore(path.Join(storePath, "stream"))
 	err = store.Open()
 	if err != nil {
@@ -60,13 +65,25 @@ func PlanWithSourcesAndSinks(rule *api.Rule, storePath string, sources []*node.S
 	if err != nil {
 		return nil, err
 	}
-	tp, err := createTopo(rule, lp, sources, sinks, streamsFromStmt)
+	tp, err := createTopo(rule, lp, sources, sinks, excludeLookupTables(streamsFromStmt, store))
 	if err != nil {
 		return nil, err
 	}
 	return tp, nil
 }
 
+// excludeLookupTables removes the lookup tables which never emit data by themselves
+func excludeLookupTables(streams []string, store kv.KeyValue) []string {
+	var result []string
+	for _, s := range streams {
+		if streamStmt, err := xsql.GetDataSource(store, s); err == nil && streamStmt.StreamType == ast.TypeTable && streamStmt.Options.KIND == ast.TableKindLookup {
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
 func createTopo(rule *api.Rule, lp LogicalPlan, sources []*node.SourceNode, sinks []*node.SinkNode, streamsFromStmt []string) (*topo.Topo, error) {
 	// Create topology
 	tp, err := topo.NewWithNameAndQos(rule.Id, rule.Options.Qos, rule.Options.CheckpointInterval)
@@ -119,13 +136,13 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources []
 	case *DataSourcePlan:
 		switch t.streamStmt.StreamType {
 		case ast.TypeStream:
-			pp, err := operator.NewPreprocessor(t.streamFields, t.allMeta, t.metaFields, t.iet, t.timestampField, t.timestampFormat, t.isBinary)
+			pp, err := operator.NewPreprocessor(t.streamFields, t.allMeta, t.metaFields, t.iet, t.timestampField, t.timestampFormat, t.isBinary, t.streamStmt.Options.STRICT_VALIDATION)
 			if err != nil {
 				return nil, 0, err
 			}
 			var srcNode *node.SourceNode
 			if len(sources) == 0 {
-				node := node.NewSourceNode(string(t.name), t.streamStmt.StreamType, t.streamStmt.Options)
+				node := node.NewSourceNode(string(t.name), t.streamStmt.StreamType, t.streamStmt.Options).WithColumns(columnNames(t.streamStmt.StreamFields))
 				srcNode = node
 			} else {
 				srcNode = getMockSource(sources, string(t.name))
@@ -146,12 +163,24 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources []
 				srcNode = getMockSource(sources, string(t.name))
 			}
 			if srcNode == nil {
-				srcNode = node.NewSourceNode(string(t.name), t.streamStmt.StreamType, t.streamStmt.Options)
+				srcNode = node.NewSourceNode(string(t.name), t.streamStmt.StreamType, t.streamStmt.Options).WithColumns(columnNames(t.streamStmt.StreamFields))
 			}
 			tp.AddSrc(srcNode)
 			op = Transform(pp, fmt.Sprintf("%d_tableprocessor_%s", newIndex, t.name), options)
 			inputs = []api.Emitter{srcNode}
 		}
+		if t.streamStmt.Options.STRICT_VALIDATION {
+			// Add invalid actions which receive the records dropped by the strict validation
+			for i, m := range options.InvalidActions {
+				for name, action := range m {
+					props, ok := action.(map[string]interface{})
+					if !ok {
+						return nil, 0, fmt.Errorf("expect map[string]interface{} type for the invalid action properties, but found %v", action)
+					}
+					tp.AddSink([]api.Emitter{op.(*node.UnaryOperator).InvalidEmitter()}, node.NewSinkNode(fmt.Sprintf("%s_invalid_%s_%d", name, t.name, i), name, props))
+				}
+			}
+		}
 	case *WindowPlan:
 		if t.condition != nil {
 			wfilterOp := Transform(&operator.FilterOp{Condition: t.condition}, fmt.Sprintf("%d_windowFilter", newIndex), options)
@@ -160,16 +189,36 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources []
 			inputs = []api.Emitter{wfilterOp}
 		}
 
-		op, err = node.NewWindowOp(fmt.Sprintf("%d_window", newIndex), node.WindowConfig{
-			Type:     t.wtype,
-			Length:   t.length,
-			Interval: t.interval,
+		var wop *node.WindowOperator
+		wop, err = node.NewWindowOp(fmt.Sprintf("%d_window", newIndex), node.WindowConfig{
+			Type:           t.wtype,
+			Length:         t.length,
+			Interval:       t.interval,
+			BeginCondition: t.beginCondition,
+			EndCondition:   t.endCondition,
 		}, streamsFromStmt, options)
 		if err != nil {
 			return nil, 0, err
 		}
+		if options.IsEventTime {
+			// Add late actions which receive the tuples dropped by the watermark
+			for i, m := range options.LateActions {
+				for name, action := range m {
+					props, ok := action.(map[string]interface{})
+					if !ok {
+						return nil, 0, fmt.Errorf("expect map[string]interface{} type for the late action properties, but found %v", action)
+					}
+					tp.AddSink([]api.Emitter{wop.LateEmitter()}, node.NewSinkNode(fmt.Sprintf("%s_late_%d", name, i), name, props))
+				}
+			}
+		}
+		op = wop
 	case *JoinAlignPlan:
 		op, err = node.NewJoinAlignNode(fmt.Sprintf("%d_join_aligner", newIndex), t.Emitters, options)
+	case *IntervalJoinPlan:
+		op, err = node.NewIntervalJoinNode(fmt.Sprintf("%d_interval_join", newIndex), t.config, options)
+	case *LookupPlan:
+		op, err = node.NewLookupNode(fmt.Sprintf("%d_lookup_%s", newIndex, t.config.Name), t.config, options)
 	case *JoinPlan:
 		op = Transform(&operator.JoinOp{Joins: t.joins, From: t.from}, fmt.Sprintf("%d_join", newIndex), options)
 	case *FilterPlan:
@@ -178,8 +227,12 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources []
 		op = Transform(&operator.AggregateOp{Dimensions: t.dimensions}, fmt.Sprintf("%d_aggregate", newIndex), options)
 	case *HavingPlan:
 		op = Transform(&operator.HavingOp{Condition: t.condition}, fmt.Sprintf("%d_having", newIndex), options)
+	case *WindowFuncPlan:
+		op = Transform(&operator.WindowFuncOp{Calls: t.calls}, fmt.Sprintf("%d_windowFunc", newIndex), options)
 	case *OrderPlan:
 		op = Transform(&operator.OrderOp{SortFields: t.SortFields}, fmt.Sprintf("%d_order", newIndex), options)
+	case *LimitPlan:
+		op = Transform(&operator.LimitOp{Limit: t.limit, Offset: t.offset, IsAggregate: t.isAggregate}, fmt.Sprintf("%d_limit", newIndex), options)
 	case *ProjectPlan:
 		op = Transform(&operator.ProjectOp{Fields: t.fields, IsAggregate: t.isAggregate, SendMeta: t.sendMeta}, fmt.Sprintf("%d_project", newIndex), options)
 	default:
@@ -192,6 +245,17 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources []
 	return op, newIndex, nil
 }
 
+func columnNames(fields ast.StreamFields) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	result := make([]string, len(fields))
+	for i, f := range fields {
+		result[i] = f.Name
+	}
+	return result
+}
+
 func getMockSource(sources []*node.SourceNode, name string) *node.SourceNode {
 	for _, source := range sources {
 		if name == source.GetName() {
@@ -210,8 +274,10 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 		// If there are tables, the plan graph will be different for join/window
 		tableChildren []LogicalPlan
 		tableEmitters []string
-		w             *ast.Window
-		ds            ast.Dimensions
+		// The lookup tables are not data sources but queried on demand during join
+		lookupTables = make(map[string]*ast.StreamStmt)
+		w            *ast.Window
+		ds           ast.Dimensions
 	)
 
 	streamStmts, err := decorateStmt(stmt, store)
@@ -220,6 +286,13 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 	}
 
 	for _, streamStmt := range streamStmts {
+		if streamStmt.StreamType == ast.TypeTable && streamStmt.Options.KIND == ast.TableKindLookup {
+			if string(streamStmt.Name) == stmt.Sources[0].(*ast.Table).Name {
+				return nil, fmt.Errorf("lookup table %s must be joined with a stream", streamStmt.Name)
+			}
+			lookupTables[string(streamStmt.Name)] = streamStmt
+			continue
+		}
 		p = DataSourcePlan{
 			name:       streamStmt.Name,
 			streamStmt: streamStmt,
@@ -241,9 +314,15 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 			}
 			wp := WindowPlan{
 				wtype:       w.WindowType,
-				length:      w.Length.Val,
 				isEventTime: opt.IsEventTime,
 			}.Init()
+			if w.Length != nil {
+				wp.length = w.Length.Val
+			}
+			if w.WindowType == ast.STATE_WINDOW {
+				wp.beginCondition = w.BeginCondition
+				wp.endCondition = w.EndCondition
+			}
 			if w.Interval != nil {
 				wp.interval = w.Interval.Val
 			} else if w.WindowType == ast.COUNT_WINDOW {
@@ -261,22 +340,47 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 		}
 	}
 	if stmt.Joins != nil {
-		if len(tableChildren) > 0 {
-			p = JoinAlignPlan{
-				Emitters: tableEmitters,
-			}.Init()
-			p.SetChildren(append(children, tableChildren...))
+		var joins, lookupJoins ast.Joins
+		for _, join := range stmt.Joins {
+			if _, ok := lookupTables[join.Name]; ok {
+				lookupJoins = append(lookupJoins, join)
+			} else {
+				joins = append(joins, join)
+			}
+		}
+		if len(joins) > 0 {
+			if len(tableChildren) > 0 {
+				p = JoinAlignPlan{
+					Emitters: tableEmitters,
+				}.Init()
+				p.SetChildren(append(children, tableChildren...))
+				children = []LogicalPlan{p}
+			}
+			if len(tableChildren) == 0 && w == nil {
+				// Stream join without window must be an interval join with the time range in the ON clause
+				p, err = newIntervalJoinPlan(stmt.Sources[0].(*ast.Table), joins)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				// TODO extract on filter
+				p = JoinPlan{
+					from:  stmt.Sources[0].(*ast.Table),
+					joins: joins,
+				}.Init()
+			}
+			p.SetChildren(children)
+			children = []LogicalPlan{p}
+		}
+		// Lookup after the join of streams so that the keys can come from any stream
+		for _, join := range lookupJoins {
+			p, err = newLookupPlan(join, lookupTables[join.Name])
+			if err != nil {
+				return nil, err
+			}
+			p.SetChildren(children)
 			children = []LogicalPlan{p}
-		} else if w == nil {
-			return nil, errors.New("need to run stream join in windows")
 		}
-		// TODO extract on filter
-		p = JoinPlan{
-			from:  stmt.Sources[0].(*ast.Table),
-			joins: stmt.Joins,
-		}.Init()
-		p.SetChildren(children)
-		children = []LogicalPlan{p}
 	}
 	if stmt.Condition != nil {
 		p = FilterPlan{
@@ -305,6 +409,20 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 		children = []LogicalPlan{p}
 	}
 
+	if calls := getWindowFuncCalls(stmt.Fields); len(calls) > 0 {
+		if w == nil {
+			return nil, errors.New("window functions with OVER clause must run in a window")
+		}
+		if stmt.Joins != nil || ast.IsAggStatement(stmt) {
+			return nil, errors.New("window functions with OVER clause cannot be used with join, GROUP BY dimensions or aggregate functions")
+		}
+		p = WindowFuncPlan{
+			calls: calls,
+		}.Init()
+		p.SetChildren(children)
+		children = []LogicalPlan{p}
+	}
+
 	if stmt.SortFields != nil {
 		p = OrderPlan{
 			SortFields: stmt.SortFields,
@@ -313,6 +431,16 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 		children = []LogicalPlan{p}
 	}
 
+	if stmt.Limit > 0 {
+		p = LimitPlan{
+			limit:       stmt.Limit,
+			offset:      stmt.Offset,
+			isAggregate: ast.IsAggStatement(stmt),
+		}.Init()
+		p.SetChildren(children)
+		children = []LogicalPlan{p}
+	}
+
 	if stmt.Fields != nil {
 		p = ProjectPlan{
 			fields:      stmt.Fields,
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"github.com/gdexlab/go-render/render"
 	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -57,11 +58,16 @@ func Test_createLogicalPlan(t *testing.T) {
 					value STRING,
 					hum BIGINT
 				) WITH (TYPE="file");`,
+		"lookupInPlanner": `CREATE TABLE lookupInPlanner (
+					id BIGINT,
+					code STRING
+				) WITH (DATASOURCE="codes", TYPE="sqlite", KIND="lookup");`,
 	}
 	types := map[string]ast.StreamType{
-		"src1":           ast.TypeStream,
-		"src2":           ast.TypeStream,
-		"tableInPlanner": ast.TypeTable,
+		"src1":            ast.TypeStream,
+		"src2":            ast.TypeStream,
+		"tableInPlanner":  ast.TypeTable,
+		"lookupInPlanner": ast.TypeTable,
 	}
 	for name, sql := range streamSqls {
 		s, err := json.Marshal(&xsql.StreamInfo{
@@ -89,6 +95,30 @@ func Test_createLogicalPlan(t *testing.T) {
 		boolFalse = false
 	)
 
+	intervalCond := &ast.BinaryExpr{
+		OP: ast.AND,
+		LHS: &ast.BinaryExpr{
+			OP:  ast.EQ,
+			LHS: &ast.FieldRef{Name: "id1", StreamName: "src1"},
+			RHS: &ast.FieldRef{Name: "id2", StreamName: "src2"},
+		},
+		RHS: &ast.BinaryExpr{
+			OP:  ast.BETWEEN,
+			LHS: &ast.FieldRef{Name: "hum", StreamName: "src2"},
+			RHS: &ast.BetweenExpr{
+				Lower: &ast.BinaryExpr{
+					OP:  ast.SUB,
+					LHS: &ast.FieldRef{Name: "temp", StreamName: "src1"},
+					RHS: &ast.IntegerLiteral{Val: 1000},
+				},
+				Higher: &ast.BinaryExpr{
+					OP:  ast.ADD,
+					LHS: &ast.FieldRef{Name: "temp", StreamName: "src1"},
+					RHS: &ast.IntegerLiteral{Val: 5000},
+				},
+			},
+		},
+	}
 	var tests = []struct {
 		sql string
 		p   LogicalPlan
@@ -1024,6 +1054,249 @@ func Test_createLogicalPlan(t *testing.T) {
 				isAggregate: false,
 				sendMeta:    false,
 			}.Init(),
+		}, { // 12 limit after order
+			sql: `SELECT name FROM src1 GROUP BY TUMBLINGWINDOW(ss, 10) ORDER BY name DESC LIMIT 3 OFFSET 1`,
+			p: ProjectPlan{
+				baseLogicalPlan: baseLogicalPlan{
+					children: []LogicalPlan{
+						LimitPlan{
+							baseLogicalPlan: baseLogicalPlan{
+								children: []LogicalPlan{
+									OrderPlan{
+										baseLogicalPlan: baseLogicalPlan{
+											children: []LogicalPlan{
+												WindowPlan{
+													baseLogicalPlan: baseLogicalPlan{
+														children: []LogicalPlan{
+															DataSourcePlan{
+																name: "src1",
+																streamFields: []interface{}{
+																	&ast.StreamField{
+																		Name:      "name",
+																		FieldType: &ast.BasicType{Type: ast.STRINGS},
+																	},
+																},
+																streamStmt: streams["src1"],
+																metaFields: []string{},
+															}.Init(),
+														},
+													},
+													condition: nil,
+													wtype:     ast.TUMBLING_WINDOW,
+													length:    10000,
+													interval:  0,
+													limit:     0,
+												}.Init(),
+											},
+										},
+										SortFields: []ast.SortField{{Name: "name", Ascending: false}},
+									}.Init(),
+								},
+							},
+							limit:       3,
+							offset:      1,
+							isAggregate: false,
+						}.Init(),
+					},
+				},
+				fields: []ast.Field{
+					{
+						Expr:  &ast.FieldRef{Name: "name", StreamName: "src1"},
+						Name:  "name",
+						AName: ""},
+				},
+				isAggregate: false,
+				sendMeta:    false,
+			}.Init(),
+		}, { // 13 interval join
+			sql: `SELECT id1 FROM src1 INNER JOIN src2 ON src1.id1 = src2.id2 AND src2.hum BETWEEN src1.temp - 1s AND src1.temp + 5s WHERE src1.name = "v1"`,
+			p: ProjectPlan{
+				baseLogicalPlan: baseLogicalPlan{
+					children: []LogicalPlan{
+						IntervalJoinPlan{
+							baseLogicalPlan: baseLogicalPlan{
+								children: []LogicalPlan{
+									FilterPlan{
+										baseLogicalPlan: baseLogicalPlan{
+											children: []LogicalPlan{
+												DataSourcePlan{
+													name: "src1",
+													streamFields: []interface{}{
+														&ast.StreamField{
+															Name:      "id1",
+															FieldType: &ast.BasicType{Type: ast.BIGINT},
+														},
+														&ast.StreamField{
+															Name:      "name",
+															FieldType: &ast.BasicType{Type: ast.STRINGS},
+														},
+														&ast.StreamField{
+															Name:      "temp",
+															FieldType: &ast.BasicType{Type: ast.BIGINT},
+														},
+													},
+													streamStmt: streams["src1"],
+													metaFields: []string{},
+												}.Init(),
+											},
+										},
+										condition: &ast.BinaryExpr{
+											OP:  ast.EQ,
+											LHS: &ast.FieldRef{Name: "name", StreamName: "src1"},
+											RHS: &ast.StringLiteral{Val: "v1"},
+										},
+									}.Init(),
+									DataSourcePlan{
+										name: "src2",
+										streamFields: []interface{}{
+											&ast.StreamField{
+												Name:      "hum",
+												FieldType: &ast.BasicType{Type: ast.BIGINT},
+											},
+											&ast.StreamField{
+												Name:      "id2",
+												FieldType: &ast.BasicType{Type: ast.BIGINT},
+											},
+										},
+										streamStmt: streams["src2"],
+										metaFields: []string{},
+									}.Init(),
+								},
+							},
+							join: ast.Join{
+								Name:     "src2",
+								JoinType: ast.INNER_JOIN,
+								Expr:     intervalCond,
+							},
+							config: node.IntervalJoinConfig{
+								JoinType: ast.INNER_JOIN,
+								Emitters: [2]string{"src1", "src2"},
+								Times:    [2]ast.Expr{&ast.FieldRef{Name: "temp", StreamName: "src1"}, &ast.FieldRef{Name: "hum", StreamName: "src2"}},
+								Keys:     [2][]ast.Expr{{&ast.FieldRef{Name: "id1", StreamName: "src1"}}, {&ast.FieldRef{Name: "id2", StreamName: "src2"}}},
+								Lower:    -1000,
+								Upper:    5000,
+							},
+						}.Init(),
+					},
+				},
+				fields: []ast.Field{
+					{
+						Expr:  &ast.FieldRef{Name: "id1", StreamName: "src1"},
+						Name:  "id1",
+						AName: ""},
+				},
+				isAggregate: false,
+				sendMeta:    false,
+			}.Init(),
+		}, { // 14
+			sql: `SELECT id1 FROM src1 INNER JOIN src2 ON src1.id1 = src2.id2`,
+			p:   nil,
+			err: "need to run stream join in windows or specify the time range in ON clause like b.ts BETWEEN a.ts AND a.ts + 5s",
+		}, { // 15
+			sql: `SELECT id1 FROM src1 INNER JOIN src2 ON src2.hum BETWEEN src1.temp AND src2.id2 + 5s`,
+			p:   nil,
+			err: "the lower and upper bounds of interval join must be based on the same time field",
+		}, { // 16
+			sql: `SELECT id1 FROM src1 INNER JOIN src2 ON src2.hum BETWEEN src1.temp + 5s AND src1.temp`,
+			p:   nil,
+			err: "the lower bound 5000 of interval join is larger than the upper bound 0",
+		}, { // 17 lookup join
+			sql: `SELECT id1, code FROM src1 LEFT JOIN lookupInPlanner ON src1.id1 = lookupInPlanner.id AND temp > 20 WHERE src1.name = "v1"`,
+			p: ProjectPlan{
+				baseLogicalPlan: baseLogicalPlan{
+					children: []LogicalPlan{
+						LookupPlan{
+							baseLogicalPlan: baseLogicalPlan{
+								children: []LogicalPlan{
+									FilterPlan{
+										baseLogicalPlan: baseLogicalPlan{
+											children: []LogicalPlan{
+												DataSourcePlan{
+													name: "src1",
+													streamFields: []interface{}{
+														&ast.StreamField{
+															Name:      "id1",
+															FieldType: &ast.BasicType{Type: ast.BIGINT},
+														},
+														&ast.StreamField{
+															Name:      "name",
+															FieldType: &ast.BasicType{Type: ast.STRINGS},
+														},
+														&ast.StreamField{
+															Name:      "temp",
+															FieldType: &ast.BasicType{Type: ast.BIGINT},
+														},
+													},
+													streamStmt: streams["src1"],
+													metaFields: []string{},
+												}.Init(),
+											},
+										},
+										condition: &ast.BinaryExpr{
+											OP:  ast.EQ,
+											LHS: &ast.FieldRef{Name: "name", StreamName: "src1"},
+											RHS: &ast.StringLiteral{Val: "v1"},
+										},
+									}.Init(),
+								},
+							},
+							join: ast.Join{
+								Name:     "lookupInPlanner",
+								JoinType: ast.LEFT_JOIN,
+								Expr: &ast.BinaryExpr{
+									OP: ast.AND,
+									LHS: &ast.BinaryExpr{
+										OP:  ast.EQ,
+										LHS: &ast.FieldRef{Name: "id1", StreamName: "src1"},
+										RHS: &ast.FieldRef{Name: "id", StreamName: "lookupInPlanner"},
+									},
+									RHS: &ast.BinaryExpr{
+										OP:  ast.GT,
+										LHS: &ast.FieldRef{Name: "temp", StreamName: "src1"},
+										RHS: &ast.IntegerLiteral{Val: 20},
+									},
+								},
+							},
+							config: node.LookupConfig{
+								JoinType: ast.LEFT_JOIN,
+								Name:     "lookupInPlanner",
+								Options:  streams["lookupInPlanner"].Options,
+								Keys:     []string{"id"},
+								Values:   []ast.Expr{&ast.FieldRef{Name: "id1", StreamName: "src1"}},
+								Condition: &ast.BinaryExpr{
+									OP:  ast.GT,
+									LHS: &ast.FieldRef{Name: "temp", StreamName: "src1"},
+									RHS: &ast.IntegerLiteral{Val: 20},
+								},
+							},
+						}.Init(),
+					},
+				},
+				fields: []ast.Field{
+					{
+						Expr:  &ast.FieldRef{Name: "id1", StreamName: "src1"},
+						Name:  "id1",
+						AName: ""},
+					{
+						Expr:  &ast.FieldRef{Name: "code", StreamName: "lookupInPlanner"},
+						Name:  "code",
+						AName: ""},
+				},
+				isAggregate: false,
+				sendMeta:    false,
+			}.Init(),
+		}, { // 18
+			sql: `SELECT id1, code FROM src1 RIGHT JOIN lookupInPlanner ON src1.id1 = lookupInPlanner.id`,
+			p:   nil,
+			err: "only inner join and left join are supported for lookup table lookupInPlanner",
+		}, { // 19
+			sql: `SELECT id1, code FROM src1 INNER JOIN lookupInPlanner ON src1.id1 > lookupInPlanner.id`,
+			p:   nil,
+			err: "lookup table lookupInPlanner must be joined by the equal conditions of its columns in ON clause like lookupInPlanner.id = a.id",
+		}, { // 20
+			sql: `SELECT id1, code FROM lookupInPlanner INNER JOIN src1 ON src1.id1 = lookupInPlanner.id`,
+			p:   nil,
+			err: "lookup table lookupInPlanner must be joined with a stream",
 		},
 	}
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
@@ -1962,3 +2235,41 @@ func Test_createLogicalPlanSchemaless(t *testing.T) {
 		}
 	}
 }
+
+func TestPlanEmitOption(t *testing.T) {
+	var tests = []struct {
+		sql string
+		err string
+	}{
+		{
+			sql: `SELECT count(*) FROM src1 GROUP BY TUMBLINGWINDOW(ss, 10)`,
+			err: "",
+		}, {
+			sql: `SELECT count(*) FROM src1 GROUP BY HOPPINGWINDOW(ss, 10, 5)`,
+			err: "Invalid option emitInterval or emitCount, it can only be applied to tumbling window",
+		}, {
+			sql: `SELECT * FROM src1`,
+			err: "Invalid option emitInterval or emitCount, it can only be applied to tumbling window",
+		},
+	}
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+
+	for i, tt := range tests {
+		_, err := PlanWithSourcesAndSinks(&api.Rule{
+			Id:  fmt.Sprintf("TestPlanEmitOption%d", i),
+			Sql: tt.sql,
+			Options: &api.RuleOption{
+				BufferLength: 100,
+				EmitCount:    2,
+			},
+		}, DbDir, nil, nil)
+		if tt.err == "" {
+			// Other errors like the missing stream are out of the scope
+			if err != nil && strings.Contains(err.Error(), "emitInterval") {
+				t.Errorf("%d. %q: unexpected error %v", i, tt.sql, err)
+			}
+		} else if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.sql, tt.err, err)
+		}
+	}
+}
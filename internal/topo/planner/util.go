@@ -76,3 +76,15 @@ func getFields(node ast.Node) []ast.Expr {
 	})
 	return result
 }
+
+// getWindowFuncCalls returns all the function calls with OVER clause
+func getWindowFuncCalls(node ast.Node) []*ast.Call {
+	var result []*ast.Call
+	ast.WalkFunc(node, func(n ast.Node) bool {
+		if c, ok := n.(*ast.Call); ok && c.Over != nil {
+			result = append(result, c)
+		}
+		return true
+	})
+	return result
+}
@@ -24,6 +24,9 @@ type WindowPlan struct {
 	interval    int //If interval is not set, it is equals to Length
 	limit       int //If limit is not positive, there will be no limit
 	isEventTime bool
+	// conditions to open and close the state window
+	beginCondition ast.Expr
+	endCondition   ast.Expr
 }
 
 func (p WindowPlan) Init() *WindowPlan {
@@ -52,5 +55,7 @@ func (p *WindowPlan) PushDownPredicate(condition ast.Expr) (ast.Expr, LogicalPla
 
 func (p *WindowPlan) PruneColumns(fields []ast.Expr) error {
 	f := getFields(p.condition)
+	f = append(f, getFields(p.beginCondition)...)
+	f = append(f, getFields(p.endCondition)...)
 	return p.baseLogicalPlan.PruneColumns(append(fields, f...))
 }
@@ -0,0 +1,115 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"sync"
+	"time"
+)
+
+// batchWriter collects the items of a sink and writes them in batches. A batch is written once it is full, or at the
+// flush interval if the interval is positive. A failed batch is kept to be written again by the next flush, except
+// the items of the failing Collect which are removed so that the retry and cache of the sink take effect on them
+// without duplicates. The batch is written at least once, so a batch which fails partially may be written twice.
+type batchWriter struct {
+	// The name of the sink in the logs such as "Sql sink"
+	name     string
+	size     int
+	interval int
+	write    func(ctx context.Context, items []interface{}) error
+
+	mu      sync.Mutex
+	pending []interface{}
+	cancel  chan struct{}
+}
+
+func newBatchWriter(name string, size int, interval int, write func(ctx context.Context, items []interface{}) error) *batchWriter {
+	return &batchWriter{
+		name:     name,
+		size:     size,
+		interval: interval,
+		write:    write,
+	}
+}
+
+// start writes the incomplete batch at the flush interval until closed
+func (b *batchWriter) start(ctx api.StreamContext) {
+	if b.size <= 1 || b.interval <= 0 {
+		return
+	}
+	logger := ctx.GetLogger()
+	cancel := make(chan struct{})
+	b.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(time.Duration(b.interval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.mu.Lock()
+				err := b.flush(ctx, logger)
+				b.mu.Unlock()
+				if err != nil {
+					logger.Warnf("%s fails to write the batch and will write it again: %v", b.name, err)
+				}
+			case <-cancel:
+				return
+			}
+		}
+	}()
+}
+
+// add appends the items of a Collect and writes the batch if it is full
+func (b *batchWriter) add(ctx context.Context, logger api.Logger, items ...interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.pending)
+	b.pending = append(b.pending, items...)
+	if len(b.pending) < b.size {
+		return nil
+	}
+	if err := b.flush(ctx, logger); err != nil {
+		b.pending = b.pending[:n]
+		return err
+	}
+	return nil
+}
+
+// flush writes the pending items which are kept if fail. It must be called with the lock.
+func (b *batchWriter) flush(ctx context.Context, logger api.Logger) error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	logger.Debugf("%s writes %d items", b.name, len(b.pending))
+	if err := b.write(ctx, b.pending); err != nil {
+		return err
+	}
+	b.pending = nil
+	return nil
+}
+
+// close stops the timer and writes the remaining items. The rule context may be done already, so they are written
+// with a new context.
+func (b *batchWriter) close(logger api.Logger) error {
+	if b.cancel != nil {
+		close(b.cancel)
+		b.cancel = nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush(context.Background(), logger)
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	gocontext "context"
+	"errors"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockBatchTarget struct {
+	sync.Mutex
+	batches [][]interface{}
+	err     error
+}
+
+func (m *mockBatchTarget) write(_ gocontext.Context, items []interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.batches = append(m.batches, append([]interface{}{}, items...))
+	return nil
+}
+
+func (m *mockBatchTarget) setErr(err error) {
+	m.Lock()
+	m.err = err
+	m.Unlock()
+}
+
+func (m *mockBatchTarget) result() [][]interface{} {
+	m.Lock()
+	defer m.Unlock()
+	return m.batches
+}
+
+func TestBatchWriterRetry(t *testing.T) {
+	m := &mockBatchTarget{}
+	b := newBatchWriter("Test sink", 3, 0, m.write)
+	ctx := context.Background()
+	b.start(ctx)
+	logger := ctx.GetLogger()
+	if err := b.add(ctx, logger, 1); err != nil {
+		t.Fatal(err)
+	}
+	m.setErr(errors.New("connection lost"))
+	// The failed batch is kept without the items of the failing collect
+	err := b.add(ctx, logger, 2, 3)
+	if !reflect.DeepEqual("connection lost", testx.Errstring(err)) {
+		t.Errorf("error mismatch, got %v", err)
+	}
+	m.setErr(nil)
+	// The retry of the sink collects the items again
+	if err := b.add(ctx, logger, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.add(ctx, logger, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.close(logger); err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]interface{}{{1, 2, 3}, {4}}
+	if !reflect.DeepEqual(exp, m.result()) {
+		t.Errorf("result mismatch:\n  exp=%v\n  got=%v\n\n", exp, m.result())
+	}
+}
+
+func TestBatchWriterInterval(t *testing.T) {
+	m := &mockBatchTarget{err: errors.New("connection lost")}
+	b := newBatchWriter("Test sink", 3, 20, m.write)
+	ctx := context.Background()
+	b.start(ctx)
+	logger := ctx.GetLogger()
+	if err := b.add(ctx, logger, 1); err != nil {
+		t.Fatal(err)
+	}
+	// The batch fails to be written at the interval and is kept
+	time.Sleep(50 * time.Millisecond)
+	if r := m.result(); len(r) != 0 {
+		t.Errorf("expect no batch written but got %v", r)
+	}
+	m.setErr(nil)
+	time.Sleep(50 * time.Millisecond)
+	exp := [][]interface{}{{1}}
+	if !reflect.DeepEqual(exp, m.result()) {
+		t.Errorf("result mismatch:\n  exp=%v\n  got=%v\n\n", exp, m.result())
+	}
+	if err := b.close(logger); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/pkg/kafkax"
+	ct "github.com/lf-edge/ekuiper/internal/template"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/segmentio/kafka-go"
+	"sort"
+	"text/template"
+	"time"
+)
+
+var kafkaCompressions = map[string]kafka.Compression{
+	"gzip":   kafka.Gzip,
+	"snappy": kafka.Snappy,
+	"lz4":    kafka.Lz4,
+	"zstd":   kafka.Zstd,
+}
+
+type KafkaSinkConfig struct {
+	kafkax.ConnConfig
+	Topic string `json:"topic"`
+	// The template of the message key
+	Key string `json:"key"`
+	// The message headers whose values are templates
+	Headers map[string]string `json:"headers"`
+	// The acknowledgement required from the brokers: -1 for all replicas, 1 for the leader and 0 for none
+	RequiredAcks int `json:"requiredAcks"`
+	// The number of the messages to send in one batch
+	BatchSize int `json:"batchSize"`
+	// The interval to send the incomplete batch, time unit is ms
+	FlushInterval int `json:"flushInterval"`
+	// none, gzip, snappy, lz4 or zstd
+	Compression string `json:"compression"`
+}
+
+// kafkaWriter is the part of the kafka writer used by the sink
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaSink sends each result as a kafka message. The message key and headers can be rendered by templates from
+// the result. The messages can be sent in batches.
+type KafkaSink struct {
+	config  *KafkaSinkConfig
+	key     *template.Template
+	headers map[string]*template.Template
+	writer  kafkaWriter
+	batch   *batchWriter
+	// Create the writer, replaced in tests
+	newWriter func() (kafkaWriter, error)
+}
+
+func (ks *KafkaSink) Configure(props map[string]interface{}) error {
+	cfg := &KafkaSinkConfig{
+		RequiredAcks:  -1,
+		BatchSize:     1,
+		FlushInterval: 1000,
+		Compression:   "none",
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Topic == "" {
+		return errors.New("missing property topic")
+	}
+	if cfg.RequiredAcks < -1 || cfg.RequiredAcks > 1 {
+		return fmt.Errorf("invalid requiredAcks %d, must be -1, 0 or 1", cfg.RequiredAcks)
+	}
+	if cfg.BatchSize <= 0 {
+		return fmt.Errorf("invalid batchSize %d, must be positive", cfg.BatchSize)
+	}
+	if cfg.FlushInterval < 0 {
+		return fmt.Errorf("invalid flushInterval %d, must not be negative", cfg.FlushInterval)
+	}
+	if _, ok := kafkaCompressions[cfg.Compression]; !ok && cfg.Compression != "none" {
+		return fmt.Errorf("invalid compression %s, must be none, gzip, snappy, lz4 or zstd", cfg.Compression)
+	}
+	if cfg.Key != "" {
+		ks.key, err = template.New("key").Funcs(ct.FuncMap).Parse(cfg.Key)
+		if err != nil {
+			return fmt.Errorf("invalid key template %s: %v", cfg.Key, err)
+		}
+	}
+	ks.headers = make(map[string]*template.Template, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		ks.headers[k], err = template.New(k).Funcs(ct.FuncMap).Parse(v)
+		if err != nil {
+			return fmt.Errorf("invalid template %s of header %s: %v", v, k, err)
+		}
+	}
+	ks.config = cfg
+	ks.batch = newBatchWriter("Kafka sink", cfg.BatchSize, cfg.FlushInterval, ks.write)
+	if ks.newWriter == nil {
+		ks.newWriter = ks.createWriter
+	}
+	return nil
+}
+
+func (ks *KafkaSink) createWriter() (kafkaWriter, error) {
+	t, err := ks.config.Transport()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Writer{
+		Addr:         kafka.TCP(ks.config.BrokerList()...),
+		Topic:        ks.config.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequiredAcks(ks.config.RequiredAcks),
+		// The batch is collected by the sink, so send it at once
+		BatchSize:    ks.config.BatchSize,
+		BatchTimeout: time.Millisecond,
+		Compression:  kafkaCompressions[ks.config.Compression],
+		Transport:    t,
+	}, nil
+}
+
+func (ks *KafkaSink) Open(ctx api.StreamContext) error {
+	logger := ctx.GetLogger()
+	logger.Infof("Opening kafka sink to topic %s", ks.config.Topic)
+	w, err := ks.newWriter()
+	if err != nil {
+		return err
+	}
+	ks.writer = w
+	ks.batch.start(ctx)
+	return nil
+}
+
+func (ks *KafkaSink) Collect(ctx api.StreamContext, item interface{}) error {
+	logger := ctx.GetLogger()
+	v, ok := item.([]byte)
+	if !ok {
+		return fmt.Errorf("kafka sink receive non []byte data: %v", item)
+	}
+	logger.Debugf("kafka sink receive %s", v)
+	msg, err := ks.toMessage(v)
+	if err != nil {
+		return err
+	}
+	return ks.batch.add(ctx, logger, msg)
+}
+
+// toMessage renders the key and headers by the payload decoded as json
+func (ks *KafkaSink) toMessage(v []byte) (kafka.Message, error) {
+	msg := kafka.Message{Value: v}
+	if ks.key == nil && len(ks.headers) == 0 {
+		return msg, nil
+	}
+	var data interface{}
+	if err := json.Unmarshal(v, &data); err != nil {
+		return msg, fmt.Errorf("kafka sink fails to decode %s as json to render the key or headers: %v", v, err)
+	}
+	if ks.key != nil {
+		var b bytes.Buffer
+		if err := ks.key.Execute(&b, data); err != nil {
+			return msg, fmt.Errorf("kafka sink fails to render the key: %v", err)
+		}
+		msg.Key = b.Bytes()
+	}
+	names := make([]string, 0, len(ks.headers))
+	for k := range ks.headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		var b bytes.Buffer
+		if err := ks.headers[k].Execute(&b, data); err != nil {
+			return msg, fmt.Errorf("kafka sink fails to render header %s: %v", k, err)
+		}
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: b.Bytes()})
+	}
+	return msg, nil
+}
+
+// write sends the messages in a batch
+func (ks *KafkaSink) write(ctx context.Context, items []interface{}) error {
+	msgs := make([]kafka.Message, len(items))
+	for i, item := range items {
+		msgs[i] = item.(kafka.Message)
+	}
+	if err := ks.writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("kafka sink fails to send out the data: %v", err)
+	}
+	return nil
+}
+
+func (ks *KafkaSink) Close(ctx api.StreamContext) error {
+	logger := ctx.GetLogger()
+	logger.Infof("Closing kafka sink")
+	if ks.writer == nil {
+		return nil
+	}
+	if err := ks.batch.close(logger); err != nil {
+		logger.Warnf("Kafka sink fails to send the remaining messages: %v", err)
+	}
+	return ks.writer.Close()
+}
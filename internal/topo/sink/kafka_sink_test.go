@@ -0,0 +1,173 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	gocontext "context"
+	"errors"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/segmentio/kafka-go"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestKafkaSinkConfigure(t *testing.T) {
+	var tests = []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{"topic": "t"},
+			err:   "missing property brokers",
+		}, {
+			props: map[string]interface{}{"brokers": "localhost:9092"},
+			err:   "missing property topic",
+		}, {
+			props: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "requiredAcks": 2},
+			err:   "invalid requiredAcks 2, must be -1, 0 or 1",
+		}, {
+			props: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "batchSize": 0},
+			err:   "invalid batchSize 0, must be positive",
+		}, {
+			props: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "flushInterval": -1},
+			err:   "invalid flushInterval -1, must not be negative",
+		}, {
+			props: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "compression": "bzip2"},
+			err:   "invalid compression bzip2, must be none, gzip, snappy, lz4 or zstd",
+		}, {
+			props: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "key": "{{.id"},
+			err:   "invalid key template {{.id: template: key:1: unclosed action",
+		}, {
+			props: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "headers": map[string]interface{}{"h": "{{.id"}},
+			err:   "invalid template {{.id of header h: template: h:1: unclosed action",
+		}, {
+			props: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "key": "{{.id}}", "requiredAcks": 1, "compression": "gzip", "batchSize": 10},
+		},
+	}
+	for i, tt := range tests {
+		s := &KafkaSink{}
+		err := s.Configure(tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+type mockKafkaWriter struct {
+	sync.Mutex
+	batches [][]kafka.Message
+	err     error
+}
+
+func (w *mockKafkaWriter) WriteMessages(_ gocontext.Context, msgs ...kafka.Message) error {
+	w.Lock()
+	defer w.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.batches = append(w.batches, msgs)
+	return nil
+}
+
+func (w *mockKafkaWriter) Close() error {
+	return nil
+}
+
+func TestKafkaSinkCollect(t *testing.T) {
+	var tests = []struct {
+		props  map[string]interface{}
+		data   [][]byte
+		result [][]kafka.Message
+		err    string
+	}{
+		{
+			props: map[string]interface{}{},
+			data:  [][]byte{[]byte(`[{"id":1}]`), []byte(`not json`)},
+			result: [][]kafka.Message{
+				{{Value: []byte(`[{"id":1}]`)}},
+				{{Value: []byte(`not json`)}},
+			},
+		}, {
+			props: map[string]interface{}{"key": "{{.id}}", "headers": map[string]interface{}{"type": "{{.type}}", "a": "static"}},
+			data:  [][]byte{[]byte(`{"id":1,"type":"t1"}`)},
+			result: [][]kafka.Message{
+				{{Key: []byte("1"), Value: []byte(`{"id":1,"type":"t1"}`), Headers: []kafka.Header{{Key: "a", Value: []byte("static")}, {Key: "type", Value: []byte("t1")}}}},
+			},
+		}, {
+			props: map[string]interface{}{"key": "{{.id}}"},
+			data:  [][]byte{[]byte(`not json`)},
+			err:   "kafka sink fails to decode not json as json to render the key or headers: invalid character 'o' in literal null (expecting 'u')",
+		}, {
+			// The remaining messages are sent when closing
+			props: map[string]interface{}{"batchSize": 2, "flushInterval": 0},
+			data:  [][]byte{[]byte(`{"id":1}`), []byte(`{"id":2}`), []byte(`{"id":3}`)},
+			result: [][]kafka.Message{
+				{{Value: []byte(`{"id":1}`)}, {Value: []byte(`{"id":2}`)}},
+				{{Value: []byte(`{"id":3}`)}},
+			},
+		},
+	}
+	for i, tt := range tests {
+		w := &mockKafkaWriter{}
+		s := &KafkaSink{newWriter: func() (kafkaWriter, error) { return w, nil }}
+		props := map[string]interface{}{"brokers": "localhost:9092", "topic": "t"}
+		for k, v := range tt.props {
+			props[k] = v
+		}
+		if err := s.Configure(props); err != nil {
+			t.Errorf("%d: configure error %v", i, err)
+			continue
+		}
+		ctx, cancel := context.Background().WithCancel()
+		if err := s.Open(ctx); err != nil {
+			t.Errorf("%d: open error %v", i, err)
+			continue
+		}
+		var err error
+		for _, d := range tt.data {
+			err = s.Collect(ctx, d)
+			if err != nil {
+				break
+			}
+		}
+		cancel()
+		_ = s.Close(ctx)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+		if !reflect.DeepEqual(tt.result, w.batches) {
+			t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.result, w.batches)
+		}
+	}
+}
+
+func TestKafkaSinkWriteError(t *testing.T) {
+	w := &mockKafkaWriter{err: errors.New("broker down")}
+	s := &KafkaSink{newWriter: func() (kafkaWriter, error) { return w, nil }}
+	if err := s.Configure(map[string]interface{}{"brokers": "localhost:9092", "topic": "t"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Collect(ctx, []byte(`{"id":1}`))
+	if !reflect.DeepEqual("kafka sink fails to send out the data: broker down", testx.Errstring(err)) {
+		t.Errorf("error mismatch, got %v", err)
+	}
+	_ = s.Close(ctx)
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/topo/memory"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+type MemorySinkConfig struct {
+	Topic string `json:"topic"`
+}
+
+// MemorySink publishes the rule results to a topic of the in memory bus. Each row of the result is published as a
+// message so that the rules subscribing to the topic by the memory source receive the rows one by one.
+type MemorySink struct {
+	topic string
+}
+
+func (ms *MemorySink) Configure(props map[string]interface{}) error {
+	cfg := &MemorySinkConfig{}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if err := memory.ValidateTopic(cfg.Topic); err != nil {
+		return err
+	}
+	ms.topic = cfg.Topic
+	return nil
+}
+
+func (ms *MemorySink) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Opening memory sink to topic %s", ms.topic)
+	return nil
+}
+
+func (ms *MemorySink) Collect(ctx api.StreamContext, item interface{}) error {
+	logger := ctx.GetLogger()
+	v, ok := item.([]byte)
+	if !ok {
+		return fmt.Errorf("memory sink receive non []byte data: %v", item)
+	}
+	logger.Debugf("memory sink receive %s", v)
+	var data interface{}
+	if err := json.Unmarshal(v, &data); err != nil {
+		return fmt.Errorf("memory sink only supports json object or array of objects, but got %s", v)
+	}
+	var rows []map[string]interface{}
+	switch d := data.(type) {
+	case map[string]interface{}:
+		rows = append(rows, d)
+	case []interface{}:
+		for _, e := range d {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("memory sink only supports json object or array of objects, but got %s", v)
+			}
+			rows = append(rows, m)
+		}
+	default:
+		return fmt.Errorf("memory sink only supports json object or array of objects, but got %s", v)
+	}
+	for _, row := range rows {
+		if err := memory.Publish(ctx, ms.topic, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ms *MemorySink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing memory sink")
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/memory"
+	"reflect"
+	"testing"
+)
+
+func TestMemorySinkConfigure(t *testing.T) {
+	var tests = []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{},
+			err:   "topic must not be empty",
+		}, {
+			props: map[string]interface{}{"topic": "devices/+"},
+			err:   "invalid topic devices/+, wildcards are only allowed in subscriptions",
+		}, {
+			props: map[string]interface{}{"topic": "devices/d1"},
+		},
+	}
+	for i, tt := range tests {
+		s := &MemorySink{}
+		err := s.Configure(tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestMemorySinkCollect(t *testing.T) {
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	sub, err := memory.Subscribe("results/#", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer memory.Unsubscribe(sub)
+	s := &MemorySink{}
+	if err := s.Configure(map[string]interface{}{"topic": "results/r1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		data   interface{}
+		result []map[string]interface{}
+		err    string
+	}{
+		{
+			data:   []byte(`{"a":1}`),
+			result: []map[string]interface{}{{"a": 1.0}},
+		}, {
+			data:   []byte(`[{"a":2},{"a":3}]`),
+			result: []map[string]interface{}{{"a": 2.0}, {"a": 3.0}},
+		}, {
+			data: []byte(`[{"a":4},5]`),
+			err:  "memory sink only supports json object or array of objects, but got [{\"a\":4},5]",
+		}, {
+			data: []byte(`a=5`),
+			err:  "memory sink only supports json object or array of objects, but got a=5",
+		}, {
+			data: "a",
+			err:  "memory sink receive non []byte data: a",
+		},
+	}
+	for i, tt := range tests {
+		err := s.Collect(ctx, tt.data)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+		var result []map[string]interface{}
+		for len(sub.Messages()) > 0 {
+			m := <-sub.Messages()
+			if m.Topic != "results/r1" {
+				t.Errorf("%d: topic mismatch, got %s", i, m.Topic)
+			}
+			result = append(result, m.Data)
+		}
+		if !reflect.DeepEqual(tt.result, result) {
+			t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.result, result)
+		}
+	}
+	_ = s.Close(ctx)
+}
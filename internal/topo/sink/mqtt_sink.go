@@ -15,16 +15,47 @@
 package sink
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
 	MQTT "github.com/eclipse/paho.mqtt.golang"
 	"github.com/google/uuid"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/mqttx"
+	ct "github.com/lf-edge/ekuiper/internal/template"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
+	"sort"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 )
 
+// mqttV5Config is the properties only supported by MQTT 5
+type mqttV5Config struct {
+	// The user properties whose values are templates
+	UserProperties map[string]string `json:"userProperties"`
+	// The template of the response topic for the request/response pattern
+	ResponseTopic string `json:"responseTopic"`
+	// The template of the correlation data for the request/response pattern
+	CorrelationData string `json:"correlationData"`
+	ContentType     string `json:"contentType"`
+	// The message expiry interval in seconds, 0 means never expire
+	MessageExpiry uint32 `json:"messageExpiry"`
+	// Publish with a topic alias if the server allows
+	TopicAlias bool `json:"topicAlias"`
+}
+
+func (c *mqttV5Config) isSet() bool {
+	return len(c.UserProperties) > 0 || c.ResponseTopic != "" || c.CorrelationData != "" || c.ContentType != "" || c.MessageExpiry > 0 || c.TopicAlias
+}
+
 type MQTTSink struct {
 	srv      string
 	tpc      string
@@ -40,6 +71,24 @@ type MQTTSink struct {
 	retained           bool
 
 	conn MQTT.Client
+
+	// The MQTT 5 connection and settings
+	cm            *autopaho.ConnectionManager
+	v5            *mqttV5Config
+	responseTopic *template.Template
+	correlation   *template.Template
+	userProps     map[string]*template.Template
+	// Whether the templates refer to the data
+	dynamic bool
+
+	// The topic alias state of the current connection
+	aliasMu sync.Mutex
+	// Increase when the connection is up or lost
+	connGen int
+	// The server accepts the topic alias
+	aliasAllowed bool
+	// The generation of the connection in which the topic is mapped to the alias, 0 if not mapped
+	aliasGen int
 }
 
 func (ms *MQTTSink) Configure(ps map[string]interface{}) error {
@@ -68,8 +117,10 @@ func (ms *MQTTSink) Configure(ps map[string]interface{}) error {
 			pVersion = 3
 		} else if v == "3.1.1" {
 			pVersion = 4
+		} else if v == "5" {
+			pVersion = 5
 		} else {
-			return fmt.Errorf("unknown protocol version %s, the value could be only 3.1 or 3.1.1 (also refers to MQTT version 4)", pVersionStr)
+			return fmt.Errorf("unknown protocol version %s, the value could be only 3.1, 3.1.1 (also refers to MQTT version 4) or 5", pVersionStr)
 		}
 	}
 
@@ -129,6 +180,17 @@ func (ms *MQTTSink) Configure(ps map[string]interface{}) error {
 		}
 	}
 
+	v5 := &mqttV5Config{}
+	if err := cast.MapToStruct(ps, v5); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", ps, err)
+	}
+	if pVersion != 5 && v5.isSet() {
+		return errors.New("userProperties, responseTopic, correlationData, contentType, messageExpiry and topicAlias can only be set with protocolVersion 5")
+	}
+	if err := ms.parseTemplates(v5); err != nil {
+		return err
+	}
+
 	ms.srv = srv.(string)
 	ms.tpc = tpc.(string)
 	ms.clientid = clientid.(string)
@@ -140,13 +202,44 @@ func (ms *MQTTSink) Configure(ps map[string]interface{}) error {
 	ms.pkeyPath = pKeyPath
 	ms.insecureSkipVerify = insecureSkipVerify
 	ms.retained = retained
+	ms.v5 = v5
+
+	return nil
+}
 
+func (ms *MQTTSink) parseTemplates(v5 *mqttV5Config) error {
+	var err error
+	if v5.ResponseTopic != "" {
+		ms.responseTopic, err = template.New("responseTopic").Funcs(ct.FuncMap).Parse(v5.ResponseTopic)
+		if err != nil {
+			return fmt.Errorf("invalid responseTopic template %s: %v", v5.ResponseTopic, err)
+		}
+		ms.dynamic = ms.dynamic || strings.Contains(v5.ResponseTopic, "{{")
+	}
+	if v5.CorrelationData != "" {
+		ms.correlation, err = template.New("correlationData").Funcs(ct.FuncMap).Parse(v5.CorrelationData)
+		if err != nil {
+			return fmt.Errorf("invalid correlationData template %s: %v", v5.CorrelationData, err)
+		}
+		ms.dynamic = ms.dynamic || strings.Contains(v5.CorrelationData, "{{")
+	}
+	ms.userProps = make(map[string]*template.Template, len(v5.UserProperties))
+	for k, v := range v5.UserProperties {
+		ms.userProps[k], err = template.New(k).Funcs(ct.FuncMap).Parse(v)
+		if err != nil {
+			return fmt.Errorf("invalid template %s of user property %s: %v", v, k, err)
+		}
+		ms.dynamic = ms.dynamic || strings.Contains(v, "{{")
+	}
 	return nil
 }
 
 func (ms *MQTTSink) Open(ctx api.StreamContext) error {
 	log := ctx.GetLogger()
 	log.Infof("Opening mqtt sink for rule %s.", ctx.GetRuleId())
+	if ms.pVersion == 5 {
+		return ms.openV5(ctx)
+	}
 	opts := MQTT.NewClientOptions().AddBroker(ms.srv).SetClientID(ms.clientid)
 
 	if ms.certPath != "" || ms.pkeyPath != "" {
@@ -199,8 +292,130 @@ func (ms *MQTTSink) Open(ctx api.StreamContext) error {
 	return nil
 }
 
+func (ms *MQTTSink) openV5(ctx api.StreamContext) error {
+	log := ctx.GetLogger()
+	tlsCfg, err := mqttx.LoadTLS(ms.certPath, ms.pkeyPath, ms.insecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	cm, err := mqttx.ConnectV5(ctx, &mqttx.V5Config{
+		Server:   ms.srv,
+		ClientId: ms.clientid,
+		Username: ms.uName,
+		Password: ms.password,
+		TLS:      tlsCfg,
+		OnConnectionUp: func(_ *autopaho.ConnectionManager, ca *paho.Connack) {
+			ms.aliasMu.Lock()
+			defer ms.aliasMu.Unlock()
+			ms.connGen++
+			ms.aliasAllowed = ms.v5.TopicAlias && ca.Properties != nil && ca.Properties.TopicAliasMaximum != nil && *ca.Properties.TopicAliasMaximum > 0
+			if ms.v5.TopicAlias && !ms.aliasAllowed {
+				log.Warnf("The server %s does not accept topic alias, publish with the topic", ms.srv)
+			}
+		},
+		// It may be called after the next connection is up, which only disables the alias of that connection
+		OnConnectionLost: func() {
+			ms.aliasMu.Lock()
+			defer ms.aliasMu.Unlock()
+			ms.connGen++
+			ms.aliasAllowed = false
+		},
+	}, log)
+	if err != nil {
+		return err
+	}
+	ms.cm = cm
+	return nil
+}
+
+// toPublish builds the MQTT 5 message. The templates are rendered by the payload decoded as json.
+func (ms *MQTTSink) toPublish(v []byte) (*paho.Publish, error) {
+	p := &paho.Publish{
+		QoS:     ms.qos,
+		Retain:  ms.retained,
+		Topic:   ms.tpc,
+		Payload: v,
+		Properties: &paho.PublishProperties{
+			ContentType: ms.v5.ContentType,
+		},
+	}
+	if ms.v5.MessageExpiry > 0 {
+		e := ms.v5.MessageExpiry
+		p.Properties.MessageExpiry = &e
+	}
+	var data interface{}
+	if ms.dynamic {
+		if err := json.Unmarshal(v, &data); err != nil {
+			return nil, fmt.Errorf("mqtt sink fails to decode %s as json to render the properties: %v", v, err)
+		}
+	}
+	if ms.responseTopic != nil {
+		var b bytes.Buffer
+		if err := ms.responseTopic.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("mqtt sink fails to render the responseTopic: %v", err)
+		}
+		p.Properties.ResponseTopic = b.String()
+	}
+	if ms.correlation != nil {
+		var b bytes.Buffer
+		if err := ms.correlation.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("mqtt sink fails to render the correlationData: %v", err)
+		}
+		p.Properties.CorrelationData = b.Bytes()
+	}
+	names := make([]string, 0, len(ms.userProps))
+	for k := range ms.userProps {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		var b bytes.Buffer
+		if err := ms.userProps[k].Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("mqtt sink fails to render user property %s: %v", k, err)
+		}
+		p.Properties.User.Add(k, b.String())
+	}
+	return p, nil
+}
+
+func (ms *MQTTSink) publishV5(ctx api.StreamContext, item interface{}) error {
+	v, ok := item.([]byte)
+	if !ok {
+		return fmt.Errorf("mqtt sink receive non []byte data: %v", item)
+	}
+	p, err := ms.toPublish(v)
+	if err != nil {
+		return err
+	}
+	// Map the topic to alias 1 by the first message of each connection, then send the alias only. The lock is held
+	// while publishing so that the connection does not change between checking the generation and publishing.
+	ms.aliasMu.Lock()
+	if !ms.aliasAllowed {
+		ms.aliasMu.Unlock()
+		if _, err := ms.cm.Publish(ctx, p); err != nil {
+			return fmt.Errorf("publish error: %s", err)
+		}
+		return nil
+	}
+	defer ms.aliasMu.Unlock()
+	a := uint16(1)
+	p.Properties.TopicAlias = &a
+	if ms.aliasGen == ms.connGen {
+		p.Topic = ""
+	}
+	if _, err := ms.cm.Publish(ctx, p); err != nil {
+		return fmt.Errorf("publish error: %s", err)
+	}
+	ms.aliasGen = ms.connGen
+	return nil
+}
+
 func (ms *MQTTSink) Collect(ctx api.StreamContext, item interface{}) error {
 	logger := ctx.GetLogger()
+	if ms.pVersion == 5 {
+		logger.Debugf("%s publish %s", ctx.GetOpId(), item)
+		return ms.publishV5(ctx, item)
+	}
 	c := ms.conn
 	logger.Debugf("%s publish %s", ctx.GetOpId(), item)
 	if token := c.Publish(ms.tpc, ms.qos, ms.retained, item); token.Wait() && token.Error() != nil {
@@ -215,5 +430,10 @@ func (ms *MQTTSink) Close(ctx api.StreamContext) error {
 	if ms.conn != nil && ms.conn.IsConnected() {
 		ms.conn.Disconnect(5000)
 	}
+	if ms.cm != nil {
+		dctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = ms.cm.Disconnect(dctx)
+	}
 	return nil
 }
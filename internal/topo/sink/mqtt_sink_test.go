@@ -0,0 +1,198 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/lf-edge/ekuiper/internal/pkg/mqttx/mqttxtest"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMQTTSinkConfigure(t *testing.T) {
+	var tests = []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{"server": "tcp://127.0.0.1:1883", "topic": "a", "protocolVersion": "4"},
+			err:   "unknown protocol version 4, the value could be only 3.1, 3.1.1 (also refers to MQTT version 4) or 5",
+		}, {
+			props: map[string]interface{}{"server": "tcp://127.0.0.1:1883", "topic": "a", "protocolVersion": "3.1.1", "responseTopic": "b"},
+			err:   "userProperties, responseTopic, correlationData, contentType, messageExpiry and topicAlias can only be set with protocolVersion 5",
+		}, {
+			props: map[string]interface{}{"server": "tcp://127.0.0.1:1883", "topic": "a", "topicAlias": true},
+			err:   "userProperties, responseTopic, correlationData, contentType, messageExpiry and topicAlias can only be set with protocolVersion 5",
+		}, {
+			props: map[string]interface{}{"server": "tcp://127.0.0.1:1883", "topic": "a", "protocolVersion": "5", "correlationData": "{{.id"},
+			err:   "invalid correlationData template {{.id: template: correlationData:1: unclosed action",
+		}, {
+			// The default values of the UI are not set
+			props: map[string]interface{}{"server": "tcp://127.0.0.1:1883", "topic": "a", "userProperties": map[string]interface{}{}, "messageExpiry": 0, "topicAlias": false},
+		}, {
+			props: map[string]interface{}{"server": "tcp://127.0.0.1:1883", "topic": "a", "protocolVersion": "5", "messageExpiry": -1},
+			err:   "read properties map[messageExpiry:-1 protocolVersion:5 server:tcp://127.0.0.1:1883 topic:a] fail with error: json: cannot unmarshal number -1 into Go struct field mqttV5Config.messageExpiry of type uint32",
+		}, {
+			props: map[string]interface{}{
+				"server":          "tcp://127.0.0.1:1883",
+				"topic":           "a",
+				"protocolVersion": "5",
+				"userProperties":  map[string]interface{}{"device": "{{.id}}"},
+				"responseTopic":   "reply/a",
+				"messageExpiry":   60,
+				"topicAlias":      true,
+			},
+		},
+	}
+	for i, tt := range tests {
+		s := &MQTTSink{}
+		err := s.Configure(tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestMQTTSinkV5(t *testing.T) {
+	b, err := mqttxtest.NewBroker(t, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	expiry := uint32(60)
+	var tests = []struct {
+		props map[string]interface{}
+		data  []byte
+		err   string
+		exp   *packets.Publish
+	}{
+		{
+			props: map[string]interface{}{"qos": 1},
+			data:  []byte(`{"id":"a"}`),
+			exp:   &packets.Publish{Topic: "result", QoS: 1, Payload: []byte(`{"id":"a"}`), Properties: &packets.Properties{}},
+		}, {
+			props: map[string]interface{}{
+				"userProperties":  map[string]interface{}{"device": "{{.id}}", "source": "ekuiper"},
+				"responseTopic":   "reply/{{.id}}",
+				"correlationData": "{{.seq}}",
+				"contentType":     "application/json",
+				"messageExpiry":   60,
+				"retained":        true,
+			},
+			data: []byte(`{"id":"b","seq":3}`),
+			exp: &packets.Publish{Topic: "result", Retain: true, Payload: []byte(`{"id":"b","seq":3}`), Properties: &packets.Properties{
+				ContentType:     "application/json",
+				ResponseTopic:   "reply/b",
+				CorrelationData: []byte("3"),
+				MessageExpiry:   &expiry,
+				User:            []packets.User{{Key: "device", Value: "b"}, {Key: "source", Value: "ekuiper"}},
+			}},
+		}, {
+			// Static properties do not require json
+			props: map[string]interface{}{"responseTopic": "reply"},
+			data:  []byte(`plain`),
+			exp:   &packets.Publish{Topic: "result", Payload: []byte(`plain`), Properties: &packets.Properties{ResponseTopic: "reply"}},
+		}, {
+			props: map[string]interface{}{"correlationData": "{{.seq}}"},
+			data:  []byte(`plain`),
+			err:   "mqtt sink fails to decode plain as json to render the properties: invalid character 'p' looking for beginning of value",
+		},
+	}
+	for i, tt := range tests {
+		s := &MQTTSink{}
+		props := map[string]interface{}{"server": b.URL(), "topic": "result", "protocolVersion": "5"}
+		for k, v := range tt.props {
+			props[k] = v
+		}
+		if err := s.Configure(props); err != nil {
+			t.Errorf("%d: configure error %v", i, err)
+			continue
+		}
+		ctx := context.Background()
+		if err := s.Open(ctx); err != nil {
+			t.Errorf("%d: open error %v", i, err)
+			continue
+		}
+		err := s.Collect(ctx, tt.data)
+		_ = s.Close(ctx)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+		if tt.exp == nil {
+			continue
+		}
+		select {
+		case p := <-b.Received:
+			p.PacketID = 0
+			if !reflect.DeepEqual(tt.exp, p) {
+				t.Errorf("%d: message mismatch:\n  exp=%+v\n  got=%+v\n\n", i, tt.exp, p)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%d: no message received", i)
+		}
+	}
+}
+
+func TestMQTTSinkTopicAlias(t *testing.T) {
+	var tests = []struct {
+		aliasMax  uint16
+		aliasOnly int
+	}{
+		{aliasMax: 10, aliasOnly: 2},
+		// The server does not accept alias
+		{aliasMax: 0, aliasOnly: 0},
+	}
+	for i, tt := range tests {
+		b, err := mqttxtest.NewBroker(t, tt.aliasMax)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &MQTTSink{}
+		if err := s.Configure(map[string]interface{}{"server": b.URL(), "topic": "devices/result", "protocolVersion": "5", "qos": 1, "topicAlias": true}); err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.Background()
+		if err := s.Open(ctx); err != nil {
+			t.Fatal(err)
+		}
+		// Wait for the connack to be handled
+		for j := 0; j < 100; j++ {
+			s.aliasMu.Lock()
+			done := s.aliasAllowed || tt.aliasMax == 0
+			s.aliasMu.Unlock()
+			if done {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		for j := 0; j < 3; j++ {
+			if err := s.Collect(ctx, []byte(`{"id":1}`)); err != nil {
+				t.Errorf("%d: collect error %v", i, err)
+			}
+			p := <-b.Received
+			if p.Topic != "devices/result" {
+				t.Errorf("%d: topic mismatch, got %s", i, p.Topic)
+			}
+		}
+		_ = s.Close(ctx)
+		if got := b.AliasOnly(); got != tt.aliasOnly {
+			t.Errorf("%d: alias only messages mismatch, exp %d got %d", i, tt.aliasOnly, got)
+		}
+		b.Close()
+	}
+}
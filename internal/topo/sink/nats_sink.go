@@ -0,0 +1,135 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/pkg/natsx"
+	ct "github.com/lf-edge/ekuiper/internal/template"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/nats-io/nats.go"
+	"strings"
+	"text/template"
+)
+
+type NatsSinkConfig struct {
+	natsx.ConnConfig
+	// The subject to publish to, which can be a template
+	Subject string `json:"subject"`
+	// Whether to publish by jetstream and wait for the acknowledgement of the stream
+	JetStream bool `json:"jetStream"`
+}
+
+// NatsSink publishes each result to a subject. The subject can be rendered by a template from the result.
+type NatsSink struct {
+	config  *NatsSinkConfig
+	subject *template.Template
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+}
+
+func (ns *NatsSink) Configure(props map[string]interface{}) error {
+	cfg := &NatsSinkConfig{}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Subject == "" {
+		return errors.New("missing property subject")
+	}
+	if strings.Contains(cfg.Subject, "{{") {
+		ns.subject, err = template.New("subject").Funcs(ct.FuncMap).Option("missingkey=error").Parse(cfg.Subject)
+		if err != nil {
+			return fmt.Errorf("invalid subject template %s: %v", cfg.Subject, err)
+		}
+	}
+	ns.config = cfg
+	return nil
+}
+
+func (ns *NatsSink) Open(ctx api.StreamContext) error {
+	logger := ctx.GetLogger()
+	logger.Infof("Opening nats sink to %s", ns.config.Server)
+	conn, err := ns.config.Connect(fmt.Sprintf("ekuiper-%s-%s", ctx.GetRuleId(), ctx.GetOpId()))
+	if err != nil {
+		return fmt.Errorf("nats sink fails to connect to %s: %v", ns.config.Server, err)
+	}
+	ns.conn = conn
+	if ns.config.JetStream {
+		ns.js, err = conn.JetStream()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ns *NatsSink) Collect(ctx api.StreamContext, item interface{}) error {
+	logger := ctx.GetLogger()
+	v, ok := item.([]byte)
+	if !ok {
+		return fmt.Errorf("nats sink receive non []byte data: %v", item)
+	}
+	logger.Debugf("nats sink receive %s", v)
+	subject, err := ns.render(v)
+	if err != nil {
+		return err
+	}
+	if ns.js != nil {
+		// Wait for the acknowledgement of the stream within the default timeout
+		_, err = ns.js.Publish(subject, v)
+	} else {
+		err = ns.conn.Publish(subject, v)
+	}
+	if err != nil {
+		return fmt.Errorf("nats sink fails to publish to %s: %v", subject, err)
+	}
+	return nil
+}
+
+// render renders the subject by the payload decoded as json
+func (ns *NatsSink) render(v []byte) (string, error) {
+	if ns.subject == nil {
+		return ns.config.Subject, nil
+	}
+	var data interface{}
+	if err := json.Unmarshal(v, &data); err != nil {
+		return "", fmt.Errorf("nats sink fails to decode %s as json to render the subject: %v", v, err)
+	}
+	var b bytes.Buffer
+	if err := ns.subject.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("nats sink fails to render the subject: %v", err)
+	}
+	if b.Len() == 0 {
+		return "", errors.New("nats sink renders an empty subject")
+	}
+	return b.String(), nil
+}
+
+func (ns *NatsSink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing nats sink")
+	if ns.conn != nil {
+		// Flush the published messages before closing
+		return ns.conn.Drain()
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"github.com/lf-edge/ekuiper/internal/pkg/natsx/natsxtest"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/nats-io/nats.go"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNatsSinkConfigure(t *testing.T) {
+	var tests = []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{"subject": "a"},
+			err:   "missing property server",
+		}, {
+			props: map[string]interface{}{"server": "nats://127.0.0.1:4222"},
+			err:   "missing property subject",
+		}, {
+			props: map[string]interface{}{"server": "nats://127.0.0.1:4222", "subject": "a", "password": "p"},
+			err:   "userName and password must be set together",
+		}, {
+			props: map[string]interface{}{"server": "nats://127.0.0.1:4222", "subject": "devices.{{.id"},
+			err:   "invalid subject template devices.{{.id: template: subject:1: unclosed action",
+		}, {
+			props: map[string]interface{}{"server": "nats://127.0.0.1:4222", "subject": "devices.{{.id}}", "jetStream": true},
+		},
+	}
+	for i, tt := range tests {
+		s := &NatsSink{}
+		err := s.Configure(tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestNatsSinkCollect(t *testing.T) {
+	srv := natsxtest.RunServer(t)
+	defer srv.Shutdown()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "ALERTS", Subjects: []string{"alerts.>"}}); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		props    map[string]interface{}
+		data     []byte
+		subject  string
+		err      string
+		received bool
+	}{
+		{
+			props:    map[string]interface{}{"subject": "devices.a"},
+			data:     []byte(`[{"id":"a"}]`),
+			subject:  "devices.a",
+			received: true,
+		}, {
+			props:    map[string]interface{}{"subject": "devices.{{.id}}"},
+			data:     []byte(`{"id":"b"}`),
+			subject:  "devices.b",
+			received: true,
+		}, {
+			props: map[string]interface{}{"subject": "devices.{{.id}}"},
+			data:  []byte(`invalid`),
+			err:   "nats sink fails to decode invalid as json to render the subject: invalid character 'i' looking for beginning of value",
+		}, {
+			props: map[string]interface{}{"subject": "{{.id}}"},
+			data:  []byte(`{"name":"c"}`),
+			err:   "nats sink fails to render the subject: template: subject:1:2: executing \"subject\" at <.id>: map has no entry for key \"id\"",
+		}, {
+			props:    map[string]interface{}{"subject": "alerts.{{.id}}", "jetStream": true},
+			data:     []byte(`{"id":"d"}`),
+			subject:  "alerts.d",
+			received: true,
+		}, {
+			// No stream to acknowledge
+			props: map[string]interface{}{"subject": "devices.e", "jetStream": true},
+			data:  []byte(`{"id":"e"}`),
+			err:   "nats sink fails to publish to devices.e: nats: no response from stream",
+		},
+	}
+	for i, tt := range tests {
+		var sub *nats.Subscription
+		if tt.received {
+			sub, err = nc.SubscribeSync(tt.subject)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = nc.Flush()
+		}
+		s := &NatsSink{}
+		props := map[string]interface{}{"server": srv.ClientURL()}
+		for k, v := range tt.props {
+			props[k] = v
+		}
+		if err := s.Configure(props); err != nil {
+			t.Errorf("%d: configure error %v", i, err)
+			continue
+		}
+		ctx := context.Background()
+		if err := s.Open(ctx); err != nil {
+			t.Errorf("%d: open error %v", i, err)
+			continue
+		}
+		err = s.Collect(ctx, tt.data)
+		_ = s.Close(ctx)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+		if tt.received {
+			msg, err := sub.NextMsg(time.Second)
+			if err != nil {
+				t.Errorf("%d: no message received", i)
+			} else if msg.Subject != tt.subject || !reflect.DeepEqual(tt.data, msg.Data) {
+				t.Errorf("%d: message mismatch, got %s on %s", i, msg.Data, msg.Subject)
+			}
+			_ = sub.Unsubscribe()
+		}
+	}
+	info, err := js.StreamInfo("ALERTS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("stream messages mismatch, got %d", info.State.Msgs)
+	}
+}
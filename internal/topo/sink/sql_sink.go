@@ -0,0 +1,219 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/pkg/sqlx"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const (
+	sqlModeInsert = "insert"
+	sqlModeUpsert = "upsert"
+)
+
+type SQLSinkConfig struct {
+	// The url of the database such as sqlite:///var/data/ekuiper.db
+	Url   string `json:"url"`
+	Table string `json:"table"`
+	// The fields of the result to write, all the fields are written if not set
+	Fields []string `json:"fields"`
+	// The column names of the fields if they are different
+	ColumnMapping map[string]string `json:"columnMapping"`
+	// insert or upsert
+	Mode string `json:"mode"`
+	// The key columns to detect the conflict in upsert mode
+	Keys []string `json:"keys"`
+	// The number of the rows to write in one transaction
+	BatchSize int `json:"batchSize"`
+	// The interval to write the incomplete batch, time unit is ms
+	FlushInterval int `json:"flushInterval"`
+}
+
+// SQLSink writes each row of the results into a table of the database. The rows can be written in batches and
+// updated if the key columns conflict in upsert mode.
+type SQLSink struct {
+	config  *SQLSinkConfig
+	db      *sql.DB
+	dialect *sqlx.Dialect
+	batch   *batchWriter
+}
+
+type sqlRow struct {
+	cols   []string
+	values []interface{}
+}
+
+func (s *SQLSink) Configure(props map[string]interface{}) error {
+	cfg := &SQLSinkConfig{
+		Mode:          sqlModeInsert,
+		BatchSize:     1,
+		FlushInterval: 1000,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if cfg.Url == "" {
+		return errors.New("missing property url")
+	}
+	if _, _, err := sqlx.ParseUrl(cfg.Url); err != nil {
+		return err
+	}
+	if cfg.Table == "" {
+		return errors.New("missing property table")
+	}
+	switch cfg.Mode {
+	case sqlModeInsert:
+		if len(cfg.Keys) > 0 {
+			return errors.New("keys can only be set in upsert mode")
+		}
+	case sqlModeUpsert:
+		if len(cfg.Keys) == 0 {
+			return errors.New("missing property keys for upsert mode")
+		}
+	default:
+		return fmt.Errorf("invalid mode %s, must be insert or upsert", cfg.Mode)
+	}
+	if cfg.BatchSize <= 0 {
+		return fmt.Errorf("invalid batchSize %d, must be positive", cfg.BatchSize)
+	}
+	if cfg.FlushInterval < 0 {
+		return fmt.Errorf("invalid flushInterval %d, must not be negative", cfg.FlushInterval)
+	}
+	s.config = cfg
+	s.batch = newBatchWriter("Sql sink", cfg.BatchSize, cfg.FlushInterval, s.write)
+	return nil
+}
+
+func (s *SQLSink) Open(ctx api.StreamContext) error {
+	logger := ctx.GetLogger()
+	logger.Infof("Opening sql sink to table %s", s.config.Table)
+	db, d, err := sqlx.Open(s.config.Url)
+	if err != nil {
+		return err
+	}
+	s.db = db
+	s.dialect = d
+	s.batch.start(ctx)
+	return nil
+}
+
+func (s *SQLSink) Collect(ctx api.StreamContext, item interface{}) error {
+	logger := ctx.GetLogger()
+	v, ok := item.([]byte)
+	if !ok {
+		return fmt.Errorf("sql sink receive non []byte data: %v", item)
+	}
+	logger.Debugf("sql sink receive %s", v)
+	var data interface{}
+	if err := json.Unmarshal(v, &data); err != nil {
+		return fmt.Errorf("sql sink only supports json object or array of objects, but got %s", v)
+	}
+	var rows []map[string]interface{}
+	switch d := data.(type) {
+	case map[string]interface{}:
+		rows = append(rows, d)
+	case []interface{}:
+		for _, e := range d {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("sql sink only supports json object or array of objects, but got %s", v)
+			}
+			rows = append(rows, m)
+		}
+	default:
+		return fmt.Errorf("sql sink only supports json object or array of objects, but got %s", v)
+	}
+	items := make([]interface{}, len(rows))
+	for i, m := range rows {
+		r, err := s.toRow(m)
+		if err != nil {
+			return err
+		}
+		items[i] = r
+	}
+	return s.batch.add(ctx, logger, items...)
+}
+
+// toRow maps the fields of the result to the columns
+func (s *SQLSink) toRow(m map[string]interface{}) (*sqlRow, error) {
+	fields := s.config.Fields
+	if len(fields) == 0 {
+		fields = sqlx.SortedKeys(m)
+	}
+	r := &sqlRow{
+		cols:   make([]string, len(fields)),
+		values: make([]interface{}, len(fields)),
+	}
+	for i, f := range fields {
+		if c, ok := s.config.ColumnMapping[f]; ok {
+			r.cols[i] = c
+		} else {
+			r.cols[i] = f
+		}
+		v, err := sqlx.ToColumnValue(m[f])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of field %s: %v", f, err)
+		}
+		r.values[i] = v
+	}
+	return r, nil
+}
+
+// write writes the rows in a transaction
+func (s *SQLSink) write(ctx context.Context, rows []interface{}) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmts := make(map[string]*sql.Stmt)
+	for _, item := range rows {
+		r := item.(*sqlRow)
+		query := s.dialect.Insert(s.config.Table, r.cols, s.config.Keys)
+		stmt, ok := stmts[query]
+		if !ok {
+			stmt, err = tx.PrepareContext(ctx, query)
+			if err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("fail to prepare %s: %v", query, err)
+			}
+			stmts[query] = stmt
+		}
+		if _, err := stmt.ExecContext(ctx, r.values...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("fail to execute %s: %v", query, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLSink) Close(ctx api.StreamContext) error {
+	logger := ctx.GetLogger()
+	logger.Infof("Closing sql sink")
+	if s.db == nil {
+		return nil
+	}
+	if err := s.batch.close(logger); err != nil {
+		logger.Warnf("Sql sink fails to write the remaining rows: %v", err)
+	}
+	return s.db.Close()
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"database/sql"
+	"github.com/lf-edge/ekuiper/internal/pkg/sqlx"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSQLSinkConfigure(t *testing.T) {
+	var tests = []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{"table": "t"},
+			err:   "missing property url",
+		}, {
+			props: map[string]interface{}{"url": "sqlite://test.db"},
+			err:   "missing property table",
+		}, {
+			props: map[string]interface{}{"url": "sqlite://test.db", "table": "t", "mode": "update"},
+			err:   "invalid mode update, must be insert or upsert",
+		}, {
+			props: map[string]interface{}{"url": "sqlite://test.db", "table": "t", "mode": "upsert"},
+			err:   "missing property keys for upsert mode",
+		}, {
+			props: map[string]interface{}{"url": "sqlite://test.db", "table": "t", "keys": []interface{}{"id"}},
+			err:   "keys can only be set in upsert mode",
+		}, {
+			props: map[string]interface{}{"url": "sqlite://test.db", "table": "t", "batchSize": 0},
+			err:   "invalid batchSize 0, must be positive",
+		}, {
+			props: map[string]interface{}{"url": "sqlite://test.db", "table": "t", "flushInterval": -1},
+			err:   "invalid flushInterval -1, must not be negative",
+		}, {
+			props: map[string]interface{}{"url": "sqlite://test.db", "table": "t", "mode": "upsert", "keys": []interface{}{"id"}, "columnMapping": map[string]interface{}{"temp": "temperature"}},
+		},
+	}
+	for i, tt := range tests {
+		s := &SQLSink{}
+		err := s.Configure(tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestSQLSinkCollect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE readings (id INTEGER PRIMARY KEY, temperature REAL, tags TEXT);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		props  map[string]interface{}
+		data   [][]byte
+		result []map[string]interface{}
+		err    string
+	}{
+		{
+			props: map[string]interface{}{"columnMapping": map[string]interface{}{"temp": "temperature"}, "fields": []interface{}{"id", "temp"}},
+			data:  [][]byte{[]byte(`{"id":1,"temp":20.5,"other":1}`), []byte(`[{"id":2,"temp":21.5},{"id":3}]`)},
+			result: []map[string]interface{}{
+				{"id": int64(1), "temperature": 20.5, "tags": nil},
+				{"id": int64(2), "temperature": 21.5, "tags": nil},
+				{"id": int64(3), "temperature": nil, "tags": nil},
+			},
+		}, {
+			props: map[string]interface{}{},
+			data:  [][]byte{[]byte(`{"id":1,"temperature":22.5}`)},
+			err:   "fail to execute INSERT INTO \"readings\" (\"id\", \"temperature\") VALUES (?, ?): UNIQUE constraint failed: readings.id",
+		}, {
+			props: map[string]interface{}{"mode": "upsert", "keys": []interface{}{"id"}},
+			data:  [][]byte{[]byte(`{"id":1,"temperature":22.5,"tags":{"a":1}}`), []byte(`{"id":4,"temperature":23.5,"tags":["a","b"]}`)},
+			result: []map[string]interface{}{
+				{"id": int64(1), "temperature": 22.5, "tags": `{"a":1}`},
+				{"id": int64(2), "temperature": 21.5, "tags": nil},
+				{"id": int64(3), "temperature": nil, "tags": nil},
+				{"id": int64(4), "temperature": 23.5, "tags": `["a","b"]`},
+			},
+		}, {
+			props: map[string]interface{}{},
+			data:  [][]byte{[]byte(`[1,2]`)},
+			err:   "sql sink only supports json object or array of objects, but got [1,2]",
+		},
+	}
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	for i, tt := range tests {
+		tt.props["url"] = "sqlite://" + file
+		tt.props["table"] = "readings"
+		s := &SQLSink{}
+		if err := s.Configure(tt.props); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Open(ctx); err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range tt.data {
+			err = s.Collect(ctx, d)
+			if err != nil {
+				break
+			}
+		}
+		_ = s.Close(ctx)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+			continue
+		}
+		if tt.result != nil {
+			if r := queryAll(t, db); !reflect.DeepEqual(tt.result, r) {
+				t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.result, r)
+			}
+		}
+	}
+}
+
+func TestSQLSinkBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE readings (id INTEGER PRIMARY KEY, temperature REAL, tags TEXT);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &SQLSink{}
+	if err := s.Configure(map[string]interface{}{"url": "sqlite://" + file, "table": "readings", "batchSize": 3, "flushInterval": 100}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	if err := s.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close(ctx)
+	for _, d := range []string{`{"id":1}`, `[{"id":2},{"id":3},{"id":4}]`} {
+		if err := s.Collect(ctx, []byte(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The batch is written once full
+	if r := queryAll(t, db); len(r) != 4 {
+		t.Errorf("expect 4 rows after the batch is full, but got %v", r)
+	}
+	if err := s.Collect(ctx, []byte(`{"id":5}`)); err != nil {
+		t.Fatal(err)
+	}
+	if r := queryAll(t, db); len(r) != 4 {
+		t.Errorf("expect 4 rows before flush, but got %v", r)
+	}
+	// The incomplete batch is written after the flush interval
+	time.Sleep(300 * time.Millisecond)
+	if r := queryAll(t, db); len(r) != 5 {
+		t.Errorf("expect 5 rows after flush, but got %v", r)
+	}
+}
+
+func queryAll(t *testing.T, db *sql.DB) []map[string]interface{} {
+	rows, err := db.Query(`SELECT * FROM readings ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	r, err := sqlx.ScanRows(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
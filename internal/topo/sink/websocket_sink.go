@@ -0,0 +1,310 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	websocketModeClient = "client"
+	websocketModeServer = "server"
+)
+
+type WebsocketSinkConfig struct {
+	// server mode to broadcast to the connected clients, or client mode to send to a websocket server
+	Mode string `json:"mode"`
+	// The address, path and tls settings to serve in server mode
+	Address       string `json:"address"`
+	Path          string `json:"path"`
+	Certification string `json:"certificationPath"`
+	PrivateKPath  string `json:"privateKeyPath"`
+	// The origins allowed to connect in server mode, * to allow any origin. If not set, only the same origin is allowed
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// The authentication of the handshake requests in server mode
+	httpx.Auth
+	// The url of the server to connect to in client mode
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	// The timeout of writing a message, time unit is ms
+	WriteTimeout int `json:"writeTimeout"`
+}
+
+// WebsocketSink sends the rule results as websocket text messages. In server mode, the sinks of the same path share
+// one endpoint, such as the instances of a sink with concurrency or the rules sending to the same path, and each
+// result is broadcast to all the connected clients of the path. In client mode, the results are sent to the server and
+// the connection is re-established for the next result if it is lost.
+type WebsocketSink struct {
+	config *WebsocketSinkConfig
+	opened bool
+	logger api.Logger
+
+	// The endpoint of the path in server mode
+	endpoint *wsEndpoint
+	// The only connection in client mode
+	client wsConns
+}
+
+// wsConns is a set of connections with the lock to serialize the writes of each connection which may be sent
+// concurrently when the sink runs async or by the sinks sharing an endpoint
+type wsConns struct {
+	sync.Mutex
+	conns map[*websocket.Conn]*sync.Mutex
+}
+
+// wsEndpoint serves a path of an address in server mode and keeps the connected clients
+type wsEndpoint struct {
+	wsConns
+	address        string
+	path           string
+	allowedOrigins []string
+	auth           httpx.Auth
+	upgrader       websocket.Upgrader
+	logger         api.Logger
+	// The number of the opened sinks of the endpoint, guarded by wsEndpointMu
+	refCount int
+}
+
+var (
+	wsEndpoints  = make(map[string]*wsEndpoint)
+	wsEndpointMu sync.Mutex
+)
+
+func (ws *WebsocketSink) Configure(props map[string]interface{}) error {
+	cfg := &WebsocketSinkConfig{
+		Mode:         websocketModeServer,
+		Address:      ":10083",
+		Path:         "/",
+		WriteTimeout: 5000,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	switch cfg.Mode {
+	case websocketModeServer:
+		if (cfg.Certification == "") != (cfg.PrivateKPath == "") {
+			return errors.New("certificationPath and privateKeyPath must be set together")
+		}
+		if !strings.HasPrefix(cfg.Path, "/") {
+			cfg.Path = "/" + cfg.Path
+		}
+		if err := cfg.Auth.Validate(); err != nil {
+			return err
+		}
+	case websocketModeClient:
+		if !strings.HasPrefix(cfg.Url, "ws://") && !strings.HasPrefix(cfg.Url, "wss://") {
+			return fmt.Errorf("invalid url %s, must start with ws:// or wss://", cfg.Url)
+		}
+	default:
+		return fmt.Errorf("invalid mode %s, must be client or server", cfg.Mode)
+	}
+	if cfg.WriteTimeout <= 0 {
+		return fmt.Errorf("invalid writeTimeout %d, must be positive", cfg.WriteTimeout)
+	}
+	ws.config = cfg
+	ws.client.conns = make(map[*websocket.Conn]*sync.Mutex)
+	return nil
+}
+
+func (ws *WebsocketSink) Open(ctx api.StreamContext) error {
+	ws.logger = ctx.GetLogger()
+	if ws.config.Mode == websocketModeClient {
+		ws.logger.Infof("Opening websocket sink to %s", ws.config.Url)
+		// Connect lazily in Collect so that the rule can start before the server
+		return nil
+	}
+	if err := ws.subscribe(); err != nil {
+		return err
+	}
+	ws.opened = true
+	ws.logger.Infof("Websocket sink is listening to %s%s", ws.config.Address, ws.config.Path)
+	return nil
+}
+
+// subscribe uses the endpoint of the path. The endpoint is created and registered to the server by the first sink, and
+// the later sinks must have the same allowed origins and authentication.
+func (ws *WebsocketSink) subscribe() error {
+	wsEndpointMu.Lock()
+	defer wsEndpointMu.Unlock()
+	key := ws.config.Address + ws.config.Path
+	if e, ok := wsEndpoints[key]; ok {
+		if !reflect.DeepEqual(e.allowedOrigins, ws.config.AllowedOrigins) {
+			return fmt.Errorf("websocket path %s of %s is already used with different allowedOrigins", ws.config.Path, ws.config.Address)
+		}
+		if e.auth != ws.config.Auth {
+			return fmt.Errorf("websocket path %s of %s is already used with different authentication", ws.config.Path, ws.config.Address)
+		}
+		e.refCount++
+		ws.endpoint = e
+		return nil
+	}
+	e := &wsEndpoint{
+		wsConns:        wsConns{conns: make(map[*websocket.Conn]*sync.Mutex)},
+		address:        ws.config.Address,
+		path:           ws.config.Path,
+		allowedOrigins: ws.config.AllowedOrigins,
+		auth:           ws.config.Auth,
+		logger:         ws.logger,
+		refCount:       1,
+	}
+	if len(e.allowedOrigins) > 0 {
+		e.upgrader.CheckOrigin = httpx.CheckOrigin(e.allowedOrigins)
+	}
+	if err := httpx.RegisterHandler(ws.logger, e.address, ws.config.Certification, ws.config.PrivateKPath, e.path, e); err != nil {
+		return err
+	}
+	wsEndpoints[key] = e
+	ws.endpoint = e
+	return nil
+}
+
+// unsubscribe releases the endpoint, and unregisters it and closes its connections once no sink uses it
+func (ws *WebsocketSink) unsubscribe() {
+	wsEndpointMu.Lock()
+	defer wsEndpointMu.Unlock()
+	e := ws.endpoint
+	ws.endpoint = nil
+	e.refCount--
+	if e.refCount == 0 {
+		httpx.UnregisterHandler(e.address, e.path)
+		e.closeAll()
+		delete(wsEndpoints, e.address+e.path)
+	}
+}
+
+func (e *wsEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !e.auth.Authorized(r) {
+		e.auth.Unauthorized(w)
+		return
+	}
+	conn, err := e.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		e.logger.Warnf("Websocket sink fails to upgrade the connection from %s: %v", r.RemoteAddr, err)
+		return
+	}
+	e.logger.Infof("Websocket sink accepts the connection from %s", r.RemoteAddr)
+	e.Lock()
+	e.conns[conn] = &sync.Mutex{}
+	e.Unlock()
+	// Read until the client closes the connection. The messages from the clients are ignored.
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			e.remove(conn)
+			return
+		}
+	}
+}
+
+func (ws *WebsocketSink) Collect(ctx api.StreamContext, item interface{}) error {
+	logger := ctx.GetLogger()
+	v, ok := item.([]byte)
+	if !ok {
+		return fmt.Errorf("websocket sink receive non []byte data: %v", item)
+	}
+	logger.Debugf("websocket sink receive %s", item)
+	timeout := time.Duration(ws.config.WriteTimeout) * time.Millisecond
+	if ws.config.Mode == websocketModeServer {
+		// The slow or disconnected clients are dropped without failing the rule
+		_ = ws.endpoint.write(logger, v, timeout)
+		return nil
+	}
+	if err := ws.dial(ctx); err != nil {
+		return err
+	}
+	if err := ws.client.write(logger, v, timeout); err != nil {
+		return fmt.Errorf("websocket sink fails to send out the data: %v", err)
+	}
+	return nil
+}
+
+// write sends the data to all the connections and returns the last error. The failed connections are removed.
+func (c *wsConns) write(logger api.Logger, data []byte, timeout time.Duration) error {
+	c.Lock()
+	conns := make(map[*websocket.Conn]*sync.Mutex, len(c.conns))
+	for conn, l := range c.conns {
+		conns[conn] = l
+	}
+	c.Unlock()
+	var lastErr error
+	for conn, l := range conns {
+		l.Lock()
+		_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+		err := conn.WriteMessage(websocket.TextMessage, data)
+		l.Unlock()
+		if err != nil {
+			logger.Warnf("Websocket sink fails to send to %s: %v", conn.RemoteAddr(), err)
+			c.remove(conn)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// dial connects to the server in client mode if not connected
+func (ws *WebsocketSink) dial(ctx api.StreamContext) error {
+	ws.client.Lock()
+	defer ws.client.Unlock()
+	if len(ws.client.conns) > 0 {
+		return nil
+	}
+	header := http.Header{}
+	for k, v := range ws.config.Headers {
+		header.Set(k, v)
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, ws.config.Url, header)
+	if err != nil {
+		return fmt.Errorf("websocket sink fails to connect to %s: %v", ws.config.Url, err)
+	}
+	ctx.GetLogger().Infof("Websocket sink connected to %s", ws.config.Url)
+	ws.client.conns[conn] = &sync.Mutex{}
+	return nil
+}
+
+func (c *wsConns) remove(conn *websocket.Conn) {
+	c.Lock()
+	delete(c.conns, conn)
+	c.Unlock()
+	conn.Close()
+}
+
+func (c *wsConns) closeAll() {
+	c.Lock()
+	defer c.Unlock()
+	for conn := range c.conns {
+		conn.Close()
+		delete(c.conns, conn)
+	}
+}
+
+func (ws *WebsocketSink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing websocket sink")
+	if ws.opened {
+		ws.unsubscribe()
+		ws.opened = false
+	}
+	ws.client.closeAll()
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebsocketSinkConfigure(t *testing.T) {
+	var tests = []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{"mode": "proxy"},
+			err:   "invalid mode proxy, must be client or server",
+		}, {
+			props: map[string]interface{}{"mode": "client", "url": "localhost:8080"},
+			err:   "invalid url localhost:8080, must start with ws:// or wss://",
+		}, {
+			props: map[string]interface{}{"certificationPath": "cert.pem"},
+			err:   "certificationPath and privateKeyPath must be set together",
+		}, {
+			props: map[string]interface{}{"writeTimeout": 0},
+			err:   "invalid writeTimeout 0, must be positive",
+		}, {
+			props: map[string]interface{}{"username": "user", "token": "abc"},
+			err:   "only one of basic auth and bearer token can be set",
+		}, {
+			props: map[string]interface{}{"path": "results"},
+		},
+	}
+	for i, tt := range tests {
+		s := &WebsocketSink{}
+		err := s.Configure(tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestWebsocketSinkServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	s := &WebsocketSink{}
+	if err := s.Configure(map[string]interface{}{"address": address, "path": "results", "allowedOrigins": []interface{}{"http://dashboard.example"}, "token": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close(ctx)
+	// Another instance of the sink shares the path
+	shared := &WebsocketSink{}
+	if err := shared.Configure(map[string]interface{}{"address": address, "path": "/results", "allowedOrigins": []interface{}{"http://dashboard.example"}, "token": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := shared.Open(ctx); err != nil {
+		t.Fatalf("shared path error: %v", err)
+	}
+	other := &WebsocketSink{}
+	if err := other.Configure(map[string]interface{}{"address": address, "path": "/results", "token": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Open(ctx); err == nil || err.Error() != "websocket path /results of "+address+" is already used with different allowedOrigins" {
+		t.Errorf("different allowedOrigins error mismatch, got %v", err)
+	}
+	other = &WebsocketSink{}
+	if err := other.Configure(map[string]interface{}{"address": address, "path": "/results", "allowedOrigins": []interface{}{"http://dashboard.example"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Open(ctx); err == nil || err.Error() != "websocket path /results of "+address+" is already used with different authentication" {
+		t.Errorf("different authentication error mismatch, got %v", err)
+	}
+	if _, _, err := websocket.DefaultDialer.Dial("ws://"+address+"/results", http.Header{"Origin": []string{"http://other.example"}, "Authorization": []string{"Bearer abc"}}); err == nil {
+		t.Errorf("should reject the origin not allowed")
+	}
+	// The clients without origin must be authorized
+	if _, resp, err := websocket.DefaultDialer.Dial("ws://"+address+"/results", nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("should reject the unauthorized client, got %v", err)
+	}
+	var clients []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial("ws://"+address+"/results", http.Header{"Origin": []string{"http://dashboard.example"}, "Authorization": []string{"Bearer abc"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		clients = append(clients, conn)
+	}
+	// Wait for the server to register the clients
+	for i := 0; i < 100; i++ {
+		s.endpoint.Lock()
+		n := len(s.endpoint.conns)
+		s.endpoint.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	data := []byte(`[{"temperature":20}]`)
+	if err := s.Collect(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range clients {
+		_ = c.SetReadDeadline(time.Now().Add(time.Second))
+		_, r, err := c.ReadMessage()
+		if err != nil {
+			t.Errorf("%d: read error %v", i, err)
+		} else if !reflect.DeepEqual(data, r) {
+			t.Errorf("%d: result mismatch:\n  exp=%s\n  got=%s\n\n", i, data, r)
+		}
+	}
+	// The async sink collects concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = s.Collect(ctx, data)
+		}()
+	}
+	wg.Wait()
+	for i, c := range clients {
+		for j := 0; j < 10; j++ {
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			if _, _, err := c.ReadMessage(); err != nil {
+				t.Errorf("%d: read concurrent message %d error %v", i, j, err)
+				break
+			}
+		}
+	}
+	if err := s.Collect(ctx, "not bytes"); err == nil {
+		t.Errorf("should fail for non []byte data")
+	}
+	// The endpoint is kept for the other instance after an instance is closed
+	s.Close(ctx)
+	if err := shared.Collect(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range clients {
+		_ = c.SetReadDeadline(time.Now().Add(time.Second))
+		_, r, err := c.ReadMessage()
+		if err != nil {
+			t.Errorf("%d: read shared message error %v", i, err)
+		} else if !reflect.DeepEqual(data, r) {
+			t.Errorf("%d: shared result mismatch:\n  exp=%s\n  got=%s\n\n", i, data, r)
+		}
+	}
+	// The clients are disconnected after all the instances are closed
+	shared.Close(ctx)
+	for i, c := range clients {
+		_ = c.SetReadDeadline(time.Now().Add(time.Second))
+		if _, _, err := c.ReadMessage(); err == nil {
+			t.Errorf("%d: should be disconnected", i)
+		}
+	}
+}
+
+func TestWebsocketSinkClient(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	received := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, d, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- d
+		}
+	}))
+	defer server.Close()
+
+	s := &WebsocketSink{}
+	if err := s.Configure(map[string]interface{}{"mode": "client", "url": "ws" + strings.TrimPrefix(server.URL, "http")}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	data := [][]byte{[]byte(`{"a":1}`), []byte(`{"a":2}`)}
+	for _, d := range data {
+		if err := s.Collect(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, exp := range data {
+		select {
+		case r := <-received:
+			if !reflect.DeepEqual(exp, r) {
+				t.Errorf("%d: result mismatch:\n  exp=%s\n  got=%s\n\n", i, exp, r)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%d: no message received", i)
+		}
+	}
+	s.Close(ctx)
+}
@@ -15,39 +15,100 @@
 package source
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/filex"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 type FileType string
 
 const (
-	JSON_TYPE FileType = "json"
+	JSON_TYPE  FileType = "json"
+	CSV_TYPE   FileType = "csv"
+	LINES_TYPE FileType = "lines"
 )
 
 var fileTypes = map[FileType]bool{
-	JSON_TYPE: true,
+	JSON_TYPE:  true,
+	CSV_TYPE:   true,
+	LINES_TYPE: true,
 }
 
+// The actions to take after a file is totally read
+const (
+	ActionKeep = iota
+	ActionDelete
+	ActionMove
+)
+
+// The default interval to check the new content of a tailing file in ms
+const defaultTailInterval = 1000
+
 type FileSourceConfig struct {
 	FileType   FileType `json:"fileType"`
 	Path       string   `json:"path"`
 	Interval   int      `json:"interval"`
 	RetainSize int      `json:"$retainSize"`
+	// The separator of the csv columns, default to comma
+	Delimiter string `json:"delimiter"`
+	// Whether the first line of the csv file is the column names
+	HasHeader bool `json:"hasHeader"`
+	// The column names of the csv file if there is no header
+	Columns []string `json:"columns"`
+	// Keep reading the lines appended to the csv or lines file like tail -f
+	Tail bool `json:"tail"`
+	// 0: keep the file, 1: delete the file, 2: move the file to moveTo after the file is read
+	ActionAfterRead int    `json:"actionAfterRead"`
+	MoveTo          string `json:"moveTo"`
+}
+
+// fileTuple carries the file and the position after the record as the offset
+type fileTuple struct {
+	*api.DefaultSourceTuple
+	offset map[string]interface{}
+}
+
+func newFileTuple(message map[string]interface{}, meta map[string]interface{}, file string, offset int64) *fileTuple {
+	return &fileTuple{
+		DefaultSourceTuple: api.NewDefaultSourceTuple(message, meta),
+		offset:             map[string]interface{}{"file": file, "offset": offset},
+	}
+}
+
+func (t *fileTuple) Offset() interface{} {
+	return t.offset
 }
 
 // The BATCH to load data from file at once
 type FileSource struct {
 	file   string
+	isDir  bool
 	config *FileSourceConfig
+	comma  rune
+	// The files in the directory which are read and kept
+	processed map[string]bool
+
+	mu sync.Mutex
+	// The file being read and its offset. The offset is the byte position for csv and lines file, and the element index for json file
+	current string
+	offset  int64
+	// The position to resume reading from, only used by the first read after rewinding
+	rewindFile   string
+	rewindOffset int64
 }
 
 func (fs *FileSource) Close(ctx api.StreamContext) error {
@@ -63,7 +124,7 @@ func (fs *FileSource) Configure(fileName string, props map[string]interface{}) e
 		return fmt.Errorf("read properties %v fail with error: %v", props, err)
 	}
 	if cfg.FileType == "" {
-		return errors.New("missing or invalid property fileType, must be 'json'")
+		return errors.New("missing or invalid property fileType, must be 'json', 'csv' or 'lines'")
 	}
 	if _, ok := fileTypes[cfg.FileType]; !ok {
 		return fmt.Errorf("invalid property fileType: %s", cfg.FileType)
@@ -74,6 +135,34 @@ func (fs *FileSource) Configure(fileName string, props map[string]interface{}) e
 	if fileName == "" {
 		return errors.New("file name must be specified")
 	}
+	fs.comma = ','
+	if cfg.Delimiter != "" {
+		if utf8.RuneCountInString(cfg.Delimiter) != 1 {
+			return fmt.Errorf("invalid property delimiter %s, must be a single character", cfg.Delimiter)
+		}
+		fs.comma, _ = utf8.DecodeRuneInString(cfg.Delimiter)
+	}
+	if cfg.Tail && cfg.FileType == JSON_TYPE {
+		return errors.New("property tail is only supported by csv and lines file type")
+	}
+	switch cfg.ActionAfterRead {
+	case ActionKeep, ActionDelete:
+	case ActionMove:
+		if cfg.MoveTo == "" {
+			return errors.New("missing property moveTo for actionAfterRead 2")
+		}
+		if !filepath.IsAbs(cfg.MoveTo) {
+			cfg.MoveTo, err = conf.GetLoc(cfg.MoveTo)
+			if err != nil {
+				return fmt.Errorf("invalid moveTo %s", cfg.MoveTo)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid property actionAfterRead %d, must be 0, 1 or 2", cfg.ActionAfterRead)
+	}
+	if cfg.Tail && cfg.ActionAfterRead != ActionKeep {
+		return errors.New("property actionAfterRead must be 0 when tail is true")
+	}
 	if !filepath.IsAbs(cfg.Path) {
 		cfg.Path, err = conf.GetLoc(cfg.Path)
 		if err != nil {
@@ -83,11 +172,18 @@ func (fs *FileSource) Configure(fileName string, props map[string]interface{}) e
 	fs.file = path.Join(cfg.Path, fileName)
 
 	if fi, err := os.Stat(fs.file); err != nil {
-		if os.IsNotExist(err) {
+		// The file may be moved or deleted by a previous run
+		if !os.IsNotExist(err) || cfg.ActionAfterRead == ActionKeep {
 			return fmt.Errorf("file %s not exist", fs.file)
-		} else if !fi.Mode().IsRegular() {
-			return fmt.Errorf("file %s is not a regular file", fs.file)
 		}
+	} else if fi.IsDir() {
+		if cfg.Tail {
+			return fmt.Errorf("cannot tail directory %s", fs.file)
+		}
+		fs.isDir = true
+		fs.processed = make(map[string]bool)
+	} else if !fi.Mode().IsRegular() {
+		return fmt.Errorf("file %s is not a regular file", fs.file)
 	}
 	fs.config = cfg
 	return nil
@@ -119,39 +215,350 @@ func (fs *FileSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTupl
 	}
 }
 
+// Load reads the file or the new files in the directory. For a single file without tailing, an EOF is sent after reading
+// so that the table can emit the whole file as a batch.
 func (fs *FileSource) Load(ctx api.StreamContext, consumer chan<- api.SourceTuple) error {
+	if fs.isDir {
+		return fs.loadDir(ctx, consumer)
+	}
+	if fs.config.Tail {
+		return fs.tail(ctx, consumer)
+	}
+	if _, err := os.Stat(fs.file); os.IsNotExist(err) && fs.config.ActionAfterRead != ActionKeep {
+		ctx.GetLogger().Debugf("File %s is not created yet", fs.file)
+		return nil
+	}
+	if err := fs.read(ctx, fs.file, fs.startOffset(fs.file), consumer); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	// Send EOF if retain size not set
+	if fs.config.RetainSize == 0 {
+		select {
+		case consumer <- api.NewDefaultSourceTuple(nil, nil):
+			// do nothing
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	ctx.GetLogger().Debug("All tuples sent")
+	return fs.afterRead(ctx, fs.file)
+}
+
+// loadDir reads the files in the directory which are not read yet in the order of the file names
+func (fs *FileSource) loadDir(ctx api.StreamContext, consumer chan<- api.SourceTuple) error {
+	entries, err := os.ReadDir(fs.file)
+	if err != nil {
+		return fmt.Errorf("read directory %s error: %v", fs.file, err)
+	}
+	fs.mu.Lock()
+	rewindFile := fs.rewindFile
+	// The rewind position is only used by the first scan, even if the file does not exist anymore
+	fs.rewindFile = ""
+	fs.mu.Unlock()
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		file := path.Join(fs.file, entry.Name())
+		// The files before the rewind position have been read
+		if rewindFile != "" && file < rewindFile {
+			fs.processed[file] = true
+		}
+		if fs.processed[file] {
+			continue
+		}
+		ctx.GetLogger().Debugf("Start to read file %s in directory", file)
+		offset := fs.startOffset(file)
+		if file == rewindFile {
+			offset = fs.rewindOffset
+			fs.setOffset(offset)
+		}
+		if err := fs.read(ctx, file, offset, consumer); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := fs.afterRead(ctx, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tail reads the lines of the file and keeps checking the appended lines at the interval until the rule stops
+func (fs *FileSource) tail(ctx api.StreamContext, consumer chan<- api.SourceTuple) error {
+	interval := fs.config.Interval
+	if interval <= 0 {
+		interval = defaultTailInterval
+	}
+	ticker := time.NewTicker(time.Millisecond * time.Duration(interval))
+	defer ticker.Stop()
+	offset := fs.startOffset(fs.file)
+	for {
+		if err := fs.readLines(ctx, fs.file, offset, consumer); err != nil {
+			return err
+		}
+		select {
+		case <-ticker.C:
+			fs.mu.Lock()
+			offset = fs.offset
+			fs.mu.Unlock()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (fs *FileSource) read(ctx api.StreamContext, file string, offset int64, consumer chan<- api.SourceTuple) error {
 	switch fs.config.FileType {
 	case JSON_TYPE:
-		ctx.GetLogger().Debugf("Start to load from file %s", fs.file)
-		resultMap := make([]map[string]interface{}, 0)
-		err := filex.ReadJsonUnmarshal(fs.file, &resultMap)
+		return fs.readJson(ctx, file, offset, consumer)
+	case CSV_TYPE, LINES_TYPE:
+		return fs.readLines(ctx, file, offset, consumer)
+	}
+	return fmt.Errorf("invalid file type %s", fs.config.FileType)
+}
+
+func (fs *FileSource) readJson(ctx api.StreamContext, file string, offset int64, consumer chan<- api.SourceTuple) error {
+	ctx.GetLogger().Debugf("Start to load from file %s", file)
+	resultMap := make([]map[string]interface{}, 0)
+	err := filex.ReadJsonUnmarshal(file, &resultMap)
+	if err != nil {
+		return fmt.Errorf("loaded %s, check error %s", file, err)
+	}
+	ctx.GetLogger().Debug("Sending tuples")
+	start := 0
+	if fs.config.RetainSize > 0 && fs.config.RetainSize < len(resultMap) {
+		start = len(resultMap) - fs.config.RetainSize
+		ctx.GetLogger().Debugf("Sending tuples for retain size %d", fs.config.RetainSize)
+	}
+	if int(offset) > start {
+		start = int(offset)
+	}
+	meta := map[string]interface{}{"file": file}
+	for i := start; i < len(resultMap); i++ {
+		select {
+		case consumer <- newFileTuple(resultMap[i], meta, file, int64(i+1)):
+			fs.setOffset(int64(i + 1))
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// readLines sends a message for each line or csv record from the offset to the end of the file. If tailing, the last
+// record without a line break may be incomplete, so it is left to be read in the next round.
+func (fs *FileSource) readLines(ctx api.StreamContext, file string, offset int64, consumer chan<- api.SourceTuple) error {
+	logger := ctx.GetLogger()
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("fail to open file %s: %v", file, err)
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.Size() < offset {
+		logger.Infof("File %s is truncated, read it from the beginning", file)
+		offset = 0
+	}
+	var header []string
+	if fs.config.FileType == CSV_TYPE && fs.config.HasHeader {
+		line, err := fs.readRecord(bufio.NewReader(f))
 		if err != nil {
-			return fmt.Errorf("loaded %s, check error %s", fs.file, err)
+			// The header is not complete yet
+			if err == io.EOF && (fs.config.Tail || line == "") {
+				return nil
+			} else if err != io.EOF {
+				return fmt.Errorf("read file %s error: %v", file, err)
+			}
 		}
-		ctx.GetLogger().Debug("Sending tuples")
-		if fs.config.RetainSize > 0 && fs.config.RetainSize < len(resultMap) {
-			resultMap = resultMap[(len(resultMap) - fs.config.RetainSize):]
-			ctx.GetLogger().Debug("Sending tuples for retain size %d", fs.config.RetainSize)
+		header, err = fs.parseCsv(strings.TrimRight(line, "\r\n"))
+		if err != nil {
+			return fmt.Errorf("invalid header of file %s: %v", file, err)
 		}
-		for _, m := range resultMap {
-			select {
-			case consumer <- api.NewDefaultSourceTuple(m, nil):
-				// do nothing
-			case <-ctx.Done():
-				return nil
+		if offset < int64(len(line)) {
+			offset = int64(len(line))
+			fs.setOffset(offset)
+		}
+	} else {
+		header = fs.config.Columns
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seek file %s to %d error: %v", file, offset, err)
+	}
+	meta := map[string]interface{}{"file": file}
+	r := bufio.NewReader(f)
+	for {
+		line, err := fs.readRecord(r)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("read file %s error: %v", file, err)
+		}
+		if err == io.EOF && (line == "" || fs.config.Tail) {
+			return nil
+		}
+		offset += int64(len(line))
+		if text := strings.TrimRight(line, "\r\n"); strings.TrimSpace(text) != "" {
+			if m, e := fs.decodeLine(text, header); e != nil {
+				logger.Warnf("Skip invalid line %s in file %s: %v", text, file, e)
+				fs.setOffset(offset)
+			} else {
+				select {
+				case consumer <- newFileTuple(m, meta, file, offset):
+					fs.setOffset(offset)
+				case <-ctx.Done():
+					return nil
+				}
 			}
+		} else {
+			fs.setOffset(offset)
 		}
-		// Send EOF if retain size not set
-		if fs.config.RetainSize == 0 {
-			select {
-			case consumer <- api.NewDefaultSourceTuple(nil, nil):
-				// do nothing
-			case <-ctx.Done():
-				return nil
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// readRecord reads a line, or a csv record which may span multiple lines if a quoted field contains line breaks. The
+// error is io.EOF if the file ends before the line break of the record.
+func (fs *FileSource) readRecord(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if fs.config.FileType != CSV_TYPE {
+		return line, err
+	}
+	for err == nil && fs.inQuotedField(line) {
+		var next string
+		next, err = r.ReadString('\n')
+		line += next
+	}
+	return line, err
+}
+
+// inQuotedField returns whether the csv record ends inside a quoted field. It follows the rules of csv.Reader with
+// LazyQuotes: a quote only opens a quoted field at the beginning of the field, and a quote inside the quoted field
+// which is not followed by the delimiter or the line break is kept as it is.
+func (fs *FileSource) inQuotedField(record string) bool {
+	quoted, fieldStart := false, true
+	runes := []rune(record)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case !quoted:
+			if c == '"' && fieldStart {
+				quoted = true
+			}
+			fieldStart = c == fs.comma || c == '\n'
+		case c == '"':
+			if i+1 == len(runes) {
+				quoted = false
+			} else if n := runes[i+1]; n == '"' {
+				i++
+			} else if n == fs.comma || n == '\r' || n == '\n' {
+				quoted = false
 			}
 		}
-		ctx.GetLogger().Debug("All tuples sent")
-		return nil
 	}
-	return fmt.Errorf("invalid file type %s", fs.config.FileType)
+	return quoted
+}
+
+func (fs *FileSource) decodeLine(line string, header []string) (map[string]interface{}, error) {
+	if fs.config.FileType == LINES_TYPE {
+		m := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	values, err := fs.parseCsv(line)
+	if err != nil {
+		return nil, err
+	}
+	// All the values are strings which will be converted by the stream schema
+	m := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		if i < len(header) {
+			m[header[i]] = v
+		} else {
+			m[fmt.Sprintf("col%d", i)] = v
+		}
+	}
+	return m, nil
+}
+
+func (fs *FileSource) parseCsv(line string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(line))
+	r.Comma = fs.comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r.Read()
+}
+
+func (fs *FileSource) afterRead(ctx api.StreamContext, file string) error {
+	switch fs.config.ActionAfterRead {
+	case ActionDelete:
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("delete file %s error: %v", file, err)
+		}
+		ctx.GetLogger().Debugf("File %s is deleted", file)
+	case ActionMove:
+		dest := path.Join(fs.config.MoveTo, filepath.Base(file))
+		if err := os.Rename(file, dest); err != nil {
+			return fmt.Errorf("move file %s to %s error: %v", file, dest, err)
+		}
+		ctx.GetLogger().Debugf("File %s is moved to %s", file, dest)
+	default:
+		if fs.isDir {
+			fs.processed[file] = true
+		}
+	}
+	return nil
+}
+
+// startOffset returns the offset to start reading the file which is the rewind position if the file is rewound
+func (fs *FileSource) startOffset(file string) int64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var offset int64
+	if fs.rewindFile == file {
+		offset = fs.rewindOffset
+		fs.rewindFile = ""
+	}
+	fs.current = file
+	fs.offset = offset
+	return offset
+}
+
+func (fs *FileSource) setOffset(offset int64) {
+	fs.mu.Lock()
+	fs.offset = offset
+	fs.mu.Unlock()
+}
+
+// GetOffset returns the position after the last record sent to the rule
+func (fs *FileSource) GetOffset() (interface{}, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return map[string]interface{}{"file": fs.current, "offset": fs.offset}, nil
+}
+
+func (fs *FileSource) Rewind(offset interface{}) error {
+	m, ok := offset.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("file source fails to rewind: invalid offset %v", offset)
+	}
+	file, err := cast.ToString(m["file"], cast.STRICT)
+	if err != nil {
+		return fmt.Errorf("file source fails to rewind: invalid file %v", m["file"])
+	}
+	o, err := cast.ToInt64(m["offset"], cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return fmt.Errorf("file source fails to rewind: invalid offset %v", m["offset"])
+	}
+	fs.mu.Lock()
+	fs.rewindFile = file
+	fs.rewindOffset = o
+	fs.mu.Unlock()
+	return nil
 }
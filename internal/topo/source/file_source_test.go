@@ -0,0 +1,316 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileSourceConfigure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(path.Join(dir, "test.csv"), []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		file  string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			file:  "test.csv",
+			props: map[string]interface{}{"fileType": "xml", "path": dir},
+			err:   "invalid property fileType: xml",
+		}, {
+			file:  "test.csv",
+			props: map[string]interface{}{"fileType": "csv", "path": dir, "delimiter": "||"},
+			err:   "invalid property delimiter ||, must be a single character",
+		}, {
+			file:  "test.csv",
+			props: map[string]interface{}{"fileType": "json", "path": dir, "tail": true},
+			err:   "property tail is only supported by csv and lines file type",
+		}, {
+			file:  "test.csv",
+			props: map[string]interface{}{"fileType": "csv", "path": dir, "actionAfterRead": 2},
+			err:   "missing property moveTo for actionAfterRead 2",
+		}, {
+			file:  "test.csv",
+			props: map[string]interface{}{"fileType": "csv", "path": dir, "actionAfterRead": 3},
+			err:   "invalid property actionAfterRead 3, must be 0, 1 or 2",
+		}, {
+			file:  "test.csv",
+			props: map[string]interface{}{"fileType": "csv", "path": dir, "tail": true, "actionAfterRead": 1},
+			err:   "property actionAfterRead must be 0 when tail is true",
+		}, {
+			file:  "",
+			props: map[string]interface{}{"fileType": "csv", "path": dir},
+			err:   "file name must be specified",
+		}, {
+			file:  "sub",
+			props: map[string]interface{}{"fileType": "csv", "path": dir, "tail": true},
+			err:   fmt.Sprintf("cannot tail directory %s", path.Join(dir, "sub")),
+		}, {
+			file:  "none.csv",
+			props: map[string]interface{}{"fileType": "csv", "path": dir},
+			err:   fmt.Sprintf("file %s not exist", path.Join(dir, "none.csv")),
+		}, {
+			file:  "none.csv",
+			props: map[string]interface{}{"fileType": "csv", "path": dir, "actionAfterRead": 1},
+		}, {
+			file:  "test.csv",
+			props: map[string]interface{}{"fileType": "csv", "path": dir, "tail": true, "delimiter": "\t"},
+		},
+	}
+	for i, tt := range tests {
+		fs := &FileSource{}
+		err := fs.Configure(tt.file, tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestFileSourceRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var tests = []struct {
+		content string
+		props   map[string]interface{}
+		offset  interface{}
+		result  []map[string]interface{}
+	}{
+		{
+			content: "id,name\n1,\"john, doe\"\n\n2,jane",
+			props:   map[string]interface{}{"fileType": "csv", "hasHeader": true},
+			result: []map[string]interface{}{
+				{"id": "1", "name": "john, doe"},
+				{"id": "2", "name": "jane"},
+			},
+		}, {
+			content: "1;john;3\r\n2;jane\r\n",
+			props:   map[string]interface{}{"fileType": "csv", "delimiter": ";", "columns": []interface{}{"id", "name"}},
+			result: []map[string]interface{}{
+				{"id": "1", "name": "john", "col2": "3"},
+				{"id": "2", "name": "jane"},
+			},
+		}, {
+			content: "{\"id\":1}\nnot json\n{\"id\":2}\n",
+			props:   map[string]interface{}{"fileType": "lines"},
+			result: []map[string]interface{}{
+				{"id": 1.0},
+				{"id": 2.0},
+			},
+		}, {
+			content: "id,name\n1,john\n2,jane\n",
+			props:   map[string]interface{}{"fileType": "csv", "hasHeader": true},
+			offset:  map[string]interface{}{"offset": int64(15)},
+			result: []map[string]interface{}{
+				{"id": "2", "name": "jane"},
+			},
+		}, {
+			content: "id,name\n1,\"john\ndoe\"\n2,\"jane \"\"j\"\"\r\nsmith\"\r\n3,5\" screen\n",
+			props:   map[string]interface{}{"fileType": "csv", "hasHeader": true},
+			result: []map[string]interface{}{
+				{"id": "1", "name": "john\ndoe"},
+				{"id": "2", "name": "jane \"j\"\nsmith"},
+				{"id": "3", "name": "5\" screen"},
+			},
+		}, {
+			content: "id,name\n1,\"a\nb\"\n2,c\n",
+			props:   map[string]interface{}{"fileType": "csv", "hasHeader": true},
+			offset:  map[string]interface{}{"offset": int64(16)},
+			result: []map[string]interface{}{
+				{"id": "2", "name": "c"},
+			},
+		}, {
+			content: `[{"id":1},{"id":2},{"id":3}]`,
+			props:   map[string]interface{}{"fileType": "json"},
+			offset:  map[string]interface{}{"offset": int64(2)},
+			result: []map[string]interface{}{
+				{"id": 3.0},
+			},
+		},
+	}
+	for i, tt := range tests {
+		name := fmt.Sprintf("test%d", i)
+		file := path.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tt.props["path"] = dir
+		fs := &FileSource{}
+		if err := fs.Configure(name, tt.props); err != nil {
+			t.Errorf("%d: configure error %v", i, err)
+			continue
+		}
+		if tt.offset != nil {
+			tt.offset.(map[string]interface{})["file"] = file
+			if err := fs.Rewind(tt.offset); err != nil {
+				t.Errorf("%d: rewind error %v", i, err)
+				continue
+			}
+		}
+		result, err := collect(fs, -1)
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.result, result) {
+			t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.result, result)
+		}
+	}
+}
+
+func TestFileSourceDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src, dest := path.Join(dir, "in"), path.Join(dir, "out")
+	for _, d := range []string{src, dest} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{"a.txt": "{\"id\":1}\n{\"id\":2}\n", "b.txt": "{\"id\":3}\n", ".tmp": "{\"id\":4}\n"}
+	for n, c := range files {
+		if err := ioutil.WriteFile(path.Join(src, n), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fs := &FileSource{}
+	if err := fs.Configure("in", map[string]interface{}{"fileType": "lines", "path": dir, "actionAfterRead": 2, "moveTo": dest}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := collect(fs, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []map[string]interface{}{{"id": 1.0}, {"id": 2.0}, {"id": 3.0}}
+	if !reflect.DeepEqual(exp, result) {
+		t.Errorf("result mismatch:\n  exp=%v\n  got=%v\n\n", exp, result)
+	}
+	for _, n := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(path.Join(dest, n)); err != nil {
+			t.Errorf("file %s is not moved: %v", n, err)
+		}
+	}
+	if _, err := os.Stat(path.Join(src, ".tmp")); err != nil {
+		t.Errorf("hidden file should be ignored: %v", err)
+	}
+}
+
+func TestFileSourceTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "test.log")
+	if err := ioutil.WriteFile(file, []byte("id\n1\n2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs := &FileSource{}
+	if err := fs.Configure("test.log", map[string]interface{}{"fileType": "csv", "path": dir, "hasHeader": true, "tail": true, "interval": 50}); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		_, _ = f.WriteString("\n3\n")
+	}()
+	tuples, err := collectTuples(fs, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []map[string]interface{}
+	var offsets []interface{}
+	for _, tuple := range tuples {
+		result = append(result, tuple.Message())
+		offsets = append(offsets, tuple.(api.OffsetTuple).Offset())
+	}
+	exp := []map[string]interface{}{{"id": "1"}, {"id": "2"}, {"id": "3"}}
+	if !reflect.DeepEqual(exp, result) {
+		t.Errorf("result mismatch:\n  exp=%v\n  got=%v\n\n", exp, result)
+	}
+	// The offset of each message is the position after its line
+	expOffsets := []interface{}{
+		map[string]interface{}{"file": file, "offset": int64(5)},
+		map[string]interface{}{"file": file, "offset": int64(7)},
+		map[string]interface{}{"file": file, "offset": int64(9)},
+	}
+	if !reflect.DeepEqual(expOffsets, offsets) {
+		t.Errorf("offset mismatch:\n  exp=%v\n  got=%v\n\n", expOffsets, offsets)
+	}
+}
+
+// collect opens the source and receives n messages or until EOF if n is negative
+func collect(fs *FileSource, n int) ([]map[string]interface{}, error) {
+	tuples, err := collectTuples(fs, n)
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]interface{}
+	for _, tuple := range tuples {
+		result = append(result, tuple.Message())
+	}
+	return result, nil
+}
+
+func collectTuples(fs *FileSource, n int) ([]api.SourceTuple, error) {
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	consumer := make(chan api.SourceTuple)
+	errCh := make(chan error, 1)
+	go fs.Open(ctx, consumer, errCh)
+	var result []api.SourceTuple
+	for n < 0 || len(result) < n {
+		select {
+		case d := <-consumer:
+			if d.Message() == nil {
+				return result, nil
+			}
+			result = append(result, d)
+		case err := <-errCh:
+			return nil, err
+		case <-time.After(5 * time.Second):
+			return nil, fmt.Errorf("timeout with %d messages received", len(result))
+		}
+	}
+	return result, nil
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/converter"
 	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
@@ -43,6 +44,7 @@ type HTTPPullSource struct {
 	bodyType      string
 	headers       map[string]string
 	messageFormat string
+	converter     message.Converter
 
 	client *http.Client
 }
@@ -117,6 +119,11 @@ func (hps *HTTPPullSource) Configure(device string, props map[string]interface{}
 			return fmt.Errorf("Not valid format value %v.", c)
 		}
 	}
+	if cv, err := converter.GetOrCreateConverter(props); err != nil {
+		return err
+	} else {
+		hps.converter = cv
+	}
 
 	if b, ok := props["body"]; ok {
 		if b1, ok1 := b.(string); ok1 {
@@ -191,7 +198,7 @@ func (hps *HTTPPullSource) initTimerPull(ctx api.StreamContext, consumer chan<-
 					}
 				}
 
-				result, e := message.Decode(c, hps.messageFormat)
+				result, e := hps.converter.Decode(c)
 				meta := make(map[string]interface{})
 				if e != nil {
 					logger.Errorf("Invalid data format, cannot decode %s to %s format with error %s", string(c), hps.messageFormat, e)
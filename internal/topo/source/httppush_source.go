@@ -0,0 +1,253 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	DEFAULT_PUSH_ADDRESS       = ":10081"
+	DEFAULT_PUSH_MAX_BODY_SIZE = 10 * 1024 * 1024
+)
+
+type HTTPPushConfig struct {
+	// The address to listen to such as :10081
+	Address string `json:"address"`
+	// The http method of the push requests
+	Method string `json:"method"`
+	// The server certification and private key to enable https
+	Certification string `json:"certificationPath"`
+	PrivateKPath  string `json:"privateKeyPath"`
+	httpx.Auth
+	// The maximum size of the request body in bytes
+	MaxBodySize int64 `json:"maxBodySize"`
+	// The settings from the stream options to decode the body. The streams sharing a path must decode the same way
+	Format    string `json:"format"`
+	SchemaId  string `json:"schemaId"`
+	Delimiter string `json:"delimiter"`
+}
+
+// HTTPPushSource opens an http endpoint and emits the body of each request as a message. The sources with the same
+// address share one server, and each path is served by one endpoint which fans out the requests to all the source
+// instances of the path, such as the instances of a rule with concurrency or the rules of the same stream.
+type HTTPPushSource struct {
+	path      string
+	config    *HTTPPushConfig
+	converter message.Converter
+
+	opened   bool
+	ctx      api.StreamContext
+	consumer chan<- api.SourceTuple
+}
+
+// pushEndpoint serves a path of an address and dispatches each request to all the subscribed sources
+type pushEndpoint struct {
+	sync.RWMutex
+	address string
+	path    string
+	config  HTTPPushConfig
+	sources []*HTTPPushSource
+}
+
+var (
+	endpoints  = make(map[string]*pushEndpoint)
+	endpointMu sync.Mutex
+)
+
+func (hps *HTTPPushSource) Configure(datasource string, props map[string]interface{}) error {
+	cfg := &HTTPPushConfig{
+		Address:     DEFAULT_PUSH_ADDRESS,
+		Method:      http.MethodPost,
+		MaxBodySize: DEFAULT_PUSH_MAX_BODY_SIZE,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	cfg.Method = strings.ToUpper(cfg.Method)
+	switch cfg.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return fmt.Errorf("not supported http method %s, must be post, put or patch", cfg.Method)
+	}
+	if (cfg.Certification == "") != (cfg.PrivateKPath == "") {
+		return errors.New("certificationPath and privateKeyPath must be set together")
+	}
+	if err := cfg.Auth.Validate(); err != nil {
+		return err
+	}
+	if cfg.MaxBodySize <= 0 {
+		return fmt.Errorf("invalid maxBodySize %d, must be positive", cfg.MaxBodySize)
+	}
+	cfg.Format = strings.ToLower(cfg.Format)
+	if cfg.Format == "" {
+		cfg.Format = message.FormatJson
+	}
+	hps.path = datasource
+	if !strings.HasPrefix(hps.path, "/") {
+		hps.path = "/" + hps.path
+	}
+	if hps.converter, err = converter.GetOrCreateConverter(props); err != nil {
+		return err
+	}
+	hps.config = cfg
+	return nil
+}
+
+func (hps *HTTPPushSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	hps.ctx = ctx
+	hps.consumer = consumer
+	if err := hps.subscribe(ctx.GetLogger()); err != nil {
+		errCh <- err
+		return
+	}
+	hps.opened = true
+	ctx.GetLogger().Infof("HTTP push source is listening to %s%s", hps.config.Address, hps.path)
+}
+
+// subscribe adds the source to the endpoint of its path. The endpoint is created and registered to the server by the
+// first source, and the later sources must have the same settings.
+func (hps *HTTPPushSource) subscribe(logger api.Logger) error {
+	endpointMu.Lock()
+	defer endpointMu.Unlock()
+	key := hps.config.Address + hps.path
+	if e, ok := endpoints[key]; ok {
+		if e.config.Format != hps.config.Format || e.config.SchemaId != hps.config.SchemaId || e.config.Delimiter != hps.config.Delimiter {
+			return fmt.Errorf("http path %s of %s is already used with a different format", hps.path, hps.config.Address)
+		}
+		if e.config != *hps.config {
+			return fmt.Errorf("http path %s of %s is already used with different settings", hps.path, hps.config.Address)
+		}
+		e.Lock()
+		e.sources = append(e.sources, hps)
+		e.Unlock()
+		return nil
+	}
+	e := &pushEndpoint{
+		address: hps.config.Address,
+		path:    hps.path,
+		config:  *hps.config,
+		sources: []*HTTPPushSource{hps},
+	}
+	if err := httpx.RegisterHandler(logger, e.address, e.config.Certification, e.config.PrivateKPath, e.path, e); err != nil {
+		return err
+	}
+	endpoints[key] = e
+	return nil
+}
+
+// unsubscribe removes the source from the endpoint, and unregisters the endpoint once no source is subscribed
+func (hps *HTTPPushSource) unsubscribe() {
+	endpointMu.Lock()
+	defer endpointMu.Unlock()
+	key := hps.config.Address + hps.path
+	e, ok := endpoints[key]
+	if !ok {
+		return
+	}
+	e.Lock()
+	for i, s := range e.sources {
+		if s == hps {
+			e.sources = append(e.sources[:i], e.sources[i+1:]...)
+			break
+		}
+	}
+	n := len(e.sources)
+	e.Unlock()
+	if n == 0 {
+		httpx.UnregisterHandler(e.address, e.path)
+		delete(endpoints, key)
+	}
+}
+
+func (hps *HTTPPushSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing HTTP push source")
+	if hps.opened {
+		hps.unsubscribe()
+		hps.opened = false
+	}
+	return nil
+}
+
+func (e *pushEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != e.config.Method {
+		w.Header().Set("Allow", e.config.Method)
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	if !e.config.Authorized(r) {
+		e.config.Unauthorized(w)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, e.config.MaxBodySize))
+	if err != nil {
+		// The reader fails after reading the maximum size if the body is too large
+		if int64(len(body)) == e.config.MaxBodySize {
+			http.Error(w, fmt.Sprintf("body exceeds the maximum size %d", e.config.MaxBodySize), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, fmt.Sprintf("read body error: %v", err), http.StatusBadRequest)
+		}
+		return
+	}
+	e.RLock()
+	sources := make([]*HTTPPushSource, len(e.sources))
+	copy(sources, e.sources)
+	e.RUnlock()
+	for _, hps := range sources {
+		if err := hps.send(r, body); err != nil {
+			hps.ctx.GetLogger().Warnf("Invalid data format, cannot decode %s with error %s", string(body), err)
+			http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if r.Context().Err() != nil {
+			// the client is gone
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// send decodes the body and sends it to the rule. Only the decode error is returned and the closed source is skipped.
+func (hps *HTTPPushSource) send(r *http.Request, body []byte) error {
+	result, err := hps.converter.Decode(body)
+	if err != nil {
+		return err
+	}
+	meta := map[string]interface{}{
+		"path":   r.URL.Path,
+		"method": r.Method,
+	}
+	for k, v := range r.Header {
+		meta[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	select {
+	case hps.consumer <- api.NewDefaultSourceTuple(result, meta):
+		hps.ctx.GetLogger().Debugf("send data to device node")
+	case <-hps.ctx.Done():
+	case <-r.Context().Done():
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHTTPPushConfigure(t *testing.T) {
+	var tests = []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{"method": "get"},
+			err:   "not supported http method GET, must be post, put or patch",
+		}, {
+			props: map[string]interface{}{"certificationPath": "cert.pem"},
+			err:   "certificationPath and privateKeyPath must be set together",
+		}, {
+			props: map[string]interface{}{"username": "user", "token": "abc"},
+			err:   "only one of basic auth and bearer token can be set",
+		}, {
+			props: map[string]interface{}{"maxBodySize": 0},
+			err:   "invalid maxBodySize 0, must be positive",
+		}, {
+			props: map[string]interface{}{"method": "put", "username": "user", "password": "pass"},
+		},
+	}
+	for i, tt := range tests {
+		s := &HTTPPushSource{}
+		err := s.Configure("data", tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestHTTPPushSource(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 2)
+	basic := &HTTPPushSource{}
+	if err := basic.Configure("basic", map[string]interface{}{"address": address, "username": "user", "password": "pass", "maxBodySize": 64}); err != nil {
+		t.Fatal(err)
+	}
+	basic.Open(ctx, consumer, errCh)
+	defer basic.Close(ctx)
+	bearer := &HTTPPushSource{}
+	if err := bearer.Configure("/bearer", map[string]interface{}{"address": address, "method": "put", "token": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	bearer.Open(ctx, consumer, errCh)
+	defer bearer.Close(ctx)
+	dup := &HTTPPushSource{}
+	if err := dup.Configure("basic", map[string]interface{}{"address": address}); err != nil {
+		t.Fatal(err)
+	}
+	dup.Open(ctx, consumer, errCh)
+	select {
+	case err := <-errCh:
+		exp := fmt.Sprintf("http path /basic of %s is already used with different settings", address)
+		if err.Error() != exp {
+			t.Errorf("duplicate path error mismatch:\n  exp=%s\n  got=%s", exp, err)
+		}
+	default:
+		t.Errorf("duplicate path should fail")
+	}
+	// The stream with another format cannot share the path
+	binary := &HTTPPushSource{}
+	if err := binary.Configure("basic", map[string]interface{}{"address": address, "username": "user", "password": "pass", "maxBodySize": 64, "format": "binary"}); err != nil {
+		t.Fatal(err)
+	}
+	binary.Open(ctx, consumer, errCh)
+	select {
+	case err := <-errCh:
+		exp := fmt.Sprintf("http path /basic of %s is already used with a different format", address)
+		if err.Error() != exp {
+			t.Errorf("different format error mismatch:\n  exp=%s\n  got=%s", exp, err)
+		}
+	default:
+		t.Errorf("different format should fail")
+	}
+	// Another instance of the same stream shares the path
+	sharedConsumer := make(chan api.SourceTuple, 10)
+	shared := &HTTPPushSource{}
+	if err := shared.Configure("basic", map[string]interface{}{"address": address, "username": "user", "password": "pass", "maxBodySize": 64, "format": "JSON"}); err != nil {
+		t.Fatal(err)
+	}
+	shared.Open(ctx, sharedConsumer, errCh)
+	defer shared.Close(ctx)
+	select {
+	case err := <-errCh:
+		t.Errorf("shared path error: %v", err)
+	default:
+	}
+
+	var tests = []struct {
+		method  string
+		path    string
+		body    string
+		headers map[string]string
+		user    string
+		status  int
+		result  api.SourceTuple
+	}{
+		{
+			method:  http.MethodPost,
+			path:    "/basic",
+			body:    `{"temperature": 20}`,
+			headers: map[string]string{"X-Device": "d1"},
+			user:    "user",
+			status:  http.StatusOK,
+			result: api.NewDefaultSourceTuple(map[string]interface{}{"temperature": 20.0}, map[string]interface{}{
+				"path": "/basic", "method": "POST", "x-device": "d1",
+			}),
+		}, {
+			method: http.MethodPost,
+			path:   "/basic",
+			body:   `{"temperature": 20}`,
+			user:   "wrong",
+			status: http.StatusUnauthorized,
+		}, {
+			method: http.MethodPut,
+			path:   "/basic",
+			body:   `{"temperature": 20}`,
+			user:   "user",
+			status: http.StatusMethodNotAllowed,
+		}, {
+			method: http.MethodPost,
+			path:   "/basic",
+			body:   `{"temperature"`,
+			user:   "user",
+			status: http.StatusBadRequest,
+		}, {
+			method: http.MethodPost,
+			path:   "/basic",
+			body:   fmt.Sprintf(`{"name": "%065d"}`, 0),
+			user:   "user",
+			status: http.StatusRequestEntityTooLarge,
+		}, {
+			method:  http.MethodPut,
+			path:    "/bearer",
+			body:    `{"humidity": 50}`,
+			headers: map[string]string{"Authorization": "Bearer abc"},
+			status:  http.StatusOK,
+			result: api.NewDefaultSourceTuple(map[string]interface{}{"humidity": 50.0}, map[string]interface{}{
+				"path": "/bearer", "method": "PUT", "authorization": "Bearer abc",
+			}),
+		}, {
+			method:  http.MethodPut,
+			path:    "/bearer",
+			body:    `{"humidity": 50}`,
+			headers: map[string]string{"Authorization": "Bearer xyz"},
+			status:  http.StatusUnauthorized,
+		}, {
+			method: http.MethodPost,
+			path:   "/none",
+			body:   `{}`,
+			status: http.StatusNotFound,
+		},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest(tt.method, "http://"+address+tt.path, bytes.NewBufferString(tt.body))
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, "pass")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("%d: request error %v", i, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%d: status mismatch, exp %d but got %d", i, tt.status, resp.StatusCode)
+			continue
+		}
+		if tt.result == nil {
+			continue
+		}
+		consumers := []chan api.SourceTuple{consumer}
+		if tt.path == "/basic" {
+			consumers = append(consumers, sharedConsumer)
+		}
+		for _, c := range consumers {
+			select {
+			case r := <-c:
+				meta := r.Meta()
+				// Remove the headers added by the client
+				for k := range meta {
+					if _, ok := tt.result.Meta()[k]; !ok {
+						delete(meta, k)
+					}
+				}
+				if !reflect.DeepEqual(tt.result, r) {
+					t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.result, r)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("%d: no message received", i)
+			}
+		}
+	}
+}
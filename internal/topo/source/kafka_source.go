@@ -0,0 +1,373 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/pkg/kafkax"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	kafkaOffsetEarliest = "earliest"
+	kafkaOffsetLatest   = "latest"
+)
+
+type KafkaSourceConfig struct {
+	kafkax.ConnConfig
+	// The consumer group to join. If not set, the source reads the partition directly
+	GroupId   string `json:"groupId"`
+	Partition int    `json:"partition"`
+	// Where to start if there is no committed offset, earliest or latest
+	StartOffset string `json:"startOffset"`
+	// The interval to commit the offsets to the consumer group, time unit is ms
+	CommitInterval int `json:"commitInterval"`
+	// The maximum bytes to fetch in one request
+	MaxBytes int `json:"maxBytes"`
+}
+
+// kafkaReader is the part of the kafka reader used by the source
+type kafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	SetOffset(offset int64) error
+	Close() error
+}
+
+// kafkaTuple carries the next offsets of all the partitions read so far as the offset
+type kafkaTuple struct {
+	*api.DefaultSourceTuple
+	offset map[string]interface{}
+}
+
+func (t *kafkaTuple) Offset() interface{} {
+	return t.offset
+}
+
+// KafkaSource consumes a kafka topic. In consumer group mode, the partitions are assigned by the group and the
+// offsets are committed to the group once the messages are processed, or once the checkpoint including them completes
+// if the rule runs with at least once qos. Only the partitions read since the last acknowledgement are committed, so
+// the partitions revoked by a rebalance are not committed with stale offsets. Otherwise, the source reads one
+// partition. The next offset of each partition is the offset to rewind.
+type KafkaSource struct {
+	topic     string
+	config    *KafkaSourceConfig
+	converter message.Converter
+	// Create the reader and commit the offsets to the group, replaced in tests
+	newReader   func() (kafkaReader, error)
+	commitGroup func(ctx context.Context, offsets map[int]int64) error
+
+	mu      sync.Mutex
+	reader  kafkaReader
+	offsets map[int]int64
+	rewound map[int]int64
+	// The offsets acknowledged or known when opened, the partitions which do not advance are not committed again
+	acked map[int]int64
+}
+
+func (ks *KafkaSource) Configure(topic string, props map[string]interface{}) error {
+	cfg := &KafkaSourceConfig{
+		StartOffset:    kafkaOffsetLatest,
+		CommitInterval: 1000,
+		MaxBytes:       1000000,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if topic == "" {
+		return errors.New("topic must be specified")
+	}
+	if cfg.StartOffset != kafkaOffsetEarliest && cfg.StartOffset != kafkaOffsetLatest {
+		return fmt.Errorf("invalid startOffset %s, must be earliest or latest", cfg.StartOffset)
+	}
+	if cfg.Partition < 0 {
+		return fmt.Errorf("invalid partition %d, must not be negative", cfg.Partition)
+	}
+	if cfg.CommitInterval <= 0 {
+		return fmt.Errorf("invalid commitInterval %d, must be positive", cfg.CommitInterval)
+	}
+	if cfg.MaxBytes <= 0 {
+		return fmt.Errorf("invalid maxBytes %d, must be positive", cfg.MaxBytes)
+	}
+	if ks.converter, err = converter.GetOrCreateConverter(props); err != nil {
+		return err
+	}
+	ks.topic = topic
+	ks.config = cfg
+	ks.offsets = make(map[int]int64)
+	if ks.newReader == nil {
+		ks.newReader = ks.createReader
+	}
+	if ks.commitGroup == nil {
+		ks.commitGroup = ks.commitGroupOffsets
+	}
+	return nil
+}
+
+func (ks *KafkaSource) createReader() (kafkaReader, error) {
+	d, err := ks.config.Dialer()
+	if err != nil {
+		return nil, err
+	}
+	rc := kafka.ReaderConfig{
+		Brokers:  ks.config.BrokerList(),
+		Topic:    ks.topic,
+		Dialer:   d,
+		MaxBytes: ks.config.MaxBytes,
+	}
+	if ks.config.StartOffset == kafkaOffsetEarliest {
+		rc.StartOffset = kafka.FirstOffset
+	} else {
+		rc.StartOffset = kafka.LastOffset
+	}
+	if ks.config.GroupId != "" {
+		rc.GroupID = ks.config.GroupId
+		rc.CommitInterval = time.Duration(ks.config.CommitInterval) * time.Millisecond
+	} else {
+		rc.Partition = ks.config.Partition
+	}
+	return kafka.NewReader(rc), nil
+}
+
+// commitGroupOffsets resets the offsets of the consumer group. It commits as a standalone consumer with generation -1,
+// which Kafka only accepts when the group has no active member. If it fails, the group continues from its committed
+// offsets which are the offsets of the last completed checkpoint unless the last commits failed.
+func (ks *KafkaSource) commitGroupOffsets(ctx context.Context, offsets map[int]int64) error {
+	t, err := ks.config.Transport()
+	if err != nil {
+		return err
+	}
+	client := &kafka.Client{Addr: kafka.TCP(ks.config.BrokerList()...), Transport: t}
+	commits := make([]kafka.OffsetCommit, 0, len(offsets))
+	for p, o := range offsets {
+		commits = append(commits, kafka.OffsetCommit{Partition: p, Offset: o})
+	}
+	resp, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      ks.config.GroupId,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{ks.topic: commits},
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range resp.Topics[ks.topic] {
+		if p.Error != nil {
+			return fmt.Errorf("partition %d: %v", p.Partition, p.Error)
+		}
+	}
+	return nil
+}
+
+func (ks *KafkaSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	ks.mu.Lock()
+	rewound := ks.rewound
+	ks.mu.Unlock()
+	if len(rewound) > 0 && ks.config.GroupId != "" {
+		if err := ks.commitGroup(ctx, rewound); err != nil {
+			logger.Warnf("Kafka source fails to rewind the consumer group %s to %v, continue from the committed offsets: %v", ks.config.GroupId, rewound, err)
+		}
+	}
+	r, err := ks.newReader()
+	if err != nil {
+		errCh <- err
+		return
+	}
+	ks.mu.Lock()
+	ks.reader = r
+	ks.acked = make(map[int]int64, len(ks.offsets))
+	for p, o := range ks.offsets {
+		ks.acked[p] = o
+	}
+	ks.mu.Unlock()
+	if o, ok := rewound[ks.config.Partition]; ok && ks.config.GroupId == "" {
+		if err := r.SetOffset(o); err != nil {
+			errCh <- fmt.Errorf("kafka source fails to rewind partition %d to %d: %v", ks.config.Partition, o, err)
+			return
+		}
+	}
+	logger.Infof("Kafka source starts to consume topic %s", ks.topic)
+	for {
+		msg, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || err == io.EOF {
+				return
+			}
+			logger.Warnf("Kafka source fails to fetch message: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		result, err := ks.converter.Decode(msg.Value)
+		if err != nil {
+			// The offset is committed along with the next valid message
+			logger.Errorf("Invalid data format, cannot decode %s with error %s", string(msg.Value), err)
+		} else {
+			tuple := &kafkaTuple{
+				DefaultSourceTuple: api.NewDefaultSourceTuple(result, kafkaMeta(msg)),
+				offset:             ks.nextOffsets(msg),
+			}
+			select {
+			case consumer <- tuple:
+				logger.Debugf("send data to device node")
+			case <-ctx.Done():
+				return
+			}
+		}
+		ks.mu.Lock()
+		ks.offsets[msg.Partition] = msg.Offset + 1
+		ks.mu.Unlock()
+	}
+}
+
+// nextOffsets returns the next offsets to read of all the partitions after the message
+func (ks *KafkaSource) nextOffsets(msg kafka.Message) map[string]interface{} {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	result := make(map[string]interface{}, len(ks.offsets)+1)
+	for p, o := range ks.offsets {
+		result[strconv.Itoa(p)] = o
+	}
+	result[strconv.Itoa(msg.Partition)] = msg.Offset + 1
+	return result
+}
+
+func kafkaMeta(msg kafka.Message) map[string]interface{} {
+	headers := make(map[string]interface{}, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	return map[string]interface{}{
+		"topic":     msg.Topic,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+		"key":       string(msg.Key),
+		"headers":   headers,
+		"timestamp": msg.Time.UnixNano() / int64(time.Millisecond),
+	}
+}
+
+// GetOffset returns the next offsets to read keyed by the partition
+func (ks *KafkaSource) GetOffset() (interface{}, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	result := make(map[string]interface{}, len(ks.offsets))
+	for p, o := range ks.offsets {
+		result[strconv.Itoa(p)] = o
+	}
+	return result, nil
+}
+
+func (ks *KafkaSource) Rewind(offset interface{}) error {
+	rewound, err := parseKafkaOffsets(offset)
+	if err != nil {
+		return fmt.Errorf("kafka source fails to rewind: %v", err)
+	}
+	ks.mu.Lock()
+	ks.rewound = rewound
+	for p, o := range rewound {
+		ks.offsets[p] = o
+	}
+	ks.mu.Unlock()
+	return nil
+}
+
+// Ack commits the next offsets of the partitions which advance since the last acknowledgement to the consumer group.
+// Nothing is committed in partition mode.
+func (ks *KafkaSource) Ack(offset interface{}) error {
+	if ks.config.GroupId == "" {
+		return nil
+	}
+	offsets, err := parseKafkaOffsets(offset)
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	r := ks.reader
+	msgs := make([]kafka.Message, 0, len(offsets))
+	for p, o := range offsets {
+		if last, ok := ks.acked[p]; ok && o <= last {
+			continue
+		}
+		// The offset of the message is committed plus one
+		msgs = append(msgs, kafka.Message{Topic: ks.topic, Partition: p, Offset: o - 1})
+	}
+	ks.mu.Unlock()
+	if r == nil || len(msgs) == 0 {
+		return nil
+	}
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].Partition < msgs[j].Partition })
+	if err := r.CommitMessages(context.Background(), msgs...); err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	for _, m := range msgs {
+		if m.Offset+1 > ks.acked[m.Partition] {
+			ks.acked[m.Partition] = m.Offset + 1
+		}
+	}
+	ks.mu.Unlock()
+	return nil
+}
+
+// parseKafkaOffsets parses the offsets keyed by the partition
+func parseKafkaOffsets(offset interface{}) (map[int]int64, error) {
+	m, ok := offset.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid offset %v", offset)
+	}
+	result := make(map[int]int64, len(m))
+	for k, v := range m {
+		p, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition %s", k)
+		}
+		o, err := cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset %v of partition %d", v, p)
+		}
+		result[p] = o
+	}
+	return result, nil
+}
+
+func (ks *KafkaSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing kafka source")
+	ks.mu.Lock()
+	r := ks.reader
+	ks.mu.Unlock()
+	if r != nil {
+		return r.Close()
+	}
+	return nil
+}
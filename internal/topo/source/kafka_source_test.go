@@ -0,0 +1,267 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	gocontext "context"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/segmentio/kafka-go"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKafkaConfigure(t *testing.T) {
+	var tests = []struct {
+		topic string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			topic: "t",
+			props: map[string]interface{}{},
+			err:   "missing property brokers",
+		}, {
+			topic: "",
+			props: map[string]interface{}{"brokers": "localhost:9092"},
+			err:   "topic must be specified",
+		}, {
+			topic: "t",
+			props: map[string]interface{}{"brokers": "localhost:9092", "saslAuthType": "plain"},
+			err:   "saslUserName and saslPassword are required for sasl auth type plain",
+		}, {
+			topic: "t",
+			props: map[string]interface{}{"brokers": "localhost:9092", "saslAuthType": "kerberos"},
+			err:   "invalid saslAuthType kerberos, must be none, plain, scram-sha-256 or scram-sha-512",
+		}, {
+			topic: "t",
+			props: map[string]interface{}{"brokers": "localhost:9092", "certificationPath": "cert.pem"},
+			err:   "certificationPath and privateKeyPath must be set together",
+		}, {
+			topic: "t",
+			props: map[string]interface{}{"brokers": "localhost:9092", "startOffset": "middle"},
+			err:   "invalid startOffset middle, must be earliest or latest",
+		}, {
+			topic: "t",
+			props: map[string]interface{}{"brokers": "localhost:9092", "partition": -1},
+			err:   "invalid partition -1, must not be negative",
+		}, {
+			topic: "t",
+			props: map[string]interface{}{"brokers": "localhost:9092", "commitInterval": 0},
+			err:   "invalid commitInterval 0, must be positive",
+		}, {
+			topic: "t",
+			props: map[string]interface{}{"brokers": "localhost:9092,localhost:9093", "groupId": "g1", "saslAuthType": "scram-sha-512", "saslUserName": "u", "saslPassword": "p"},
+		},
+	}
+	for i, tt := range tests {
+		s := &KafkaSource{}
+		err := s.Configure(tt.topic, tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+type mockKafkaReader struct {
+	sync.Mutex
+	msgs      []kafka.Message
+	committed []kafka.Message
+	offset    int64
+}
+
+func (r *mockKafkaReader) FetchMessage(ctx gocontext.Context) (kafka.Message, error) {
+	r.Lock()
+	if len(r.msgs) > 0 {
+		m := r.msgs[0]
+		r.msgs = r.msgs[1:]
+		r.Unlock()
+		return m, nil
+	}
+	r.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *mockKafkaReader) CommitMessages(_ gocontext.Context, msgs ...kafka.Message) error {
+	r.Lock()
+	defer r.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *mockKafkaReader) SetOffset(offset int64) error {
+	r.offset = offset
+	return nil
+}
+
+func (r *mockKafkaReader) Close() error {
+	return nil
+}
+
+func TestKafkaSourceGroup(t *testing.T) {
+	ts := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	msgs := []kafka.Message{
+		{Topic: "t", Partition: 0, Offset: 10, Key: []byte("k1"), Value: []byte(`{"a":1}`), Time: ts, Headers: []kafka.Header{{Key: "h", Value: []byte("v")}}},
+		{Topic: "t", Partition: 1, Offset: 5, Value: []byte(`invalid`), Time: ts},
+		{Topic: "t", Partition: 0, Offset: 11, Value: []byte(`{"a":2}`), Time: ts},
+	}
+	reader := &mockKafkaReader{msgs: msgs}
+	var rewound map[int]int64
+	s := &KafkaSource{
+		newReader: func() (kafkaReader, error) { return reader, nil },
+		commitGroup: func(_ gocontext.Context, offsets map[int]int64) error {
+			rewound = offsets
+			return nil
+		},
+	}
+	if err := s.Configure("t", map[string]interface{}{"brokers": "localhost:9092", "groupId": "g1"}); err != nil {
+		t.Fatal(err)
+	}
+	// Partition 2 is assigned to another member after the rule restarts
+	if err := s.Rewind(map[string]interface{}{"0": int64(10), "1": int64(3), "2": int64(7)}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	go s.Open(ctx, consumer, errCh)
+	exps := []api.SourceTuple{
+		api.NewDefaultSourceTuple(map[string]interface{}{"a": 1.0}, map[string]interface{}{
+			"topic": "t", "partition": 0, "offset": int64(10), "key": "k1", "headers": map[string]interface{}{"h": "v"}, "timestamp": int64(1635724800000),
+		}),
+		api.NewDefaultSourceTuple(map[string]interface{}{"a": 2.0}, map[string]interface{}{
+			"topic": "t", "partition": 0, "offset": int64(11), "key": "", "headers": map[string]interface{}{}, "timestamp": int64(1635724800000),
+		}),
+	}
+	// The invalid message of partition 1 is covered by the offset of the next message
+	expOffsets := []interface{}{
+		map[string]interface{}{"0": int64(11), "1": int64(3), "2": int64(7)},
+		map[string]interface{}{"0": int64(12), "1": int64(6), "2": int64(7)},
+	}
+	for i, exp := range exps {
+		select {
+		case r := <-consumer:
+			if !reflect.DeepEqual(exp.Message(), r.Message()) || !reflect.DeepEqual(exp.Meta(), r.Meta()) {
+				t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, exp, r)
+			}
+			if ot, ok := r.(api.OffsetTuple); !ok {
+				t.Errorf("%d: tuple should carry the offset", i)
+			} else if !reflect.DeepEqual(expOffsets[i], ot.Offset()) {
+				t.Errorf("%d: offset mismatch, got %v", i, ot.Offset())
+			}
+		case err := <-errCh:
+			t.Fatal(err)
+		case <-time.After(time.Second):
+			t.Fatal("no message received")
+		}
+	}
+	// Nothing is committed until acknowledged
+	reader.Lock()
+	if len(reader.committed) != 0 {
+		t.Errorf("should not commit before ack, got %v", reader.committed)
+	}
+	reader.Unlock()
+	if err := s.Ack(expOffsets[1]); err != nil {
+		t.Fatal(err)
+	}
+	// The partitions which do not advance are not committed again
+	if err := s.Ack(expOffsets[0]); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the last offset to be recorded
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	_ = s.Close(ctx)
+	if !reflect.DeepEqual(map[int]int64{0: 10, 1: 3, 2: 7}, rewound) {
+		t.Errorf("rewind offsets mismatch, got %v", rewound)
+	}
+	offset, _ := s.GetOffset()
+	if !reflect.DeepEqual(map[string]interface{}{"0": int64(12), "1": int64(6), "2": int64(7)}, offset) {
+		t.Errorf("offset mismatch, got %v", offset)
+	}
+	reader.Lock()
+	defer reader.Unlock()
+	if !reflect.DeepEqual([]kafka.Message{{Topic: "t", Partition: 0, Offset: 11}, {Topic: "t", Partition: 1, Offset: 5}}, reader.committed) {
+		t.Errorf("committed messages mismatch, got %v", reader.committed)
+	}
+}
+
+func TestKafkaSourcePartition(t *testing.T) {
+	reader := &mockKafkaReader{msgs: []kafka.Message{{Topic: "t", Partition: 2, Offset: 7, Value: []byte(`{"a":1}`)}}}
+	s := &KafkaSource{
+		newReader: func() (kafkaReader, error) { return reader, nil },
+		commitGroup: func(_ gocontext.Context, _ map[int]int64) error {
+			t.Error("commit group in partition mode")
+			return nil
+		},
+	}
+	if err := s.Configure("t", map[string]interface{}{"brokers": "localhost:9092", "partition": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rewind(map[string]interface{}{"2": int64(7)}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	go s.Open(ctx, consumer, errCh)
+	select {
+	case r := <-consumer:
+		if !reflect.DeepEqual(map[string]interface{}{"a": 1.0}, r.Message()) {
+			t.Errorf("result mismatch, got %v", r.Message())
+		}
+	case err := <-errCh:
+		t.Fatal(err)
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+	}
+	if err := s.Ack(map[string]interface{}{"2": int64(8)}); err != nil {
+		t.Fatal(err)
+	}
+	if reader.offset != 7 {
+		t.Errorf("rewind offset mismatch, got %d", reader.offset)
+	}
+	reader.Lock()
+	defer reader.Unlock()
+	if len(reader.committed) != 0 {
+		t.Errorf("should not commit in partition mode, got %v", reader.committed)
+	}
+}
+
+func TestKafkaRewindError(t *testing.T) {
+	s := &KafkaSource{}
+	if err := s.Configure("t", map[string]interface{}{"brokers": "localhost:9092"}); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		offset interface{}
+		err    string
+	}{
+		{offset: 1, err: "kafka source fails to rewind: invalid offset 1"},
+		{offset: map[string]interface{}{"a": int64(1)}, err: "kafka source fails to rewind: invalid partition a"},
+		{offset: map[string]interface{}{"0": "a"}, err: "kafka source fails to rewind: invalid offset a of partition 0"},
+	}
+	for i, tt := range tests {
+		err := s.Rewind(tt.offset)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
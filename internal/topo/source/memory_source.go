@@ -0,0 +1,103 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/topo/memory"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"sync"
+)
+
+const DEFAULT_MEMORY_BUFFER_LENGTH = 1024
+
+type MemorySourceConfig struct {
+	// The number of the messages queued for the source before the publishers are blocked
+	BufferLength int `json:"bufferLength"`
+}
+
+// MemorySource subscribes to the topics of the in memory bus which the memory sinks of the other rules publish to.
+// The data source is the topic filter which supports the "+" and "#" wildcards.
+type MemorySource struct {
+	topic  string
+	config *MemorySourceConfig
+
+	// The subscription is set by Open which runs in another goroutine and released by Open or Close
+	mu  sync.Mutex
+	sub *memory.Subscription
+}
+
+func (ms *MemorySource) Configure(datasource string, props map[string]interface{}) error {
+	cfg := &MemorySourceConfig{
+		BufferLength: DEFAULT_MEMORY_BUFFER_LENGTH,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if cfg.BufferLength < 0 {
+		return fmt.Errorf("invalid bufferLength %d, must not be negative", cfg.BufferLength)
+	}
+	if err := memory.ValidateFilter(datasource); err != nil {
+		return err
+	}
+	ms.topic = datasource
+	ms.config = cfg
+	return nil
+}
+
+func (ms *MemorySource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	sub, err := memory.Subscribe(ms.topic, ms.config.BufferLength)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	ms.mu.Lock()
+	ms.sub = sub
+	ms.mu.Unlock()
+	// Release the subscription even if the source is closed before subscribing
+	defer ms.unsubscribe()
+	logger.Infof("Memory source subscribes to topic %s", ms.topic)
+	for {
+		select {
+		case msg := <-sub.Messages():
+			select {
+			case consumer <- api.NewDefaultSourceTuple(msg.Data, map[string]interface{}{"topic": msg.Topic}):
+				logger.Debugf("send data to device node")
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (ms *MemorySource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing memory source")
+	ms.unsubscribe()
+	return nil
+}
+
+func (ms *MemorySource) unsubscribe() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.sub != nil {
+		memory.Unsubscribe(ms.sub)
+		ms.sub = nil
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/memory"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryConfigure(t *testing.T) {
+	var tests = []struct {
+		datasource string
+		props      map[string]interface{}
+		err        string
+	}{
+		{
+			datasource: "",
+			props:      map[string]interface{}{},
+			err:        "topic must not be empty",
+		}, {
+			datasource: "devices/#/temp",
+			props:      map[string]interface{}{},
+			err:        "invalid topic devices/#/temp, wildcard # must be the last level",
+		}, {
+			datasource: "devices/+/temp",
+			props:      map[string]interface{}{"bufferLength": -1},
+			err:        "invalid bufferLength -1, must not be negative",
+		}, {
+			datasource: "devices/+/temp",
+			props:      map[string]interface{}{"bufferLength": 10},
+		},
+	}
+	for i, tt := range tests {
+		s := &MemorySource{}
+		err := s.Configure(tt.datasource, tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestMemorySourceOpen(t *testing.T) {
+	s := &MemorySource{}
+	if err := s.Configure("devices/+/temp", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	go s.Open(ctx, consumer, errCh)
+	defer s.Close(ctx)
+	// Wait for the subscription
+	time.Sleep(100 * time.Millisecond)
+
+	for i, topic := range []string{"devices/d1/temp", "devices/d1/humidity", "devices/d2/temp"} {
+		if err := memory.Publish(ctx, topic, map[string]interface{}{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exps := []api.SourceTuple{
+		api.NewDefaultSourceTuple(map[string]interface{}{"i": 0}, map[string]interface{}{"topic": "devices/d1/temp"}),
+		api.NewDefaultSourceTuple(map[string]interface{}{"i": 2}, map[string]interface{}{"topic": "devices/d2/temp"}),
+	}
+	for _, exp := range exps {
+		select {
+		case r := <-consumer:
+			if !reflect.DeepEqual(exp, r) {
+				t.Errorf("result mismatch:\n  exp=%v\n  got=%v\n\n", exp, r)
+			}
+		case err := <-errCh:
+			t.Fatal(err)
+		case <-time.After(time.Second):
+			t.Fatal("no message received")
+		}
+	}
+}
+
+func TestMemorySourceCloseBeforeOpen(t *testing.T) {
+	s := &MemorySource{}
+	if err := s.Configure("close/test", map[string]interface{}{"bufferLength": 0}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	consumer := make(chan api.SourceTuple)
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		s.Open(ctx, consumer, errCh)
+		close(done)
+	}()
+	// The rule stops while the source may be subscribing
+	_ = s.Close(ctx)
+	cancel()
+	<-done
+	// A leaked subscription without buffer would block the publisher
+	published := make(chan error, 1)
+	go func() {
+		published <- memory.Publish(context.Background(), "close/test", map[string]interface{}{"a": 1})
+	}()
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the subscription is not released")
+	}
+}
@@ -15,18 +15,24 @@
 package source
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
 	MQTT "github.com/eclipse/paho.mqtt.golang"
 	"github.com/google/uuid"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/pkg/mqttx"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/message"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type MQTTSource struct {
@@ -35,14 +41,18 @@ type MQTTSource struct {
 	tpc      string
 	clientid string
 	pVersion uint
+	qos      byte
 	uName    string
 	password string
 	certPath string
 	pkeyPath string
 
-	model  modelVersion
-	schema map[string]interface{}
-	conn   MQTT.Client
+	model     modelVersion
+	schema    map[string]interface{}
+	conn      MQTT.Client
+	converter message.Converter
+	// The MQTT 5 connection
+	cm *autopaho.ConnectionManager
 }
 
 type MQTTConfig struct {
@@ -77,12 +87,22 @@ func (ms *MQTTSource) Configure(topic string, props map[string]interface{}) erro
 	}
 
 	ms.format = cfg.Format
+	ms.converter, err = converter.GetOrCreateConverter(props)
+	if err != nil {
+		return err
+	}
 	ms.clientid = cfg.Clientid
 
 	ms.pVersion = 3
 	if cfg.PVersion == "3.1.1" {
 		ms.pVersion = 4
+	} else if cfg.PVersion == "5" {
+		ms.pVersion = 5
+	}
+	if cfg.Qos < 0 || cfg.Qos > 2 {
+		return fmt.Errorf("not valid qos value %v, the value could be only int 0 or 1 or 2", cfg.Qos)
 	}
+	ms.qos = byte(cfg.Qos)
 
 	ms.uName = cfg.Uname
 	ms.password = strings.Trim(cfg.Password, " ")
@@ -104,6 +124,10 @@ func (ms *MQTTSource) Configure(topic string, props map[string]interface{}) erro
 }
 
 func (ms *MQTTSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	if ms.pVersion == 5 {
+		ms.openV5(ctx, consumer, errCh)
+		return
+	}
 	log := ctx.GetLogger()
 
 	opts := MQTT.NewClientOptions().AddBroker(ms.srv).SetProtocolVersion(ms.pVersion)
@@ -154,13 +178,13 @@ func (ms *MQTTSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTupl
 	opts.SetConnectionLostHandler(func(client MQTT.Client, e error) {
 		log.Errorf("The connection %s is disconnected due to error %s, will try to re-connect later.", ms.srv+": "+ms.clientid, e)
 		reconn = true
-		subscribe(ms.tpc, client, ctx, consumer, ms.model, ms.format)
+		subscribe(ms.tpc, ms.qos, client, ctx, consumer, ms.model, ms.format, ms.converter)
 	})
 
 	opts.SetOnConnectHandler(func(client MQTT.Client) {
 		if reconn {
 			log.Infof("The connection is %s re-established successfully.", ms.srv+": "+ms.clientid)
-			subscribe(ms.tpc, client, ctx, consumer, ms.model, ms.format)
+			subscribe(ms.tpc, ms.qos, client, ctx, consumer, ms.model, ms.format, ms.converter)
 		}
 	})
 
@@ -170,45 +194,125 @@ func (ms *MQTTSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTupl
 	}
 	log.Infof("The connection to server %s was established successfully", ms.srv)
 	ms.conn = c
-	subscribe(ms.tpc, c, ctx, consumer, ms.model, ms.format)
+	subscribe(ms.tpc, ms.qos, c, ctx, consumer, ms.model, ms.format, ms.converter)
 	log.Infof("Successfully subscribe to topic %s", ms.srv+": "+ms.clientid)
 }
 
-func subscribe(topic string, client MQTT.Client, ctx api.StreamContext, consumer chan<- api.SourceTuple, model modelVersion, format string) {
+func subscribe(topic string, qos byte, client MQTT.Client, ctx api.StreamContext, consumer chan<- api.SourceTuple, model modelVersion, format string, c message.Converter) {
 	log := ctx.GetLogger()
 	h := func(client MQTT.Client, msg MQTT.Message) {
-		log.Debugf("instance %d received %s", ctx.GetInstanceId(), msg.Payload())
-		result, e := message.Decode(msg.Payload(), format)
-		//The unmarshal type can only be bool, float64, string, []interface{}, map[string]interface{}, nil
-		if e != nil {
-			log.Errorf("Invalid data format, cannot decode %s to %s format with error %s", string(msg.Payload()), format, e)
-			return
-		}
-
 		meta := make(map[string]interface{})
 		meta["topic"] = msg.Topic()
 		meta["messageid"] = strconv.Itoa(int(msg.MessageID()))
+		sendMQTTMessage(ctx, consumer, model, format, c, msg.Payload(), meta)
+	}
 
-		if nil != model {
-			sliErr := model.checkType(result, msg.Topic())
-			for _, v := range sliErr {
-				log.Errorf(v)
-			}
+	if token := client.Subscribe(topic, qos, h); token.Wait() && token.Error() != nil {
+		log.Errorf("Found error: %s", token.Error())
+	} else {
+		log.Infof("Successfully subscribe to topic %s", topic)
+	}
+}
+
+// sendMQTTMessage decodes the payload and sends it to the source node
+func sendMQTTMessage(ctx api.StreamContext, consumer chan<- api.SourceTuple, model modelVersion, format string, c message.Converter, payload []byte, meta map[string]interface{}) {
+	log := ctx.GetLogger()
+	log.Debugf("instance %d received %s", ctx.GetInstanceId(), payload)
+	result, e := c.Decode(payload)
+	//The unmarshal type can only be bool, float64, string, []interface{}, map[string]interface{}, nil
+	if e != nil {
+		log.Errorf("Invalid data format, cannot decode %s to %s format with error %s", string(payload), format, e)
+		return
+	}
+
+	if nil != model {
+		sliErr := model.checkType(result, meta["topic"].(string))
+		for _, v := range sliErr {
+			log.Errorf(v)
 		}
+	}
+
+	select {
+	case consumer <- api.NewDefaultSourceTuple(result, meta):
+		log.Debugf("send data to source node")
+	case <-ctx.Done():
+		return
+	}
+}
 
-		select {
-		case consumer <- api.NewDefaultSourceTuple(result, meta):
-			log.Debugf("send data to source node")
-		case <-ctx.Done():
+// openV5 connects with MQTT 5 and subscribes again after each reconnection
+func (ms *MQTTSource) openV5(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	log := ctx.GetLogger()
+	if ms.clientid == "" {
+		if uuid, err := uuid.NewUUID(); err != nil {
+			errCh <- fmt.Errorf("failed to get uuid, the error is %s", err)
 			return
+		} else {
+			ms.clientid = uuid.String()
 		}
 	}
+	tlsCfg, err := mqttx.LoadTLS(ms.certPath, ms.pkeyPath, false)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	cm, err := mqttx.ConnectV5(ctx, &mqttx.V5Config{
+		Server:   ms.srv,
+		ClientId: ms.clientid,
+		Username: ms.uName,
+		Password: ms.password,
+		TLS:      tlsCfg,
+		OnConnectionUp: func(cm *autopaho.ConnectionManager, _ *paho.Connack) {
+			sub := &paho.Subscribe{Subscriptions: map[string]paho.SubscribeOptions{ms.tpc: {QoS: ms.qos}}}
+			if _, err := cm.Subscribe(ctx, sub); err != nil {
+				log.Errorf("Found error: %s", err)
+			} else {
+				log.Infof("Successfully subscribe to topic %s", ms.tpc)
+			}
+		},
+		OnPublish: func(p *paho.Publish) {
+			sendMQTTMessage(ctx, consumer, ms.model, ms.format, ms.converter, p.Payload, metaV5(p))
+		},
+	}, log)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	ms.cm = cm
+}
 
-	if token := client.Subscribe(topic, 0, h); token.Wait() && token.Error() != nil {
-		log.Errorf("Found error: %s", token.Error())
-	} else {
-		log.Infof("Successfully subscribe to topic %s", topic)
+// metaV5 exposes the properties of the MQTT 5 message. The user properties are a map whose repeated keys
+// have their values joined by comma, so that they can be read like meta(userProperties->key).
+func metaV5(p *paho.Publish) map[string]interface{} {
+	meta := map[string]interface{}{
+		"topic":     p.Topic,
+		"messageid": strconv.Itoa(int(p.PacketID)),
+		"qos":       int(p.QoS),
 	}
+	up := make(map[string]interface{})
+	if p.Properties != nil {
+		for _, u := range p.Properties.User {
+			if v, ok := up[u.Key]; ok {
+				up[u.Key] = v.(string) + "," + u.Value
+			} else {
+				up[u.Key] = u.Value
+			}
+		}
+		if p.Properties.ResponseTopic != "" {
+			meta["responseTopic"] = p.Properties.ResponseTopic
+		}
+		if p.Properties.CorrelationData != nil {
+			meta["correlationData"] = string(p.Properties.CorrelationData)
+		}
+		if p.Properties.ContentType != "" {
+			meta["contentType"] = p.Properties.ContentType
+		}
+		if p.Properties.MessageExpiry != nil {
+			meta["messageExpiry"] = int(*p.Properties.MessageExpiry)
+		}
+	}
+	meta["userProperties"] = up
+	return meta
 }
 
 func (ms *MQTTSource) Close(ctx api.StreamContext) error {
@@ -216,5 +320,10 @@ func (ms *MQTTSource) Close(ctx api.StreamContext) error {
 	if ms.conn != nil && ms.conn.IsConnected() {
 		ms.conn.Disconnect(5000)
 	}
+	if ms.cm != nil {
+		dctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = ms.cm.Disconnect(dctx)
+	}
 	return nil
 }
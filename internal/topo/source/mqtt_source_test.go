@@ -0,0 +1,128 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/lf-edge/ekuiper/internal/pkg/mqttx/mqttxtest"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetaV5(t *testing.T) {
+	expiry := uint32(30)
+	var tests = []struct {
+		p    *paho.Publish
+		meta map[string]interface{}
+	}{
+		{
+			p: &paho.Publish{Topic: "a", PacketID: 1, QoS: 1},
+			meta: map[string]interface{}{
+				"topic":          "a",
+				"messageid":      "1",
+				"qos":            1,
+				"userProperties": map[string]interface{}{},
+			},
+		}, {
+			p: &paho.Publish{Topic: "b", Properties: &paho.PublishProperties{
+				ResponseTopic:   "reply/b",
+				CorrelationData: []byte("req1"),
+				ContentType:     "application/json",
+				MessageExpiry:   &expiry,
+				User:            paho.UserProperties{{Key: "device", Value: "d1"}, {Key: "tag", Value: "x"}, {Key: "tag", Value: "y"}},
+			}},
+			meta: map[string]interface{}{
+				"topic":           "b",
+				"messageid":       "0",
+				"qos":             0,
+				"responseTopic":   "reply/b",
+				"correlationData": "req1",
+				"contentType":     "application/json",
+				"messageExpiry":   30,
+				"userProperties":  map[string]interface{}{"device": "d1", "tag": "x,y"},
+			},
+		},
+	}
+	for i, tt := range tests {
+		meta := metaV5(tt.p)
+		if !reflect.DeepEqual(tt.meta, meta) {
+			t.Errorf("%d: meta mismatch:\n  exp=%v\n  got=%v\n\n", i, tt.meta, meta)
+		}
+	}
+}
+
+func TestMQTTSourceV5Shared(t *testing.T) {
+	b, err := mqttxtest.NewBroker(t, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	topic := "$share/g1/devices/+"
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 2)
+	// Two rule instances share the subscription
+	for i := 0; i < 2; i++ {
+		s := &MQTTSource{}
+		if err := s.Configure(topic, map[string]interface{}{"servers": []string{b.URL()}, "protocolVersion": "5", "qos": 1}); err != nil {
+			t.Fatal(err)
+		}
+		s.Open(ctx, consumer, errCh)
+		defer s.Close(ctx)
+	}
+	for i := 0; b.Subscribers(topic) < 2; i++ {
+		select {
+		case err := <-errCh:
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+		if i > 100 {
+			t.Fatal("mqtt source fails to subscribe")
+		}
+	}
+	for i := 0; i < 4; i++ {
+		b.Publish(&packets.Publish{Topic: "devices/d1", Payload: []byte(`{"temperature":20}`), Properties: &packets.Properties{
+			User: []packets.User{{Key: "seq", Value: string(rune('0' + i))}},
+		}})
+	}
+	seqs := make(map[interface{}]bool)
+	for i := 0; i < 4; i++ {
+		select {
+		case r := <-consumer:
+			if !reflect.DeepEqual(map[string]interface{}{"temperature": float64(20)}, r.Message()) {
+				t.Errorf("message mismatch, got %v", r.Message())
+			}
+			if r.Meta()["topic"] != "devices/d1" {
+				t.Errorf("topic mismatch, got %v", r.Meta()["topic"])
+			}
+			seqs[r.Meta()["userProperties"].(map[string]interface{})["seq"]] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only received %d of 4 messages", i)
+		}
+	}
+	select {
+	case r := <-consumer:
+		t.Fatalf("unexpected message %v", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if len(seqs) != 4 {
+		t.Errorf("duplicate messages received, got %v", seqs)
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/pkg/natsx"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"github.com/nats-io/nats.go"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsDeliverAll  = "all"
+	natsDeliverNew  = "new"
+	natsDeliverLast = "last"
+)
+
+type NatsSourceConfig struct {
+	natsx.ConnConfig
+	// The queue group to join. The messages are distributed among the members of the group
+	Queue string `json:"queue"`
+	// Whether to consume by a jetstream consumer
+	JetStream bool `json:"jetStream"`
+	// The stream to bind. If not set, the stream is looked up by the subject
+	Stream string `json:"stream"`
+	// The name of the durable consumer. If not set, an ephemeral consumer is created
+	Durable string `json:"durable"`
+	// Where to start when the consumer is created, all, new or last
+	DeliverPolicy string `json:"deliverPolicy"`
+	// The time to wait for the acknowledgement before redelivery, time unit is ms
+	AckWait int `json:"ackWait"`
+	// The maximum number of the messages delivered but not acknowledged
+	MaxAckPending int `json:"maxAckPending"`
+	// The size of the buffer to receive the messages
+	BufferLength int `json:"bufferLength"`
+}
+
+// natsTuple carries the stream sequence of a jetstream message as the offset
+type natsTuple struct {
+	*api.DefaultSourceTuple
+	seq int64
+}
+
+func (t *natsTuple) Offset() interface{} {
+	return t.seq
+}
+
+// NatsSource subscribes a subject which may contain wildcards. In jetstream mode, the messages are acknowledged once
+// they are processed, or once the checkpoint including them completes if the rule runs with at least once qos.
+// The stream sequence of the last processed message is the offset.
+type NatsSource struct {
+	subject   string
+	config    *NatsSourceConfig
+	converter message.Converter
+
+	mu   sync.Mutex
+	conn *nats.Conn
+	sub  *nats.Subscription
+	// The jetstream messages sent to the rule but not acknowledged yet, in the order of delivery
+	pending []*nats.Msg
+	seq     int64
+	rewound int64
+}
+
+func (ns *NatsSource) Configure(subject string, props map[string]interface{}) error {
+	cfg := &NatsSourceConfig{
+		DeliverPolicy: natsDeliverAll,
+		// Longer than the default checkpoint interval so that the messages are not redelivered before it completes
+		AckWait:       600000,
+		MaxAckPending: 1000,
+		BufferLength:  1024,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if subject == "" {
+		return errors.New("subject must be specified")
+	}
+	if !cfg.JetStream && (cfg.Stream != "" || cfg.Durable != "") {
+		return errors.New("stream and durable can only be set in jetstream mode")
+	}
+	if cfg.DeliverPolicy != natsDeliverAll && cfg.DeliverPolicy != natsDeliverNew && cfg.DeliverPolicy != natsDeliverLast {
+		return fmt.Errorf("invalid deliverPolicy %s, must be all, new or last", cfg.DeliverPolicy)
+	}
+	if cfg.AckWait <= 0 {
+		return fmt.Errorf("invalid ackWait %d, must be positive", cfg.AckWait)
+	}
+	if cfg.MaxAckPending <= 0 {
+		return fmt.Errorf("invalid maxAckPending %d, must be positive", cfg.MaxAckPending)
+	}
+	if cfg.BufferLength <= 0 {
+		return fmt.Errorf("invalid bufferLength %d, must be positive", cfg.BufferLength)
+	}
+	if ns.converter, err = converter.GetOrCreateConverter(props); err != nil {
+		return err
+	}
+	ns.subject = subject
+	ns.config = cfg
+	return nil
+}
+
+func (ns *NatsSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	conn, err := ns.config.Connect(fmt.Sprintf("ekuiper-%s-%s", ctx.GetRuleId(), ctx.GetOpId()))
+	if err != nil {
+		errCh <- fmt.Errorf("nats source fails to connect to %s: %v", ns.config.Server, err)
+		return
+	}
+	defer conn.Close()
+	ch := make(chan *nats.Msg, ns.config.BufferLength)
+	var sub *nats.Subscription
+	if ns.config.JetStream {
+		sub, err = ns.subscribeJetStream(ctx, conn, ch)
+	} else if ns.config.Queue != "" {
+		sub, err = conn.ChanQueueSubscribe(ns.subject, ns.config.Queue, ch)
+	} else {
+		sub, err = conn.ChanSubscribe(ns.subject, ch)
+	}
+	if err == nil {
+		err = conn.Flush()
+	}
+	if err != nil {
+		errCh <- fmt.Errorf("nats source fails to subscribe %s: %v", ns.subject, err)
+		return
+	}
+	ns.mu.Lock()
+	ns.conn = conn
+	ns.sub = sub
+	ns.mu.Unlock()
+	logger.Infof("Nats source starts to subscribe %s", ns.subject)
+	for {
+		select {
+		case msg := <-ch:
+			result, err := ns.converter.Decode(msg.Data)
+			if err != nil {
+				logger.Errorf("Invalid data format, cannot decode %s with error %s", string(msg.Data), err)
+				// Do not redeliver the invalid message
+				if ns.config.JetStream {
+					_ = msg.Term()
+				}
+				continue
+			}
+			t := api.NewDefaultSourceTuple(result, ns.meta(msg))
+			var tuple api.SourceTuple = t
+			if ns.config.JetStream {
+				nt := &natsTuple{DefaultSourceTuple: t}
+				if md, err := msg.Metadata(); err == nil {
+					nt.seq = int64(md.Sequence.Stream)
+				}
+				ns.mu.Lock()
+				ns.pending = append(ns.pending, msg)
+				ns.seq = nt.seq
+				ns.mu.Unlock()
+				tuple = nt
+			}
+			select {
+			case consumer <- tuple:
+				logger.Debugf("send data to device node")
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (ns *NatsSource) subscribeJetStream(ctx api.StreamContext, conn *nats.Conn, ch chan *nats.Msg) (*nats.Subscription, error) {
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, err
+	}
+	opts := []nats.SubOpt{
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.AckWait(time.Duration(ns.config.AckWait) * time.Millisecond),
+		nats.MaxAckPending(ns.config.MaxAckPending),
+	}
+	if ns.config.Stream != "" {
+		opts = append(opts, nats.BindStream(ns.config.Stream))
+	}
+	if ns.config.Durable != "" {
+		// The server redelivers the messages not acknowledged by the durable consumer
+		opts = append(opts, nats.Durable(ns.config.Durable))
+	}
+	ns.mu.Lock()
+	rewound := ns.rewound
+	ns.mu.Unlock()
+	switch {
+	case rewound > 0 && ns.config.Durable == "":
+		ctx.GetLogger().Infof("Nats source rewinds to stream sequence %d", rewound+1)
+		opts = append(opts, nats.StartSequence(uint64(rewound+1)))
+	case ns.config.DeliverPolicy == natsDeliverNew:
+		opts = append(opts, nats.DeliverNew())
+	case ns.config.DeliverPolicy == natsDeliverLast:
+		opts = append(opts, nats.DeliverLast())
+	default:
+		opts = append(opts, nats.DeliverAll())
+	}
+	if ns.config.Queue != "" {
+		return js.ChanQueueSubscribe(ns.subject, ns.config.Queue, ch, opts...)
+	}
+	return js.ChanSubscribe(ns.subject, ch, opts...)
+}
+
+func (ns *NatsSource) meta(msg *nats.Msg) map[string]interface{} {
+	headers := make(map[string]interface{}, len(msg.Header))
+	for k, v := range msg.Header {
+		headers[k] = strings.Join(v, ",")
+	}
+	result := map[string]interface{}{
+		"subject": msg.Subject,
+		"headers": headers,
+	}
+	if ns.config.JetStream {
+		if md, err := msg.Metadata(); err == nil {
+			result["stream"] = md.Stream
+			result["sequence"] = int64(md.Sequence.Stream)
+			result["delivered"] = int64(md.NumDelivered)
+			result["timestamp"] = md.Timestamp.UnixNano() / int64(time.Millisecond)
+		}
+	}
+	return result
+}
+
+// GetOffset returns the stream sequence of the last jetstream message sent to the rule
+func (ns *NatsSource) GetOffset() (interface{}, error) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.seq, nil
+}
+
+// Rewind starts an ephemeral jetstream consumer after the stream sequence. The durable consumer continues from
+// the messages not acknowledged which are redelivered by the server.
+func (ns *NatsSource) Rewind(offset interface{}) error {
+	seq, err := cast.ToInt64(offset, cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return fmt.Errorf("nats source fails to rewind: invalid offset %v", offset)
+	}
+	ns.mu.Lock()
+	ns.rewound = seq
+	ns.seq = seq
+	ns.mu.Unlock()
+	return nil
+}
+
+// Ack acknowledges the pending jetstream messages up to the last one with the stream sequence. The offsets not
+// found, such as the offset restored from the previous run, are ignored.
+func (ns *NatsSource) Ack(offset interface{}) error {
+	seq, err := cast.ToInt64(offset, cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return fmt.Errorf("invalid offset %v", offset)
+	}
+	ns.mu.Lock()
+	var msgs []*nats.Msg
+	for i := len(ns.pending) - 1; i >= 0; i-- {
+		if md, err := ns.pending[i].Metadata(); err == nil && int64(md.Sequence.Stream) == seq {
+			msgs = ns.pending[:i+1]
+			ns.pending = ns.pending[i+1:]
+			break
+		}
+	}
+	ns.mu.Unlock()
+	for _, msg := range msgs {
+		if err := msg.Ack(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ns *NatsSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing nats source")
+	ns.mu.Lock()
+	conn := ns.conn
+	ns.mu.Unlock()
+	// Do not unsubscribe which deletes the durable consumer
+	if conn != nil {
+		conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"github.com/lf-edge/ekuiper/internal/pkg/natsx/natsxtest"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/nats-io/nats.go"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNatsConfigure(t *testing.T) {
+	var tests = []struct {
+		subject string
+		props   map[string]interface{}
+		err     string
+	}{
+		{
+			subject: "a",
+			props:   map[string]interface{}{},
+			err:     "missing property server",
+		}, {
+			subject: "",
+			props:   map[string]interface{}{"server": "nats://127.0.0.1:4222"},
+			err:     "subject must be specified",
+		}, {
+			subject: "a",
+			props:   map[string]interface{}{"server": "nats://127.0.0.1:4222", "userName": "u"},
+			err:     "userName and password must be set together",
+		}, {
+			subject: "a",
+			props:   map[string]interface{}{"server": "nats://127.0.0.1:4222", "durable": "d"},
+			err:     "stream and durable can only be set in jetstream mode",
+		}, {
+			subject: "a",
+			props:   map[string]interface{}{"server": "nats://127.0.0.1:4222", "jetStream": true, "deliverPolicy": "first"},
+			err:     "invalid deliverPolicy first, must be all, new or last",
+		}, {
+			subject: "a",
+			props:   map[string]interface{}{"server": "nats://127.0.0.1:4222", "jetStream": true, "ackWait": 0},
+			err:     "invalid ackWait 0, must be positive",
+		}, {
+			subject: "a",
+			props:   map[string]interface{}{"server": "nats://127.0.0.1:4222", "maxAckPending": -1},
+			err:     "invalid maxAckPending -1, must be positive",
+		}, {
+			subject: "a",
+			props:   map[string]interface{}{"server": "nats://127.0.0.1:4222", "bufferLength": 0},
+			err:     "invalid bufferLength 0, must be positive",
+		}, {
+			subject: "devices.>",
+			props:   map[string]interface{}{"server": "nats://127.0.0.1:4222", "jetStream": true, "stream": "s", "durable": "d", "queue": "q", "deliverPolicy": "new"},
+		},
+	}
+	for i, tt := range tests {
+		s := &NatsSource{}
+		err := s.Configure(tt.subject, tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func openNatsSource(t *testing.T, subject string, props map[string]interface{}, offset interface{}) (*NatsSource, <-chan api.SourceTuple, func()) {
+	s := &NatsSource{}
+	if err := s.Configure(subject, props); err != nil {
+		t.Fatal(err)
+	}
+	if offset != nil {
+		if err := s.Rewind(offset); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.Background().WithCancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	go s.Open(ctx, consumer, errCh)
+	// Wait for the subscription
+	for i := 0; !s.subscribed(); i++ {
+		select {
+		case err := <-errCh:
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+		if i > 100 {
+			t.Fatal("nats source fails to subscribe")
+		}
+	}
+	return s, consumer, func() {
+		cancel()
+		_ = s.Close(ctx)
+	}
+}
+
+func (ns *NatsSource) subscribed() bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.sub != nil
+}
+
+func receiveNats(t *testing.T, consumer <-chan api.SourceTuple, n int) []api.SourceTuple {
+	var result []api.SourceTuple
+	for i := 0; i < n; i++ {
+		select {
+		case r := <-consumer:
+			result = append(result, r)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only received %d of %d messages", i, n)
+		}
+	}
+	select {
+	case r := <-consumer:
+		t.Fatalf("unexpected message %v", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return result
+}
+
+func TestNatsSource(t *testing.T) {
+	srv := natsxtest.RunServer(t)
+	defer srv.Shutdown()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	_, consumer, closer := openNatsSource(t, "devices.*", map[string]interface{}{"server": srv.ClientURL()}, nil)
+	defer closer()
+	msg := nats.NewMsg("devices.a")
+	msg.Data = []byte(`{"temperature":20}`)
+	msg.Header.Add("type", "t1")
+	_ = nc.PublishMsg(msg)
+	_ = nc.Publish("devices.b", []byte(`invalid`))
+	_ = nc.Publish("devices.a.b", []byte(`{"temperature":30}`))
+	_ = nc.Publish("devices.c", []byte(`{"temperature":40}`))
+	_ = nc.Flush()
+	exps := []api.SourceTuple{
+		api.NewDefaultSourceTuple(map[string]interface{}{"temperature": 20.0}, map[string]interface{}{"subject": "devices.a", "headers": map[string]interface{}{"type": "t1"}}),
+		api.NewDefaultSourceTuple(map[string]interface{}{"temperature": 40.0}, map[string]interface{}{"subject": "devices.c", "headers": map[string]interface{}{}}),
+	}
+	result := receiveNats(t, consumer, 2)
+	if !reflect.DeepEqual(exps, result) {
+		t.Errorf("result mismatch:\n  exp=%+v\n  got=%+v\n\n", exps, result)
+	}
+}
+
+func TestNatsSourceQueue(t *testing.T) {
+	srv := natsxtest.RunServer(t)
+	defer srv.Shutdown()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	props := map[string]interface{}{"server": srv.ClientURL(), "queue": "q"}
+	_, c1, closer1 := openNatsSource(t, "devices.>", props, nil)
+	defer closer1()
+	_, c2, closer2 := openNatsSource(t, "devices.>", props, nil)
+	defer closer2()
+	for i := 0; i < 20; i++ {
+		_ = nc.Publish("devices.a", []byte(`{"temperature":20}`))
+	}
+	_ = nc.Flush()
+	total := 0
+	timeout := time.After(2 * time.Second)
+	for total < 20 {
+		select {
+		case <-c1:
+			total++
+		case <-c2:
+			total++
+		case <-timeout:
+			t.Fatalf("only received %d of 20 messages", total)
+		}
+	}
+	select {
+	case <-c1:
+		t.Error("message delivered to more than one member of the queue group")
+	case <-c2:
+		t.Error("message delivered to more than one member of the queue group")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNatsJetStream(t *testing.T) {
+	srv := natsxtest.RunServer(t)
+	defer srv.Shutdown()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "DEVICES", Subjects: []string{"devices.>"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if _, err := js.Publish("devices.a", []byte(`{"id":`+string(rune('0'+i))+`}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	props := map[string]interface{}{"server": srv.ClientURL(), "jetStream": true, "stream": "DEVICES", "durable": "rule1", "ackWait": 100}
+	s, consumer, closer := openNatsSource(t, "devices.>", props, nil)
+	result := receiveNats(t, consumer, 3)
+	for i, r := range result {
+		ot, ok := r.(api.OffsetTuple)
+		if !ok {
+			t.Fatalf("%d: tuple %v has no offset", i, r)
+		}
+		if ot.Offset() != int64(i+1) {
+			t.Errorf("%d: offset mismatch, got %v", i, ot.Offset())
+		}
+		if r.Meta()["stream"] != "DEVICES" || r.Meta()["sequence"] != int64(i+1) {
+			t.Errorf("%d: meta mismatch, got %v", i, r.Meta())
+		}
+	}
+	// Unknown offsets are ignored
+	if err := s.Ack(int64(10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ack(int64(2)); err != nil {
+		t.Fatal(err)
+	}
+	_ = nc.Flush()
+	closer()
+	info, err := js.ConsumerInfo("DEVICES", "rule1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.AckFloor.Stream != 2 || info.NumAckPending != 1 {
+		t.Errorf("ack mismatch, got ack floor %d and pending %d", info.AckFloor.Stream, info.NumAckPending)
+	}
+
+	// The durable consumer redelivers the message not acknowledged
+	_, consumer, closer = openNatsSource(t, "devices.>", props, int64(1))
+	result = receiveNats(t, consumer, 1)
+	closer()
+	if !reflect.DeepEqual(map[string]interface{}{"id": 3.0}, result[0].Message()) {
+		t.Errorf("redelivery mismatch, got %v", result[0].Message())
+	}
+
+	// The ephemeral consumer starts after the rewound offset
+	props = map[string]interface{}{"server": srv.ClientURL(), "jetStream": true}
+	_, consumer, closer = openNatsSource(t, "devices.>", props, int64(2))
+	result = receiveNats(t, consumer, 1)
+	closer()
+	if !reflect.DeepEqual(map[string]interface{}{"id": 3.0}, result[0].Message()) {
+		t.Errorf("rewind mismatch, got %v", result[0].Message())
+	}
+
+	// Deliver the new messages only
+	props = map[string]interface{}{"server": srv.ClientURL(), "jetStream": true, "deliverPolicy": "new"}
+	_, consumer, closer = openNatsSource(t, "devices.>", props, nil)
+	defer closer()
+	if _, err := js.Publish("devices.b", []byte(`{"id":4}`)); err != nil {
+		t.Fatal(err)
+	}
+	result = receiveNats(t, consumer, 1)
+	if !reflect.DeepEqual(map[string]interface{}{"id": 4.0}, result[0].Message()) {
+		t.Errorf("deliver new mismatch, got %v", result[0].Message())
+	}
+}
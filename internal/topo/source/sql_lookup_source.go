@@ -0,0 +1,99 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/pkg/sqlx"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"strings"
+)
+
+type SQLLookupConfig struct {
+	Url string `json:"url"`
+}
+
+// SQLLookupSource queries the rows of a table in the database of the url by keys
+type SQLLookupSource struct {
+	table   string
+	url     string
+	db      *sql.DB
+	dialect *sqlx.Dialect
+}
+
+func (s *SQLLookupSource) Configure(table string, props map[string]interface{}) error {
+	cfg := &SQLLookupConfig{}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if cfg.Url == "" {
+		return errors.New("missing property url")
+	}
+	if _, _, err := sqlx.ParseUrl(cfg.Url); err != nil {
+		return err
+	}
+	if table == "" {
+		return errors.New("table name must be specified")
+	}
+	s.table = table
+	s.url = cfg.Url
+	return nil
+}
+
+func (s *SQLLookupSource) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Open sql lookup source with table %s", s.table)
+	db, d, err := sqlx.Open(s.url)
+	if err != nil {
+		return err
+	}
+	s.db = db
+	s.dialect = d
+	return nil
+}
+
+func (s *SQLLookupSource) Lookup(ctx api.StreamContext, keys []string, values []interface{}) ([]api.SourceTuple, error) {
+	conditions := make([]string, len(keys))
+	for i, k := range keys {
+		conditions[i] = fmt.Sprintf("%s = %s", s.dialect.Quote(k), s.dialect.Placeholder(i+1))
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s", s.dialect.Quote(s.table), strings.Join(conditions, " AND "))
+	ctx.GetLogger().Debugf("Lookup sql with %s and values %v", query, values)
+	rows, err := s.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ms, err := sqlx.ScanRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	var result []api.SourceTuple
+	for _, m := range ms {
+		result = append(result, api.NewDefaultSourceTuple(m, nil))
+	}
+	return result, nil
+}
+
+func (s *SQLLookupSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Close sql lookup source")
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"database/sql"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestSQLLookupConfigure(t *testing.T) {
+	var tests = []struct {
+		table string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			table: "t",
+			props: map[string]interface{}{},
+			err:   "missing property url",
+		}, {
+			table: "t",
+			props: map[string]interface{}{"url": "db2://test"},
+			err:   "unsupported database db2",
+		}, {
+			table: "",
+			props: map[string]interface{}{"url": "sqlite://test.db"},
+			err:   "table name must be specified",
+		}, {
+			table: "t",
+			props: map[string]interface{}{"url": "sqlite://test.db"},
+		},
+	}
+	for i, tt := range tests {
+		s := &SQLLookupSource{}
+		err := s.Configure(tt.table, tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestSQLLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "lookup.db")
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE devices (id INTEGER, name TEXT);
+		INSERT INTO devices VALUES (1, 'd1'), (2, 'd2');`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &SQLLookupSource{}
+	if err := s.Configure("devices", map[string]interface{}{"url": "sqlite://" + file}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close(ctx)
+	r, err := s.Lookup(ctx, []string{"id"}, []interface{}{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []api.SourceTuple{
+		api.NewDefaultSourceTuple(map[string]interface{}{"id": int64(2), "name": "d2"}, nil),
+	}
+	if !reflect.DeepEqual(exp, r) {
+		t.Errorf("result mismatch:\n  exp=%v\n  got=%v", exp, r)
+	}
+}
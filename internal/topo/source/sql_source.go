@@ -0,0 +1,262 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"database/sql"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/pkg/sqlx"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"sync"
+	"time"
+)
+
+func init() {
+	// The index value of a timestamp column is saved in the state as the offset
+	gob.Register(time.Time{})
+}
+
+type SQLSourceConfig struct {
+	// The url of the database such as sqlite:///var/data/ekuiper.db
+	Url string `json:"url"`
+	// The interval to query the table, time unit is ms
+	Interval int `json:"interval"`
+	// The monotonically increasing column to read the new rows only such as an auto increment id or a timestamp
+	IndexField string `json:"indexField"`
+	// The initial value of the index field, only the rows with a larger index are read
+	IndexValue interface{} `json:"indexValue"`
+	// The maximum number of the rows of one query, 0 means no limit
+	Limit int `json:"limit"`
+	// Set by the table with RETAIN_SIZE, the EOF is not sent after each query if set
+	RetainSize int `json:"$retainSize"`
+}
+
+// SQLSource queries the table of the data source in a database periodically. If the index field is set, only the
+// rows with a larger index than the last read row are queried and the index of the last row is the offset to rewind.
+// Otherwise, all the rows of the table are read in each query followed by an EOF tuple, so that each query replaces
+// the rows of the scan table.
+type SQLSource struct {
+	table  string
+	config *SQLSourceConfig
+	db     *sql.DB
+	query  *sqlQuery
+
+	mu         sync.Mutex
+	indexValue interface{}
+}
+
+// sqlTuple carries the index value of the row as the offset
+type sqlTuple struct {
+	*api.DefaultSourceTuple
+	offset map[string]interface{}
+}
+
+func (t *sqlTuple) Offset() interface{} {
+	return t.offset
+}
+
+type sqlQuery struct {
+	// The query without index condition
+	all string
+	// The query of the rows after the index
+	after string
+}
+
+func (s *SQLSource) Configure(datasource string, props map[string]interface{}) error {
+	cfg := &SQLSourceConfig{
+		Interval: DEFAULT_INTERVAL,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if cfg.Url == "" {
+		return errors.New("missing property url")
+	}
+	d, _, err := sqlx.ParseUrl(cfg.Url)
+	if err != nil {
+		return err
+	}
+	if datasource == "" {
+		return errors.New("table name must be specified")
+	}
+	if cfg.Interval <= 0 {
+		return fmt.Errorf("invalid interval %d, must be positive", cfg.Interval)
+	}
+	if cfg.Limit < 0 {
+		return fmt.Errorf("invalid limit %d, must not be negative", cfg.Limit)
+	}
+	if cfg.IndexValue != nil && cfg.IndexField == "" {
+		return errors.New("indexValue is set without indexField")
+	}
+	s.table = datasource
+	s.config = cfg
+	s.indexValue = cfg.IndexValue
+	s.query = buildSQLQuery(d, datasource, cfg.IndexField, cfg.Limit)
+	return nil
+}
+
+func buildSQLQuery(d *sqlx.Dialect, table string, indexField string, limit int) *sqlQuery {
+	q := &sqlQuery{}
+	var (
+		order string
+		lim   string
+	)
+	if indexField != "" {
+		order = fmt.Sprintf(" ORDER BY %s ASC", d.Quote(indexField))
+	}
+	if limit > 0 {
+		lim = fmt.Sprintf(" LIMIT %d", limit)
+	}
+	from := fmt.Sprintf("SELECT * FROM %s", d.Quote(table))
+	q.all = from + order + lim
+	if indexField != "" {
+		q.after = fmt.Sprintf("%s WHERE %s > %s%s%s", from, d.Quote(indexField), d.Placeholder(1), order, lim)
+	}
+	return q
+}
+
+func (s *SQLSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	db, _, err := sqlx.Open(s.config.Url)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	s.db = db
+	logger.Infof("Sql source starts to query table %s every %d ms", s.table, s.config.Interval)
+	ticker := time.NewTicker(time.Duration(s.config.Interval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := s.read(ctx, consumer)
+			if err != nil {
+				logger.Warnf("Sql source fails to query table %s: %v", s.table, err)
+				break
+			}
+			// Read the remaining new rows at once if the limit is reached
+			if s.config.IndexField == "" || s.config.Limit == 0 || n < s.config.Limit || ctx.Err() != nil {
+				break
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// read queries the table once and sends out the rows. It returns the number of the rows.
+func (s *SQLSource) read(ctx api.StreamContext, consumer chan<- api.SourceTuple) (int, error) {
+	logger := ctx.GetLogger()
+	s.mu.Lock()
+	index := s.indexValue
+	s.mu.Unlock()
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if index == nil || s.config.IndexField == "" {
+		logger.Debugf("Sql source query %s", s.query.all)
+		rows, err = s.db.QueryContext(ctx, s.query.all)
+	} else {
+		logger.Debugf("Sql source query %s with index %v", s.query.after, index)
+		rows, err = s.db.QueryContext(ctx, s.query.after, index)
+	}
+	if err != nil {
+		return 0, err
+	}
+	result, err := sqlx.ScanRows(rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	if s.config.IndexField == "" {
+		return s.sendAll(ctx, consumer, result)
+	}
+	for _, m := range result {
+		v, ok := m[s.config.IndexField]
+		if !ok {
+			return 0, fmt.Errorf("index field %s not found", s.config.IndexField)
+		}
+		tuple := &sqlTuple{
+			DefaultSourceTuple: api.NewDefaultSourceTuple(m, nil),
+			offset:             map[string]interface{}{"indexValue": v},
+		}
+		select {
+		case consumer <- tuple:
+			logger.Debugf("send data to device node")
+		case <-ctx.Done():
+			return 0, nil
+		}
+		// Only advance the index after the row is sent, so that the row is read again after rewinding
+		s.mu.Lock()
+		s.indexValue = v
+		s.mu.Unlock()
+	}
+	return len(result), nil
+}
+
+// sendAll sends all the rows of the table followed by an EOF if retain size not set
+func (s *SQLSource) sendAll(ctx api.StreamContext, consumer chan<- api.SourceTuple, result []map[string]interface{}) (int, error) {
+	logger := ctx.GetLogger()
+	for _, m := range result {
+		select {
+		case consumer <- api.NewDefaultSourceTuple(m, nil):
+			logger.Debugf("send data to device node")
+		case <-ctx.Done():
+			return 0, nil
+		}
+	}
+	if s.config.RetainSize == 0 {
+		select {
+		case consumer <- api.NewDefaultSourceTuple(nil, nil):
+			// do nothing
+		case <-ctx.Done():
+			return 0, nil
+		}
+	}
+	return len(result), nil
+}
+
+// GetOffset returns the index value of the last row sent to the rule
+func (s *SQLSource) GetOffset() (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]interface{}{"indexValue": s.indexValue}, nil
+}
+
+func (s *SQLSource) Rewind(offset interface{}) error {
+	m, ok := offset.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("sql source fails to rewind: invalid offset %v", offset)
+	}
+	s.mu.Lock()
+	s.indexValue = m["indexValue"]
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *SQLSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing sql source")
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"database/sql"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSQLConfigure(t *testing.T) {
+	var tests = []struct {
+		table string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			table: "t",
+			props: map[string]interface{}{},
+			err:   "missing property url",
+		}, {
+			table: "t",
+			props: map[string]interface{}{"url": "test.db"},
+			err:   "invalid url test.db, must be in the format of driver://datasource",
+		}, {
+			table: "",
+			props: map[string]interface{}{"url": "sqlite://test.db"},
+			err:   "table name must be specified",
+		}, {
+			table: "t",
+			props: map[string]interface{}{"url": "sqlite://test.db", "interval": 0},
+			err:   "invalid interval 0, must be positive",
+		}, {
+			table: "t",
+			props: map[string]interface{}{"url": "sqlite://test.db", "limit": -1},
+			err:   "invalid limit -1, must not be negative",
+		}, {
+			table: "t",
+			props: map[string]interface{}{"url": "sqlite://test.db", "indexValue": 1},
+			err:   "indexValue is set without indexField",
+		}, {
+			table: "t",
+			props: map[string]interface{}{"url": "sqlite://test.db", "indexField": "id", "indexValue": 1, "limit": 10},
+		},
+	}
+	for i, tt := range tests {
+		s := &SQLSource{}
+		err := s.Configure(tt.table, tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestSQLSourceIncremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE readings (id INTEGER PRIMARY KEY, temp REAL);
+		INSERT INTO readings VALUES (1, 20.5), (2, 21.5), (3, 22.5);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &SQLSource{}
+	err = s.Configure("readings", map[string]interface{}{"url": "sqlite://" + file, "interval": 100, "indexField": "id", "indexValue": 1, "limit": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	go s.Open(ctx, consumer, errCh)
+	// The rows after the index are read at once even if the limit is reached
+	expectTuples(t, consumer, errCh, []map[string]interface{}{
+		{"id": int64(2), "temp": 21.5},
+		{"id": int64(3), "temp": 22.5},
+	})
+	// The new rows are read in the next query
+	if _, err := db.Exec(`INSERT INTO readings VALUES (4, 23.5)`); err != nil {
+		t.Fatal(err)
+	}
+	tuples := expectTuples(t, consumer, errCh, []map[string]interface{}{
+		{"id": int64(4), "temp": 23.5},
+	})
+	// Each row carries its index as the offset
+	if ot, ok := tuples[0].(api.OffsetTuple); !ok {
+		t.Errorf("tuple should carry the offset")
+	} else if offset := ot.Offset(); !reflect.DeepEqual(map[string]interface{}{"indexValue": int64(4)}, offset) {
+		t.Errorf("offset mismatch, got %v", offset)
+	}
+	cancel()
+	_ = s.Close(ctx)
+
+	// Rewind to read from the offset
+	s = &SQLSource{}
+	err = s.Configure("readings", map[string]interface{}{"url": "sqlite://" + file, "interval": 100, "indexField": "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rewind(map[string]interface{}{"indexValue": int64(3)}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.Background().WithCancel()
+	defer cancel()
+	go s.Open(ctx, consumer, errCh)
+	defer s.Close(ctx)
+	expectTuples(t, consumer, errCh, []map[string]interface{}{
+		{"id": int64(4), "temp": 23.5},
+	})
+}
+
+func TestSQLSourceAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE devices (id INTEGER, name TEXT);
+		INSERT INTO devices VALUES (1, 'd1'), (2, 'd2');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &SQLSource{}
+	err = s.Configure("devices", map[string]interface{}{"url": "sqlite://" + file, "interval": 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	go s.Open(ctx, consumer, errCh)
+	defer s.Close(ctx)
+	// All the rows are read in every query followed by an EOF
+	exp := []map[string]interface{}{
+		{"id": int64(1), "name": "d1"},
+		{"id": int64(2), "name": "d2"},
+		nil,
+	}
+	expectTuples(t, consumer, errCh, exp)
+	expectTuples(t, consumer, errCh, exp)
+	// The deleted rows are not read in the next query
+	if _, err := db.Exec(`DELETE FROM devices WHERE id = 2`); err != nil {
+		t.Fatal(err)
+	}
+	// Skip the query which may run before the delete
+	for {
+		select {
+		case r := <-consumer:
+			if r.Message() != nil {
+				continue
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no EOF received")
+		}
+		break
+	}
+	expectTuples(t, consumer, errCh, []map[string]interface{}{
+		{"id": int64(1), "name": "d1"},
+		nil,
+	})
+
+	// The table with retain size receives no EOF
+	rs := &SQLSource{}
+	err = rs.Configure("devices", map[string]interface{}{"url": "sqlite://" + file, "interval": 100, "$retainSize": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := make(chan api.SourceTuple, 10)
+	go rs.Open(ctx, rc, errCh)
+	defer rs.Close(ctx)
+	expectTuples(t, rc, errCh, []map[string]interface{}{
+		{"id": int64(1), "name": "d1"},
+		{"id": int64(1), "name": "d1"},
+	})
+}
+
+func expectTuples(t *testing.T, consumer chan api.SourceTuple, errCh chan error, exps []map[string]interface{}) []api.SourceTuple {
+	var tuples []api.SourceTuple
+	for _, exp := range exps {
+		select {
+		case r := <-consumer:
+			if !reflect.DeepEqual(exp, r.Message()) {
+				t.Errorf("result mismatch:\n  exp=%v\n  got=%v\n\n", exp, r.Message())
+			}
+			tuples = append(tuples, r)
+		case err := <-errCh:
+			t.Fatal(err)
+		case <-time.After(time.Second):
+			t.Fatalf("no message received, expect %v", exp)
+		}
+	}
+	return tuples
+}
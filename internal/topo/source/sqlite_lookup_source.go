@@ -0,0 +1,59 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"os"
+	"path/filepath"
+)
+
+type SQLiteLookupConfig struct {
+	Path string `json:"path"`
+}
+
+// SQLiteLookupSource is the sql lookup source of a SQLite database configured by the file path
+type SQLiteLookupSource struct {
+	SQLLookupSource
+}
+
+func (s *SQLiteLookupSource) Configure(table string, props map[string]interface{}) error {
+	cfg := &SQLiteLookupConfig{}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if cfg.Path == "" {
+		return errors.New("missing property path")
+	}
+	if table == "" {
+		return errors.New("table name must be specified")
+	}
+	if !filepath.IsAbs(cfg.Path) {
+		cfg.Path, err = conf.GetLoc(cfg.Path)
+		if err != nil {
+			return fmt.Errorf("invalid path %s", cfg.Path)
+		}
+	}
+	if _, err := os.Stat(cfg.Path); err != nil {
+		return fmt.Errorf("database file %s not exist", cfg.Path)
+	}
+	s.table = table
+	s.url = "sqlite://" + cfg.Path
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"database/sql"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestSQLiteLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "lookup.db")
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE devices (id INTEGER, type TEXT, name TEXT);
+		INSERT INTO devices VALUES (1, 'a', 'd1'), (2, 'a', 'd2'), (2, 'b', 'd3');`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &SQLiteLookupSource{}
+	err = s.Configure("devices", map[string]interface{}{"path": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	err = s.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close(ctx)
+	var tests = []struct {
+		keys   []string
+		values []interface{}
+		result []api.SourceTuple
+	}{
+		{
+			keys:   []string{"id"},
+			values: []interface{}{1},
+			result: []api.SourceTuple{
+				api.NewDefaultSourceTuple(map[string]interface{}{"id": int64(1), "type": "a", "name": "d1"}, nil),
+			},
+		}, {
+			keys:   []string{"id", "type"},
+			values: []interface{}{float64(2), "b"},
+			result: []api.SourceTuple{
+				api.NewDefaultSourceTuple(map[string]interface{}{"id": int64(2), "type": "b", "name": "d3"}, nil),
+			},
+		}, {
+			keys:   []string{"type"},
+			values: []interface{}{"a"},
+			result: []api.SourceTuple{
+				api.NewDefaultSourceTuple(map[string]interface{}{"id": int64(1), "type": "a", "name": "d1"}, nil),
+				api.NewDefaultSourceTuple(map[string]interface{}{"id": int64(2), "type": "a", "name": "d2"}, nil),
+			},
+		}, {
+			keys:   []string{"id"},
+			values: []interface{}{3},
+			result: nil,
+		},
+	}
+	for i, tt := range tests {
+		r, err := s.Lookup(ctx, tt.keys, tt.values)
+		if err != nil {
+			t.Errorf("%d: lookup error %v", i, err)
+		} else if !reflect.DeepEqual(tt.result, r) {
+			t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v", i, tt.result, r)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/message"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WebsocketModeClient = "client"
+	WebsocketModeServer = "server"
+
+	DEFAULT_WEBSOCKET_ADDRESS  = ":10082"
+	DEFAULT_RECONNECT_INTERVAL = 3000
+)
+
+type WebsocketSourceConfig struct {
+	// client mode to connect to a websocket server, or server mode to accept the connections
+	Mode string `json:"mode"`
+	// The url of the server to connect to in client mode
+	Url string `json:"url"`
+	// The headers of the handshake request in client mode
+	Headers map[string]string `json:"headers"`
+	// The interval to reconnect after the connection is lost in client mode, time unit is ms
+	ReconnectInterval int `json:"reconnectInterval"`
+	// The address to listen to and the tls settings in server mode
+	Address       string `json:"address"`
+	Certification string `json:"certificationPath"`
+	PrivateKPath  string `json:"privateKeyPath"`
+	// The origins allowed to connect in server mode, * to allow any origin. If not set, only the same origin is allowed
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// The authentication of the handshake requests in server mode
+	httpx.Auth
+	// The settings from the stream options to decode the messages. The streams sharing a path must decode the same way
+	Format    string `json:"format"`
+	SchemaId  string `json:"schemaId"`
+	Delimiter string `json:"delimiter"`
+}
+
+// WebsocketSource emits each websocket message as a tuple. In client mode, it connects to the url + data source and
+// reconnects if the connection is lost. In server mode, the sources with the same address share one server, and each
+// path is served by one endpoint which sends the messages from all the connected clients to all the source instances
+// of the path, such as the instances of a rule with concurrency or the rules of the same stream.
+type WebsocketSource struct {
+	path      string
+	config    *WebsocketSourceConfig
+	converter message.Converter
+
+	opened   bool
+	ctx      api.StreamContext
+	consumer chan<- api.SourceTuple
+}
+
+// wsEndpoint serves a path of an address in server mode and dispatches the messages to all the subscribed sources
+type wsEndpoint struct {
+	sync.RWMutex
+	address  string
+	path     string
+	config   WebsocketSourceConfig
+	upgrader websocket.Upgrader
+	sources  []*WebsocketSource
+	// closed when the endpoint is unregistered to close the connections
+	done chan struct{}
+}
+
+var (
+	wsEndpoints  = make(map[string]*wsEndpoint)
+	wsEndpointMu sync.Mutex
+)
+
+func (wss *WebsocketSource) Configure(datasource string, props map[string]interface{}) error {
+	cfg := &WebsocketSourceConfig{
+		Mode:              WebsocketModeClient,
+		Address:           DEFAULT_WEBSOCKET_ADDRESS,
+		ReconnectInterval: DEFAULT_RECONNECT_INTERVAL,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	switch cfg.Mode {
+	case WebsocketModeClient:
+		if cfg.Url == "" {
+			return errors.New("missing property url for client mode")
+		}
+		if !strings.HasPrefix(cfg.Url, "ws://") && !strings.HasPrefix(cfg.Url, "wss://") {
+			return fmt.Errorf("invalid url %s, must start with ws:// or wss://", cfg.Url)
+		}
+		if cfg.ReconnectInterval <= 0 {
+			return fmt.Errorf("invalid reconnectInterval %d, must be positive", cfg.ReconnectInterval)
+		}
+		wss.path = datasource
+	case WebsocketModeServer:
+		if (cfg.Certification == "") != (cfg.PrivateKPath == "") {
+			return errors.New("certificationPath and privateKeyPath must be set together")
+		}
+		if err := cfg.Auth.Validate(); err != nil {
+			return err
+		}
+		cfg.Format = strings.ToLower(cfg.Format)
+		if cfg.Format == "" {
+			cfg.Format = message.FormatJson
+		}
+		wss.path = datasource
+		if !strings.HasPrefix(wss.path, "/") {
+			wss.path = "/" + wss.path
+		}
+	default:
+		return fmt.Errorf("invalid mode %s, must be client or server", cfg.Mode)
+	}
+	if wss.converter, err = converter.GetOrCreateConverter(props); err != nil {
+		return err
+	}
+	wss.config = cfg
+	return nil
+}
+
+func (wss *WebsocketSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	wss.ctx = ctx
+	wss.consumer = consumer
+	if wss.config.Mode == WebsocketModeServer {
+		if err := wss.subscribe(ctx.GetLogger()); err != nil {
+			errCh <- err
+			return
+		}
+		wss.opened = true
+		ctx.GetLogger().Infof("Websocket source is listening to %s%s", wss.config.Address, wss.path)
+		return
+	}
+	wss.connect(ctx)
+}
+
+// connect keeps reading from the server and reconnects until the rule is stopped
+func (wss *WebsocketSource) connect(ctx api.StreamContext) {
+	logger := ctx.GetLogger()
+	u := wss.config.Url + wss.path
+	header := http.Header{}
+	for k, v := range wss.config.Headers {
+		header.Set(k, v)
+	}
+	for {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, u, header)
+		if err != nil {
+			logger.Warnf("Websocket source fails to connect to %s: %v", u, err)
+		} else {
+			logger.Infof("Websocket source connected to %s", u)
+			wss.read(ctx, conn, map[string]interface{}{"url": u})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(wss.config.ReconnectInterval) * time.Millisecond):
+			logger.Infof("Websocket source reconnects to %s", u)
+		}
+	}
+}
+
+// read receives the messages of the connection in client mode until it is closed or the rule stops
+func (wss *WebsocketSource) read(ctx api.StreamContext, conn *websocket.Conn, meta map[string]interface{}) {
+	readConn(conn, ctx.Done(), func(data []byte) bool {
+		return wss.send(data, meta)
+	}, ctx.GetLogger())
+}
+
+// send decodes the message and sends it to the rule. It returns false if the rule stops.
+func (wss *WebsocketSource) send(data []byte, meta map[string]interface{}) bool {
+	logger := wss.ctx.GetLogger()
+	result, err := wss.converter.Decode(data)
+	if err != nil {
+		logger.Errorf("Invalid data format, cannot decode %s with error %s", string(data), err)
+		return true
+	}
+	select {
+	case wss.consumer <- api.NewDefaultSourceTuple(result, meta):
+		logger.Debugf("send data to device node")
+		return true
+	case <-wss.ctx.Done():
+		return false
+	}
+}
+
+// subscribe adds the source to the endpoint of its path in server mode. The endpoint is created and registered to the
+// server by the first source, and the later sources must have the same settings.
+func (wss *WebsocketSource) subscribe(logger api.Logger) error {
+	wsEndpointMu.Lock()
+	defer wsEndpointMu.Unlock()
+	key := wss.config.Address + wss.path
+	if e, ok := wsEndpoints[key]; ok {
+		if e.config.Format != wss.config.Format || e.config.SchemaId != wss.config.SchemaId || e.config.Delimiter != wss.config.Delimiter {
+			return fmt.Errorf("websocket path %s of %s is already used with a different format", wss.path, wss.config.Address)
+		}
+		if e.config.Certification != wss.config.Certification || e.config.PrivateKPath != wss.config.PrivateKPath ||
+			e.config.Auth != wss.config.Auth || !reflect.DeepEqual(e.config.AllowedOrigins, wss.config.AllowedOrigins) {
+			return fmt.Errorf("websocket path %s of %s is already used with different settings", wss.path, wss.config.Address)
+		}
+		e.Lock()
+		e.sources = append(e.sources, wss)
+		e.Unlock()
+		return nil
+	}
+	e := &wsEndpoint{
+		address: wss.config.Address,
+		path:    wss.path,
+		config:  *wss.config,
+		sources: []*WebsocketSource{wss},
+		done:    make(chan struct{}),
+	}
+	if len(e.config.AllowedOrigins) > 0 {
+		e.upgrader.CheckOrigin = httpx.CheckOrigin(e.config.AllowedOrigins)
+	}
+	if err := httpx.RegisterHandler(logger, e.address, e.config.Certification, e.config.PrivateKPath, e.path, e); err != nil {
+		return err
+	}
+	wsEndpoints[key] = e
+	return nil
+}
+
+// unsubscribe removes the source from the endpoint, and unregisters the endpoint and closes its connections once no
+// source is subscribed
+func (wss *WebsocketSource) unsubscribe() {
+	wsEndpointMu.Lock()
+	defer wsEndpointMu.Unlock()
+	key := wss.config.Address + wss.path
+	e, ok := wsEndpoints[key]
+	if !ok {
+		return
+	}
+	e.Lock()
+	for i, s := range e.sources {
+		if s == wss {
+			e.sources = append(e.sources[:i], e.sources[i+1:]...)
+			break
+		}
+	}
+	n := len(e.sources)
+	e.Unlock()
+	if n == 0 {
+		httpx.UnregisterHandler(e.address, e.path)
+		close(e.done)
+		delete(wsEndpoints, key)
+	}
+}
+
+func (e *wsEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !e.config.Authorized(r) {
+		e.config.Unauthorized(w)
+		return
+	}
+	e.RLock()
+	if len(e.sources) == 0 {
+		e.RUnlock()
+		http.NotFound(w, r)
+		return
+	}
+	logger := e.sources[0].ctx.GetLogger()
+	e.RUnlock()
+	conn, err := e.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Warnf("Websocket source fails to upgrade the connection from %s: %v", r.RemoteAddr, err)
+		return
+	}
+	logger.Infof("Websocket source accepts the connection from %s", r.RemoteAddr)
+	meta := map[string]interface{}{"path": r.URL.Path, "remoteAddr": r.RemoteAddr}
+	readConn(conn, e.done, func(data []byte) bool {
+		e.RLock()
+		sources := make([]*WebsocketSource, len(e.sources))
+		copy(sources, e.sources)
+		e.RUnlock()
+		// The stopped sources are skipped and the connection is kept for the others
+		for _, wss := range sources {
+			wss.send(data, meta)
+		}
+		return true
+	}, logger)
+}
+
+// readConn receives the messages of the connection until it is closed, done is closed or the handler returns false
+func readConn(conn *websocket.Conn, done <-chan struct{}, handler func(data []byte) bool, logger api.Logger) {
+	finished := make(chan struct{})
+	defer func() {
+		close(finished)
+		conn.Close()
+	}()
+	go func() {
+		// unblock the read when done
+		select {
+		case <-done:
+			conn.Close()
+		case <-finished:
+		}
+	}()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-done:
+			default:
+				logger.Warnf("Websocket source connection is closed: %v", err)
+			}
+			return
+		}
+		if !handler(data) {
+			return
+		}
+	}
+}
+
+func (wss *WebsocketSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing websocket source")
+	if wss.opened {
+		wss.unsubscribe()
+		wss.opened = false
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebsocketConfigure(t *testing.T) {
+	var tests = []struct {
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			props: map[string]interface{}{"mode": "proxy"},
+			err:   "invalid mode proxy, must be client or server",
+		}, {
+			props: map[string]interface{}{},
+			err:   "missing property url for client mode",
+		}, {
+			props: map[string]interface{}{"url": "http://localhost"},
+			err:   "invalid url http://localhost, must start with ws:// or wss://",
+		}, {
+			props: map[string]interface{}{"url": "ws://localhost", "reconnectInterval": -1},
+			err:   "invalid reconnectInterval -1, must be positive",
+		}, {
+			props: map[string]interface{}{"mode": "server", "privateKeyPath": "key.pem"},
+			err:   "certificationPath and privateKeyPath must be set together",
+		}, {
+			props: map[string]interface{}{"mode": "server", "username": "user", "token": "abc"},
+			err:   "only one of basic auth and bearer token can be set",
+		}, {
+			props: map[string]interface{}{"mode": "server"},
+		},
+	}
+	for i, tt := range tests {
+		s := &WebsocketSource{}
+		err := s.Configure("data", tt.props)
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+}
+
+func TestWebsocketSourceServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	props := map[string]interface{}{"mode": "server", "address": address, "token": "abc", "allowedOrigins": []interface{}{"http://app.example"}}
+	s := &WebsocketSource{}
+	if err := s.Configure("ws", props); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	s.Open(ctx, consumer, errCh)
+	defer s.Close(ctx)
+	// Another instance of the same stream shares the path
+	sharedConsumer := make(chan api.SourceTuple, 10)
+	shared := &WebsocketSource{}
+	if err := shared.Configure("ws", props); err != nil {
+		t.Fatal(err)
+	}
+	shared.Open(ctx, sharedConsumer, errCh)
+	defer shared.Close(ctx)
+	// The stream with another format cannot share the path
+	binary := &WebsocketSource{}
+	if err := binary.Configure("ws", map[string]interface{}{"mode": "server", "address": address, "token": "abc", "format": "binary"}); err != nil {
+		t.Fatal(err)
+	}
+	binary.Open(ctx, consumer, errCh)
+	select {
+	case err := <-errCh:
+		exp := "websocket path /ws of " + address + " is already used with a different format"
+		if err.Error() != exp {
+			t.Errorf("different format error mismatch:\n  exp=%s\n  got=%s", exp, err)
+		}
+	default:
+		t.Errorf("different format should fail")
+	}
+
+	url := "ws://" + address + "/ws"
+	if _, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{"http://app.example"}}); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("should reject the connection without token")
+	}
+	if _, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": []string{"Bearer abc"}, "Origin": []string{"http://other.example"}}); err == nil {
+		t.Errorf("should reject the origin not allowed")
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": []string{"Bearer abc"}, "Origin": []string{"http://app.example"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, m := range []string{`{"a": 1}`, `invalid`, `{"a": 2}`} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each instance receives all the messages
+	for _, c := range []chan api.SourceTuple{consumer, sharedConsumer} {
+		for _, exp := range []map[string]interface{}{{"a": 1.0}, {"a": 2.0}} {
+			select {
+			case r := <-c:
+				if !reflect.DeepEqual(exp, r.Message()) {
+					t.Errorf("result mismatch:\n  exp=%v\n  got=%v\n\n", exp, r.Message())
+				}
+				if r.Meta()["path"] != "/ws" {
+					t.Errorf("meta path mismatch, got %v", r.Meta())
+				}
+			case err := <-errCh:
+				t.Fatal(err)
+			case <-time.After(time.Second):
+				t.Fatal("no message received")
+			}
+		}
+	}
+	// The connection is kept for the other instance after an instance is closed
+	s.Close(ctx)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"a": 3}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-sharedConsumer:
+		if exp := map[string]interface{}{"a": 3.0}; !reflect.DeepEqual(exp, r.Message()) {
+			t.Errorf("result mismatch:\n  exp=%v\n  got=%v\n\n", exp, r.Message())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message received after an instance is closed")
+	}
+}
+
+func TestWebsocketSourceClient(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	conns := make(chan int, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data" {
+			http.NotFound(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- 1
+		// Close after sending one message to verify the reconnection
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"temperature": 20}`))
+		conn.Close()
+	}))
+	defer server.Close()
+
+	s := &WebsocketSource{}
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	if err := s.Configure("/data", map[string]interface{}{"url": url, "reconnectInterval": 50}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.Background().WithCancel()
+	defer cancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	go s.Open(ctx, consumer, errCh)
+	exp := api.NewDefaultSourceTuple(map[string]interface{}{"temperature": 20.0}, map[string]interface{}{"url": url + "/data"})
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-consumer:
+			if !reflect.DeepEqual(exp, r) {
+				t.Errorf("%d: result mismatch:\n  exp=%v\n  got=%v\n\n", i, exp, r)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%d: no message received", i)
+		}
+	}
+	if len(conns) < 2 {
+		t.Errorf("expect connected at least 2 times but got %d", len(conns))
+	}
+}
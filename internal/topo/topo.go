@@ -205,6 +205,9 @@ func (s *Topo) GetMetrics() (keys []string, values []interface{}) {
 	for _, sn := range s.sources {
 		for ins, metrics := range sn.GetMetrics() {
 			for i, v := range metrics {
+				if v == nil {
+					continue
+				}
 				keys = append(keys, "source_"+sn.GetName()+"_"+strconv.Itoa(ins)+"_"+node.MetricNames[i])
 				values = append(values, v)
 			}
@@ -213,6 +216,9 @@ func (s *Topo) GetMetrics() (keys []string, values []interface{}) {
 	for _, so := range s.ops {
 		for ins, metrics := range so.GetMetrics() {
 			for i, v := range metrics {
+				if v == nil {
+					continue
+				}
 				keys = append(keys, "op_"+so.GetName()+"_"+strconv.Itoa(ins)+"_"+node.MetricNames[i])
 				values = append(values, v)
 			}
@@ -221,6 +227,9 @@ func (s *Topo) GetMetrics() (keys []string, values []interface{}) {
 	for _, sn := range s.sinks {
 		for ins, metrics := range sn.GetMetrics() {
 			for i, v := range metrics {
+				if v == nil {
+					continue
+				}
 				keys = append(keys, "sink_"+sn.GetName()+"_"+strconv.Itoa(ins)+"_"+node.MetricNames[i])
 				values = append(values, v)
 			}
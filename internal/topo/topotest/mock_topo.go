@@ -345,6 +345,12 @@ func HandleStream(createOrDrop bool, names []string, t *testing.T) {
 					size BIGINT,
 					id BIGINT
 				) WITH (DATASOURCE="lookup.json", FORMAT="json", CONF_KEY="test");`
+			case "tableLookup":
+				sql = `CREATE TABLE tableLookup (
+					color STRING,
+					code STRING,
+					level BIGINT
+				) WITH (DATASOURCE="colors", TYPE="sqlite", KIND="lookup", CONF_KEY="test");`
 			case "helloStr":
 				sql = `CREATE STREAM helloStr (name string) WITH (DATASOURCE="helloStr", TYPE="mock", FORMAT="JSON")`
 			case "commands":
@@ -15,10 +15,14 @@
 package topotest
 
 import (
+	"database/sql"
 	"encoding/json"
+	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/topo"
 	"github.com/lf-edge/ekuiper/internal/topo/topotest/mocknode"
 	"github.com/lf-edge/ekuiper/pkg/api"
+	_ "github.com/mattn/go-sqlite3"
+	"path"
 	"testing"
 )
 
@@ -924,3 +928,181 @@ func TestSingleSQLForBinary(t *testing.T) {
 		doRuleTestBySinkProps(t, tests, j, opt, 0, nil, byteFunc)
 	}
 }
+
+func TestIntervalJoin(t *testing.T) {
+	//Reset
+	streamList := []string{"demo", "demo1"}
+	HandleStream(false, streamList, t)
+	var tests = []RuleTest{
+		{
+			Name: `TestIntervalJoinRule1`,
+			Sql:  `SELECT color, temp, demo.ts AS ts1 FROM demo INNER JOIN demo1 ON demo1.ts BETWEEN demo.ts AND demo.ts + 500ms WHERE temp > 26`,
+			R: [][]map[string]interface{}{
+				{{
+					"color": "blue",
+					"temp":  27.5,
+					"ts1":   float64(1541152486822),
+				}}, {{
+					"color": "blue",
+					"temp":  28.1,
+					"ts1":   float64(1541152487632),
+				}}, {{
+					"color": "yellow",
+					"temp":  27.4,
+					"ts1":   float64(1541152488442),
+				}},
+			},
+			M: map[string]interface{}{
+				"op_4_interval_join_0_exceptions_total":  int64(0),
+				"op_4_interval_join_0_records_in_total":  int64(8),
+				"op_4_interval_join_0_records_out_total": int64(3),
+
+				"sink_mockSink_0_exceptions_total":  int64(0),
+				"sink_mockSink_0_records_in_total":  int64(3),
+				"sink_mockSink_0_records_out_total": int64(3),
+			},
+		}, {
+			Name: `TestIntervalJoinRule2`,
+			Sql:  `SELECT color, size, temp FROM demo LEFT JOIN demo1 ON demo1.ts BETWEEN demo.ts + 1 AND demo.ts + 500ms`,
+			R: [][]map[string]interface{}{
+				{{
+					"color": "red",
+					"size":  float64(3),
+				}}, {{
+					"color": "blue",
+					"size":  float64(6),
+					"temp":  27.5,
+				}}, {{
+					"color": "blue",
+					"size":  float64(2),
+				}}, {{
+					"color": "yellow",
+					"size":  float64(4),
+				}},
+			},
+			M: map[string]interface{}{
+				"op_3_interval_join_0_exceptions_total":  int64(0),
+				"op_3_interval_join_0_records_in_total":  int64(10),
+				"op_3_interval_join_0_records_out_total": int64(4),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.ExactlyOnce,
+			CheckpointInterval: 5000,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 0)
+	}
+}
+
+func TestLookupJoin(t *testing.T) {
+	// Create the lookup table in the sqlite database
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path.Join(dataDir, "lookup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`DROP TABLE IF EXISTS colors;
+		CREATE TABLE colors (color TEXT, code TEXT, level INTEGER);
+		INSERT INTO colors VALUES ('red', '#f00', 1), ('blue', '#00f', 2);`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Reset
+	streamList := []string{"demo", "tableLookup"}
+	HandleStream(false, streamList, t)
+	var tests = []RuleTest{
+		{
+			Name: `TestLookupJoinRule1`,
+			Sql:  `SELECT demo.color, code, level, size FROM demo INNER JOIN tableLookup ON demo.color = tableLookup.color`,
+			R: [][]map[string]interface{}{
+				{{
+					"color": "red",
+					"code":  "#f00",
+					"level": float64(1),
+					"size":  float64(3),
+				}}, {{
+					"color": "blue",
+					"code":  "#00f",
+					"level": float64(2),
+					"size":  float64(6),
+				}}, {{
+					"color": "blue",
+					"code":  "#00f",
+					"level": float64(2),
+					"size":  float64(2),
+				}}, {{
+					"color": "red",
+					"code":  "#f00",
+					"level": float64(1),
+					"size":  float64(1),
+				}},
+			},
+			M: map[string]interface{}{
+				"op_2_lookup_tableLookup_0_exceptions_total":  int64(0),
+				"op_2_lookup_tableLookup_0_records_in_total":  int64(5),
+				"op_2_lookup_tableLookup_0_records_out_total": int64(4),
+
+				"sink_mockSink_0_exceptions_total":  int64(0),
+				"sink_mockSink_0_records_in_total":  int64(4),
+				"sink_mockSink_0_records_out_total": int64(4),
+			},
+		}, {
+			Name: `TestLookupJoinRule2`,
+			Sql:  `SELECT size, code FROM demo LEFT JOIN tableLookup ON demo.color = tableLookup.color AND size > 2`,
+			R: [][]map[string]interface{}{
+				{{
+					"size": float64(3),
+					"code": "#f00",
+				}}, {{
+					"size": float64(6),
+					"code": "#00f",
+				}}, {{
+					"size": float64(2),
+				}}, {{
+					"size": float64(4),
+				}}, {{
+					"size": float64(1),
+				}},
+			},
+			M: map[string]interface{}{
+				"op_2_lookup_tableLookup_0_exceptions_total":  int64(0),
+				"op_2_lookup_tableLookup_0_records_in_total":  int64(5),
+				"op_2_lookup_tableLookup_0_records_out_total": int64(5),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 0)
+	}
+}
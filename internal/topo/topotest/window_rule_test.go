@@ -737,6 +737,256 @@ func TestWindow(t *testing.T) {
 				"source_table1_0_records_in_total":  int64(4),
 				"source_table1_0_records_out_total": int64(4),
 			},
+		}, {
+			Name: `TestWindowRule12`,
+			Sql:  `SELECT count(*) AS c, window_start() AS ws, window_end() AS we FROM demo GROUP BY STATEWINDOW(color = "blue", color = "yellow")`,
+			R: [][]map[string]interface{}{
+				{{
+					"c":  float64(3),
+					"ws": float64(1541152486822),
+					"we": float64(1541152488442),
+				}},
+			},
+			M: map[string]interface{}{
+				"op_1_preprocessor_demo_0_exceptions_total":   int64(0),
+				"op_1_preprocessor_demo_0_process_latency_us": int64(0),
+				"op_1_preprocessor_demo_0_records_in_total":   int64(5),
+				"op_1_preprocessor_demo_0_records_out_total":  int64(5),
+
+				"op_3_project_0_exceptions_total":   int64(0),
+				"op_3_project_0_process_latency_us": int64(0),
+				"op_3_project_0_records_in_total":   int64(1),
+				"op_3_project_0_records_out_total":  int64(1),
+
+				"sink_mockSink_0_exceptions_total":  int64(0),
+				"sink_mockSink_0_records_in_total":  int64(1),
+				"sink_mockSink_0_records_out_total": int64(1),
+
+				"source_demo_0_exceptions_total":  int64(0),
+				"source_demo_0_records_in_total":  int64(5),
+				"source_demo_0_records_out_total": int64(5),
+
+				"op_2_window_0_exceptions_total":   int64(0),
+				"op_2_window_0_process_latency_us": int64(0),
+				"op_2_window_0_records_in_total":   int64(5),
+				"op_2_window_0_records_out_total":  int64(1),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.ExactlyOnce,
+			CheckpointInterval: 5000,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 15)
+	}
+}
+
+func TestWindowEmit(t *testing.T) {
+	//Reset
+	streamList := []string{"demo"}
+	HandleStream(false, streamList, t)
+	var tests = []RuleTest{
+		{
+			Name: `TestWindowEmitRule1`,
+			Sql:  `SELECT count(*) AS c, window_start() AS ws, window_end() AS we, window_trigger() AS t FROM demo GROUP BY TUMBLINGWINDOW(ss, 2)`,
+			R: [][]map[string]interface{}{
+				{{
+					"c":  float64(2),
+					"ws": float64(1541152486000),
+					"we": float64(1541152488000),
+					"t":  "count",
+				}}, {{
+					"c":  float64(3),
+					"ws": float64(1541152486000),
+					"we": float64(1541152488000),
+					"t":  "final",
+				}}, {{
+					"c":  float64(2),
+					"ws": float64(1541152488000),
+					"we": float64(1541152490000),
+					"t":  "count",
+				}}, {{
+					"c":  float64(2),
+					"ws": float64(1541152488000),
+					"we": float64(1541152490000),
+					"t":  "final",
+				}},
+			},
+			M: map[string]interface{}{
+				"op_2_window_0_exceptions_total":  int64(0),
+				"op_2_window_0_records_in_total":  int64(5),
+				"op_2_window_0_records_out_total": int64(4),
+
+				"sink_mockSink_0_exceptions_total":  int64(0),
+				"sink_mockSink_0_records_in_total":  int64(4),
+				"sink_mockSink_0_records_out_total": int64(4),
+			},
+		}, {
+			Name: `TestWindowEmitRule2`,
+			Sql:  `SELECT count(*) AS c, meta(window_trigger) AS t FROM demo GROUP BY TUMBLINGWINDOW(ss, 2)`,
+			R: [][]map[string]interface{}{
+				{{
+					"c": float64(2),
+					"t": "count",
+				}}, {{
+					"c": float64(3),
+					"t": "final",
+				}}, {{
+					"c": float64(2),
+					"t": "count",
+				}}, {{
+					"c": float64(2),
+					"t": "final",
+				}},
+			},
+			M: map[string]interface{}{
+				"op_2_window_0_exceptions_total":  int64(0),
+				"op_2_window_0_records_in_total":  int64(5),
+				"op_2_window_0_records_out_total": int64(4),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+			EmitCount:    2,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+			EmitCount:          2,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.ExactlyOnce,
+			CheckpointInterval: 5000,
+			EmitCount:          2,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 15)
+	}
+}
+
+func TestWindowEmitInterval(t *testing.T) {
+	//Reset
+	streamList := []string{"demo"}
+	HandleStream(false, streamList, t)
+	var tests = []RuleTest{
+		{
+			Name: `TestWindowEmitIntervalRule1`,
+			Sql:  `SELECT count(*) AS c, window_trigger() AS t FROM demo GROUP BY TUMBLINGWINDOW(ss, 2)`,
+			R: [][]map[string]interface{}{
+				{{
+					"c": float64(1),
+					"t": "interval",
+				}}, {{
+					"c": float64(2),
+					"t": "interval",
+				}}, {{
+					"c": float64(3),
+					"t": "final",
+				}}, {{
+					"c": float64(1),
+					"t": "interval",
+				}}, {{
+					"c": float64(2),
+					"t": "interval",
+				}}, {{
+					"c": float64(2),
+					"t": "final",
+				}},
+			},
+			M: map[string]interface{}{
+				"op_2_window_0_exceptions_total":  int64(0),
+				"op_2_window_0_records_in_total":  int64(5),
+				"op_2_window_0_records_out_total": int64(6),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+			EmitInterval: 700,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 15)
+	}
+}
+
+func TestWindowFunc(t *testing.T) {
+	//Reset
+	streamList := []string{"demo"}
+	HandleStream(false, streamList, t)
+	var tests = []RuleTest{
+		{
+			Name: `TestWindowFuncRule1`,
+			Sql:  `SELECT color, size, row_number() OVER (PARTITION BY color ORDER BY size) AS rn, sum(size) OVER (ORDER BY ts) AS s FROM demo GROUP BY TUMBLINGWINDOW(ss, 2) ORDER BY size`,
+			R: [][]map[string]interface{}{
+				{{
+					"color": "blue",
+					"size":  float64(2),
+					"rn":    float64(1),
+					"s":     float64(11),
+				}, {
+					"color": "red",
+					"size":  float64(3),
+					"rn":    float64(1),
+					"s":     float64(3),
+				}, {
+					"color": "blue",
+					"size":  float64(6),
+					"rn":    float64(2),
+					"s":     float64(9),
+				}}, {{
+					"color": "red",
+					"size":  float64(1),
+					"rn":    float64(1),
+					"s":     float64(5),
+				}, {
+					"color": "yellow",
+					"size":  float64(4),
+					"rn":    float64(1),
+					"s":     float64(4),
+				}},
+			},
+			M: map[string]interface{}{
+				"op_2_window_0_exceptions_total":  int64(0),
+				"op_2_window_0_records_in_total":  int64(5),
+				"op_2_window_0_records_out_total": int64(2),
+
+				"op_3_windowFunc_0_exceptions_total":  int64(0),
+				"op_3_windowFunc_0_records_in_total":  int64(2),
+				"op_3_windowFunc_0_records_out_total": int64(2),
+
+				"op_5_project_0_exceptions_total":  int64(0),
+				"op_5_project_0_records_in_total":  int64(2),
+				"op_5_project_0_records_out_total": int64(2),
+
+				"sink_mockSink_0_exceptions_total":  int64(0),
+				"sink_mockSink_0_records_in_total":  int64(2),
+				"sink_mockSink_0_records_out_total": int64(2),
+			},
 		},
 	}
 	HandleStream(true, streamList, t)
@@ -1285,6 +1535,172 @@ func TestEventWindow(t *testing.T) {
 				"op_2_window_0_records_in_total":   int64(6),
 				"op_2_window_0_records_out_total":  int64(5),
 			},
+		}, {
+			Name: `TestEventWindowRule10`,
+			Sql:  `SELECT count(*) AS c, window_start() AS ws, window_end() AS we FROM demoE GROUP BY STATEWINDOW(color = "blue", color = "yellow")`,
+			R: [][]map[string]interface{}{
+				{{
+					"c":  float64(2),
+					"ws": float64(1541152487632),
+					"we": float64(1541152488442),
+				}},
+			},
+			M: map[string]interface{}{
+				"op_1_preprocessor_demoE_0_exceptions_total":   int64(0),
+				"op_1_preprocessor_demoE_0_process_latency_us": int64(0),
+				"op_1_preprocessor_demoE_0_records_in_total":   int64(6),
+				"op_1_preprocessor_demoE_0_records_out_total":  int64(6),
+
+				"op_3_project_0_exceptions_total":   int64(0),
+				"op_3_project_0_process_latency_us": int64(0),
+				"op_3_project_0_records_in_total":   int64(1),
+				"op_3_project_0_records_out_total":  int64(1),
+
+				"sink_mockSink_0_exceptions_total":  int64(0),
+				"sink_mockSink_0_records_in_total":  int64(1),
+				"sink_mockSink_0_records_out_total": int64(1),
+
+				"source_demoE_0_exceptions_total":  int64(0),
+				"source_demoE_0_records_in_total":  int64(6),
+				"source_demoE_0_records_out_total": int64(6),
+
+				"op_2_window_0_exceptions_total":   int64(0),
+				"op_2_window_0_process_latency_us": int64(0),
+				"op_2_window_0_records_in_total":   int64(6),
+				"op_2_window_0_records_out_total":  int64(1),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+			IsEventTime:  true,
+			LateTol:      1000,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+			IsEventTime:        true,
+			LateTol:            1000,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.ExactlyOnce,
+			CheckpointInterval: 5000,
+			IsEventTime:        true,
+			LateTol:            1000,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 10)
+	}
+}
+
+func TestEventWindowLate(t *testing.T) {
+	//Reset
+	streamList := []string{"demoE"}
+	HandleStream(false, streamList, t)
+	var tests = []RuleTest{
+		{
+			Name: `TestEventWindowLateRule1`,
+			Sql:  `SELECT count(*) AS c FROM demoE GROUP BY TUMBLINGWINDOW(ss, 2)`,
+			R: [][]map[string]interface{}{
+				{{
+					"c": float64(2),
+				}}, {{
+					"c": float64(2),
+				}},
+			},
+			M: map[string]interface{}{
+				"op_2_window_0_exceptions_total":   int64(0),
+				"op_2_window_0_records_in_total":   int64(6),
+				"op_2_window_0_records_out_total":  int64(2),
+				"op_2_window_0_records_late_total": int64(1),
+
+				"sink_logToMemory_late_0_0_exceptions_total":  int64(0),
+				"sink_logToMemory_late_0_0_records_in_total":  int64(1),
+				"sink_logToMemory_late_0_0_records_out_total": int64(1),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	lateActions := []map[string]interface{}{
+		{"logToMemory": map[string]interface{}{}},
+	}
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+			IsEventTime:  true,
+			LateTol:      1000,
+			LateActions:  lateActions,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+			IsEventTime:        true,
+			LateTol:            1000,
+			LateActions:        lateActions,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.ExactlyOnce,
+			CheckpointInterval: 5000,
+			IsEventTime:        true,
+			LateTol:            1000,
+			LateActions:        lateActions,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 10)
+	}
+}
+
+func TestEventWindowEmit(t *testing.T) {
+	//Reset
+	streamList := []string{"demoE"}
+	HandleStream(false, streamList, t)
+	var tests = []RuleTest{
+		{
+			Name: `TestEventWindowEmitRule1`,
+			Sql:  `SELECT count(*) AS c, window_start() AS ws, window_end() AS we, window_trigger() AS t FROM demoE GROUP BY TUMBLINGWINDOW(ss, 2)`,
+			R: [][]map[string]interface{}{
+				{{
+					"c":  float64(2),
+					"ws": float64(1541152486000),
+					"we": float64(1541152488000),
+					"t":  "count",
+				}}, {{
+					"c":  float64(2),
+					"ws": float64(1541152486000),
+					"we": float64(1541152488000),
+					"t":  "final",
+				}}, {{
+					"c":  float64(2),
+					"ws": float64(1541152488000),
+					"we": float64(1541152490000),
+					"t":  "count",
+				}}, {{
+					"c":  float64(2),
+					"ws": float64(1541152488000),
+					"we": float64(1541152490000),
+					"t":  "final",
+				}},
+			},
+			M: map[string]interface{}{
+				"op_2_window_0_exceptions_total":   int64(0),
+				"op_2_window_0_records_in_total":   int64(6),
+				"op_2_window_0_records_out_total":  int64(4),
+				"op_2_window_0_records_late_total": int64(1),
+
+				"sink_mockSink_0_exceptions_total":  int64(0),
+				"sink_mockSink_0_records_in_total":  int64(4),
+				"sink_mockSink_0_records_out_total": int64(4),
+			},
 		},
 	}
 	HandleStream(true, streamList, t)
@@ -1294,6 +1710,7 @@ func TestEventWindow(t *testing.T) {
 			SendError:    true,
 			IsEventTime:  true,
 			LateTol:      1000,
+			EmitCount:    2,
 		}, {
 			BufferLength:       100,
 			SendError:          true,
@@ -1301,6 +1718,7 @@ func TestEventWindow(t *testing.T) {
 			CheckpointInterval: 5000,
 			IsEventTime:        true,
 			LateTol:            1000,
+			EmitCount:          2,
 		}, {
 			BufferLength:       100,
 			SendError:          true,
@@ -1308,6 +1726,7 @@ func TestEventWindow(t *testing.T) {
 			CheckpointInterval: 5000,
 			IsEventTime:        true,
 			LateTol:            1000,
+			EmitCount:          2,
 		},
 	}
 	for j, opt := range options {
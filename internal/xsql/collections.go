@@ -30,6 +30,7 @@ type AggregateData interface {
 	AggregateEval(expr ast.Expr, v CallValuer) []interface{}
 	GetWindowStart() int64
 	GetWindowEnd() int64
+	GetWindowTrigger() string
 }
 
 // Message is a valuer that substitutes values for the mapped interface.
@@ -173,6 +174,10 @@ func (t *Tuple) GetWindowEnd() int64 {
 	return 0
 }
 
+func (t *Tuple) GetWindowTrigger() string {
+	return ""
+}
+
 func (t *Tuple) GetTimestamp() int64 {
 	return t.Timestamp
 }
@@ -212,9 +217,17 @@ type WindowTuples struct {
 	Tuples  []Tuple
 }
 
+// The triggers of a window emission
+const (
+	TriggerFinal    = "final"    // The window is closed
+	TriggerInterval = "interval" // Early emission by the emit interval
+	TriggerCount    = "count"    // Early emission by the emit count
+)
+
 type WindowRange struct {
 	WindowStart int64
 	WindowEnd   int64
+	Trigger     string
 }
 
 func (r *WindowRange) GetWindowStart() int64 {
@@ -225,6 +238,10 @@ func (r *WindowRange) GetWindowEnd() int64 {
 	return r.WindowEnd
 }
 
+func (r *WindowRange) GetWindowTrigger() string {
+	return r.Trigger
+}
+
 type WindowTuplesSet struct {
 	Content []WindowTuples
 	*WindowRange
@@ -84,6 +84,8 @@ func (fv *FunctionValuer) Call(name string, args []interface{}) (interface{}, bo
 		return jsonCall(lowerName, args)
 	case ast.OtherFunc:
 		return otherCall(lowerName, args)
+	case ast.AnalyticFunc:
+		return analyticCall(fv.runtime.parentCtx, lowerName, args)
 	default:
 		return nil, false
 	}
@@ -17,7 +17,10 @@ package xsql
 import (
 	"fmt"
 	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
+	"math"
+	"sort"
 	"strings"
 )
 
@@ -185,6 +188,50 @@ func (v *AggregateFunctionValuer) Call(name string, args []interface{}) (interfa
 			}
 		}
 		return 0, true
+	case "stddev", "stddevs", "var", "vars":
+		arg0 := args[0].([]interface{})
+		vals, err := sliceFloatValues(arg0)
+		if err != nil {
+			return fmt.Errorf("run %s function error: %v", lowerName, err), false
+		}
+		sample := lowerName == "stddevs" || lowerName == "vars"
+		if len(vals) == 0 || (sample && len(vals) < 2) {
+			return nil, true
+		}
+		r := variance(vals, sample)
+		if lowerName == "stddev" || lowerName == "stddevs" {
+			r = math.Sqrt(r)
+		}
+		return r, true
+	case "median", "percentile":
+		arg0 := args[0].([]interface{})
+		vals, err := sliceFloatValues(arg0)
+		if err != nil {
+			return fmt.Errorf("run %s function error: %v", lowerName, err), false
+		}
+		if len(vals) == 0 {
+			return nil, true
+		}
+		p := 0.5
+		if lowerName == "percentile" {
+			v, ok := args[1].([]interface{})
+			if !ok {
+				return fmt.Errorf("Invalid argument type found."), false
+			}
+			if pv, err := cast.ToFloat64(getFirstValidArg(v), cast.CONVERT_SAMEKIND); err != nil || pv < 0 || pv > 1 {
+				return fmt.Errorf("run percentile function error: the percentile must be a number between 0 and 1"), false
+			} else {
+				p = pv
+			}
+		}
+		return percentile(vals, p), true
+	case "mode":
+		arg0 := args[0].([]interface{})
+		if r, err := sliceMode(arg0); err != nil {
+			return fmt.Errorf("run mode function error: %v", err), false
+		} else {
+			return r, true
+		}
 	case "collect":
 		return args[0], true
 	case "deduplicate":
@@ -367,3 +414,92 @@ func dedup(r []interface{}, col []interface{}, all bool) (interface{}, error) {
 		return result, nil
 	}
 }
+
+// sliceFloatValues converts the non-nil numeric values to float64
+func sliceFloatValues(s []interface{}) ([]float64, error) {
+	r := make([]float64, 0, len(s))
+	for _, v := range s {
+		if v == nil {
+			continue
+		}
+		if vf, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND); err != nil {
+			return nil, fmt.Errorf("requires number but found %[1]T(%[1]v)", v)
+		} else {
+			r = append(r, vf)
+		}
+	}
+	return r, nil
+}
+
+// variance calculates the population variance or the sample variance if sample is set
+func variance(s []float64, sample bool) float64 {
+	var mean float64
+	for _, v := range s {
+		mean += v
+	}
+	mean /= float64(len(s))
+	var total float64
+	for _, v := range s {
+		total += (v - mean) * (v - mean)
+	}
+	if sample {
+		return total / float64(len(s)-1)
+	}
+	return total / float64(len(s))
+}
+
+// percentile calculates the continuous percentile by linear interpolation between the closest ranks.
+// The input slice will be sorted.
+func percentile(s []float64, p float64) float64 {
+	sort.Float64s(s)
+	rank := p * float64(len(s)-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= len(s) {
+		return s[lower]
+	}
+	return s[lower] + (rank-float64(lower))*(s[lower+1]-s[lower])
+}
+
+// sliceMode returns the most frequent non-nil value. If there are multiple ones, return the first met.
+// The numbers are counted by value, so 1 and 1.0 are the same and the first met one is returned.
+func sliceMode(s []interface{}) (interface{}, error) {
+	var (
+		keys   []interface{}
+		counts = make(map[interface{}]int)
+		firsts = make(map[interface{}]interface{})
+	)
+	for _, v := range s {
+		key := v
+		switch vt := v.(type) {
+		case nil:
+			continue
+		case int:
+			key = int64(vt)
+		case int64:
+		case float64:
+			// Integral floats share the key with integers, other floats are keyed by themselves so that large integers are not merged
+			if vt == math.Trunc(vt) && math.Abs(vt) <= 1<<53 {
+				key = int64(vt)
+			}
+		case string, bool:
+		default:
+			return nil, fmt.Errorf("found invalid arg %[1]T(%[1]v)", v)
+		}
+		if _, ok := firsts[key]; !ok {
+			firsts[key] = v
+			keys = append(keys, key)
+		}
+		counts[key]++
+	}
+	var (
+		r   interface{}
+		max int
+	)
+	for _, k := range keys {
+		if counts[k] > max {
+			max = counts[k]
+			r = firsts[k]
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsql
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"reflect"
+)
+
+func init() {
+	// the history of lag is saved as a slice in the checkpoint
+	gob.Register([]interface{}{})
+}
+
+// analyticCall runs the analytic functions which remember the previous rows.
+// The last arg is the state key of the call which is appended by the valuer and
+// composed by the function id and the partition values. The state is saved in the
+// operator context, so it is restored after the rule restarts from a checkpoint.
+func analyticCall(ctx api.StreamContext, name string, args []interface{}) (interface{}, bool) {
+	if ctx == nil {
+		return fmt.Errorf("analytic function %s can only run in a rule", name), false
+	}
+	l := len(args) - 1
+	key := fmt.Sprintf("$$a_%s_%v", name, args[l])
+	args = args[:l]
+	switch name {
+	case "lag":
+		n := 1
+		if len(args) > 1 {
+			if v, err := cast.ToInt(args[1], cast.STRICT); err != nil || v <= 0 {
+				return fmt.Errorf("the second parameter of lag must be a positive integer, but got %v", args[1]), false
+			} else {
+				n = v
+			}
+		}
+		var h []interface{}
+		if v, err := ctx.GetState(key); err != nil {
+			return err, false
+		} else if v != nil {
+			h = v.([]interface{})
+		}
+		var r interface{}
+		if len(h) >= n {
+			r = h[len(h)-n]
+		}
+		// create a new slice so that the taken snapshot won't be changed
+		start := 0
+		if len(h) >= n {
+			start = len(h) - n + 1
+		}
+		nh := make([]interface{}, 0, n)
+		nh = append(nh, h[start:]...)
+		nh = append(nh, args[0])
+		if err := ctx.PutState(key, nh); err != nil {
+			return err, false
+		}
+		return r, true
+	case "latest":
+		if args[0] != nil {
+			if err := ctx.PutState(key, args[0]); err != nil {
+				return err, false
+			}
+			return args[0], true
+		}
+		if v, err := ctx.GetState(key); err != nil {
+			return err, false
+		} else if v != nil {
+			return v, true
+		}
+		if len(args) > 1 {
+			return args[1], true
+		}
+		return nil, true
+	case "changed_col":
+		ignoreNull, ok := args[0].(bool)
+		if !ok {
+			return fmt.Errorf("the first parameter of changed_col must be a bool, but got %v", args[0]), false
+		}
+		changed, err := updateChanged(ctx, key, ignoreNull, args[1])
+		if err != nil {
+			return err, false
+		}
+		if changed {
+			return args[1], true
+		}
+		return nil, true
+	case "had_changed":
+		ignoreNull, ok := args[0].(bool)
+		if !ok {
+			return fmt.Errorf("the first parameter of had_changed must be a bool, but got %v", args[0]), false
+		}
+		r := false
+		for i, arg := range args[1:] {
+			changed, err := updateChanged(ctx, fmt.Sprintf("%s_%d", key, i), ignoreNull, arg)
+			if err != nil {
+				return err, false
+			}
+			r = r || changed
+		}
+		return r, true
+	default:
+		return fmt.Errorf("unknown analytic function %s", name), false
+	}
+}
+
+// updateChanged compares the value with the last one saved in the state and
+// saves the new value. A nil value is skipped if ignoreNull is set.
+func updateChanged(ctx api.StreamContext, key string, ignoreNull bool, val interface{}) (bool, error) {
+	if val == nil && ignoreNull {
+		return false, nil
+	}
+	last, err := ctx.GetState(key)
+	if err != nil {
+		return false, err
+	}
+	if reflect.DeepEqual(last, val) {
+		return false, nil
+	}
+	return true, ctx.PutState(key, val)
+}
@@ -51,6 +51,10 @@ func validateFuncs(funcName string, args []ast.Expr) error {
 		return validateJsonFunc(lowerName, args)
 	case ast.OtherFunc:
 		return validateOtherFunc(lowerName, args)
+	case ast.AnalyticFunc:
+		return validateAnalyticFunc(lowerName, args)
+	case ast.WindowFunc:
+		return ast.ValidateLen(lowerName, 0, len(args))
 	default:
 		return fmt.Errorf("unkndow function %s", lowerName)
 	}
@@ -343,6 +347,37 @@ func validateOtherFunc(name string, args []ast.Expr) error {
 	return nil
 }
 
+func validateAnalyticFunc(name string, args []ast.Expr) error {
+	len := len(args)
+	switch name {
+	case "lag":
+		if len != 1 && len != 2 {
+			return fmt.Errorf("The arguments for %s should be 1 or 2.", name)
+		}
+		if len == 2 {
+			if n, ok := args[1].(*ast.IntegerLiteral); !ok || n.Val <= 0 {
+				return ast.ProduceErrInfo(name, 1, "positive int")
+			}
+		}
+	case "latest":
+		if len != 1 && len != 2 {
+			return fmt.Errorf("The arguments for %s should be 1 or 2.", name)
+		}
+	case "changed_col", "had_changed":
+		if name == "changed_col" {
+			if err := ast.ValidateLen(name, 2, len); err != nil {
+				return err
+			}
+		} else if len < 2 {
+			return fmt.Errorf("The arguments for %s should be at least 2.", name)
+		}
+		if ast.IsNumericArg(args[0]) || ast.IsStringArg(args[0]) || ast.IsTimeArg(args[0]) {
+			return ast.ProduceErrInfo(name, 0, "bool")
+		}
+	}
+	return nil
+}
+
 func validateJsonFunc(name string, args []ast.Expr) error {
 	len := len(args)
 	if err := ast.ValidateLen(name, 2, len); err != nil {
@@ -357,14 +392,32 @@ func validateJsonFunc(name string, args []ast.Expr) error {
 func validateAggFunc(name string, args []ast.Expr) error {
 	len := len(args)
 	switch name {
-	case "avg", "max", "min", "sum":
+	case "avg", "max", "min", "sum", "stddev", "stddevs", "var", "vars", "median":
 		if err := ast.ValidateLen(name, 1, len); err != nil {
 			return err
 		}
 		if ast.IsStringArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
 			return ast.ProduceErrInfo(name, 0, "number - float or int")
 		}
-	case "count":
+	case "percentile":
+		if err := ast.ValidateLen(name, 2, len); err != nil {
+			return err
+		}
+		if ast.IsStringArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+			return ast.ProduceErrInfo(name, 0, "number - float or int")
+		}
+		switch p := args[1].(type) {
+		case *ast.NumberLiteral:
+			if p.Val >= 0 && p.Val <= 1 {
+				return nil
+			}
+		case *ast.IntegerLiteral:
+			if p.Val == 0 || p.Val == 1 {
+				return nil
+			}
+		}
+		return ast.ProduceErrInfo(name, 1, "number between 0 and 1")
+	case "count", "mode":
 		if err := ast.ValidateLen(name, 1, len); err != nil {
 			return err
 		}
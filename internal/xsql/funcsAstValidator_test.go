@@ -481,6 +481,161 @@ func TestFuncValidator(t *testing.T) {
 			stmt: nil,
 			err:  "Expect bool type for 2 parameter of function deduplicate.",
 		},
+		{
+			s:    `SELECT stddev("a") from tbl`,
+			stmt: nil,
+			err:  "Expect number - float or int type for 1 parameter of function stddev.",
+		},
+		{
+			s:    `SELECT percentile(temp) from tbl`,
+			stmt: nil,
+			err:  "The arguments for percentile should be 2.",
+		},
+		{
+			s:    `SELECT percentile(temp, 95) from tbl`,
+			stmt: nil,
+			err:  "Expect number between 0 and 1 type for 2 parameter of function percentile.",
+		},
+		{
+			s: `SELECT percentile(temp, 0.95) from tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{{AName: "", Name: "percentile", Expr: &ast.Call{
+				Name: "percentile",
+				Args: []ast.Expr{&ast.FieldRef{Name: "temp", StreamName: ast.DefaultStream}, &ast.NumberLiteral{Val: 0.95}},
+			}}}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s:    `SELECT mode(a, b) from tbl`,
+			stmt: nil,
+			err:  "The arguments for mode should be 1.",
+		},
+		{
+			s: `SELECT lag(temp, 2) OVER (PARTITION BY id) from tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{{AName: "", Name: "lag", Expr: &ast.Call{
+				Name: "lag",
+				Args: []ast.Expr{&ast.FieldRef{Name: "temp", StreamName: ast.DefaultStream}, &ast.IntegerLiteral{Val: 2}},
+				Partition: &ast.PartitionExpr{
+					Exprs: []ast.Expr{&ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}},
+				},
+			}}}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s: `SELECT latest(temp), changed_col(true, temp) from tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{
+				{AName: "", Name: "latest", Expr: &ast.Call{Name: "latest", Args: []ast.Expr{&ast.FieldRef{Name: "temp", StreamName: ast.DefaultStream}}}},
+				{AName: "", Name: "changed_col", Expr: &ast.Call{Name: "changed_col", FuncId: 1, Args: []ast.Expr{&ast.BooleanLiteral{Val: true}, &ast.FieldRef{Name: "temp", StreamName: ast.DefaultStream}}}},
+			}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s:    `SELECT lag(temp, 0) from tbl`,
+			stmt: nil,
+			err:  "Expect positive int type for 2 parameter of function lag.",
+		},
+		{
+			s:    `SELECT latest(temp, 1, 2) from tbl`,
+			stmt: nil,
+			err:  "The arguments for latest should be 1 or 2.",
+		},
+		{
+			s:    `SELECT changed_col(temp) from tbl`,
+			stmt: nil,
+			err:  "The arguments for changed_col should be 2.",
+		},
+		{
+			s:    `SELECT had_changed("true", temp) from tbl`,
+			stmt: nil,
+			err:  "Expect bool type for 1 parameter of function had_changed.",
+		},
+		{
+			s:    `SELECT had_changed(true, temp) OVER (id) from tbl`,
+			stmt: nil,
+			err:  "found \"id\", expected PARTITION.",
+		},
+		{
+			s: `SELECT row_number() OVER (PARTITION BY id ORDER BY ts DESC), avg(temp) OVER (ORDER BY ts ROWS 2 PRECEDING) from tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{
+				{AName: "", Name: "row_number", Expr: &ast.Call{
+					Name: "row_number",
+					Over: &ast.OverExpr{
+						Partition:  &ast.PartitionExpr{Exprs: []ast.Expr{&ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}}},
+						SortFields: ast.SortFields{{Name: "ts", Ascending: false}},
+					},
+				}},
+				{AName: "", Name: "avg", Expr: &ast.Call{
+					Name:   "avg",
+					FuncId: 1,
+					Args:   []ast.Expr{&ast.FieldRef{Name: "temp", StreamName: ast.DefaultStream}},
+					Over: &ast.OverExpr{
+						SortFields: ast.SortFields{{Name: "ts", Ascending: true}},
+						Preceding:  2,
+					},
+				}},
+			}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s: `SELECT count(*) OVER (PARTITION BY id), rank() OVER () from tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{
+				{AName: "", Name: "count", Expr: &ast.Call{
+					Name: "count",
+					Args: []ast.Expr{&ast.Wildcard{Token: ast.ASTERISK}},
+					Over: &ast.OverExpr{
+						Partition: &ast.PartitionExpr{Exprs: []ast.Expr{&ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}}},
+					},
+				}},
+				{AName: "", Name: "rank", Expr: &ast.Call{
+					Name:   "rank",
+					FuncId: 1,
+					Over:   &ast.OverExpr{},
+				}},
+			}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s: `SELECT sum(temp) OVER (ORDER BY ts DESC, id ROWS 2 PRECEDING) from tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{
+				{AName: "", Name: "sum", Expr: &ast.Call{
+					Name: "sum",
+					Args: []ast.Expr{&ast.FieldRef{Name: "temp", StreamName: ast.DefaultStream}},
+					Over: &ast.OverExpr{
+						SortFields: ast.SortFields{{Name: "ts", Ascending: false}, {Name: "id", Ascending: true}},
+						Preceding:  2,
+					},
+				}},
+			}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s:    `SELECT had_changed(true, temp) OVER (PARTITION BY id ORDER BY ts) from tbl`,
+			stmt: nil,
+			err:  "found \"ORDER\", expected right paren.",
+		},
+		{
+			s:    `SELECT row_number() from tbl`,
+			stmt: nil,
+			err:  "window function row_number requires OVER clause.",
+		},
+		{
+			s:    `SELECT rank(temp) OVER (ORDER BY temp) from tbl`,
+			stmt: nil,
+			err:  "The arguments for rank should be 0.",
+		},
+		{
+			s:    `SELECT abs(temp) OVER (ORDER BY temp) from tbl`,
+			stmt: nil,
+			err:  "OVER clause is only allowed for window functions and aggregate functions, but found abs.",
+		},
+		{
+			s:    `SELECT sum(temp) OVER (PARTITION BY id ROWS 2 PRECEDING) from tbl`,
+			stmt: nil,
+			err:  "ROWS frame of function sum requires ORDER BY clause.",
+		},
+		{
+			s:    `SELECT sum(temp) OVER (ORDER BY ts ROWS 0 PRECEDING) from tbl`,
+			stmt: nil,
+			err:  "found \"0\", expected positive integer after ROWS.",
+		},
+		{
+			s:    `SELECT sum(temp) OVER (ORDER BY ts ROWS 2 FOLLOWING) from tbl`,
+			stmt: nil,
+			err:  "found \"FOLLOWING\", expected PRECEDING.",
+		},
 	}
 
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
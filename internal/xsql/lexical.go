@@ -187,8 +187,16 @@ func (s *Scanner) ScanIdent() (tok ast.Token, lit string) {
 		return ast.DESC, lit
 	case "ASC":
 		return ast.ASC, lit
+	case "LIMIT":
+		return ast.LIMIT, lit
+	case "OFFSET":
+		return ast.OFFSET, lit
 	case "FILTER":
 		return ast.FILTER, lit
+	case "OVER":
+		return ast.OVER, lit
+	case "PARTITION":
+		return ast.PARTITION, lit
 	case "INNER":
 		return ast.INNER, lit
 	case "LEFT":
@@ -263,6 +271,18 @@ func (s *Scanner) ScanIdent() (tok ast.Token, lit string) {
 		return ast.TRUE, lit
 	case "FALSE":
 		return ast.FALSE, lit
+	case "NOT":
+		return ast.NOT, lit
+	case "NULL":
+		return ast.NULL, lit
+	case "IN":
+		return ast.IN, lit
+	case "BETWEEN":
+		return ast.BETWEEN, lit
+	case "LIKE":
+		return ast.LIKE, lit
+	case "IS":
+		return ast.IS, lit
 	case "STRICT_VALIDATION":
 		return ast.STRICT_VALIDATION, lit
 	case "TIMESTAMP":
@@ -355,11 +375,34 @@ func (s *Scanner) ScanNumber(startWithDot bool, isNeg bool) (tok ast.Token, lit
 	}
 	if isNum || startWithDot {
 		return ast.NUMBER, buf.String()
+	} else if unit := s.peekDurationUnit(); unit != "" {
+		for range unit {
+			s.read()
+		}
+		buf.WriteString(unit)
+		return ast.DURATION, buf.String()
 	} else {
 		return ast.INTEGER, buf.String()
 	}
 }
 
+// peekDurationUnit returns the time unit which immediately follows an integer like 5s without consuming it
+func (s *Scanner) peekDurationUnit() string {
+	b, _ := s.r.Peek(3)
+	for _, unit := range []string{"ms", "s", "m", "h"} {
+		if !bytes.HasPrefix(b, []byte(unit)) {
+			continue
+		}
+		if len(b) > len(unit) {
+			if r := rune(b[len(unit)]); isLetter(r) || isDigit(r) || r == '_' {
+				return ""
+			}
+		}
+		return unit
+	}
+	return ""
+}
+
 func (s *Scanner) ScanBackquoteIdent() (tok ast.Token, lit string) {
 	var buf bytes.Buffer
 	for {
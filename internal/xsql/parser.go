@@ -24,6 +24,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Parser struct {
@@ -36,6 +37,14 @@ type Parser struct {
 		lit string
 	}
 	inmeta bool
+	// whether parsing the OVER clause, the sort fields stop at the ROWS frame in it
+	inover bool
+	// whether parsing the ON condition of join and the range of BETWEEN in it,
+	// duration literal like 5s is only allowed in the time range of the join condition
+	injoin  bool
+	inrange bool
+	// the number of analytic function calls parsed, used as the id of the next one
+	fn int
 }
 
 func (p *Parser) parseCondition() (ast.Expr, error) {
@@ -171,6 +180,13 @@ func (p *Parser) Parse() (*ast.SelectStatement, error) {
 		selects.SortFields = sorts
 	}
 
+	if limit, offset, err := p.parseLimit(); err != nil {
+		return nil, err
+	} else {
+		selects.Limit = limit
+		selects.Offset = offset
+	}
+
 	if tok, lit := p.scanIgnoreWhitespace(); tok == ast.SEMICOLON {
 		p.unscan()
 		return selects, nil
@@ -299,11 +315,13 @@ func (p *Parser) ParseJoin(joinType ast.JoinType) (*ast.Join, error) {
 			if ast.CROSS_JOIN == joinType {
 				return nil, fmt.Errorf("On expression is not required for cross join type.\n")
 			}
-			if exp, err := p.ParseExpr(); err != nil {
+			p.injoin = true
+			exp, err := p.ParseExpr()
+			p.injoin = false
+			if err != nil {
 				return nil, err
-			} else {
-				j.Expr = exp
 			}
+			j.Expr = exp
 		} else {
 			p.unscan()
 		}
@@ -356,6 +374,10 @@ func (p *Parser) parseSorts() (ast.SortFields, error) {
 		if t1, l1 := p.scanIgnoreWhitespace(); t1 == ast.BY {
 			for {
 				if t1, l1 = p.scanIgnoreWhitespace(); t1 == ast.IDENT {
+					if p.inover && len(ss) > 0 && strings.EqualFold(l1, "rows") {
+						p.unscan()
+						break
+					}
 					s := ast.SortField{Ascending: true}
 
 					p.unscan()
@@ -392,6 +414,41 @@ func (p *Parser) parseSorts() (ast.SortFields, error) {
 	return ss, nil
 }
 
+func (p *Parser) parseLimit() (int, int, error) {
+	if t, _ := p.scanIgnoreWhitespace(); t != ast.LIMIT {
+		p.unscan()
+		return 0, 0, nil
+	}
+	limit, err := p.parseNonNegativeInt(ast.LIMIT)
+	if err != nil {
+		return 0, 0, err
+	}
+	if limit == 0 {
+		return 0, 0, fmt.Errorf("LIMIT value must be larger than 0.")
+	}
+	if t, _ := p.scanIgnoreWhitespace(); t != ast.OFFSET {
+		p.unscan()
+		return limit, 0, nil
+	}
+	offset, err := p.parseNonNegativeInt(ast.OFFSET)
+	if err != nil {
+		return 0, 0, err
+	}
+	return limit, offset, nil
+}
+
+func (p *Parser) parseNonNegativeInt(keyword ast.Token) (int, error) {
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok != ast.INTEGER {
+		return 0, fmt.Errorf("found %q, expected non-negative integer after %s.", lit, keyword)
+	}
+	v, err := strconv.Atoi(lit)
+	if err != nil {
+		return 0, fmt.Errorf("found %q, invalid integer value for %s.", lit, keyword)
+	}
+	return v, nil
+}
+
 func (p *Parser) parseFields() (ast.Fields, error) {
 	var fields ast.Fields
 
@@ -458,6 +515,12 @@ func (p *Parser) parseAlias() (string, error) {
 }
 
 func (p *Parser) ParseExpr() (ast.Expr, error) {
+	return p.parseExprWithPrecedence(0)
+}
+
+// parseExprWithPrecedence parses an expression until it meets an operator whose precedence is lower than minPrec.
+// The operator with lower precedence will be left unscanned.
+func (p *Parser) parseExprWithPrecedence(minPrec int) (ast.Expr, error) {
 	var err error
 	root := &ast.BinaryExpr{}
 
@@ -467,19 +530,47 @@ func (p *Parser) ParseExpr() (ast.Expr, error) {
 	}
 
 	for {
-		op, _ := p.scanIgnoreWhitespace()
-		if !op.IsOperator() {
-			p.unscan()
-			return root.RHS, nil
-		} else if op == ast.ASTERISK { //Change the asterisk to Mul token.
+		op, n, err := p.scanOperator()
+		if err != nil {
+			return nil, err
+		}
+		if op == ast.ASTERISK { //Change the asterisk to Mul token.
 			op = ast.MUL
 		} else if op == ast.LBRACKET { //LBRACKET is a special token, need to unscan
 			op = ast.SUBSET
 			p.unscan()
+			n--
+		}
+		if !op.IsOperator() || op.Precedence() < minPrec {
+			for i := 0; i < n; i++ {
+				p.unscan()
+			}
+			return root.RHS, nil
 		}
 
 		var rhs ast.Expr
-		if rhs, err = p.parseUnaryExpr(op == ast.ARROW); err != nil {
+		switch op {
+		case ast.IN, ast.NOTIN:
+			rhs, err = p.parseValueSet()
+		case ast.BETWEEN, ast.NOTBETWEEN:
+			rhs, err = p.parseBetween()
+		case ast.LIKE, ast.NOTLIKE:
+			rhs, err = p.parseUnaryExpr(false)
+			if err == nil {
+				if _, ok := rhs.(ast.Literal); ok && !ast.IsStringArg(rhs) {
+					err = fmt.Errorf("expect string pattern for %s operator.", op)
+				}
+			}
+		case ast.IS, ast.ISNOT:
+			if tok, lit := p.scanIgnoreWhitespace(); tok != ast.NULL {
+				err = fmt.Errorf("found %q, expected NULL after %s.", lit, op)
+			} else {
+				rhs = &ast.NullLiteral{}
+			}
+		default:
+			rhs, err = p.parseUnaryExpr(op == ast.ARROW)
+		}
+		if err != nil {
 			return nil, err
 		}
 
@@ -492,8 +583,71 @@ func (p *Parser) ParseExpr() (ast.Expr, error) {
 			node = r
 		}
 	}
+}
 
-	return nil, nil
+// scanOperator scans the next operator and returns the count of the scanned tokens.
+// The keyword operators composed by multiple keywords like NOT IN are merged into one token.
+func (p *Parser) scanOperator() (ast.Token, int, error) {
+	op, _ := p.scanIgnoreWhitespace()
+	switch op {
+	case ast.NOT:
+		switch op1, lit1 := p.scanIgnoreWhitespace(); op1 {
+		case ast.IN:
+			return ast.NOTIN, 2, nil
+		case ast.BETWEEN:
+			return ast.NOTBETWEEN, 2, nil
+		case ast.LIKE:
+			return ast.NOTLIKE, 2, nil
+		default:
+			return ast.ILLEGAL, 2, fmt.Errorf("found %q, expected IN, BETWEEN or LIKE after NOT.", lit1)
+		}
+	case ast.IS:
+		if op1, _ := p.scanIgnoreWhitespace(); op1 == ast.NOT {
+			return ast.ISNOT, 2, nil
+		}
+		p.unscan()
+	}
+	return op, 1, nil
+}
+
+// parseValueSet parses the right side of IN operator. It can be a value list like (1, 2, 3) or an array expression
+func (p *Parser) parseValueSet() (ast.Expr, error) {
+	if tok, _ := p.scanIgnoreWhitespace(); tok != ast.LPAREN {
+		p.unscan()
+		return p.parseUnaryExpr(false)
+	}
+	vs := &ast.ValueSetExpr{}
+	for {
+		if exp, err := p.ParseExpr(); err != nil {
+			return nil, err
+		} else {
+			vs.LiteralExprs = append(vs.LiteralExprs, exp)
+		}
+		if tok, lit := p.scanIgnoreWhitespace(); tok == ast.RPAREN {
+			return vs, nil
+		} else if tok != ast.COMMA {
+			return nil, fmt.Errorf("found %q, expected comma or right paren in value list.", lit)
+		}
+	}
+}
+
+// parseBetween parses the range of BETWEEN operator like "1 AND 3". The AND here is not a logical operator,
+// so the bound expressions can only include operators with higher precedence than comparison.
+func (p *Parser) parseBetween() (ast.Expr, error) {
+	p.inrange = p.injoin
+	defer func() { p.inrange = false }()
+	lower, err := p.parseExprWithPrecedence(ast.ADD.Precedence())
+	if err != nil {
+		return nil, err
+	}
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.AND {
+		return nil, fmt.Errorf("found %q, expected AND in BETWEEN expression.", lit)
+	}
+	higher, err := p.parseExprWithPrecedence(ast.ADD.Precedence())
+	if err != nil {
+		return nil, err
+	}
+	return &ast.BetweenExpr{Lower: lower, Higher: higher}, nil
 }
 
 func (p *Parser) parseUnaryExpr(isSubField bool) (ast.Expr, error) {
@@ -517,6 +671,15 @@ func (p *Parser) parseUnaryExpr(isSubField bool) (ast.Expr, error) {
 	tok, lit := p.scanIgnoreWhiteSpaceWithNegativeNum()
 	if tok == ast.CASE {
 		return p.parseCaseExpr()
+	} else if tok == ast.NOT {
+		// NOT has lower precedence than comparison, so NOT a = 1 means NOT (a = 1)
+		expr, err := p.parseExprWithPrecedence(ast.EQ.Precedence())
+		if err != nil {
+			return nil, err
+		}
+		return &ast.UnaryExpr{OP: ast.NOT, Expr: expr}, nil
+	} else if tok == ast.NULL {
+		return &ast.NullLiteral{}, nil
 	} else if tok == ast.IDENT {
 		if tok1, _ := p.scanIgnoreWhitespace(); tok1 == ast.LPAREN {
 			return p.parseCall(lit)
@@ -549,6 +712,16 @@ func (p *Parser) parseUnaryExpr(isSubField bool) (ast.Expr, error) {
 	} else if tok == ast.INTEGER {
 		val, _ := strconv.Atoi(lit)
 		return &ast.IntegerLiteral{Val: val}, nil
+	} else if tok == ast.DURATION {
+		if !p.inrange {
+			return nil, fmt.Errorf("found %q, duration literal is only allowed in the time range of join condition like b.ts BETWEEN a.ts AND a.ts + 5s.", lit)
+		}
+		// duration literal like 5s is converted to milliseconds
+		if d, err := time.ParseDuration(lit); err != nil {
+			return nil, fmt.Errorf("found %q, invalid duration value.", lit)
+		} else {
+			return &ast.IntegerLiteral{Val: int(d.Milliseconds())}, nil
+		}
 	} else if tok == ast.NUMBER {
 		if v, err := strconv.ParseFloat(lit, 64); err != nil {
 			return nil, fmt.Errorf("found %q, invalid number value.", lit)
@@ -646,7 +819,7 @@ func (p *Parser) parseCall(name string) (ast.Expr, error) {
 			if valErr := validateFuncs(name, nil); valErr != nil {
 				return nil, valErr
 			}
-			return &ast.Call{Name: name, Args: args}, nil
+			return p.parseOver(&ast.Call{Name: name, Args: args})
 		} else if tok == ast.ASTERISK {
 			if tok2, lit2 := p.scanIgnoreWhitespace(); tok2 != ast.RPAREN {
 				return nil, fmt.Errorf("found %q, expected right paren.", lit2)
@@ -656,7 +829,7 @@ func (p *Parser) parseCall(name string) (ast.Expr, error) {
 				} else {
 					args = append(args, &ast.Wildcard{Token: ast.ASTERISK})
 				}
-				return &ast.Call{Name: name, Args: args}, nil
+				return p.parseOver(&ast.Call{Name: name, Args: args})
 			}
 		} else {
 			p.unscan()
@@ -685,7 +858,7 @@ func (p *Parser) parseCall(name string) (ast.Expr, error) {
 		if name == "deduplicate" {
 			args = append([]ast.Expr{&ast.Wildcard{Token: ast.ASTERISK}}, args...)
 		}
-		return &ast.Call{Name: name, Args: args}, nil
+		return p.parseOver(&ast.Call{Name: name, Args: args})
 	} else {
 		if error != nil {
 			return nil, error
@@ -705,6 +878,100 @@ func (p *Parser) parseCall(name string) (ast.Expr, error) {
 	}
 }
 
+// parsePartitionExprs parses the expressions after PARTITION keyword
+func (p *Parser) parsePartitionExprs() (*ast.PartitionExpr, error) {
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.BY {
+		return nil, fmt.Errorf("found %q, expected BY.", lit)
+	}
+	pe := &ast.PartitionExpr{}
+	for {
+		exp, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		pe.Exprs = append(pe.Exprs, exp)
+		if tok, _ := p.scanIgnoreWhitespace(); tok != ast.COMMA {
+			p.unscan()
+			break
+		}
+	}
+	return pe, nil
+}
+
+// parseOver parses the OVER ([PARTITION BY expr, ...] [ORDER BY field, ...] [ROWS n PRECEDING]) clause
+// of a window function or an aggregate function. The analytic functions only support OVER (PARTITION BY expr, ...).
+func (p *Parser) parseOver(c *ast.Call) (*ast.Call, error) {
+	isAnalyticFunc := ast.FuncFinderSingleton().IsAnalyticFunc(c.Name)
+	if isAnalyticFunc {
+		c.FuncId = p.fn
+		p.fn++
+	}
+	isWindowFunc := ast.FuncFinderSingleton().IsWindowFunc(c.Name)
+	if tok, _ := p.scanIgnoreWhitespace(); tok != ast.OVER {
+		p.unscan()
+		if isWindowFunc {
+			return nil, fmt.Errorf("window function %s requires OVER clause.", c.Name)
+		}
+		return c, nil
+	}
+	if !isAnalyticFunc && !isWindowFunc && !ast.FuncFinderSingleton().IsAggFunc(c) {
+		return nil, fmt.Errorf("OVER clause is only allowed for window functions and aggregate functions, but found %s.", c.Name)
+	}
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.LPAREN {
+		return nil, fmt.Errorf("found %q after OVER, expected left paren.", lit)
+	}
+	over := &ast.OverExpr{}
+	if tok, lit := p.scanIgnoreWhitespace(); tok == ast.PARTITION {
+		pe, err := p.parsePartitionExprs()
+		if err != nil {
+			return nil, err
+		}
+		over.Partition = pe
+	} else if isAnalyticFunc {
+		return nil, fmt.Errorf("found %q, expected PARTITION.", lit)
+	} else {
+		p.unscan()
+	}
+	if !isAnalyticFunc {
+		p.inover = true
+		sorts, err := p.parseSorts()
+		p.inover = false
+		if err != nil {
+			return nil, err
+		}
+		over.SortFields = sorts
+		if tok, lit := p.scanIgnoreWhitespace(); tok == ast.IDENT && strings.EqualFold(lit, "rows") {
+			if over.SortFields == nil {
+				return nil, fmt.Errorf("ROWS frame of function %s requires ORDER BY clause.", c.Name)
+			}
+			if tok, lit = p.scanIgnoreWhitespace(); tok != ast.INTEGER {
+				return nil, fmt.Errorf("found %q, expected positive integer after ROWS.", lit)
+			}
+			if n, err := strconv.Atoi(lit); err != nil || n <= 0 {
+				return nil, fmt.Errorf("found %q, expected positive integer after ROWS.", lit)
+			} else {
+				over.Preceding = n
+			}
+			if tok, lit = p.scanIgnoreWhitespace(); tok != ast.IDENT || !strings.EqualFold(lit, "preceding") {
+				return nil, fmt.Errorf("found %q, expected PRECEDING.", lit)
+			}
+		} else {
+			p.unscan()
+		}
+	}
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.RPAREN {
+		return nil, fmt.Errorf("found %q, expected right paren.", lit)
+	}
+	if isAnalyticFunc {
+		c.Partition = over.Partition
+		return c, nil
+	}
+	c.FuncId = p.fn
+	p.fn++
+	c.Over = over
+	return c, nil
+}
+
 func (p *Parser) parseCaseExpr() (*ast.CaseExpr, error) {
 	c := &ast.CaseExpr{}
 	tok, _ := p.scanIgnoreWhitespace()
@@ -813,6 +1080,17 @@ func validateWindows(name string, args []ast.Expr) (ast.WindowType, error) {
 		} else {
 			return ast.COUNT_WINDOW, fmt.Errorf("Invalid parameter count.")
 		}
+	case "statewindow":
+		if len(args) != 2 {
+			return ast.STATE_WINDOW, fmt.Errorf("The arguments for %s should be 2.\n", fname)
+		}
+		for i, arg := range args {
+			switch arg.(type) {
+			case *ast.IntegerLiteral, *ast.NumberLiteral, *ast.StringLiteral, *ast.TimeLiteral, *ast.BooleanLiteral:
+				return ast.STATE_WINDOW, fmt.Errorf("The %d argument for %s is expecting condition expression. \n", i+1, fname)
+			}
+		}
+		return ast.STATE_WINDOW, nil
 
 	}
 	return ast.NOT_WINDOW, nil
@@ -837,6 +1115,11 @@ func validateWindow(funcName string, expectLen int, args []ast.Expr) error {
 
 func (p *Parser) ConvertToWindows(wtype ast.WindowType, args []ast.Expr) (*ast.Window, error) {
 	win := &ast.Window{WindowType: wtype}
+	if wtype == ast.STATE_WINDOW {
+		win.BeginCondition = args[0]
+		win.EndCondition = args[1]
+		return win, nil
+	}
 	if wtype == ast.COUNT_WINDOW {
 		win.Length = &ast.IntegerLiteral{Val: args[0].(*ast.IntegerLiteral).Val}
 		if len(args) == 2 {
@@ -941,8 +1224,18 @@ func validateStream(stmt *ast.StreamStmt) error {
 		default:
 			return fmt.Errorf("'binary' format stream can have only one field")
 		}
+	case message.FormatProtobuf:
+		if stmt.Options.SCHEMAID == "" {
+			return fmt.Errorf("option 'schemaId' is required for 'protobuf' format")
+		}
 	default:
-		return fmt.Errorf("option 'format=%s' is invalid", f)
+		// delimited and the formats provided by plugins are validated when creating the converter
+	}
+	if stmt.Options.DELIMITER != "" && strings.ToLower(f) != message.FormatDelimited {
+		return fmt.Errorf("option 'delimiter' is only supported for 'delimited' format")
+	}
+	if stmt.Options.KIND != "" && stmt.StreamType != ast.TypeTable {
+		return fmt.Errorf("option 'kind' is only supported for table")
 	}
 	return nil
 }
@@ -1215,6 +1508,24 @@ func (p *Parser) parseStreamStructType() (ast.FieldType, error) {
 	return rf, nil
 }
 
+// streamOptionIdents are the option keys which are not reserved keywords so that they can still be used as
+// identifiers in the rule SQL. They are only recognized as keys in the stream options.
+var streamOptionIdents = map[string]ast.Token{
+	"KIND":      ast.KIND,
+	"SCHEMAID":  ast.SCHEMAID,
+	"DELIMITER": ast.DELIMITER,
+}
+
+func (p *Parser) scanStreamOptionKey() (ast.Token, string) {
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok == ast.IDENT {
+		if t, ok := streamOptionIdents[strings.ToUpper(lit)]; ok {
+			return t, strings.ToUpper(lit)
+		}
+	}
+	return tok, lit
+}
+
 func (p *Parser) parseStreamOptions() (*ast.Options, error) {
 	opts := &ast.Options{}
 	v := reflect.ValueOf(opts)
@@ -1222,7 +1533,7 @@ func (p *Parser) parseStreamOptions() (*ast.Options, error) {
 	if tok, lit := p.scanIgnoreWhitespace(); tok == ast.LPAREN {
 		lStack.Push(ast.LPAREN)
 		for {
-			if tok1, lit1 := p.scanIgnoreWhitespace(); tok1 == ast.DATASOURCE || tok1 == ast.FORMAT || tok1 == ast.KEY || tok1 == ast.CONF_KEY || tok1 == ast.STRICT_VALIDATION || tok1 == ast.TYPE || tok1 == ast.TIMESTAMP || tok1 == ast.TIMESTAMP_FORMAT || tok1 == ast.RETAIN_SIZE || tok1 == ast.SHARED {
+			if tok1, lit1 := p.scanStreamOptionKey(); tok1 == ast.DATASOURCE || tok1 == ast.FORMAT || tok1 == ast.KEY || tok1 == ast.CONF_KEY || tok1 == ast.STRICT_VALIDATION || tok1 == ast.TYPE || tok1 == ast.TIMESTAMP || tok1 == ast.TIMESTAMP_FORMAT || tok1 == ast.RETAIN_SIZE || tok1 == ast.SHARED || tok1 == ast.KIND || tok1 == ast.SCHEMAID || tok1 == ast.DELIMITER {
 				if tok2, lit2 := p.scanIgnoreWhitespace(); tok2 == ast.EQ {
 					if tok3, lit3 := p.scanIgnoreWhitespace(); tok3 == ast.STRING {
 						switch tok1 {
@@ -1244,6 +1555,12 @@ func (p *Parser) parseStreamOptions() (*ast.Options, error) {
 							} else {
 								opts.SHARED = (val == "TRUE")
 							}
+						case ast.KIND:
+							if val := strings.ToLower(lit3); (val != ast.TableKindScan) && (val != ast.TableKindLookup) {
+								return nil, fmt.Errorf("found %q, expect scan/lookup value in %s option.", lit3, tok1)
+							} else {
+								opts.KIND = val
+							}
 						default:
 							f := v.Elem().FieldByName(lit1)
 							if f.IsValid() {
@@ -1268,7 +1585,7 @@ func (p *Parser) parseStreamOptions() (*ast.Options, error) {
 					return nil, fmt.Errorf("Parenthesis is not matched in options definition.")
 				}
 			} else {
-				return nil, fmt.Errorf("found %q, unknown option keys(DATASOURCE|FORMAT|KEY|CONF_KEY|SHARED|STRICT_VALIDATION|TYPE|TIMESTAMP|TIMESTAMP_FORMAT|RETAIN_SIZE).", lit1)
+				return nil, fmt.Errorf("found %q, unknown option keys(DATASOURCE|FORMAT|KEY|CONF_KEY|SHARED|STRICT_VALIDATION|TYPE|TIMESTAMP|TIMESTAMP_FORMAT|RETAIN_SIZE|KIND|SCHEMAID|DELIMITER).", lit1)
 			}
 		}
 	} else {
@@ -255,7 +255,7 @@ func TestParser_ParseCreateStream(t *testing.T) {
 				StreamFields: nil,
 				Options:      nil,
 			},
-			err: `found "sources", unknown option keys(DATASOURCE|FORMAT|KEY|CONF_KEY|SHARED|STRICT_VALIDATION|TYPE|TIMESTAMP|TIMESTAMP_FORMAT|RETAIN_SIZE).`,
+			err: `found "sources", unknown option keys(DATASOURCE|FORMAT|KEY|CONF_KEY|SHARED|STRICT_VALIDATION|TYPE|TIMESTAMP|TIMESTAMP_FORMAT|RETAIN_SIZE|KIND|SCHEMAID|DELIMITER).`,
 		},
 
 		{
@@ -412,6 +412,58 @@ func TestParser_ParseCreateStream(t *testing.T) {
 					FORMAT:     "BINARY",
 				},
 			},
+		}, {
+			s: `CREATE STREAM demo (
+					name STRING,
+					id BIGINT
+				) WITH (DATASOURCE="users", FORMAT="protobuf", schemaid="test1.Person");`,
+			stmt: &ast.StreamStmt{
+				Name: ast.StreamName("demo"),
+				StreamFields: []ast.StreamField{
+					{Name: "name", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+					{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+				},
+				Options: &ast.Options{
+					DATASOURCE: "users",
+					FORMAT:     "protobuf",
+					SCHEMAID:   "test1.Person",
+				},
+			},
+		}, {
+			s: `CREATE STREAM demo () WITH (DATASOURCE="users", FORMAT="protobuf");`,
+			stmt: &ast.StreamStmt{
+				Name:         "",
+				StreamFields: nil,
+				Options:      nil,
+			},
+			err: "option 'schemaId' is required for 'protobuf' format",
+		}, {
+			s: `CREATE STREAM demo (
+					temperature FLOAT,
+					humidity BIGINT,
+					status STRING
+				) WITH (DATASOURCE="users", FORMAT="delimited", delimiter="|");`,
+			stmt: &ast.StreamStmt{
+				Name: ast.StreamName("demo"),
+				StreamFields: []ast.StreamField{
+					{Name: "temperature", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+					{Name: "humidity", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+					{Name: "status", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				},
+				Options: &ast.Options{
+					DATASOURCE: "users",
+					FORMAT:     "delimited",
+					DELIMITER:  "|",
+				},
+			},
+		}, {
+			s: `CREATE STREAM demo () WITH (DATASOURCE="users", DELIMITER="|");`,
+			stmt: &ast.StreamStmt{
+				Name:         "",
+				StreamFields: nil,
+				Options:      nil,
+			},
+			err: "option 'delimiter' is only supported for 'delimited' format",
 		},
 	}
 
@@ -43,6 +43,42 @@ func TestParser_ParseStatement(t *testing.T) {
 				Sources: []ast.Source{&ast.Table{Name: "tbl"}},
 			},
 		},
+		{
+			s: `SELECT kind FROM tbl`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr:  &ast.FieldRef{Name: "kind", StreamName: ast.DefaultStream},
+						Name:  "kind",
+						AName: ""},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "tbl"}},
+			},
+		},
+		{
+			s: `SELECT schemaid FROM tbl`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr:  &ast.FieldRef{Name: "schemaid", StreamName: ast.DefaultStream},
+						Name:  "schemaid",
+						AName: ""},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "tbl"}},
+			},
+		},
+		{
+			s: `SELECT delimiter FROM tbl`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr:  &ast.FieldRef{Name: "delimiter", StreamName: ast.DefaultStream},
+						Name:  "delimiter",
+						AName: ""},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "tbl"}},
+			},
+		},
 		{
 			s: "SELECT `select` FROM tbl",
 			stmt: &ast.SelectStatement{
@@ -514,6 +550,113 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: `SELECT abc FROM tbl WHERE abc IN (1, 2) AND def NOT IN ("a", "b")`,
+			stmt: &ast.SelectStatement{
+				Fields:  []ast.Field{{AName: "", Name: "abc", Expr: &ast.FieldRef{Name: "abc", StreamName: ast.DefaultStream}}},
+				Sources: []ast.Source{&ast.Table{Name: "tbl"}},
+				Condition: &ast.BinaryExpr{
+					LHS: &ast.BinaryExpr{
+						LHS: &ast.FieldRef{Name: "abc", StreamName: ast.DefaultStream},
+						OP:  ast.IN,
+						RHS: &ast.ValueSetExpr{LiteralExprs: []ast.Expr{&ast.IntegerLiteral{Val: 1}, &ast.IntegerLiteral{Val: 2}}},
+					},
+					OP: ast.AND,
+					RHS: &ast.BinaryExpr{
+						LHS: &ast.FieldRef{Name: "def", StreamName: ast.DefaultStream},
+						OP:  ast.NOTIN,
+						RHS: &ast.ValueSetExpr{LiteralExprs: []ast.Expr{&ast.StringLiteral{Val: "a"}, &ast.StringLiteral{Val: "b"}}},
+					},
+				},
+			},
+		},
+
+		{
+			s: `SELECT abc FROM tbl WHERE abc BETWEEN def - 1 AND def * 2 OR abc NOT BETWEEN 1 AND 3`,
+			stmt: &ast.SelectStatement{
+				Fields:  []ast.Field{{AName: "", Name: "abc", Expr: &ast.FieldRef{Name: "abc", StreamName: ast.DefaultStream}}},
+				Sources: []ast.Source{&ast.Table{Name: "tbl"}},
+				Condition: &ast.BinaryExpr{
+					LHS: &ast.BinaryExpr{
+						LHS: &ast.FieldRef{Name: "abc", StreamName: ast.DefaultStream},
+						OP:  ast.BETWEEN,
+						RHS: &ast.BetweenExpr{
+							Lower: &ast.BinaryExpr{
+								LHS: &ast.FieldRef{Name: "def", StreamName: ast.DefaultStream},
+								OP:  ast.SUB,
+								RHS: &ast.IntegerLiteral{Val: 1},
+							},
+							Higher: &ast.BinaryExpr{
+								LHS: &ast.FieldRef{Name: "def", StreamName: ast.DefaultStream},
+								OP:  ast.MUL,
+								RHS: &ast.IntegerLiteral{Val: 2},
+							},
+						},
+					},
+					OP: ast.OR,
+					RHS: &ast.BinaryExpr{
+						LHS: &ast.FieldRef{Name: "abc", StreamName: ast.DefaultStream},
+						OP:  ast.NOTBETWEEN,
+						RHS: &ast.BetweenExpr{Lower: &ast.IntegerLiteral{Val: 1}, Higher: &ast.IntegerLiteral{Val: 3}},
+					},
+				},
+			},
+		},
+
+		{
+			s: `SELECT abc FROM tbl WHERE NOT abc LIKE "sensor_%" AND def IS NOT NULL`,
+			stmt: &ast.SelectStatement{
+				Fields:  []ast.Field{{AName: "", Name: "abc", Expr: &ast.FieldRef{Name: "abc", StreamName: ast.DefaultStream}}},
+				Sources: []ast.Source{&ast.Table{Name: "tbl"}},
+				Condition: &ast.BinaryExpr{
+					LHS: &ast.UnaryExpr{
+						OP: ast.NOT,
+						Expr: &ast.BinaryExpr{
+							LHS: &ast.FieldRef{Name: "abc", StreamName: ast.DefaultStream},
+							OP:  ast.LIKE,
+							RHS: &ast.StringLiteral{Val: "sensor_%"},
+						},
+					},
+					OP: ast.AND,
+					RHS: &ast.BinaryExpr{
+						LHS: &ast.FieldRef{Name: "def", StreamName: ast.DefaultStream},
+						OP:  ast.ISNOT,
+						RHS: &ast.NullLiteral{},
+					},
+				},
+			},
+		},
+
+		{
+			s:    `SELECT abc FROM tbl WHERE abc NOT 1`,
+			stmt: nil,
+			err:  `found "1", expected IN, BETWEEN or LIKE after NOT.`,
+		},
+
+		{
+			s:    `SELECT abc FROM tbl WHERE abc = 1s`,
+			stmt: nil,
+			err:  `found "1s", duration literal is only allowed in the time range of join condition like b.ts BETWEEN a.ts AND a.ts + 5s.`,
+		},
+
+		{
+			s:    `SELECT abc FROM tbl WHERE abc IS 1`,
+			stmt: nil,
+			err:  `found "1", expected NULL after IS.`,
+		},
+
+		{
+			s:    `SELECT abc FROM tbl WHERE abc LIKE 1`,
+			stmt: nil,
+			err:  `expect string pattern for LIKE operator.`,
+		},
+
+		{
+			s:    `SELECT abc FROM tbl WHERE abc BETWEEN 1 OR 3`,
+			stmt: nil,
+			err:  `found "OR", expected AND in BETWEEN expression.`,
+		},
+
 		{
 			s: `SELECT abc FROM tbl WHERE abc = "hello" `,
 			stmt: &ast.SelectStatement{
@@ -1070,6 +1213,20 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: `SELECT * FROM topic/sensor1 ORDER BY name name2`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr:  &ast.Wildcard{Token: ast.ASTERISK},
+						Name:  "",
+						AName: ""},
+				},
+				Sources:    []ast.Source{&ast.Table{Name: "topic/sensor1"}},
+				SortFields: []ast.SortField{{Name: "name", Ascending: true}, {Name: "name2", Ascending: true}},
+			},
+		},
+
 		{
 			s: `SELECT * FROM topic/sensor1 GROUP BY name, name2,power(name3,1.8) ORDER BY name DESC, name2 ASC`,
 			stmt: &ast.SelectStatement{
@@ -1094,6 +1251,55 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: `SELECT * FROM topic/sensor1 ORDER BY name DESC LIMIT 5`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr:  &ast.Wildcard{Token: ast.ASTERISK},
+						Name:  "",
+						AName: ""},
+				},
+				Sources:    []ast.Source{&ast.Table{Name: "topic/sensor1"}},
+				SortFields: []ast.SortField{{Name: "name", Ascending: false}},
+				Limit:      5,
+			},
+		},
+
+		{
+			s: `SELECT * FROM topic/sensor1 ORDER BY name LIMIT 5 OFFSET 10`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr:  &ast.Wildcard{Token: ast.ASTERISK},
+						Name:  "",
+						AName: ""},
+				},
+				Sources:    []ast.Source{&ast.Table{Name: "topic/sensor1"}},
+				SortFields: []ast.SortField{{Name: "name", Ascending: true}},
+				Limit:      5,
+				Offset:     10,
+			},
+		},
+
+		{
+			s:    `SELECT * FROM topic/sensor1 LIMIT 0`,
+			stmt: nil,
+			err:  "LIMIT value must be larger than 0.",
+		},
+
+		{
+			s:    `SELECT * FROM topic/sensor1 LIMIT -1`,
+			stmt: nil,
+			err:  "found \"-\", expected non-negative integer after LIMIT.",
+		},
+
+		{
+			s:    `SELECT * FROM topic/sensor1 LIMIT 2 OFFSET abc`,
+			stmt: nil,
+			err:  "found \"abc\", expected non-negative integer after OFFSET.",
+		},
+
 		//{
 		//	s: `SELECT .2sd FROM tbl`,
 		//	stmt: &SelectStatement{
@@ -1625,6 +1831,45 @@ func TestParser_ParseWindowsExpr(t *testing.T) {
 				},
 			},
 		},
+		{
+			s: `SELECT count(*) FROM demo GROUP BY STATEWINDOW(state = "running", state = "stopped")`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr:  &ast.Call{Name: "count", Args: []ast.Expr{&ast.Wildcard{Token: ast.ASTERISK}}},
+						Name:  "count",
+						AName: ""},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "demo"}},
+				Dimensions: ast.Dimensions{
+					ast.Dimension{
+						Expr: &ast.Window{
+							WindowType: ast.STATE_WINDOW,
+							BeginCondition: &ast.BinaryExpr{
+								LHS: &ast.FieldRef{Name: "state", StreamName: ast.DefaultStream},
+								OP:  ast.EQ,
+								RHS: &ast.StringLiteral{Val: "running"},
+							},
+							EndCondition: &ast.BinaryExpr{
+								LHS: &ast.FieldRef{Name: "state", StreamName: ast.DefaultStream},
+								OP:  ast.EQ,
+								RHS: &ast.StringLiteral{Val: "stopped"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			s:    `SELECT count(*) FROM demo GROUP BY STATEWINDOW(state = "running")`,
+			stmt: nil,
+			err:  "The arguments for statewindow should be 2.\n",
+		},
+		{
+			s:    `SELECT count(*) FROM demo GROUP BY STATEWINDOW(true, state = "stopped")`,
+			stmt: nil,
+			err:  "The 1 argument for statewindow is expecting condition expression. \n",
+		},
 		{
 			s: `SELECT * FROM demo GROUP BY department, COUNTWINDOW(3,1) FILTER( where revenue > 100 ), year`,
 			stmt: &ast.SelectStatement{
@@ -2166,6 +2411,51 @@ func TestParser_ParseJoins(t *testing.T) {
 				},
 			},
 		},
+
+		{
+			s: `SELECT * FROM demo INNER JOIN demo2 ON demo2.ts BETWEEN demo.ts - 1m AND demo.ts + 5s`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr:  &ast.Wildcard{Token: ast.ASTERISK},
+						Name:  "",
+						AName: ""},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "demo"}},
+				Joins: []ast.Join{
+					{
+						Name: "demo2", Alias: "", JoinType: ast.INNER_JOIN, Expr: &ast.BinaryExpr{
+							LHS: &ast.FieldRef{StreamName: ast.StreamName("demo2"), Name: "ts"},
+							OP:  ast.BETWEEN,
+							RHS: &ast.BetweenExpr{
+								Lower: &ast.BinaryExpr{
+									LHS: &ast.FieldRef{StreamName: ast.StreamName("demo"), Name: "ts"},
+									OP:  ast.SUB,
+									RHS: &ast.IntegerLiteral{Val: 60000},
+								},
+								Higher: &ast.BinaryExpr{
+									LHS: &ast.FieldRef{StreamName: ast.StreamName("demo"), Name: "ts"},
+									OP:  ast.ADD,
+									RHS: &ast.IntegerLiteral{Val: 5000},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			s:    `SELECT * FROM demo INNER JOIN demo2 ON demo2.ts < demo.ts + 200ms`,
+			stmt: nil,
+			err:  `found "200ms", duration literal is only allowed in the time range of join condition like b.ts BETWEEN a.ts AND a.ts + 5s.`,
+		},
+
+		{
+			s:    `SELECT * FROM demo WHERE a BETWEEN 1s AND 2s`,
+			stmt: nil,
+			err:  `found "1s", duration literal is only allowed in the time range of join condition like b.ts BETWEEN a.ts AND a.ts + 5s.`,
+		},
 	}
 
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
@@ -85,6 +85,35 @@ func TestParser_ParseTree(t *testing.T) {
 				StreamType: ast.TypeTable,
 			},
 		},
+		{
+			s: `CREATE TABLE table2 (
+					id BIGINT,
+					code STRING
+				) WITH (DATASOURCE="codes", TYPE="sqlite", kind="Lookup");`,
+			stmt: &ast.StreamStmt{
+				Name: ast.StreamName("table2"),
+				StreamFields: []ast.StreamField{
+					{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+					{Name: "code", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				},
+				Options: &ast.Options{
+					DATASOURCE: "codes",
+					TYPE:       "sqlite",
+					KIND:       "lookup",
+				},
+				StreamType: ast.TypeTable,
+			},
+		},
+		{
+			s:    `CREATE TABLE table2 (id BIGINT) WITH (DATASOURCE="codes", KIND="index");`,
+			stmt: nil,
+			err:  `found "index", expect scan/lookup value in KIND option.`,
+		},
+		{
+			s:    `CREATE STREAM demo (id BIGINT) WITH (DATASOURCE="codes", KIND="lookup");`,
+			stmt: nil,
+			err:  `option 'kind' is only supported for table`,
+		},
 		{
 			s:    `SHOW STREAMS`,
 			stmt: &ast.ShowStreamsStatement{},
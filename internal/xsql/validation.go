@@ -0,0 +1,39 @@
+// Copyright 2021 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsql
+
+import "strings"
+
+// ValidationError is the result of the strict validation of a message against the stream definition.
+// Each error message contains the full json path of the invalid field such as a.b[1].c
+type ValidationError struct {
+	// The original message which fails the validation
+	Message Message
+	Errors  []string
+	// The operation which validates the message such as preprocessor, used as the prefix of the error
+	Op string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Op != "" {
+		return "error in " + e.Op + ": " + strings.Join(e.Errors, "; ")
+	}
+	return "invalid data: " + strings.Join(e.Errors, "; ")
+}
+
+// Add records the error of an invalid field
+func (e *ValidationError) Add(err error) {
+	e.Errors = append(e.Errors, err.Error())
+}
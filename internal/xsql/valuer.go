@@ -20,8 +20,11 @@ import (
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"math"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -353,20 +356,32 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 		return expr.Val
 	case *ast.BooleanLiteral:
 		return expr.Val
+	case *ast.NullLiteral:
+		return nil
+	case *ast.UnaryExpr:
+		return v.evalUnaryExpr(expr)
 	case *ast.ColonExpr:
 		return &BracketEvalResult{Start: expr.Start, End: expr.End}
 	case *ast.IndexExpr:
 		return &BracketEvalResult{Start: expr.Index, End: expr.Index}
 	case *ast.Call:
+		if expr.Over != nil {
+			// The result is calculated in advance by the window function operator for each row
+			val, _ := v.Valuer.Value(PRIVATE_PREFIX + WindowFuncKey(expr))
+			return val
+		}
 		if valuer, ok := v.Valuer.(CallValuer); ok {
 			switch expr.Name {
-			case "window_start", "window_end":
+			case "window_start", "window_end", "window_trigger":
 				if aggreValuer, ok := valuer.(AggregateCallValuer); ok {
 					ad := aggreValuer.GetAllTuples()
-					if expr.Name == "window_start" {
+					switch expr.Name {
+					case "window_start":
 						return ad.GetWindowStart()
-					} else {
+					case "window_end":
 						return ad.GetWindowEnd()
+					default:
+						return ad.GetWindowTrigger()
 					}
 				}
 			default:
@@ -384,6 +399,13 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 						}
 					}
 				}
+				if ast.FuncFinderSingleton().IsAnalyticFunc(expr.Name) {
+					key, err := v.evalPartitionKey(expr)
+					if err != nil {
+						return err
+					}
+					args = append(args, key)
+				}
 				val, _ := valuer.Call(expr.Name, args)
 				return val
 			}
@@ -429,15 +451,40 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 	}
 }
 
+// WindowFuncKey returns the alias key to save the result of a function call with OVER clause in a row
+func WindowFuncKey(expr *ast.Call) string {
+	return fmt.Sprintf("window_func_%d", expr.FuncId)
+}
+
+// evalPartitionKey returns the state key of an analytic function call for the current row
+func (v *ValuerEval) evalPartitionKey(expr *ast.Call) (string, error) {
+	key := strconv.Itoa(expr.FuncId)
+	if expr.Partition != nil {
+		for _, e := range expr.Partition.Exprs {
+			r := v.Eval(e)
+			if err, ok := r.(error); ok {
+				return "", err
+			}
+			key += fmt.Sprintf("_%v", r)
+		}
+	}
+	return key, nil
+}
+
 func (v *ValuerEval) evalBinaryExpr(expr *ast.BinaryExpr) interface{} {
 	lhs := v.Eval(expr.LHS)
+	if err, ok := lhs.(error); ok {
+		return err
+	}
+	switch expr.OP {
+	case ast.IN, ast.NOTIN, ast.BETWEEN, ast.NOTBETWEEN, ast.LIKE, ast.NOTLIKE, ast.IS, ast.ISNOT:
+		return v.evalPredicate(lhs, expr.OP, expr.RHS)
+	}
 	switch val := lhs.(type) {
 	case map[string]interface{}:
 		return v.evalJsonExpr(val, expr.OP, expr.RHS)
 	case Message:
 		return v.evalJsonExpr(map[string]interface{}(val), expr.OP, expr.RHS)
-	case error:
-		return val
 	}
 	// shortcut for bool
 	switch expr.OP {
@@ -460,6 +507,182 @@ func (v *ValuerEval) evalBinaryExpr(expr *ast.BinaryExpr) interface{} {
 	return v.simpleDataEval(lhs, rhs, expr.OP)
 }
 
+func (v *ValuerEval) evalUnaryExpr(expr *ast.UnaryExpr) interface{} {
+	val := v.Eval(expr.Expr)
+	switch r := val.(type) {
+	case error:
+		return r
+	case nil:
+		return nil
+	case bool:
+		if expr.OP == ast.NOT {
+			return !r
+		}
+	}
+	return fmt.Errorf("invalid operation %s %[2]T(%[2]v)", ast.Tokens[expr.OP], val)
+}
+
+// evalPredicate evaluates the keyword operators. The result is always a bool value or an error.
+func (v *ValuerEval) evalPredicate(lhs interface{}, op ast.Token, rhsExpr ast.Expr) interface{} {
+	var r interface{}
+	switch op {
+	case ast.IS:
+		return lhs == nil
+	case ast.ISNOT:
+		return lhs != nil
+	case ast.IN, ast.NOTIN:
+		r = v.evalIn(lhs, rhsExpr)
+	case ast.BETWEEN, ast.NOTBETWEEN:
+		r = v.evalBetween(lhs, rhsExpr)
+	case ast.LIKE, ast.NOTLIKE:
+		r = v.evalLike(lhs, rhsExpr)
+	}
+	if b, ok := r.(bool); ok && (op == ast.NOTIN || op == ast.NOTBETWEEN || op == ast.NOTLIKE) {
+		return !b
+	}
+	return r
+}
+
+func (v *ValuerEval) evalIn(lhs interface{}, expr ast.Expr) interface{} {
+	var values []interface{}
+	if vs, ok := expr.(*ast.ValueSetExpr); ok {
+		values = make([]interface{}, len(vs.LiteralExprs))
+		for i, e := range vs.LiteralExprs {
+			values[i] = v.Eval(e)
+			if err, ok := values[i].(error); ok {
+				return err
+			}
+		}
+	} else {
+		rhs := v.Eval(expr)
+		if err, ok := rhs.(error); ok {
+			return err
+		}
+		if rhs == nil {
+			return false
+		}
+		if !isSliceOrArray(rhs) {
+			return invalidOpError(lhs, ast.IN, rhs)
+		}
+		rv := reflect.ValueOf(rhs)
+		values = make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			values[i] = rv.Index(i).Interface()
+		}
+	}
+	for _, val := range values {
+		switch r := v.simpleDataEval(lhs, val, ast.EQ).(type) {
+		case error:
+			return r
+		case bool:
+			if r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *ValuerEval) evalBetween(lhs interface{}, expr ast.Expr) interface{} {
+	b, ok := expr.(*ast.BetweenExpr)
+	if !ok {
+		return fmt.Errorf("invalid range for BETWEEN operator")
+	}
+	if lhs == nil {
+		return false
+	}
+	for i, bound := range []ast.Expr{b.Lower, b.Higher} {
+		bv := v.Eval(bound)
+		if err, ok := bv.(error); ok {
+			return err
+		}
+		op := ast.GTE
+		if i == 1 {
+			op = ast.LTE
+		}
+		switch r := v.simpleDataEval(lhs, bv, op).(type) {
+		case error:
+			return r
+		case bool:
+			if !r {
+				return false
+			}
+		default:
+			return invalidOpError(lhs, op, bv)
+		}
+	}
+	return true
+}
+
+func (v *ValuerEval) evalLike(lhs interface{}, expr ast.Expr) interface{} {
+	rhs := v.Eval(expr)
+	if err, ok := rhs.(error); ok {
+		return err
+	}
+	if lhs == nil || rhs == nil {
+		return false
+	}
+	ls, ok := lhs.(string)
+	if !ok {
+		return invalidOpError(lhs, ast.LIKE, rhs)
+	}
+	ps, ok := rhs.(string)
+	if !ok {
+		return invalidOpError(lhs, ast.LIKE, rhs)
+	}
+	// Only cache the constant patterns to avoid unlimited growth of the cache
+	_, isLiteral := expr.(*ast.StringLiteral)
+	re, err := likeRegexp(ps, isLiteral)
+	if err != nil {
+		return err
+	}
+	return re.MatchString(ls)
+}
+
+var likePatterns sync.Map
+
+// likeRegexp converts the LIKE pattern to a regular expression. The % wildcard matches any sequence of characters
+// and the _ wildcard matches any single character. Use backslash to escape the wildcards.
+func likeRegexp(pattern string, cache bool) (*regexp.Regexp, error) {
+	if cache {
+		if re, ok := likePatterns.Load(pattern); ok {
+			return re.(*regexp.Regexp), nil
+		}
+	}
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	escaped := false
+	for _, c := range pattern {
+		if escaped {
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			escaped = false
+			continue
+		}
+		switch c {
+		case '\\':
+			escaped = true
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if escaped {
+		b.WriteString(`\\`)
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid LIKE pattern %s: %v", pattern, err)
+	}
+	if cache {
+		likePatterns.Store(pattern, re)
+	}
+	return re, nil
+}
+
 func (v *ValuerEval) evalCase(expr *ast.CaseExpr) interface{} {
 	if expr.Value != nil { // compare value to all when clause
 		ev := v.Eval(expr.Value)
@@ -168,6 +168,88 @@ func TestComparison(t *testing.T) {
 	}
 }
 
+func TestPredicates(t *testing.T) {
+	data := []struct {
+		m Message
+		r []interface{}
+	}{
+		{
+			m: map[string]interface{}{
+				"a":   int64(32),
+				"b":   "sensor_01",
+				"arr": []interface{}{int64(1), int64(32)},
+			},
+			r: []interface{}{
+				true, false, true, false, true, true,
+				true, false, true, false, true, false,
+			},
+		}, {
+			m: map[string]interface{}{
+				"a":   float64(3.5),
+				"b":   "sensor201",
+				"arr": []interface{}{int64(1), int64(32)},
+			},
+			r: []interface{}{
+				false, true, true, false, false, false,
+				false, false, false, true, true, false,
+			},
+		}, {
+			m: map[string]interface{}{
+				"c": "nothing",
+			},
+			r: []interface{}{
+				false, true, false, true, false, true,
+				false, true, false, true, false, true,
+			},
+		}, {
+			m: map[string]interface{}{
+				"a":   "32",
+				"b":   int64(12),
+				"arr": "not array",
+			},
+			r: []interface{}{
+				errors.New("invalid operation string(32) = int64(1)"), errors.New("invalid operation string(32) = int64(1)"), errors.New("invalid operation string(32) >= int64(1)"), errors.New("invalid operation string(32) >= int64(1)"), errors.New("invalid operation int64(12) LIKE string(sensor\\_%)"), errors.New("invalid operation int64(12) LIKE string(%2%)"),
+				errors.New("invalid operation string(32) IN string(not array)"), false, errors.New("invalid operation string(32) > int64(10)"), errors.New("invalid operation string(32) > int64(10)"), true, false,
+			},
+		},
+	}
+	sqls := []string{
+		"select * from src where a IN (1, 32, 64)",
+		"select * from src where a NOT IN (1, 32, 64)",
+		"select * from src where a BETWEEN 1 AND 16 * 2",
+		"select * from src where a NOT BETWEEN 1 AND 32",
+		"select * from src where b LIKE \"sensor\\\\_%\"",
+		"select * from src where b NOT LIKE \"%2%\"",
+		"select * from src where a IN arr",
+		"select * from src where a IS NULL",
+		"select * from src where a IS NOT NULL AND a > 10",
+		"select * from src where NOT a > 10",
+		"select * from src where NOT a IS NULL",
+		"select * from src where NOT (b IS NOT NULL)",
+	}
+	var conditions []ast.Expr
+	for _, sql := range sqls {
+		stmt, err := NewParser(strings.NewReader(sql)).Parse()
+		if err != nil {
+			t.Errorf("parse sql %s error: %s", sql, err)
+			return
+		}
+		conditions = append(conditions, stmt.Condition)
+	}
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(data)*len(sqls))
+	for i, tt := range data {
+		for j, c := range conditions {
+			tuple := &Tuple{Emitter: "src", Message: tt.m, Timestamp: conf.GetNowInMilli(), Metadata: nil}
+			ve := &ValuerEval{Valuer: MultiValuer(tuple)}
+			result := ve.Eval(c)
+			if !reflect.DeepEqual(tt.r[j], result) {
+				t.Errorf("%d-%d. %s\nstmt mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, j, sqls[j], tt.r[j], result)
+			}
+		}
+	}
+}
+
 func TestCalculation(t *testing.T) {
 	data := []struct {
 		m Message
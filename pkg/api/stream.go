@@ -86,6 +86,18 @@ type TableSource interface {
 	Configure(datasource string, props map[string]interface{}) error
 }
 
+// LookupSource queries the external data source by keys on demand instead of loading all the data in memory
+type LookupSource interface {
+	// Open creates the connection to the external data source
+	Open(ctx StreamContext) error
+	//Called during initialization. Configure the source with the data source(e.g. table name for sql) and the properties
+	//read from the yaml
+	Configure(datasource string, props map[string]interface{}) error
+	// Lookup returns the rows whose key columns equal to the values
+	Lookup(ctx StreamContext, keys []string, values []interface{}) ([]SourceTuple, error)
+	Closable
+}
+
 type Sink interface {
 	//Should be sync function for normal case. The container will run it in go func
 	Open(ctx StreamContext) error
@@ -113,6 +125,22 @@ type Rewindable interface {
 	Rewind(offset interface{}) error
 }
 
+// Acknowledgeable is implemented by the Rewindable sources which acknowledge the consumed messages to the upstream.
+// If the rule runs with at least once qos, Ack is called with the offset saved in a checkpoint once the checkpoint
+// completes. Otherwise, it is called with the offset right after each tuple is processed.
+// The source must acknowledge all the messages up to the offset.
+type Acknowledgeable interface {
+	Ack(offset interface{}) error
+}
+
+// OffsetTuple is a SourceTuple carrying the offset of the source right after it. When the tuple is processed, the
+// source node takes the offset of the tuple instead of calling GetOffset of the source, so that the tuples buffered
+// in the source node are not covered.
+type OffsetTuple interface {
+	SourceTuple
+	Offset() interface{}
+}
+
 type RuleOption struct {
 	IsEventTime        bool  `json:"isEventTime" yaml:"isEventTime"`
 	LateTol            int64 `json:"lateTolerance" yaml:"lateTolerance"`
@@ -122,6 +150,17 @@ type RuleOption struct {
 	SendError          bool  `json:"sendError" yaml:"sendError"`
 	Qos                Qos   `json:"qos" yaml:"qos"`
 	CheckpointInterval int   `json:"checkpointInterval" yaml:"checkpointInterval"`
+	// The time in millisecond after which a stream without new events is excluded from the watermark calculation
+	IdleTimeout int64 `json:"idleTimeout" yaml:"idleTimeout"`
+	// The idle timeout of each stream by the stream name, which overrides idleTimeout for that stream
+	IdleTimeouts map[string]int64 `json:"idleTimeouts" yaml:"idleTimeouts"`
+	// Emit the intermediate results of a tumbling window every emitInterval milliseconds or every emitCount events
+	EmitInterval int `json:"emitInterval" yaml:"emitInterval"`
+	EmitCount    int `json:"emitCount" yaml:"emitCount"`
+	// The actions to receive the events which arrive later than the watermark. Only available for event time window
+	LateActions []map[string]interface{} `json:"lateActions" yaml:"lateActions"`
+	// The actions to receive the records which fail the strict validation of the source streams
+	InvalidActions []map[string]interface{} `json:"invalidActions" yaml:"invalidActions"`
 }
 
 type Rule struct {
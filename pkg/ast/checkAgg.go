@@ -71,3 +71,19 @@ func HasAggFuncs(node Node) bool {
 	})
 	return r
 }
+
+// HasWindowFuncs checks if the node contains any function call with OVER clause
+func HasWindowFuncs(node Node) bool {
+	if node == nil {
+		return false
+	}
+	var r = false
+	WalkFunc(node, func(n Node) bool {
+		if f, ok := n.(*Call); ok && f.Over != nil {
+			r = true
+			return false
+		}
+		return true
+	})
+	return r
+}
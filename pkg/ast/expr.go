@@ -78,6 +78,8 @@ type NumberLiteral struct {
 	Val float64
 }
 
+type NullLiteral struct{}
+
 type Wildcard struct {
 	Token Token
 }
@@ -120,15 +122,42 @@ func (sl *StringLiteral) expr()    {}
 func (sl *StringLiteral) literal() {}
 func (sl *StringLiteral) node()    {}
 
+func (nl *NullLiteral) expr()    {}
+func (nl *NullLiteral) literal() {}
+func (nl *NullLiteral) node()    {}
+
 type Call struct {
 	Name string
 	Args []Expr
+	// FuncId identifies an analytic function call in a statement so that its state is kept separately
+	FuncId int
+	// Partition is the optional OVER (PARTITION BY ...) clause of an analytic function
+	Partition *PartitionExpr
+	// Over is the OVER clause of a window function or an aggregate function running as a window function
+	Over *OverExpr
 }
 
 func (c *Call) expr()    {}
 func (c *Call) literal() {}
 func (c *Call) node()    {}
 
+type PartitionExpr struct {
+	Exprs []Expr
+}
+
+func (pe *PartitionExpr) expr() {}
+func (pe *PartitionExpr) node() {}
+
+type OverExpr struct {
+	Partition  *PartitionExpr
+	SortFields SortFields
+	// Preceding is the row count of the ROWS n PRECEDING frame. 0 means the frame starts from the partition start
+	Preceding int
+}
+
+func (oe *OverExpr) expr() {}
+func (oe *OverExpr) node() {}
+
 type BinaryExpr struct {
 	OP  Token
 	LHS Expr
@@ -138,6 +167,32 @@ type BinaryExpr struct {
 func (fe *BinaryExpr) expr() {}
 func (be *BinaryExpr) node() {}
 
+// UnaryExpr is a prefix operator on an expression such as NOT
+type UnaryExpr struct {
+	OP   Token
+	Expr Expr
+}
+
+func (ue *UnaryExpr) expr() {}
+func (ue *UnaryExpr) node() {}
+
+// ValueSetExpr is the value list in the right side of the IN operator, like (1, 2, 3)
+type ValueSetExpr struct {
+	LiteralExprs []Expr
+}
+
+func (vs *ValueSetExpr) expr() {}
+func (vs *ValueSetExpr) node() {}
+
+// BetweenExpr is the range in the right side of the BETWEEN operator, like 1 AND 3
+type BetweenExpr struct {
+	Lower  Expr
+	Higher Expr
+}
+
+func (b *BetweenExpr) expr() {}
+func (b *BetweenExpr) node() {}
+
 type WhenClause struct {
 	// The condition Expression
 	Expr   Expr
@@ -59,11 +59,13 @@ func IsBooleanArg(arg Expr) bool {
 		return true
 	case *BinaryExpr:
 		switch t.OP {
-		case AND, OR, EQ, NEQ, LT, LTE, GT, GTE:
+		case AND, OR, EQ, NEQ, LT, LTE, GT, GTE, IN, NOTIN, BETWEEN, NOTBETWEEN, LIKE, NOTLIKE, IS, ISNOT:
 			return true
 		default:
 			return false
 		}
+	case *UnaryExpr:
+		return t.OP == NOT
 	default:
 		return false
 	}
@@ -31,20 +31,28 @@ const (
 	HashFunc
 	JsonFunc
 	OtherFunc
+	AnalyticFunc
+	WindowFunc
 )
 
 var maps = []map[string]string{
-	aggFuncMap, mathFuncMap, strFuncMap, convFuncMap, hashFuncMap, jsonFuncMap, otherFuncMap,
+	aggFuncMap, mathFuncMap, strFuncMap, convFuncMap, hashFuncMap, jsonFuncMap, otherFuncMap, analyticFuncMap, windowFuncMap,
 }
 
 var aggFuncMap = map[string]string{"avg": "",
 	"count": "",
 	"max":   "", "min": "",
-	"sum":          "",
-	"collect":      "",
-	"deduplicate":  "",
-	"window_start": "",
-	"window_end":   "",
+	"sum":            "",
+	"collect":        "",
+	"deduplicate":    "",
+	"window_start":   "",
+	"window_end":     "",
+	"window_trigger": "",
+	"stddev":         "", "stddevs": "",
+	"var": "", "vars": "",
+	"median":     "",
+	"percentile": "",
+	"mode":       "",
 }
 
 var funcWithAsteriskSupportMap = map[string]string{
@@ -93,6 +101,14 @@ var otherFuncMap = map[string]string{"isnull": "",
 	"newuuid": "", "tstamp": "", "mqtt": "", "meta": "", "cardinality": "",
 }
 
+var analyticFuncMap = map[string]string{
+	"lag": "", "latest": "", "changed_col": "", "had_changed": "",
+}
+
+var windowFuncMap = map[string]string{
+	"row_number": "", "rank": "", "dense_rank": "",
+}
+
 type FuncRuntime interface {
 	Get(name string) (api.Function, api.FunctionContext, error)
 }
@@ -121,6 +137,10 @@ func FuncFinderSingleton() *FuncFinder {
 }
 
 func (ff *FuncFinder) IsAggFunc(f *Call) bool {
+	// An aggregate function with OVER clause is calculated for each row, thus not aggregate
+	if f.Over != nil {
+		return false
+	}
 	fn := strings.ToLower(f.Name)
 	if _, ok := aggFuncMap[fn]; ok {
 		return true
@@ -134,6 +154,10 @@ func (ff *FuncFinder) IsAggFunc(f *Call) bool {
 		return false
 	} else if _, ok := mathFuncMap[fn]; ok {
 		return false
+	} else if _, ok := analyticFuncMap[fn]; ok {
+		return false
+	} else if _, ok := windowFuncMap[fn]; ok {
+		return false
 	} else {
 		if nf, _, err := ff.runtime.Get(f.Name); err == nil {
 			if nf.IsAggregate() {
@@ -146,6 +170,18 @@ func (ff *FuncFinder) IsAggFunc(f *Call) bool {
 	return false
 }
 
+// IsAnalyticFunc checks if the function keeps state of the previous rows
+func (ff *FuncFinder) IsAnalyticFunc(name string) bool {
+	_, ok := analyticFuncMap[strings.ToLower(name)]
+	return ok
+}
+
+// IsWindowFunc checks if the function can only run with an OVER clause
+func (ff *FuncFinder) IsWindowFunc(name string) bool {
+	_, ok := windowFuncMap[strings.ToLower(name)]
+	return ok
+}
+
 func (ff *FuncFinder) FuncType(name string) FuncType {
 	for i, m := range maps {
 		if _, ok := m[strings.ToLower(name)]; ok {
@@ -88,8 +88,21 @@ type Options struct {
 	TIMESTAMP_FORMAT  string
 	RETAIN_SIZE       int
 	SHARED            bool
+	// The kind of table, scan or lookup
+	KIND string
+	// The schema id like fileName.messageName for the schema based format such as protobuf
+	SCHEMAID string
+	// The column separator for the delimited format
+	DELIMITER string
 }
 
+const (
+	// TableKindScan loads the whole table into memory
+	TableKindScan = "scan"
+	// TableKindLookup queries the external data source by keys on demand
+	TableKindLookup = "lookup"
+)
+
 func (o Options) node() {}
 
 type ShowStreamsStatement struct {
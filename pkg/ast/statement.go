@@ -27,6 +27,10 @@ type SelectStatement struct {
 	Dimensions Dimensions
 	Having     Expr
 	SortFields SortFields
+	// Limit is the max rows to emit for each input. 0 means no limit
+	Limit int
+	// Offset is the count of rows to skip before emitting
+	Offset int
 
 	Statement
 }
@@ -166,6 +170,7 @@ const (
 	SLIDING_WINDOW
 	SESSION_WINDOW
 	COUNT_WINDOW
+	STATE_WINDOW
 )
 
 type Window struct {
@@ -173,6 +178,9 @@ type Window struct {
 	Length     *IntegerLiteral
 	Interval   *IntegerLiteral
 	Filter     Expr
+	// BeginCondition and EndCondition are only for state window
+	BeginCondition Expr
+	EndCondition   Expr
 	Expr
 }
 
@@ -29,6 +29,7 @@ const (
 
 	INTEGER   // 12345
 	NUMBER    //12345.67
+	DURATION  // 5s
 	STRING    // "abc"
 	BADSTRING // "abc
 
@@ -56,6 +57,15 @@ const (
 	SUBSET //[
 	ARROW  //->
 
+	IN         // IN
+	NOTIN      // NOT IN
+	BETWEEN    // BETWEEN
+	NOTBETWEEN // NOT BETWEEN
+	LIKE       // LIKE
+	NOTLIKE    // NOT LIKE
+	IS         // IS
+	ISNOT      // IS NOT
+
 	operatorEnd
 
 	// Misc characters
@@ -88,7 +98,11 @@ const (
 	BY
 	ASC
 	DESC
+	LIMIT
+	OFFSET
 	FILTER
+	OVER
+	PARTITION
 	CASE
 	WHEN
 	THEN
@@ -97,6 +111,8 @@ const (
 
 	TRUE
 	FALSE
+	NOT
+	NULL
 
 	CREATE
 	DROP
@@ -128,6 +144,9 @@ const (
 	TIMESTAMP_FORMAT
 	RETAIN_SIZE
 	SHARED
+	KIND
+	SCHEMAID
+	DELIMITER
 
 	DD
 	HH
@@ -137,14 +156,15 @@ const (
 )
 
 var Tokens = []string{
-	ILLEGAL: "ILLEGAL",
-	EOF:     "EOF",
-	AS:      "AS",
-	WS:      "WS",
-	IDENT:   "IDENT",
-	INTEGER: "INTEGER",
-	NUMBER:  "NUMBER",
-	STRING:  "STRING",
+	ILLEGAL:  "ILLEGAL",
+	EOF:      "EOF",
+	AS:       "AS",
+	WS:       "WS",
+	IDENT:    "IDENT",
+	INTEGER:  "INTEGER",
+	NUMBER:   "NUMBER",
+	DURATION: "DURATION",
+	STRING:   "STRING",
 
 	ADD:         "+",
 	SUB:         "-",
@@ -165,6 +185,15 @@ var Tokens = []string{
 	SUBSET: "[]",
 	ARROW:  "->",
 
+	IN:         "IN",
+	NOTIN:      "NOT IN",
+	BETWEEN:    "BETWEEN",
+	NOTBETWEEN: "NOT BETWEEN",
+	LIKE:       "LIKE",
+	NOTLIKE:    "NOT LIKE",
+	IS:         "IS",
+	ISNOT:      "IS NOT",
+
 	ASTERISK: "*",
 	COMMA:    ",",
 
@@ -191,6 +220,11 @@ var Tokens = []string{
 	BY:     "BY",
 	ASC:    "ASC",
 	DESC:   "DESC",
+	LIMIT:  "LIMIT",
+	OFFSET: "OFFSET",
+
+	OVER:      "OVER",
+	PARTITION: "PARTITION",
 
 	CREATE:   "CREATE",
 	DROP:     "RROP",
@@ -222,11 +256,16 @@ var Tokens = []string{
 	TIMESTAMP_FORMAT:  "TIMESTAMP_FORMAT",
 	RETAIN_SIZE:       "RETAIN_SIZE",
 	SHARED:            "SHARED",
+	KIND:              "KIND",
+	SCHEMAID:          "SCHEMAID",
+	DELIMITER:         "DELIMITER",
 
 	AND:   "AND",
 	OR:    "OR",
 	TRUE:  "TRUE",
 	FALSE: "FALSE",
+	NOT:   "NOT",
+	NULL:  "NULL",
 
 	DD: "DD",
 	HH: "HH",
@@ -263,7 +302,7 @@ func (tok Token) Precedence() int {
 		return 1
 	case AND:
 		return 2
-	case EQ, NEQ, LT, LTE, GT, GTE:
+	case EQ, NEQ, LT, LTE, GT, GTE, IN, NOTIN, BETWEEN, NOTBETWEEN, LIKE, NOTLIKE, IS, ISNOT:
 		return 3
 	case ADD, SUB, BITWISE_OR, BITWISE_XOR:
 		return 4
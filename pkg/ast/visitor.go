@@ -77,6 +77,8 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.Length)
 		Walk(v, n.Interval)
 		Walk(v, n.Filter)
+		Walk(v, n.BeginCondition)
+		Walk(v, n.EndCondition)
 
 	case SortFields:
 		for _, sf := range n {
@@ -89,10 +91,39 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.LHS)
 		Walk(v, n.RHS)
 
+	case *UnaryExpr:
+		Walk(v, n.Expr)
+
+	case *ValueSetExpr:
+		for _, e := range n.LiteralExprs {
+			Walk(v, e)
+		}
+
+	case *BetweenExpr:
+		Walk(v, n.Lower)
+		Walk(v, n.Higher)
+
 	case *Call:
 		for _, expr := range n.Args {
 			Walk(v, expr)
 		}
+		if n.Partition != nil {
+			Walk(v, n.Partition)
+		}
+		if n.Over != nil {
+			Walk(v, n.Over)
+		}
+
+	case *OverExpr:
+		if n.Partition != nil {
+			Walk(v, n.Partition)
+		}
+		Walk(v, n.SortFields)
+
+	case *PartitionExpr:
+		for _, expr := range n.Exprs {
+			Walk(v, expr)
+		}
 
 	case *ParenExpr:
 		Walk(v, n.Expr)
@@ -21,13 +21,24 @@ import (
 )
 
 const (
-	FormatBinary = "binary"
-	FormatJson   = "json"
+	FormatBinary    = "binary"
+	FormatJson      = "json"
+	FormatProtobuf  = "protobuf"
+	FormatDelimited = "delimited"
 
 	DefaultField = "self"
 	MetaKey      = "__meta"
 )
 
+// Converter encodes the data into the payload of a format and decodes the payload back
+type Converter interface {
+	Encode(d interface{}) ([]byte, error)
+	Decode(b []byte) (map[string]interface{}, error)
+}
+
+// ConverterCreator creates a converter by the properties such as the schemaId
+type ConverterCreator func(props map[string]interface{}) (Converter, error)
+
 func Decode(payload []byte, format string) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 	switch strings.ToLower(format) {